@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+)
+
+// withinBudget runs fn for the request of glspContext (nil outside of
+// one) but stops waiting for it after budget, so a pathological document
+// can't stall the editor. An abandoned call finishes in the background and
+// its result is dropped.
+func withinBudget[T any](glspContext *glsp.Context, name string, budget time.Duration, fn func() (T, error)) (T, error) {
+	return withinBudgetContext(glspContext, name, budget, func(context.Context) (T, error) {
+		return fn()
+	})
+}
+
+// withinBudgetContext is withinBudget for work that honors cancellation: its
+// context is cancelled once the budget runs out or the client cancels the
+// request, so an abandoned call stops early instead of running to
+// completion.
+func withinBudgetContext[T any](glspContext *glsp.Context, name string, budget time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	id := inflight.add(glspContext, cancel)
+
+	done := make(chan result, 1)
+	go func() {
+		defer inflight.remove(id)
+		value, err := fn(ctx)
+		done <- result{value, err}
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		log.Warn().Str("request", name).Dur("budget", budget).Msg("Request exceeded its time budget, returning no results")
+		var zero T
+		return zero, nil
+	}
+}
+
+// inflight holds the cancel functions of work that is still running, by
+// the jsonrpc ID of the request it belongs to.
+var inflight = &requestSet{
+	requests:  make(map[*glsp.Context]string),
+	pending:   make(map[string]bool),
+	cancelled: make(map[string]bool),
+	cancels:   make(map[int]runningWork),
+}
+
+type requestSet struct {
+	mu        sync.Mutex
+	next      int
+	requests  map[*glsp.Context]string // ID of each request being handled
+	pending   map[string]bool          // requests read but not yet answered
+	cancelled map[string]bool          // pending requests cancelled before their work started
+	cancels   map[int]runningWork
+}
+
+type runningWork struct {
+	request string // "" outside of a request
+	cancel  context.CancelFunc
+}
+
+// expect records that the request with the given ID was read and waits
+// its turn, so that cancelling it before its work starts still counts.
+func (s *requestSet) expect(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[id] = true
+}
+
+// forget drops a pending request that won't be handled.
+func (s *requestSet) forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	delete(s.cancelled, id)
+}
+
+// begin records that glspContext handles the request with the given ID,
+// until end.
+func (s *requestSet) begin(glspContext *glsp.Context, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[glspContext] = id
+}
+
+func (s *requestSet) end(glspContext *glsp.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.requests[glspContext]
+	delete(s.requests, glspContext)
+	delete(s.pending, id)
+	delete(s.cancelled, id)
+}
+
+// add records the cancel function of work for the request of glspContext,
+// cancelling it right away if the request already was.
+func (s *requestSet) add(glspContext *glsp.Context, cancel context.CancelFunc) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request := s.requests[glspContext]
+	if request != "" && s.cancelled[request] {
+		cancel()
+	}
+	s.next++
+	s.cancels[s.next] = runningWork{request: request, cancel: cancel}
+	return s.next
+}
+
+func (s *requestSet) remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cancels, id)
+}
+
+// cancel cancels the running work of the request with the given ID and
+// returns how much there was. The request is also marked while it is
+// pending, so that work it starts later is cancelled at once.
+func (s *requestSet) cancel(request string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if request == "" {
+		return 0
+	}
+	n := 0
+	for id, work := range s.cancels {
+		if work.request == request {
+			work.cancel()
+			delete(s.cancels, id)
+			n++
+		}
+	}
+	if s.pending[request] {
+		s.cancelled[request] = true
+	}
+	return n
+}
+
+// requestID reads the ID of a $/cancelRequest from its params, formatted
+// the way jsonrpc2.ID formats the ID of the request it cancels. glsp's
+// CancelParams can't be used for this: IntegerOrString unmarshals into a
+// copy of itself, so its ID always comes out empty.
+func requestID(params json.RawMessage) (string, error) {
+	var cancel struct {
+		ID jsonrpc2.ID `json:"id"`
+	}
+	if err := json.Unmarshal(params, &cancel); err != nil {
+		return "", err
+	}
+	return cancel.ID.String(), nil
+}
+
+// analysisBudget is the configured wall-clock budget of a single request.
+func analysisBudget() time.Duration {
+	return state.Indexer.Config.Settings.DocumentLimits.Timeout()
+}
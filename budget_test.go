@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestWithinBudget(t *testing.T) {
+	value, err := withinBudget(nil, "fast", time.Second, func() (int, error) {
+		return 42, nil
+	})
+	if value != 42 || err != nil {
+		t.Errorf("Expected the result, got %d, %v", value, err)
+	}
+
+	_, err = withinBudget(nil, "failing", time.Second, func() (int, error) {
+		return 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Error("Expected the error to be passed through")
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	start := time.Now()
+	value, err = withinBudget(nil, "slow", 10*time.Millisecond, func() (int, error) {
+		<-release
+		return 42, nil
+	})
+	if value != 0 || err != nil {
+		t.Errorf("Expected no result after the budget, got %d, %v", value, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected to stop waiting at the budget")
+	}
+}
+
+func TestWithinBudgetContextCancelsAbandonedWork(t *testing.T) {
+	stopped := make(chan struct{})
+	_, err := withinBudgetContext(nil, "slow", 10*time.Millisecond, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(stopped)
+		return 0, ctx.Err()
+	})
+	if err != nil {
+		t.Errorf("Expected no error after the budget, got %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the abandoned call to be cancelled")
+	}
+
+}
+
+func TestCancelRequestStopsOnlyThatRequest(t *testing.T) {
+	start := func(id string) chan struct{} {
+		glspContext := &glsp.Context{}
+		inflight.begin(glspContext, id)
+		started := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer inflight.end(glspContext)
+			withinBudgetContext(glspContext, "slow", time.Minute, func(ctx context.Context) (int, error) {
+				close(started)
+				<-ctx.Done()
+				return 0, nil
+			})
+			close(stopped)
+		}()
+		<-started
+		return stopped
+	}
+	first := start("1")
+	second := start(`"two"`)
+
+	cancel := func(params string) {
+		t.Helper()
+		if err := cancelRequest(&glsp.Context{Params: json.RawMessage(params)}, &protocol.CancelParams{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel(`{"id": 1}`)
+	select {
+	case <-first:
+	case <-time.After(time.Second):
+		t.Fatal("Expected request 1 to be cancelled")
+	}
+	select {
+	case <-second:
+		t.Fatal("Expected request \"two\" to keep running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel(`{"id": "two"}`)
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("Expected request \"two\" to be cancelled")
+	}
+}
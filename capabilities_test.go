@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestInitializeDeclaresCapabilities(t *testing.T) {
+	state = newServerState("rules")
+	result, err := initialize(&glsp.Context{}, &protocol.InitializeParams{})
+	if err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	capabilities := result.(initializeResult).Capabilities
+	if capabilities.DefinitionProvider != true {
+		t.Error("Expected a definition provider")
+	}
+	if capabilities.ReferencesProvider != true {
+		t.Error("Expected a references provider")
+	}
+	if capabilities.CompletionProvider == nil {
+		t.Error("Expected a completion provider")
+	}
+	if capabilities.HoverProvider != true {
+		t.Error("Expected a hover provider")
+	}
+	if capabilities.ExecuteCommandProvider == nil || len(capabilities.ExecuteCommandProvider.Commands) == 0 {
+		t.Error("Expected an execute command provider")
+	}
+	if state.HoverPlainText {
+		t.Error("Expected markdown hovers without a contentFormat")
+	}
+}
+
+func TestHoverFallsBackToPlainText(t *testing.T) {
+	state = newServerState("rules")
+	_, err := initialize(&glsp.Context{}, &protocol.InitializeParams{
+		Capabilities: protocol.ClientCapabilities{
+			TextDocument: &protocol.TextDocumentClientCapabilities{
+				Hover: &protocol.HoverClientCapabilities{ContentFormat: []protocol.MarkupKind{protocol.MarkupKindPlainText}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	if !state.HoverPlainText {
+		t.Fatal("Expected plain text hovers for a plaintext-only client")
+	}
+
+	state.Indexer.IndexContent("/ws/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+`)
+	uri := "file:///ws/deploy.yaml"
+	state.Documents.Set(uri, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: app-config
+`, 1)
+	hover, err := textDocumentHover(&glsp.Context{}, &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 10, Character: 20},
+		},
+	})
+	if err != nil || hover == nil {
+		t.Fatalf("Expected a hover, got %v (err %v)", hover, err)
+	}
+	contents := hover.Contents.(protocol.MarkupContent)
+	if contents.Kind != protocol.MarkupKindPlainText || !strings.HasPrefix(contents.Value, "app-config\n") || strings.Contains(contents.Value, "**") {
+		t.Errorf("Expected the hover as plain text, got %+v", contents)
+	}
+}
+
+func TestMarkdownToPlainText(t *testing.T) {
+	got := markdownToPlainText("## Embedded File: **app.conf**\n\n[Open File](command:k8sLsp.open?x) · [Find Usages](command:k8sLsp.find?y)\n\nSee [docs](https://kubernetes.io) for `data`.\n```yaml\nkey: value\n```\n")
+	want := "Embedded File: app.conf\n\nSee docs (https://kubernetes.io) for data.\nkey: value"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
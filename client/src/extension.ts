@@ -207,6 +207,37 @@ export function activate(context: ExtensionContext) {
           })
         );
 
+        context.subscriptions.push(
+          commands.registerCommand('k8s.showReferences', async (args: any) => {
+            const uriStr = args?.uri as string | undefined;
+            const pos = args?.position as { line: number; character: number } | undefined;
+            if (!uriStr || !pos) {
+              return;
+            }
+
+            const uri = Uri.parse(uriStr);
+            const position = new Position(pos.line, pos.character);
+
+            const lspLocations = await client.sendRequest<any[]>('textDocument/references', {
+              textDocument: { uri: uriStr },
+              position: { line: pos.line, character: pos.character },
+              context: { includeDeclaration: false }
+            });
+
+            const vscodeLocations = [] as any[];
+            if (Array.isArray(lspLocations)) {
+              for (const loc of lspLocations) {
+                const converted = await client.protocol2CodeConverter.asLocation(loc);
+                if (converted) {
+                  vscodeLocations.push(converted);
+                }
+              }
+            }
+
+            await commands.executeCommand('editor.action.showReferences', uri, position, vscodeLocations);
+          })
+        );
+
         context.subscriptions.push(
           commands.registerCommand('k8sLsp.showSubPathTargets', async (args: any) => {
             const uriStr = args?.uri as string | undefined;
@@ -244,6 +275,21 @@ export function activate(context: ExtensionContext) {
             new SubPathDocumentLinkProvider()
           )
         );
+
+        // Embedded files aren't synced to the server (the document selector
+        // only covers files), so forward go-to-definition in them by hand.
+        // The server reads their content from the source document.
+        context.subscriptions.push(
+          languages.registerDefinitionProvider({ scheme: 'k8s-embedded' }, {
+            provideDefinition: async (document, position, token) => {
+              const result = await client.sendRequest<any>('textDocument/definition', {
+                textDocument: { uri: document.uri.toString() },
+                position: { line: position.line, character: position.character }
+              }, token);
+              return client.protocol2CodeConverter.asDefinitionResult(result);
+            }
+          })
+        );
       })
       .catch((err) => {
         outputChannel.appendLine(`Failed to start language client: ${String(err)}`);
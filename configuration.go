@@ -0,0 +1,188 @@
+package main
+
+import (
+	gocontext "context"
+	"strings"
+	"sync"
+
+	"k8s-lsp/pkg/config"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// settingsSection is the client settings section the server reads.
+const settingsSection = "k8sLsp"
+
+// configMu keeps the rules, resolver and validator fixed while they are in
+// use: handlers and background publishing hold it for reading, and a
+// configuration change replaces them holding it for writing. The workspace
+// scans only touch the Store and Indexer, which lock themselves.
+var configMu sync.RWMutex
+
+// lockedHandler runs every message under configMu.
+type lockedHandler struct {
+	glsp.Handler
+}
+
+func (h lockedHandler) Handle(context *glsp.Context) (any, bool, bool, error) {
+	// shutdown waits for background publishing, which may itself be
+	// queued behind a pending configuration change, and $/cancelRequest
+	// must not wait behind one for the request it cancels.
+	if context.Method != string(protocol.MethodShutdown) && context.Method != string(protocol.MethodCancelRequest) {
+		configMu.RLock()
+		defer configMu.RUnlock()
+	}
+	return h.Handler.Handle(context)
+}
+
+func workspaceDidChangeConfiguration(context *glsp.Context, params *protocol.DidChangeConfigurationParams) error {
+	if section, ok := configurationSection(params.Settings); ok {
+		reconfiguring.Add(1)
+		go func() {
+			defer reconfiguring.Done()
+			reconfigure(context, section)
+		}()
+		return nil
+	}
+	if !state.ConfigurationPull {
+		log.Debug().Msg("Ignoring configuration change without a k8sLsp section")
+		return nil
+	}
+
+	// VS Code sends null settings and expects the server to pull them.
+	section := settingsSection
+	reconfiguring.Add(1)
+	go func() {
+		defer reconfiguring.Done()
+		var result []any
+		context.Call(string(protocol.ServerWorkspaceConfiguration), protocol.ConfigurationParams{
+			Items: []protocol.ConfigurationItem{{Section: &section}},
+		}, &result)
+		if len(result) == 0 {
+			log.Warn().Msg("Client returned no configuration")
+			return
+		}
+		reconfigure(context, result[0])
+	}()
+	return nil
+}
+
+// configurationSection picks the k8sLsp section out of didChangeConfiguration
+// settings.
+func configurationSection(settings any) (any, bool) {
+	m, ok := settings.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	section, ok := m[settingsSection]
+	return section, ok && section != nil
+}
+
+// reconfiguring tracks configuration changes being applied, so tests can
+// wait for them.
+var reconfiguring sync.WaitGroup
+
+// reconfigure applies the k8sLsp settings section and re-validates the open
+// documents under the new configuration. Settings the section leaves out
+// keep their current values, whether from initializationOptions or an
+// earlier change.
+func reconfigure(context *glsp.Context, section any) {
+	configMu.Lock()
+	opts := mergeServerOptions(state.options, section)
+	rulesDir := state.rulesDir
+	applyOptions(opts, state.RootPaths)
+	if state.rulesDir != rulesDir {
+		watchRules(context)
+	}
+	docs := state.Documents.All()
+	validating := state.Validator != nil
+	configMu.Unlock()
+	log.Info().Int("documents", len(docs)).Msg("Applied configuration change")
+
+	if !validating && !state.DiagnosticPull {
+		// Clear what was published before validation was turned off.
+		for uri := range docs {
+			context.Notify(string(protocol.ServerTextDocumentPublishDiagnostics), protocol.PublishDiagnosticsParams{
+				URI:         uri,
+				Diagnostics: []protocol.Diagnostic{},
+			})
+		}
+	} else {
+		publishInBackground(context, docs)
+	}
+	scheduleWorkspaceValidation()
+	refreshCodeLenses(context)
+}
+
+// rulesWatchInterval is how often watchRules polls the rules directory.
+var rulesWatchInterval = config.WatchInterval
+
+// watchRules reloads the rules whenever a file in state.rulesDir changes,
+// replacing any previous watch. The new rules take effect for the next
+// request; open documents are re-validated against them, but nothing is
+// re-indexed. The watch ends with the session or the next watchRules.
+func watchRules(context *glsp.Context) {
+	if state.stopRulesWatch != nil {
+		state.stopRulesWatch()
+	}
+	ctx, stop := gocontext.WithCancel(state.scanContext())
+	state.stopRulesWatch = stop
+	rulesDir := state.rulesDir
+	go config.Watch(ctx, rulesDir, rulesWatchInterval, func(cfg *config.Config) {
+		configMu.Lock()
+		if ctx.Err() != nil || state.rulesDir != rulesDir {
+			configMu.Unlock()
+			return
+		}
+		applyRules(rulesDir, cfg)
+		docs := state.Documents.All()
+		configMu.Unlock()
+
+		publishInBackground(context, docs)
+		scheduleWorkspaceValidation()
+		refreshCodeLenses(context)
+	})
+}
+
+// overrideSeverities applies state.SeverityOverrides to diagnostics that
+// carry a code, dropping those set to "off".
+func overrideSeverities(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	if len(state.SeverityOverrides) == 0 {
+		return diagnostics
+	}
+	kept := diagnostics[:0]
+	for _, diag := range diagnostics {
+		if diag.Code != nil {
+			if code, ok := diag.Code.Value.(string); ok {
+				if override, ok := state.SeverityOverrides[code]; ok {
+					if strings.EqualFold(override, "off") {
+						continue
+					}
+					if severity, ok := parseSeverity(override); ok {
+						diag.Severity = &severity
+					} else {
+						log.Warn().Str("code", code).Str("severity", override).Msg("Ignoring unknown severity override")
+					}
+				}
+			}
+		}
+		kept = append(kept, diag)
+	}
+	return kept
+}
+
+func parseSeverity(name string) (protocol.DiagnosticSeverity, bool) {
+	switch strings.ToLower(name) {
+	case "error":
+		return protocol.DiagnosticSeverityError, true
+	case "warning":
+		return protocol.DiagnosticSeverityWarning, true
+	case "information", "info":
+		return protocol.DiagnosticSeverityInformation, true
+	case "hint":
+		return protocol.DiagnosticSeverityHint, true
+	}
+	return 0, false
+}
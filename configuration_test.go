@@ -0,0 +1,208 @@
+package main
+
+import (
+	gocontext "context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s-lsp/pkg/validator"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDidChangeConfigurationAppliesLive(t *testing.T) {
+	state = newServerState("rules")
+	state.Indexer.IndexContent("/ws/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+`)
+	uri := "file:///ws/service.yaml"
+	state.Documents.Set(uri, `apiVersion: v1
+kind: Service
+metadata:
+  name: external
+spec:
+  type: ExternalName
+  selector:
+    app: web
+`, 1)
+
+	var mu sync.Mutex
+	var published []protocol.Diagnostic
+	var pulled any
+	ctx := &glsp.Context{
+		Notify: func(method string, params any) {
+			mu.Lock()
+			defer mu.Unlock()
+			published = params.(protocol.PublishDiagnosticsParams).Diagnostics
+		},
+		Call: func(method string, params any, result any) {
+			if method != string(protocol.ServerWorkspaceConfiguration) {
+				return
+			}
+			*result.(*[]any) = []any{pulled}
+		},
+	}
+	change := func(settings any) []protocol.Diagnostic {
+		t.Helper()
+		if err := workspaceDidChangeConfiguration(ctx, &protocol.DidChangeConfigurationParams{Settings: settings}); err != nil {
+			t.Fatalf("workspaceDidChangeConfiguration failed: %v", err)
+		}
+		reconfiguring.Wait()
+		publishing.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		return published
+	}
+	codes := func(diagnostics []protocol.Diagnostic) map[string]protocol.DiagnosticSeverity {
+		got := make(map[string]protocol.DiagnosticSeverity)
+		for _, diag := range diagnostics {
+			if diag.Code != nil {
+				got[diag.Code.Value.(string)] = *diag.Severity
+			}
+		}
+		return got
+	}
+
+	got := codes(change(map[string]any{settingsSection: map[string]any{
+		"severityOverrides": map[string]any{
+			validator.CodeExternalNameSelector: "hint",
+			validator.CodeExternalNameInvalid:  "off",
+		},
+	}}))
+	if len(got) != 1 || got[validator.CodeExternalNameSelector] != protocol.DiagnosticSeverityHint {
+		t.Errorf("Expected only the selector diagnostic, as a hint, got %v", got)
+	}
+
+	if diagnostics := change(map[string]any{settingsSection: map[string]any{"validation": false}}); len(diagnostics) != 0 || state.Validator != nil {
+		t.Errorf("Expected validation off to clear diagnostics, got %v", diagnostics)
+	}
+
+	// A null payload makes the server pull its section, as VS Code expects.
+	// Settings it leaves out keep their values.
+	state.ConfigurationPull = true
+	pulled = map[string]any{"validation": true}
+	got = codes(change(nil))
+	if len(got) != 1 || got[validator.CodeExternalNameSelector] != protocol.DiagnosticSeverityHint {
+		t.Errorf("Expected validation back on with the overridden severities, got %v", got)
+	}
+
+	got = codes(change(map[string]any{settingsSection: map[string]any{"severityOverrides": map[string]any{}}}))
+	if got[validator.CodeExternalNameSelector] != protocol.DiagnosticSeverityError || got[validator.CodeExternalNameInvalid] != protocol.DiagnosticSeverityWarning {
+		t.Errorf("Expected the default severities once the overrides are cleared, got %v", got)
+	}
+
+	rulesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rulesDir, "k8s.yaml"), []byte("symbols: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rulesDir, "validation.yaml"), []byte("rules: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := state.Store
+	change(map[string]any{settingsSection: map[string]any{"rulesPath": rulesDir}})
+	if state.Store != store || len(state.Store.FindByFile("/ws/config.yaml")) == 0 {
+		t.Error("Expected the Store to survive a rules reload")
+	}
+	if len(state.Indexer.Config.Symbols) != 0 || state.rulesDir != rulesDir {
+		t.Errorf("Expected the rules from %s, got %+v", rulesDir, state.Indexer.Config)
+	}
+}
+
+func TestDidChangeConfigurationIgnoresOtherSections(t *testing.T) {
+	state = newServerState("rules")
+	before := state.Validator
+	ctx := &glsp.Context{
+		Notify: func(string, any) {},
+		Call: func(string, any, any) {
+			t.Error("Expected no workspace/configuration request without pull support")
+		},
+	}
+	for _, settings := range []any{nil, map[string]any{"yaml": map[string]any{"validate": false}}} {
+		if err := workspaceDidChangeConfiguration(ctx, &protocol.DidChangeConfigurationParams{Settings: settings}); err != nil {
+			t.Fatalf("workspaceDidChangeConfiguration failed: %v", err)
+		}
+	}
+	reconfiguring.Wait()
+	if state.Validator != before {
+		t.Error("Expected the configuration to stay unchanged")
+	}
+}
+
+func TestRulesHotReload(t *testing.T) {
+	rulesDir := t.TempDir()
+	write := func(rules string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(rulesDir, "k8s.yaml"), []byte(rules), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`symbols:
+  - name: k8s.resource.name
+    definitions:
+      - kinds: ["ServiceAccount"]
+        path: "metadata.name"
+`)
+	interval := rulesWatchInterval
+	rulesWatchInterval = 10 * time.Millisecond
+	defer func() { rulesWatchInterval = interval }()
+
+	state = newServerState(rulesDir)
+	defer state.stopBackground()
+	ctx := &glsp.Context{Notify: func(string, any) {}, Call: func(string, any, any) {}}
+	if err := initialized(ctx, &protocol.InitializedParams{}); err != nil {
+		t.Fatal(err)
+	}
+	scanning.Wait()
+
+	state.Indexer.IndexContent("/ws/sa.yaml", "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: builder\n")
+	content := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  serviceAccountName: builder\n"
+	define := func() int {
+		configMu.RLock()
+		defer configMu.RUnlock()
+		locs, err := state.Resolver.ResolveDefinition(gocontext.Background(), content, "file:///ws/pod.yaml", 5, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(locs)
+	}
+	if define() != 0 {
+		t.Fatal("Expected no reference rule for serviceAccountName yet")
+	}
+
+	// Keep the modification time from matching the first write.
+	time.Sleep(20 * time.Millisecond)
+	write(`symbols:
+  - name: k8s.resource.name
+    definitions:
+      - kinds: ["ServiceAccount"]
+        path: "metadata.name"
+references:
+  - name: pod.serviceaccount
+    symbol: k8s.resource.name
+    targetKind: ServiceAccount
+    match:
+      kinds: ["Pod"]
+      path: "spec.serviceAccountName"
+`)
+	deadline := time.Now().Add(2 * time.Second)
+	for define() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the edited rules to be picked up without a restart")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Broken rules keep the previous ones in effect.
+	time.Sleep(20 * time.Millisecond)
+	write("references: [\n")
+	time.Sleep(100 * time.Millisecond)
+	if define() != 1 {
+		t.Error("Expected broken rules not to replace the working ones")
+	}
+}
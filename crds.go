@@ -0,0 +1,68 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+)
+
+// crdDownloadTimeout bounds how long a scan waits for the configured CRD
+// sources, so an unreachable one doesn't hold up indexing the workspace.
+var crdDownloadTimeout = 30 * time.Second
+
+// methodCRDsRefreshed is the notification k8s.refreshCRDs sends its
+// summary with once it is done.
+const methodCRDsRefreshed = "k8s/crdsRefreshed"
+
+// CRDRefreshSummary is what k8s.refreshCRDs reports when it completes.
+// Error is the first source that failed, if any did.
+type CRDRefreshSummary struct {
+	Sources int    `json:"sources"`
+	Error   string `json:"error,omitempty"`
+}
+
+// downloadCRDs indexes the CRDs the configured sources serve. Scans call it
+// first so the kinds are registered by the time custom resources in the
+// workspace are indexed. It returns the first source that failed.
+func downloadCRDs() error {
+	if len(state.CRDSources) == 0 {
+		return nil
+	}
+	ctx, cancel := gocontext.WithTimeout(state.scanContext(), crdDownloadTimeout)
+	defer cancel()
+	log.Info().Int("sources", len(state.CRDSources)).Msg("Downloading CRDs")
+	err := state.CRDs.DownloadAndIndex(ctx, state.CRDSources, state.Indexer)
+	if err != nil && !errors.Is(err, gocontext.Canceled) {
+		log.Error().Err(err).Msg("Failed to download CRDs")
+	}
+	return err
+}
+
+// handleRefreshCRDs starts downloading the configured CRD sources again,
+// registering any kinds that appeared, and then indexes the open documents
+// again so custom resources of those kinds resolve. The reply doesn't wait
+// for the download, which sends a CRDRefreshSummary in a
+// k8s/crdsRefreshed notification when done.
+func handleRefreshCRDs(context *glsp.Context) error {
+	summary := &CRDRefreshSummary{Sources: len(state.CRDSources)}
+	scanning.Add(1)
+	go func() {
+		defer scanning.Done()
+		scanGate.RLock()
+		defer scanGate.RUnlock()
+		if err := downloadCRDs(); err != nil {
+			summary.Error = err.Error()
+		}
+		docs := state.Documents.All()
+		for uri, content := range docs {
+			state.Indexer.IndexContent(uriToPath(uri), content)
+		}
+		publishInBackground(context, docs)
+		refreshCodeLenses(context)
+		context.Notify(methodCRDsRefreshed, summary)
+	}()
+	return nil
+}
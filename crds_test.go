@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestCRDSourcesIndexedBeforeScan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A slow source still has to be in before the workspace is scanned.
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+`))
+	}))
+	defer srv.Close()
+
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "widget.yaml"), []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: spinner\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	state = newServerState("rules")
+	defer state.stopBackground()
+	state.RootPaths = []string{ws}
+	state.CRDSources = []string{srv.URL + "/crds.yaml"}
+	ctx := &glsp.Context{Notify: func(string, any) {}, Call: func(string, any, any) {}}
+	if err := initialized(ctx, &protocol.InitializedParams{}); err != nil {
+		t.Fatal(err)
+	}
+	scanning.Wait()
+
+	widgets := state.Store.ListByKind("Widget")
+	if len(widgets) != 1 || widgets[0].Name != "spinner" || widgets[0].FilePath != filepath.Join(ws, "widget.yaml") {
+		t.Fatalf("Expected the workspace's Widget to be indexed on the first scan, got %v", widgets)
+	}
+}
+
+func TestCRDDownloadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	timeout := crdDownloadTimeout
+	crdDownloadTimeout = 20 * time.Millisecond
+	defer func() { crdDownloadTimeout = timeout }()
+
+	state = newServerState("rules")
+	defer state.stopBackground()
+	state.CRDSources = []string{srv.URL}
+
+	done := make(chan struct{})
+	go func() {
+		downloadCRDs()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected an unresponsive CRD source to be given up on")
+	}
+}
+
+func TestRefreshCRDsInBackground(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	timeout := crdDownloadTimeout
+	crdDownloadTimeout = 20 * time.Millisecond
+	defer func() { crdDownloadTimeout = timeout }()
+
+	state = newServerState("rules")
+	defer state.stopBackground()
+	state.CRDSources = []string{srv.URL}
+
+	refreshed := make(chan *CRDRefreshSummary, 1)
+	ctx := &glsp.Context{
+		Notify: func(method string, params any) {
+			if method == methodCRDsRefreshed {
+				refreshed <- params.(*CRDRefreshSummary)
+			}
+		},
+		Call: func(string, any, any) {},
+	}
+	start := time.Now()
+	if err := handleRefreshCRDs(ctx); err != nil {
+		t.Fatalf("handleRefreshCRDs failed: %v", err)
+	}
+	if time.Since(start) > crdDownloadTimeout {
+		t.Error("Expected the command to reply before the download is done")
+	}
+	select {
+	case summary := <-refreshed:
+		if summary.Sources != 1 || summary.Error == "" {
+			t.Errorf("Expected the stalled source to be reported, got %+v", summary)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the refresh to give up on the stalled source")
+	}
+	scanning.Wait()
+	publishing.Wait()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// afterFunc schedules f after d and returns a function that cancels it, as
+// time.AfterFunc(d, f).Stop does. Tests substitute a manual clock.
+type afterFunc func(d time.Duration, f func()) (stop func() bool)
+
+func timeAfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// ChangeDebouncer delays work per document until its edits pause: every
+// Schedule restarts that URI's quiet period, and apply runs once it elapses.
+// apply reads the latest content itself, so only the newest edit is analyzed.
+type ChangeDebouncer struct {
+	delay time.Duration
+	apply func(uri string)
+	after afterFunc
+
+	mu      sync.Mutex
+	pending map[string]*pendingChange
+}
+
+type pendingChange struct {
+	stop func() bool
+}
+
+func NewChangeDebouncer(delay time.Duration, apply func(uri string)) *ChangeDebouncer {
+	return &ChangeDebouncer{
+		delay:   delay,
+		apply:   apply,
+		after:   timeAfterFunc,
+		pending: make(map[string]*pendingChange),
+	}
+}
+
+// Schedule (re)starts the quiet period of uri.
+func (d *ChangeDebouncer) Schedule(uri string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p, ok := d.pending[uri]; ok {
+		p.stop()
+	}
+	p := &pendingChange{}
+	p.stop = d.after(d.delay, func() {
+		d.mu.Lock()
+		// A timer that fired while being replaced must not run.
+		if d.pending[uri] != p {
+			d.mu.Unlock()
+			return
+		}
+		delete(d.pending, uri)
+		d.mu.Unlock()
+		d.apply(uri)
+	})
+	d.pending[uri] = p
+}
+
+// Cancel drops the pending work of uri and reports whether there was any.
+// Callers that apply the document right away use it to bypass the delay.
+func (d *ChangeDebouncer) Cancel(uri string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.pending[uri]
+	if ok {
+		p.stop()
+		delete(d.pending, uri)
+	}
+	return ok
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// manualClock collects scheduled functions so tests decide when they run.
+type manualClock struct {
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	f       func()
+	stopped bool
+	fired   bool
+}
+
+func (c *manualClock) after(d time.Duration, f func()) func() bool {
+	t := &manualTimer{f: f}
+	c.timers = append(c.timers, t)
+	return func() bool {
+		active := !t.stopped && !t.fired
+		t.stopped = true
+		return active
+	}
+}
+
+// fire runs every timer that is neither stopped nor already fired.
+func (c *manualClock) fire() {
+	for _, t := range c.timers {
+		if !t.stopped && !t.fired {
+			t.fired = true
+			t.f()
+		}
+	}
+}
+
+func TestChangeDebouncerCoalescesEdits(t *testing.T) {
+	clock := &manualClock{}
+	var applied []string
+	d := NewChangeDebouncer(300*time.Millisecond, func(uri string) {
+		applied = append(applied, uri)
+	})
+	d.after = clock.after
+
+	// Typing a resource name: one change per keystroke.
+	for i := 0; i < 5; i++ {
+		d.Schedule("file:///a.yaml")
+	}
+	d.Schedule("file:///b.yaml")
+	if len(applied) != 0 {
+		t.Fatalf("Expected nothing applied before the quiet period, got %v", applied)
+	}
+
+	clock.fire()
+	if len(applied) != 2 || applied[0] != "file:///a.yaml" || applied[1] != "file:///b.yaml" {
+		t.Fatalf("Expected each document applied once, got %v", applied)
+	}
+
+	// Cancel drops pending work, e.g. when didSave applies it directly.
+	applied = nil
+	d.Schedule("file:///a.yaml")
+	if !d.Cancel("file:///a.yaml") {
+		t.Error("Expected pending work to be cancelled")
+	}
+	if d.Cancel("file:///a.yaml") {
+		t.Error("Expected nothing left to cancel")
+	}
+	clock.fire()
+	if len(applied) != 0 {
+		t.Errorf("Expected cancelled work not to run, got %v", applied)
+	}
+}
+
+func TestChangeDebouncerIgnoresReplacedTimer(t *testing.T) {
+	clock := &manualClock{}
+	var applied int
+	d := NewChangeDebouncer(300*time.Millisecond, func(uri string) {
+		applied++
+	})
+	d.after = clock.after
+
+	d.Schedule("file:///a.yaml")
+	first := clock.timers[0]
+	d.Schedule("file:///a.yaml")
+
+	// The first timer fires anyway, as a real one can while being stopped.
+	first.f()
+	if applied != 0 {
+		t.Fatalf("Expected the replaced timer to do nothing, got %d", applied)
+	}
+	clock.fire()
+	if applied != 1 {
+		t.Errorf("Expected the latest timer to apply once, got %d", applied)
+	}
+}
+
+func TestChangeDebouncerWithRealTimers(t *testing.T) {
+	done := make(chan string, 1)
+	d := NewChangeDebouncer(10*time.Millisecond, func(uri string) {
+		done <- uri
+	})
+	d.Schedule("file:///a.yaml")
+	select {
+	case uri := <-done:
+		if uri != "file:///a.yaml" {
+			t.Errorf("Expected file:///a.yaml, got %s", uri)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the debounced change to apply")
+	}
+}
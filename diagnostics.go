@@ -0,0 +1,79 @@
+package main
+
+import (
+	"k8s-lsp/pkg/validator"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// DocumentDiagnosticParams are the parameters of textDocument/diagnostic.
+// PreviousResultID is the resultId of the client's last report for the
+// document.
+type DocumentDiagnosticParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	Identifier       *string                         `json:"identifier,omitempty"`
+	PreviousResultID *string                         `json:"previousResultId,omitempty"`
+}
+
+// Document diagnostic report kinds.
+const (
+	DocumentDiagnosticReportKindFull      = "full"
+	DocumentDiagnosticReportKindUnchanged = "unchanged"
+)
+
+// DocumentDiagnosticReport answers textDocument/diagnostic. Items are left
+// out of unchanged reports.
+type DocumentDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId"`
+	Items    []protocol.Diagnostic `json:"items,omitempty"`
+}
+
+// textDocumentDiagnostic validates a document for a client pulling
+// diagnostics. The resultId hashes the diagnostics, so a document whose
+// diagnostics haven't changed since previousResultId gets an unchanged
+// report.
+func textDocumentDiagnostic(context *glsp.Context, params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
+	uri := params.TextDocument.URI
+	log.Debug().Str("uri", uri).Msg("Received diagnostic request")
+
+	var diagnostics []protocol.Diagnostic
+	if content, ok := state.Documents.GetOrLoadFromDisk(uri); ok {
+		diagnostics = documentDiagnostics(uri, content)
+	}
+	resultID := validator.ResultID(diagnostics)
+	if params.PreviousResultID != nil && *params.PreviousResultID == resultID {
+		return &DocumentDiagnosticReport{Kind: DocumentDiagnosticReportKindUnchanged, ResultID: resultID}, nil
+	}
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	return &DocumentDiagnosticReport{Kind: DocumentDiagnosticReportKindFull, ResultID: resultID, Items: diagnostics}, nil
+}
+
+// documentDiagnostics validates content with the severity overrides
+// applied. It is empty, never nil, when validation is off.
+func documentDiagnostics(uri, content string) []protocol.Diagnostic {
+	if state.Validator == nil {
+		return []protocol.Diagnostic{}
+	}
+	diagnostics, _ := withinBudget(nil, "diagnostics", analysisBudget(), func() ([]protocol.Diagnostic, error) {
+		return state.Validator.Validate(uri, content), nil
+	})
+	diagnostics = overrideSeverities(diagnostics)
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	return diagnostics
+}
+
+// refreshDiagnostics asks a pulling client to pull diagnostics again,
+// since they change whenever any file is re-indexed.
+func refreshDiagnostics(context *glsp.Context) {
+	if !state.DiagnosticRefresh {
+		return
+	}
+	go context.Call(methodDiagnosticRefresh, nil, nil)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+func TestPullDiagnostics(t *testing.T) {
+	state = newServerState("rules")
+	refreshed := make(chan string, 10)
+	notified := make(chan string, 10)
+	handler := newHandler()
+	request := func(method, params string) any {
+		t.Helper()
+		result, validMethod, validParams, err := handler.Handle(&glsp.Context{
+			Method: method,
+			Params: json.RawMessage(params),
+			Notify: func(method string, params any) { notified <- method },
+			Call:   func(method string, params any, result any) { refreshed <- method },
+		})
+		if !validMethod || !validParams || err != nil {
+			t.Fatalf("Expected %s to be handled, got %v %v %v", method, validMethod, validParams, err)
+		}
+		return result
+	}
+
+	result := request("initialize", `{"capabilities":{"textDocument":{"diagnostic":{}},"workspace":{"diagnostics":{"refreshSupport":true}}}}`)
+	if result.(initializeResult).Capabilities.DiagnosticProvider == nil {
+		t.Error("Expected a diagnostic provider")
+	}
+	if !state.DiagnosticPull || !state.DiagnosticRefresh {
+		t.Fatal("Expected the client to pull diagnostics")
+	}
+
+	uri := "file:///ws/deploy.yaml"
+	content := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      serviceAccountName: builder
+  selector: {matchLabels: {app: web}}
+`
+	state.Documents.Set(uri, content, 1)
+	params := `{"textDocument":{"uri":"file:///ws/deploy.yaml"}}`
+	first := request(methodTextDocumentDiagnostic, params).(*DocumentDiagnosticReport)
+	if first.Kind != DocumentDiagnosticReportKindFull || len(first.Items) != 1 || first.ResultID == "" {
+		t.Fatalf("Expected a full report with the missing ServiceAccount, got %+v", first)
+	}
+
+	withPrevious := `{"textDocument":{"uri":"file:///ws/deploy.yaml"},"previousResultId":"` + first.ResultID + `"}`
+	again := request(methodTextDocumentDiagnostic, withPrevious).(*DocumentDiagnosticReport)
+	if again.Kind != DocumentDiagnosticReportKindUnchanged || again.ResultID != first.ResultID || again.Items != nil {
+		t.Errorf("Expected an unchanged report, got %+v", again)
+	}
+
+	// Indexing the ServiceAccount changes the result; pulling clients are
+	// asked to refresh instead of being pushed diagnostics.
+	state.Indexer.IndexContent("/ws/sa.yaml", "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: builder\n")
+	publishInBackground(&glsp.Context{
+		Notify: func(method string, params any) { notified <- method },
+		Call:   func(method string, params any, result any) { refreshed <- method },
+	}, map[string]string{uri: content})
+	publishing.Wait()
+	select {
+	case method := <-refreshed:
+		if method != methodDiagnosticRefresh {
+			t.Errorf("Expected a diagnostic refresh, got %s", method)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a diagnostic refresh")
+	}
+	select {
+	case method := <-notified:
+		t.Errorf("Expected nothing pushed to a pulling client, got %s", method)
+	default:
+	}
+
+	fixed := request(methodTextDocumentDiagnostic, withPrevious).(*DocumentDiagnosticReport)
+	if fixed.Kind != DocumentDiagnosticReportKindFull || len(fixed.Items) != 0 || fixed.ResultID == first.ResultID {
+		t.Errorf("Expected a new, empty full report, got %+v", fixed)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/sha256"
+	"os"
+	"strings"
+	"sync"
+
+	"k8s-lsp/pkg/position"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// DocumentStore holds the text and version of documents opened in the client.
+// It is written from didOpen/didChange/didClose and read concurrently from
+// request handlers and diagnostics goroutines.
+type DocumentStore struct {
+	mu       sync.RWMutex
+	docs     map[string]string
+	versions map[string]protocol.Integer
+	// applied is the hash of the text each document was last indexed and
+	// validated with.
+	applied map[string][sha256.Size]byte
+}
+
+func NewDocumentStore() *DocumentStore {
+	return &DocumentStore{
+		docs:     make(map[string]string),
+		versions: make(map[string]protocol.Integer),
+		applied:  make(map[string][sha256.Size]byte),
+	}
+}
+
+// Get returns the in-memory text of an open document.
+func (d *DocumentStore) Get(uri string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	content, ok := d.docs[uri]
+	return content, ok
+}
+
+// GetWithVersion returns the text and client version of an open document.
+func (d *DocumentStore) GetWithVersion(uri string) (string, protocol.Integer, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	content, ok := d.docs[uri]
+	if !ok {
+		return "", 0, false
+	}
+	return content, d.versions[uri], true
+}
+
+func (d *DocumentStore) Set(uri, content string, version protocol.Integer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.docs[uri] = content
+	d.versions[uri] = version
+}
+
+// Update replaces a document's text with fn applied to its current text
+// (empty if the document isn't open) and returns the new text.
+func (d *DocumentStore) Update(uri string, version protocol.Integer, fn func(string) string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	content := fn(d.docs[uri])
+	d.docs[uri] = content
+	d.versions[uri] = version
+	return content
+}
+
+func (d *DocumentStore) Delete(uri string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.docs, uri)
+	delete(d.versions, uri)
+	delete(d.applied, uri)
+}
+
+// MarkApplied records content as what uri was last indexed and validated
+// with.
+func (d *DocumentStore) MarkApplied(uri, content string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.applied[uri] = sha256.Sum256([]byte(content))
+}
+
+// Applied reports whether uri was last indexed and validated with content.
+func (d *DocumentStore) Applied(uri, content string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	hash, ok := d.applied[uri]
+	return ok && hash == sha256.Sum256([]byte(content))
+}
+
+// All returns a snapshot of every open document keyed by URI.
+func (d *DocumentStore) All() map[string]string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	docs := make(map[string]string, len(d.docs))
+	for uri, content := range d.docs {
+		docs[uri] = content
+	}
+	return docs
+}
+
+// GetOrLoadFromDisk returns the open document's text, falling back to reading
+// file:// URIs from disk for documents the client hasn't opened. Disk reads
+// are not cached so they never shadow later edits made outside the editor.
+func (d *DocumentStore) GetOrLoadFromDisk(uri string) (string, bool) {
+	if content, ok := d.Get(uri); ok {
+		return content, true
+	}
+	return loadFromDisk(uri)
+}
+
+// loadFromDisk reads the file behind a file:// URI.
+func loadFromDisk(uri string) (string, bool) {
+	path, ok := fileURIPath(uri)
+	if !ok {
+		return "", false
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(bytes), true
+}
+
+// applyContentChanges applies didChange content changes in order. Ranged
+// changes are spliced into the text; a change without a range replaces the
+// whole document.
+func applyContentChanges(content string, changes []any) string {
+	for _, change := range changes {
+		switch c := change.(type) {
+		case protocol.TextDocumentContentChangeEventWhole:
+			content = c.Text
+		case protocol.TextDocumentContentChangeEvent:
+			if c.Range == nil {
+				content = c.Text
+				continue
+			}
+			start := positionOffset(content, c.Range.Start)
+			end := positionOffset(content, c.Range.End)
+			if end < start {
+				start, end = end, start
+			}
+			content = content[:start] + c.Text + content[end:]
+		}
+	}
+	return content
+}
+
+// positionOffset converts an LSP position, in the client's encoding, into a
+// byte offset. Positions past the end of a line or document are clamped.
+func positionOffset(content string, pos protocol.Position) int {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		idx := strings.IndexByte(content[offset:], '\n')
+		if idx < 0 {
+			return len(content)
+		}
+		offset += idx + 1
+	}
+
+	line := content[offset:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return offset + position.ToByte(line, pos.Character, clientEncoding())
+}
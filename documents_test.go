@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/resolver"
+	"k8s-lsp/pkg/uri"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDocumentStoreConcurrentChangeAndHover(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	state = &ServerState{
+		Store:     store,
+		Indexer:   indexer.NewIndexer(store, cfg),
+		Resolver:  resolver.NewResolver(store, cfg),
+		Documents: NewDocumentStore(),
+	}
+
+	const uri = "file:///tmp/race.yaml"
+	ctx := &glsp.Context{}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			_ = textDocumentDidChange(ctx, &protocol.DidChangeTextDocumentParams{
+				TextDocument: protocol.VersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+					Version:                protocol.Integer(i),
+				},
+				ContentChanges: []any{protocol.TextDocumentContentChangeEventWhole{
+					Text: fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-%d\n", i),
+				}},
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			_, _ = textDocumentHover(ctx, &protocol.HoverParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+					Position:     protocol.Position{Line: 3, Character: 9},
+				},
+			})
+		}
+	}()
+	wg.Wait()
+	publishing.Wait()
+
+	content, version, ok := state.Documents.GetWithVersion(uri)
+	if !ok || version != 199 || content != "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-199\n" {
+		t.Fatalf("Unexpected final document state: %q (version %d, ok %v)", content, version, ok)
+	}
+}
+
+func TestDocumentStoreDeleteFallsBackToDisk(t *testing.T) {
+	docs := NewDocumentStore()
+	docs.Set("untitled:scratch", "kind: ConfigMap", 1)
+	docs.Delete("untitled:scratch")
+
+	if _, ok := docs.GetOrLoadFromDisk("untitled:scratch"); ok {
+		t.Fatal("Expected closed non-file document to be unavailable")
+	}
+}
+
+func rangeChange(startLine, startChar, endLine, endChar uint32, text string) protocol.TextDocumentContentChangeEvent {
+	return protocol.TextDocumentContentChangeEvent{
+		Range: &protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startChar},
+			End:   protocol.Position{Line: endLine, Character: endChar},
+		},
+		Text: text,
+	}
+}
+
+func TestApplyContentChanges_SingleLineEdit(t *testing.T) {
+	content := "kind: ConfigMap\nmetadata:\n  name: app\n"
+
+	got := applyContentChanges(content, []any{rangeChange(2, 8, 2, 11, "web")})
+	if want := "kind: ConfigMap\nmetadata:\n  name: web\n"; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+
+	// Characters are counted in UTF-16 code units.
+	got = applyContentChanges("a: \"😀x\"\n", []any{rangeChange(0, 6, 0, 7, "y")})
+	if want := "a: \"😀y\"\n"; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestApplyContentChanges_MultiLineReplacement(t *testing.T) {
+	content := "kind: ConfigMap\nmetadata:\n  name: app\ndata:\n  a: b\n"
+
+	got := applyContentChanges(content, []any{
+		rangeChange(1, 0, 3, 5, "metadata:\n  name: app\n  namespace: prod\ndata:"),
+		rangeChange(5, 2, 5, 6, "c: d"),
+	})
+	want := "kind: ConfigMap\nmetadata:\n  name: app\n  namespace: prod\ndata:\n  c: d\n"
+	if got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+
+	// A change without a range replaces the whole document.
+	got = applyContentChanges(content, []any{protocol.TextDocumentContentChangeEventWhole{Text: "kind: Secret\n"}})
+	if got != "kind: Secret\n" {
+		t.Fatalf("Expected full replacement, got %q", got)
+	}
+}
+
+func TestDidSaveReloadsFromDisk(t *testing.T) {
+	state = newServerState("rules")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	configMap := func(name string) string {
+		return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n"
+	}
+	if err := os.WriteFile(path, []byte(configMap("before")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	documentURI := uri.FromPath(path)
+	var published atomic.Int32
+	ctx := &glsp.Context{Notify: func(string, any) { published.Add(1) }}
+	save := func(text *string) {
+		t.Helper()
+		if err := textDocumentDidSave(ctx, &protocol.DidSaveTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: documentURI}, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+		publishing.Wait()
+	}
+
+	if err := textDocumentDidOpen(ctx, &protocol.DidOpenTextDocumentParams{TextDocument: protocol.TextDocumentItem{URI: documentURI, Version: 1, Text: configMap("before")}}); err != nil {
+		t.Fatal(err)
+	}
+	publishing.Wait()
+
+	// Nothing changed: no re-indexing or publishing.
+	published.Store(0)
+	save(nil)
+	if published.Load() != 0 {
+		t.Errorf("Expected an unchanged save to do nothing, got %d publishes", published.Load())
+	}
+
+	// A formatter or checkout rewrote the file behind the editor's back.
+	if err := os.WriteFile(path, []byte(configMap("after")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	save(nil)
+	if content, _ := state.Documents.Get(documentURI); content != configMap("after") {
+		t.Errorf("Expected the document to be reloaded from disk, got %q", content)
+	}
+	if state.Store.Get("ConfigMap", "default", "after") == nil || state.Store.Get("ConfigMap", "default", "before") != nil {
+		t.Error("Expected the index to follow the file on disk")
+	}
+	if published.Load() == 0 {
+		t.Error("Expected diagnostics to be re-published")
+	}
+
+	// Included text wins over the disk.
+	text := configMap("included")
+	save(&text)
+	if state.Store.Get("ConfigMap", "default", "included") == nil {
+		t.Error("Expected the text sent with didSave to be indexed")
+	}
+}
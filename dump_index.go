@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+)
+
+// maxDumpResources caps the resources k8s.dumpIndex returns.
+const maxDumpResources = 2000
+
+// DumpIndexParams optionally narrows k8s.dumpIndex; empty fields match
+// everything.
+type DumpIndexParams struct {
+	Kind         string `json:"kind"`
+	Namespace    string `json:"namespace"`
+	NameContains string `json:"nameContains"`
+}
+
+// IndexDump is the result of k8s.dumpIndex. Total counts the matching
+// resources, of which at most maxDumpResources are listed.
+type IndexDump struct {
+	Resources []DumpedResource `json:"resources"`
+	Total     int              `json:"total"`
+	Truncated bool             `json:"truncated"`
+}
+
+type DumpedResource struct {
+	Kind       string            `json:"kind"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Name       string            `json:"name"`
+	File       string            `json:"file"`
+	Line       int               `json:"line"`
+	Col        int               `json:"col"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	References []DumpedReference `json:"references,omitempty"`
+}
+
+type DumpedReference struct {
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Symbol    string `json:"symbol,omitempty"`
+	Line      int    `json:"line"`
+	Col       int    `json:"col"`
+}
+
+// handleDumpIndex returns the Store as JSON, for debugging lookups that
+// don't find what they should.
+func handleDumpIndex(context *glsp.Context, params *DumpIndexParams) (*IndexDump, error) {
+	settings := state.Indexer.Config.Settings
+	var matches []indexer.K8sResource
+	for _, res := range state.Store.Snapshot() {
+		if params.Kind != "" && !strings.EqualFold(res.Kind, params.Kind) {
+			continue
+		}
+		if params.Namespace != "" && settings.CanonicalNamespace(res.Namespace) != settings.CanonicalNamespace(params.Namespace) {
+			continue
+		}
+		if params.NameContains != "" && !strings.Contains(res.Name, params.NameContains) {
+			continue
+		}
+		matches = append(matches, res)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.FilePath < b.FilePath
+	})
+
+	dump := &IndexDump{Resources: []DumpedResource{}, Total: len(matches)}
+	if len(matches) > maxDumpResources {
+		matches = matches[:maxDumpResources]
+		dump.Truncated = true
+	}
+	for _, res := range matches {
+		dumped := DumpedResource{
+			Kind:      res.Kind,
+			Namespace: res.Namespace,
+			Name:      res.Name,
+			File:      res.FilePath,
+			Line:      res.Line,
+			Col:       res.Col,
+			Labels:    res.Labels,
+		}
+		for _, ref := range res.References {
+			dumped.References = append(dumped.References, DumpedReference{
+				Kind:      ref.Kind,
+				Name:      ref.Name,
+				Key:       ref.Key,
+				Namespace: ref.Namespace,
+				Symbol:    ref.Symbol,
+				Line:      ref.Line,
+				Col:       ref.Col,
+			})
+		}
+		dump.Resources = append(dump.Resources, dumped)
+	}
+	log.Debug().Int("resources", len(dump.Resources)).Int("total", dump.Total).Msg("Dumped index")
+	return dump, nil
+}
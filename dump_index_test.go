@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	"github.com/tliron/glsp"
+)
+
+func TestDumpIndex(t *testing.T) {
+	cfg, err := config.Load(".")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	state = &ServerState{Store: store, Indexer: indexer.NewIndexer(store, cfg)}
+	state.Indexer.IndexContent("/ws/app.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: web
+  labels:
+    app: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: MODE
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+                  key: mode
+`)
+	state.Indexer.IndexContent("/ws/other.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other-config
+`)
+
+	ctx := &glsp.Context{}
+	dump, err := handleDumpIndex(ctx, &DumpIndexParams{Kind: "deployment", Namespace: "web"})
+	if err != nil {
+		t.Fatalf("handleDumpIndex failed: %v", err)
+	}
+	if dump.Total != 1 || dump.Truncated || dump.Resources[0].Name != "app" {
+		t.Fatalf("Expected the app Deployment only, got %+v", dump)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"file":"/ws/app.yaml"`, `"labels":{"app":"web"}`, `"key":"mode"`, `"name":"app-config"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in %s", want, data)
+		}
+	}
+
+	dump, err = handleDumpIndex(ctx, &DumpIndexParams{NameContains: "config"})
+	if err != nil {
+		t.Fatalf("handleDumpIndex failed: %v", err)
+	}
+	if dump.Total != 2 || dump.Resources[0].Name != "other-config" || dump.Resources[1].Name != "app-config" {
+		t.Errorf("Expected both ConfigMaps, sorted by namespace, got %+v", dump.Resources)
+	}
+}
+
+func TestDumpIndexTruncates(t *testing.T) {
+	store := indexer.NewStore()
+	state = &ServerState{Store: store, Indexer: indexer.NewIndexer(store, &config.Config{})}
+	for i := 0; i <= maxDumpResources; i++ {
+		store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: fmt.Sprintf("cm-%d", i)})
+	}
+	dump, err := handleDumpIndex(&glsp.Context{}, &DumpIndexParams{})
+	if err != nil {
+		t.Fatalf("handleDumpIndex failed: %v", err)
+	}
+	if !dump.Truncated || dump.Total != maxDumpResources+1 || len(dump.Resources) != maxDumpResources {
+		t.Errorf("Expected %d of %d resources, truncated; got %d of %d (truncated %v)", maxDumpResources, maxDumpResources+1, len(dump.Resources), dump.Total, dump.Truncated)
+	}
+}
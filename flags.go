@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// cliOptions are the command line flags. Flags left unset fall back to
+// their K8S_LSP_* environment variable, for editors that can't pass
+// arguments, and then to the built-in default.
+type cliOptions struct {
+	Listen    string
+	KeepStore bool
+	LogLevel  zerolog.Level
+	LogFile   string
+	RulesDir  string
+	Version   bool
+}
+
+// logLevels are the levels --log-level accepts.
+var logLevels = map[string]zerolog.Level{
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+}
+
+// parseFlags parses args (without the program name). lookupEnv is
+// os.LookupEnv outside of tests.
+func parseFlags(args []string, lookupEnv func(string) (string, bool), output io.Writer) (cliOptions, error) {
+	env := func(name, fallback string) string {
+		if v, ok := lookupEnv(name); ok {
+			return v
+		}
+		return fallback
+	}
+
+	var opts cliOptions
+	var level string
+	fs := flag.NewFlagSet(lsName, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.Listen, "listen", env("K8S_LSP_LISTEN", "stdio"), "transport: stdio, tcp://host:port or unix:///path/to/socket (K8S_LSP_LISTEN)")
+	fs.BoolVar(&opts.KeepStore, "keep-store", false, "keep the index across client sessions when listening on a socket")
+	fs.StringVar(&level, "log-level", env("K8S_LSP_LOG_LEVEL", "info"), "log level: debug, info, warn or error (K8S_LSP_LOG_LEVEL)")
+	fs.StringVar(&opts.LogFile, "log-file", env("K8S_LSP_LOG_FILE", getLogFilePath()), "log file, rotated at 10MB; empty logs to stderr only (K8S_LSP_LOG_FILE)")
+	fs.StringVar(&opts.RulesDir, "rules-dir", env("K8S_LSP_RULES_DIR", ""), "rules directory, by default the rules next to the executable (K8S_LSP_RULES_DIR)")
+	fs.BoolVar(&opts.Version, "version", false, "print the version and exit")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	l, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return opts, fmt.Errorf("invalid log level %q: want debug, info, warn or error", level)
+	}
+	opts.LogLevel = l
+	return opts, nil
+}
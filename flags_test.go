@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseFlags(t *testing.T) {
+	noEnv := func(string) (string, bool) { return "", false }
+	opts, err := parseFlags(nil, noEnv, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Listen != "stdio" || opts.LogLevel != zerolog.InfoLevel || opts.LogFile != getLogFilePath() || opts.RulesDir != "" || opts.Version {
+		t.Errorf("Unexpected defaults: %+v", opts)
+	}
+
+	env := map[string]string{
+		"K8S_LSP_LOG_LEVEL": "warn",
+		"K8S_LSP_LOG_FILE":  "",
+		"K8S_LSP_RULES_DIR": "/etc/k8s-lsp/rules",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	opts, err = parseFlags(nil, lookupEnv, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.LogLevel != zerolog.WarnLevel || opts.LogFile != "" || opts.RulesDir != "/etc/k8s-lsp/rules" {
+		t.Errorf("Expected the environment to fill in unset flags, got %+v", opts)
+	}
+
+	opts, err = parseFlags([]string{"--log-level", "ERROR", "--log-file", "/var/log/k8s-lsp.log", "--version"}, lookupEnv, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.LogLevel != zerolog.ErrorLevel || opts.LogFile != "/var/log/k8s-lsp.log" || !opts.Version {
+		t.Errorf("Expected flags to win over the environment, got %+v", opts)
+	}
+
+	if _, err := parseFlags([]string{"--log-level", "trace"}, noEnv, io.Discard); err == nil || !strings.Contains(err.Error(), "invalid log level") {
+		t.Errorf("Expected an invalid log level to be rejected, got %v", err)
+	}
+	if _, err := parseFlags([]string{"rules"}, noEnv, io.Discard); err == nil {
+		t.Error("Expected a stray argument to be rejected")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "k8s-lsp.log")
+	file, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for p, want := range expected {
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Expected %s to hold %q, got %q", filepath.Base(p), want, got)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only two backups to be kept, got %v", err)
+	}
+
+	// Reopening appends to the current file, counting what it holds.
+	file, err = openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("fifth\n"))
+	file.Close()
+	if got, _ := os.ReadFile(path + ".1"); string(got) != "fourth\n" {
+		t.Errorf("Expected the reopened file to rotate by its existing size, got %q in the newest backup", got)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// markdownLink matches [text](target).
+var markdownLink = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+
+// markdownToPlainText flattens a markdown hover for clients that only
+// render plain text. Command links only work in markdown, so they are
+// dropped; other links keep their target after the text.
+func markdownToPlainText(markdown string) string {
+	var lines []string
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		hadLink := markdownLink.MatchString(line)
+		line = markdownLink.ReplaceAllStringFunc(line, func(link string) string {
+			m := markdownLink.FindStringSubmatch(link)
+			if strings.HasPrefix(m[2], "command:") {
+				return ""
+			}
+			return m[1] + " (" + m[2] + ")"
+		})
+		// A line left with only the separators between command links goes.
+		if hadLink && strings.Trim(line, " ·|") == "" {
+			continue
+		}
+		line = strings.NewReplacer("**", "", "__", "", "`", "").Replace(line)
+		if heading := strings.TrimLeft(line, "#"); heading != line {
+			line = strings.TrimLeft(heading, " ")
+		}
+		line = strings.TrimRight(line, " ")
+		if line == "" && len(lines) > 0 && lines[len(lines)-1] == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
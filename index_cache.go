@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"k8s-lsp/pkg/indexer"
+
+	"github.com/rs/zerolog/log"
+)
+
+// userCacheDir is where the index cache is kept, os.UserCacheDir outside
+// of tests.
+var userCacheDir = os.UserCacheDir
+
+// indexCacheFlushChanges is how many re-indexed files make watched changes
+// save the index cache right away rather than with the next scan or at
+// shutdown.
+const indexCacheFlushChanges = 20
+
+// indexCachePath is the cache file of the workspace with roots, under the
+// user's cache directory.
+func indexCachePath(roots []string) (string, error) {
+	dir, err := userCacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := indexer.CacheKey(slices.Sorted(slices.Values(roots)))
+	return filepath.Join(dir, "k8s-lsp", "index", key+".json"), nil
+}
+
+// openIndexCache loads the index cache of the workspace with roots for the
+// scans to use, unless the client turned it off.
+func openIndexCache(roots []string) {
+	if state.options.indexCacheOff() || len(roots) == 0 {
+		return
+	}
+	path, err := indexCachePath(roots)
+	if err != nil {
+		log.Warn().Err(err).Msg("No cache directory; indexing without a cache")
+		return
+	}
+	state.Indexer.SetCache(indexer.LoadIndexCache(path))
+}
+
+// saveIndexCache saves the index cache once at least minChanges files were
+// indexed differently since it was last saved.
+func saveIndexCache(minChanges int) {
+	if err := state.Indexer.SaveCache(minChanges); err != nil {
+		log.Warn().Err(err).Msg("Failed to save index cache")
+	}
+}
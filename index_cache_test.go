@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestIndexCacheSavedAfterScan(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "cm.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := indexCachePath([]string{ws})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := func(opts ServerOptions) {
+		t.Helper()
+		state = newServerState("rules")
+		state.RootPaths = []string{ws}
+		state.options = opts
+		ctx := &glsp.Context{Notify: func(string, any) {}, Call: func(string, any, any) {}}
+		if err := initialized(ctx, &protocol.InitializedParams{}); err != nil {
+			t.Fatal(err)
+		}
+		scanning.Wait()
+		state.stopBackground()
+	}
+
+	off := false
+	start(ServerOptions{IndexCache: &off})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected no cache with indexCache off, got %v", err)
+	}
+
+	start(ServerOptions{})
+	if n := indexer.LoadIndexCache(path).Len(); n != 1 {
+		t.Fatalf("Expected the scanned file to be cached, got %d entries", n)
+	}
+	// A corrupt cache is indexed around and replaced.
+	if err := os.WriteFile(path, []byte("{corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	start(ServerOptions{})
+	if len(state.Store.ListByKind("ConfigMap")) != 1 {
+		t.Fatal("Expected a corrupt cache to be ignored")
+	}
+	if n := indexer.LoadIndexCache(path).Len(); n != 1 {
+		t.Errorf("Expected the corrupt cache to be replaced, got %d entries", n)
+	}
+}
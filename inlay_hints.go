@@ -0,0 +1,30 @@
+package main
+
+import (
+	"k8s-lsp/pkg/resolver"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// InlayHintParams are the parameters of textDocument/inlayHint.
+type InlayHintParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+func textDocumentInlayHint(context *glsp.Context, params *InlayHintParams) ([]resolver.InlayHint, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Int("start", int(params.Range.Start.Line)).Int("end", int(params.Range.End.Line)).Msg("Received inlay hint request")
+
+	if state.options.inlayHintsOff() {
+		return nil, nil
+	}
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	return withinBudget(context, "inlayHint", analysisBudget(), func() ([]resolver.InlayHint, error) {
+		return state.Resolver.InlayHints(content, params.TextDocument.URI, params.Range), nil
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s-lsp/pkg/resolver"
+
+	"github.com/tliron/glsp"
+)
+
+func TestInlayHintRequest(t *testing.T) {
+	state = newServerState("rules")
+	handler := newHandler()
+	result, _, _, err := handler.Handle(&glsp.Context{Method: "initialize", Params: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	if !result.(initializeResult).Capabilities.InlayHintProvider {
+		t.Error("Expected an inlay hint provider")
+	}
+
+	uri := "file:///ws/deploy.yaml"
+	state.Documents.Set(uri, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: app-config
+`, 1)
+	request := &glsp.Context{
+		Method: methodTextDocumentInlayHint,
+		Params: json.RawMessage(`{"textDocument":{"uri":"file:///ws/deploy.yaml"},"range":{"start":{"line":0,"character":0},"end":{"line":20,"character":0}}}`),
+	}
+	result, validMethod, validParams, err := handler.Handle(request)
+	if !validMethod || !validParams || err != nil {
+		t.Fatalf("Expected the inlay hint request to be handled, got %v %v %v", validMethod, validParams, err)
+	}
+	hints := result.([]resolver.InlayHint)
+	if len(hints) != 1 || hints[0].Label != "→ not found" {
+		t.Errorf("Expected a not found hint, got %+v", hints)
+	}
+
+	off := false
+	state.options.InlayHints = &off
+	result, _, _, err = handler.Handle(request)
+	if err != nil || len(result.([]resolver.InlayHint)) != 0 {
+		t.Errorf("Expected no hints when disabled, got %v (err %v)", result, err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// shutdownTimeout bounds how long shutdown waits for background work.
+const shutdownTimeout = 5 * time.Second
+
+// lifecycle follows the shutdown/exit handshake of a client session. Per
+// the spec, the process exits with 0 when exit follows a shutdown request
+// and with 1 otherwise, including when the client just goes away.
+type lifecycle struct {
+	shutdownRequested atomic.Bool
+}
+
+var serverLifecycle lifecycle
+
+func (l *lifecycle) exitCode() int {
+	if l.shutdownRequested.Load() {
+		return 0
+	}
+	return 1
+}
+
+// reset starts the lifecycle of a new client session.
+func (l *lifecycle) reset() {
+	l.shutdownRequested.Store(false)
+}
+
+// shutdown stops background scans, waits a bounded time for indexing,
+// publishing and configuration changes to finish, and flushes the log.
+func shutdown(context *glsp.Context) error {
+	serverLifecycle.shutdownRequested.Store(true)
+	protocol.SetTraceValue(protocol.TraceValueOff)
+	if state.stopBackground != nil {
+		state.stopBackground()
+	}
+	if !waitTimeout(shutdownTimeout, &reconfiguring, &scanning, &publishing) {
+		log.Warn().Dur("timeout", shutdownTimeout).Msg("Shutting down with background work still running")
+	}
+	saveIndexCache(1)
+	flushLog()
+	return nil
+}
+
+// exit only logs; the connection closes once it is handled, and main then
+// exits with serverLifecycle.exitCode().
+func exit(context *glsp.Context) error {
+	log.Info().Int("code", serverLifecycle.exitCode()).Msg("Exit requested")
+	return nil
+}
+
+// waitTimeout waits for groups in order, giving up once timeout has passed
+// in total. It reports whether all of them finished.
+func waitTimeout(timeout time.Duration, groups ...*sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, wg := range groups {
+			wg.Wait()
+		}
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// logFile is the log file main opened, nil when logging to stderr only.
+var logFile *rotatingFile
+
+// flushLog writes buffered log lines through to the log file.
+func flushLog() {
+	if logFile != nil {
+		logFile.Sync()
+	}
+}
+
+// closeLog flushes and closes the log file before the process exits.
+// Anything logged afterwards goes to stderr.
+func closeLog() {
+	if logFile == nil {
+		return
+	}
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, NoColor: true})
+	logFile.Sync()
+	logFile.Close()
+	logFile = nil
+}
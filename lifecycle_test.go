@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+func TestLifecycleExitCodes(t *testing.T) {
+	state = newServerState("rules")
+	serverLifecycle.reset()
+	handler := newHandler()
+	handle := func(method string) {
+		t.Helper()
+		_, validMethod, validParams, err := handler.Handle(&glsp.Context{
+			Method: method,
+			Params: json.RawMessage(`null`),
+			Notify: func(method string, params any) {},
+			Call:   func(method string, params any, result any) {},
+		})
+		if !validMethod || !validParams || err != nil {
+			t.Fatalf("Expected %s to be handled, got %v %v %v", method, validMethod, validParams, err)
+		}
+	}
+
+	handle("initialize")
+	handle("exit")
+	if code := serverLifecycle.exitCode(); code != 1 {
+		t.Errorf("Expected exit without shutdown to exit with 1, got %d", code)
+	}
+
+	handle("shutdown")
+	if state.background.Err() == nil {
+		t.Error("Expected shutdown to cancel background scans")
+	}
+	handle("exit")
+	if code := serverLifecycle.exitCode(); code != 0 {
+		t.Errorf("Expected exit after shutdown to exit with 0, got %d", code)
+	}
+
+	serverLifecycle.reset()
+	if code := serverLifecycle.exitCode(); code != 1 {
+		t.Errorf("Expected a new session to start over, got exit code %d", code)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	var done, stuck sync.WaitGroup
+	if !waitTimeout(time.Second, &done) {
+		t.Error("Expected an idle group to be waited out")
+	}
+
+	stuck.Add(1)
+	defer stuck.Done()
+	start := time.Now()
+	if waitTimeout(50*time.Millisecond, &done, &stuck) {
+		t.Error("Expected a stuck group to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected waitTimeout to give up after its timeout, took %v", elapsed)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Log files are rotated once they reach logMaxSize, keeping logBackups
+// older files next to them as <path>.1 (the newest) to <path>.N.
+const (
+	logMaxSize = 10 << 20
+	logBackups = 3
+)
+
+// rotatingFile is an append-only log file that rotates by size.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first when p would take the file past its
+// maximum size. A single write is never split across files.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups up by one, dropping the oldest, and starts a
+// new file at path. Missing backups are fine, so rename errors are
+// ignored.
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	r.file = nil
+	os.Remove(backupPath(r.path, r.backups))
+	for i := r.backups - 1; i >= 1; i-- {
+		os.Rename(backupPath(r.path, i), backupPath(r.path, i+1))
+	}
+	if r.backups > 0 {
+		os.Rename(r.path, backupPath(r.path, 1))
+	} else {
+		os.Remove(r.path)
+	}
+	// If the file couldn't be moved aside, logging carries on in it.
+	return r.open()
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	r.file.Sync()
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
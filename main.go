@@ -1,24 +1,34 @@
 package main
 
 import (
+	gocontext "context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/crd"
 	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
 	"k8s-lsp/pkg/resolver"
+	"k8s-lsp/pkg/uri"
 	"k8s-lsp/pkg/validator"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
-	"github.com/tliron/glsp/server"
 )
 
 const lsName = "k8s-lsp"
@@ -26,44 +36,146 @@ const lsName = "k8s-lsp"
 var version = "0.0.1"
 
 type ServerState struct {
-	Store     *indexer.Store
-	Indexer   *indexer.Indexer
-	Resolver  *resolver.Resolver
-	Validator *validator.Validator
-	Documents map[string]string
-	RootPath  string
+	Store      *indexer.Store
+	Indexer    *indexer.Indexer
+	Resolver   *resolver.Resolver
+	Validator  *validator.Validator
+	Documents  *DocumentStore
+	CRDs       *crd.Downloader
+	CRDSources []string
+
+	// RootPaths holds every open workspace folder; the Store indexes them
+	// all, so resolution and validation don't care which one a file is in.
+	RootPaths []string
+
+	// WatcherRegistration is set when the client lets the server register
+	// file watchers, which then cover every workspace folder.
+	WatcherRegistration bool
+	watchersRegistered  bool
+
+	// CodeLensRefresh is set when the client accepts
+	// workspace/codeLens/refresh, sent when the index changes.
+	CodeLensRefresh bool
+
+	// Watcher coalesces watched-file events; set once the client is
+	// initialized.
+	Watcher *WatchCoalescer
+
+	// Changes debounces re-indexing and validation while typing; set once
+	// the client is initialized.
+	Changes *ChangeDebouncer
+
+	// WorkspaceValidation debounces validating the files that aren't open
+	// after the index changes; set once the client is initialized.
+	WorkspaceValidation  *ChangeDebouncer
+	workspaceDiagnostics workspaceDiagnostics
+
+	// ConfigurationPull is set when the client answers
+	// workspace/configuration, asked for the k8sLsp section when a
+	// didChangeConfiguration notification doesn't carry it.
+	ConfigurationPull bool
+
+	// HoverPlainText is set when the client's hover contentFormat leaves
+	// out markdown; hovers are then flattened to plain text.
+	HoverPlainText bool
+
+	// DiagnosticPull is set when the client pulls diagnostics with
+	// textDocument/diagnostic; nothing is pushed to it then.
+	// DiagnosticRefresh is set when it also accepts
+	// workspace/diagnostic/refresh, sent when the index changes.
+	DiagnosticPull    bool
+	DiagnosticRefresh bool
+
+	// PositionEncoding is the encoding positions are exchanged in with the
+	// client, negotiated from general.positionEncodings.
+	PositionEncoding position.Encoding
+
+	// SeverityOverrides maps diagnostic codes to the severity they are
+	// published with; "off" drops them.
+	SeverityOverrides map[string]string
+
+	// rulesDir and options are what the state was last configured from.
+	rulesDir string
+	options  ServerOptions
+
+	// stopRulesWatch stops watching rulesDir for changes.
+	stopRulesWatch gocontext.CancelFunc
+
+	// indexKeys is the Store.KeysVersion open documents were last
+	// validated against.
+	indexKeys atomic.Uint64
+
+	// background scopes workspace scans and CRD downloads; shutdown
+	// cancels it through stopBackground.
+	background     gocontext.Context
+	stopBackground gocontext.CancelFunc
 }
 
 var state *ServerState
 
-func main() {
-	// Configure logging to file and stderr
-	logFile, err := os.OpenFile(getLogFilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stderr, NoColor: true}
-	if err != nil {
-		// Fallback to stderr if file fails
-		log.Logger = log.Output(consoleWriter)
-		log.Error().Err(err).Msg("Failed to open log file")
-	} else {
-		multi := zerolog.MultiLevelWriter(consoleWriter, logFile)
-		log.Logger = log.Output(multi)
+// newServerState loads the rules in rulesDir and builds the index,
+// resolver and validator around them.
+func newServerState(rulesDir string) *ServerState {
+	cfg := loadRules(rulesDir)
+	store := indexer.NewStore()
+	store.SetCaseInsensitiveKinds(cfg.Settings.CaseInsensitiveKinds)
+	store.SetNamespaceAliases(cfg.Settings.NamespaceAliases)
+	if err := store.SetKeyTemplate(cfg.ResourceKeyTemplate()); err != nil {
+		log.Error().Err(err).Msg("Ignoring keyTemplate")
 	}
 
-	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	options := defaultOptions
+	options.RulesPath = rulesDir
+
+	background, stop := gocontext.WithCancel(gocontext.Background())
+	return &ServerState{
+		Store:      store,
+		Indexer:    indexer.NewIndexer(store, cfg),
+		Resolver:   resolver.NewResolver(store, cfg),
+		Validator:  loadValidator(rulesDir, store, cfg),
+		Documents:  NewDocumentStore(),
+		CRDs:       crd.NewDownloader(nil),
+		CRDSources: cfg.Settings.CRDSources,
+		rulesDir:   rulesDir,
+		options:    options,
+
+		background:     background,
+		stopBackground: stop,
+	}
+}
 
-	// Determine executable path to find rules directory
-	exePath, err := os.Executable()
-	configPath := "."
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get executable path, using current directory")
-	} else {
-		configPath = filepath.Dir(exePath)
+// scanContext is the context background scans run in, cancelled by
+// shutdown.
+func (s *ServerState) scanContext() gocontext.Context {
+	if s.background == nil {
+		return gocontext.Background()
 	}
+	return s.background
+}
+
+// reloadRules swaps in the rules from rulesDir, rebuilding the resolver and
+// validator but keeping everything already indexed.
+func reloadRules(rulesDir string) {
+	applyRules(rulesDir, loadRules(rulesDir))
+}
+
+// applyRules is reloadRules with rules already loaded from rulesDir.
+func applyRules(rulesDir string, cfg *config.Config) {
+	state.Store.SetCaseInsensitiveKinds(cfg.Settings.CaseInsensitiveKinds)
+	state.Store.SetNamespaceAliases(cfg.Settings.NamespaceAliases)
+	if err := state.Store.SetKeyTemplate(cfg.ResourceKeyTemplate()); err != nil {
+		log.Error().Err(err).Msg("Ignoring keyTemplate")
+	}
+	state.Indexer.SetConfig(cfg)
+	state.Resolver = resolver.NewResolver(state.Store, cfg)
+	state.Validator = loadValidator(rulesDir, state.Store, cfg)
+	state.rulesDir = rulesDir
+}
 
-	// Load config
-	cfg, err := config.Load(configPath)
+func loadRules(rulesDir string) *config.Config {
+	cfg, err := config.LoadDir(rulesDir)
 	if err != nil {
-		log.Error().Err(err).Str("path", configPath).Msg("Failed to load config")
+		log.Error().Err(err).Str("path", rulesDir).Msg("Failed to load config")
 		// Continue with empty config or default?
 		// config.Load returns partial config even on error if it read something, or we can just use empty.
 		if cfg == nil {
@@ -71,77 +183,200 @@ func main() {
 		}
 	}
 	log.Info().Int("symbols", len(cfg.Symbols)).Int("references", len(cfg.References)).Msg("Loaded configuration")
+	return cfg
+}
 
-	// Initialize state
-	store := indexer.NewStore()
-	idx := indexer.NewIndexer(store, cfg)
-	res := resolver.NewResolver(store, cfg)
-
-	val, err := validator.NewValidator(filepath.Join(configPath, "rules/validation.yaml"), store)
+func loadValidator(rulesDir string, store *indexer.Store, cfg *config.Config) *validator.Validator {
+	val, err := validator.NewValidator(filepath.Join(rulesDir, "validation.yaml"), store, cfg)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to load validation rules")
 	}
+	return val
+}
 
-	state = &ServerState{
-		Store:     store,
-		Indexer:   idx,
-		Resolver:  res,
-		Validator: val,
-		Documents: make(map[string]string),
+// setDefaultOptions makes the command line the defaults that
+// initializationOptions override: initialize without a logLevel keeps
+// --log-level.
+func setDefaultOptions(cli cliOptions) {
+	defaultOptions.LogLevel = cli.LogLevel.String()
+	defaultOptions.RulesPath = cli.RulesDir
+	if defaultOptions.RulesPath == "" {
+		// Determine executable path to find rules directory
+		exePath, err := os.Executable()
+		configPath := "."
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get executable path, using current directory")
+		} else {
+			configPath = filepath.Dir(exePath)
+		}
+		defaultOptions.RulesPath = filepath.Join(configPath, "rules")
 	}
+}
 
-	handler := protocol.Handler{
-		Initialize:                     initialize,
-		Initialized:                    initialized,
-		Shutdown:                       shutdown,
-		SetTrace:                       setTrace,
-		TextDocumentDidOpen:            textDocumentDidOpen,
-		TextDocumentDidChange:          textDocumentDidChange,
-		TextDocumentDefinition:         textDocumentDefinition,
-		TextDocumentReferences:         textDocumentReferences,
-		TextDocumentCompletion:         textDocumentCompletion,
-		TextDocumentHover:              textDocumentHover,
-		TextDocumentDidSave:            textDocumentDidSave,
-		WorkspaceDidChangeWatchedFiles: workspaceDidChangeWatchedFiles,
-		WorkspaceExecuteCommand:        workspaceExecuteCommand,
+func main() {
+	cli, err := parseFlags(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cli.Version {
+		fmt.Println(lsName, version)
+		return
 	}
 
-	s := server.NewServer(&handler, lsName, false)
+	// Configure logging to file and stderr
+	zerolog.SetGlobalLevel(cli.LogLevel)
+	consoleWriter := zerolog.ConsoleWriter{Out: os.Stderr, NoColor: true}
+	log.Logger = log.Output(consoleWriter)
+	if cli.LogFile != "" {
+		file, err := openRotatingFile(cli.LogFile, logMaxSize, logBackups)
+		if err != nil {
+			// Fallback to stderr if file fails
+			log.Error().Err(err).Str("path", cli.LogFile).Msg("Failed to open log file")
+		} else {
+			log.Logger = log.Output(zerolog.MultiLevelWriter(consoleWriter, file))
+			logFile = file
+		}
+	}
+
+	setDefaultOptions(cli)
+	state = newServerState(defaultOptions.RulesPath)
+
+	network, address, err := parseListen(cli.Listen)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid --listen address")
+	}
 
 	log.Info().Msg("Starting Kubernetes LSP Server...")
 
-	if err := s.RunStdio(); err != nil {
+	if network == "" {
+		serveStdio(newHandler())
+		closeLog()
+		os.Exit(serverLifecycle.exitCode())
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		log.Fatal().Err(err).Str("address", cli.Listen).Msg("Failed to listen")
+	}
+	log.Info().Str("address", listener.Addr().String()).Msg("Listening for clients")
+	ctx, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt)
+	defer stop()
+	if err := serveSessions(ctx, listener, newHandler(), sessionReset(defaultOptions.RulesPath, cli.KeepStore)); err != nil {
 		log.Fatal().Err(err).Msg("Server failed")
 	}
+	log.Info().Msg("Server stopped")
+	closeLog()
+}
+
+// newHandler routes LSP messages to the handlers below.
+func newHandler() glsp.Handler {
+	return lockedHandler{positionHandler{protocol317Handler{&protocol.Handler{
+		Initialize:                         initialize,
+		Initialized:                        initialized,
+		Shutdown:                           shutdown,
+		SetTrace:                           setTrace,
+		CancelRequest:                      cancelRequest,
+		TextDocumentDidOpen:                textDocumentDidOpen,
+		TextDocumentDidChange:              textDocumentDidChange,
+		TextDocumentDidClose:               textDocumentDidClose,
+		TextDocumentDefinition:             textDocumentDefinition,
+		TextDocumentReferences:             textDocumentReferences,
+		TextDocumentCompletion:             textDocumentCompletion,
+		CompletionItemResolve:              completionItemResolve,
+		TextDocumentHover:                  textDocumentHover,
+		TextDocumentDidSave:                textDocumentDidSave,
+		WorkspaceDidChangeWorkspaceFolders: workspaceDidChangeWorkspaceFolders,
+		WorkspaceDidChangeWatchedFiles:     workspaceDidChangeWatchedFiles,
+		WorkspaceDidChangeConfiguration:    workspaceDidChangeConfiguration,
+		WorkspaceExecuteCommand:            workspaceExecuteCommand,
+		WorkspaceSymbol:                    workspaceSymbol,
+		TextDocumentDocumentSymbol:         textDocumentDocumentSymbol,
+		TextDocumentPrepareRename:          textDocumentPrepareRename,
+		TextDocumentRename:                 textDocumentRename,
+		TextDocumentCodeAction:             textDocumentCodeAction,
+		TextDocumentCodeLens:               textDocumentCodeLens,
+		TextDocumentPrepareCallHierarchy:   textDocumentPrepareCallHierarchy,
+		CallHierarchyIncomingCalls:         callHierarchyIncomingCalls,
+		CallHierarchyOutgoingCalls:         callHierarchyOutgoingCalls,
+		TextDocumentDocumentLink:           textDocumentDocumentLink,
+		TextDocumentFoldingRange:           textDocumentFoldingRange,
+	}}}}
 }
 
 func initialize(context *glsp.Context, params *protocol.InitializeParams) (any, error) {
+	roots := workspaceRoots(params)
+	applyServerOptions(params.InitializationOptions, roots)
+
+	prepareRename := true
+	openClose := true
+	syncKind := protocol.TextDocumentSyncKindIncremental
+	workspaceFolders := true
+	workDoneProgress := true
+	completionResolve := true
 	capabilities := protocol.ServerCapabilities{
-		TextDocumentSync:   protocol.TextDocumentSyncKindFull,
-		DefinitionProvider: true,
-		ReferencesProvider: true,
+		// didSave applies edits still waiting out the change debounce.
+		TextDocumentSync: protocol.TextDocumentSyncOptions{
+			OpenClose: &openClose,
+			Change:    &syncKind,
+			Save:      true,
+		},
+		DefinitionProvider:      true,
+		ReferencesProvider:      true,
+		HoverProvider:           true,
+		WorkspaceSymbolProvider: true,
+		DocumentSymbolProvider:  true,
+		RenameProvider:          protocol.RenameOptions{PrepareProvider: &prepareRename},
+		CodeActionProvider: protocol.CodeActionOptions{
+			CodeActionKinds: []protocol.CodeActionKind{protocol.CodeActionKindQuickFix},
+		},
+		CodeLensProvider:      &protocol.CodeLensOptions{},
+		CallHierarchyProvider: true,
+		DocumentLinkProvider:  &protocol.DocumentLinkOptions{},
+		FoldingRangeProvider:  true,
 		CompletionProvider: &protocol.CompletionOptions{
 			TriggerCharacters: []string{":", " "},
+			ResolveProvider:   &completionResolve,
+		},
+		Workspace: &protocol.ServerCapabilitiesWorkspace{
+			WorkspaceFolders: &protocol.WorkspaceFoldersServerCapabilities{
+				Supported:           &workspaceFolders,
+				ChangeNotifications: &protocol.BoolOrString{Value: true},
+			},
 		},
 		ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
-			Commands: []string{"k8s.embeddedContent", "k8s.saveEmbeddedContent"},
+			WorkDoneProgressOptions: protocol.WorkDoneProgressOptions{WorkDoneProgress: &workDoneProgress},
+			Commands:                []string{"k8s.embeddedContent", "k8s.saveEmbeddedContent", "k8s.explainPosition", "k8s.refreshCRDs", "k8s.rescanWorkspace", "k8s.embeddedDiff", "k8s.dumpIndex", "k8s.detailedReferences", "k8s.envVarUsages"},
 		},
 	}
 
-	// Determine root path
-	if params.RootURI != nil {
-		parsed, err := url.Parse(*params.RootURI)
-		if err == nil && parsed.Scheme == "file" {
-			state.RootPath = parsed.Path
-		}
-	} else if params.RootPath != nil {
-		state.RootPath = *params.RootPath
+	if ws := params.Capabilities.Workspace; ws != nil && ws.CodeLens != nil && ws.CodeLens.RefreshSupport != nil {
+		state.CodeLensRefresh = *ws.CodeLens.RefreshSupport
+	}
+	if ws := params.Capabilities.Workspace; ws != nil && ws.DidChangeWatchedFiles != nil && ws.DidChangeWatchedFiles.DynamicRegistration != nil {
+		state.WatcherRegistration = *ws.DidChangeWatchedFiles.DynamicRegistration
+	}
+	if ws := params.Capabilities.Workspace; ws != nil && ws.Configuration != nil {
+		state.ConfigurationPull = *ws.Configuration
+	}
+	if td := params.Capabilities.TextDocument; td != nil && td.Hover != nil && len(td.Hover.ContentFormat) > 0 {
+		state.HoverPlainText = !slices.Contains(td.Hover.ContentFormat, protocol.MarkupKindMarkdown)
 	}
 
-	log.Info().Str("root", state.RootPath).Msg("Initializing...")
+	state.RootPaths = roots
 
-	return protocol.InitializeResult{
-		Capabilities: capabilities,
+	log.Info().Strs("roots", state.RootPaths).Msg("Initializing...")
+
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			ServerCapabilities: capabilities,
+			InlayHintProvider:  true,
+			DiagnosticProvider: &DiagnosticOptions{InterFileDependencies: true},
+			PositionEncoding:   clientEncoding(),
+		},
 		ServerInfo: &protocol.InitializeResultServerInfo{
 			Name:    lsName,
 			Version: &version,
@@ -152,22 +387,88 @@ func initialize(context *glsp.Context, params *protocol.InitializeParams) (any,
 func initialized(context *glsp.Context, params *protocol.InitializedParams) error {
 	log.Info().Msg("Client initialized")
 
-	if state.RootPath != "" {
+	state.Indexer.SetLibraryRoots(resolveLibraryRoots(primaryRoot(), state.Indexer.Config.Settings.LibraryRoots))
+	state.Watcher = NewWatchCoalescer(state.Indexer.Config.Settings.WatchDebounce(), func(changes []WatchedChange) {
+		configMu.RLock()
+		defer configMu.RUnlock()
+		applyWatchedChanges(context, changes)
+	})
+	state.Changes = NewChangeDebouncer(state.Indexer.Config.Settings.ChangeDebounce(), func(uri string) {
+		configMu.RLock()
+		defer configMu.RUnlock()
+		applyDocument(context, uri)
+	})
+	state.WorkspaceValidation = NewChangeDebouncer(state.Indexer.Config.Settings.ChangeDebounce(), func(string) {
+		validateWorkspace(context)
+	})
+	watchRules(context)
+
+	if len(state.RootPaths) > 0 {
+		registerWatchers(context)
+		roots := append([]string(nil), state.RootPaths...)
+		scanning.Add(1)
 		go func() {
+			defer scanning.Done()
+			openIndexCache(roots)
+			downloadCRDs()
 			log.Info().Msg("Starting workspace scan...")
-			if err := state.Indexer.ScanWorkspace(state.RootPath); err != nil {
-				log.Error().Err(err).Msg("Failed to scan workspace")
-			} else {
-				log.Info().Msg("Workspace scan completed")
+			scanRoots(roots)
+			log.Info().Msg("Workspace scan completed")
+			if err := state.Indexer.ScanLibrariesContext(state.scanContext()); err != nil && !errors.Is(err, gocontext.Canceled) {
+				log.Error().Err(err).Msg("Failed to scan library roots")
 			}
+			saveIndexCache(1)
+			republishOnIndexChange(context, "")
+			configMu.RLock()
+			scheduleWorkspaceValidation()
+			configMu.RUnlock()
+			refreshCodeLenses(context)
+		}()
+	} else {
+		// Without a workspace there is nothing to scan or cache, but CRD
+		// sources and absolute library roots still apply.
+		scanning.Add(1)
+		go func() {
+			defer scanning.Done()
+			downloadCRDs()
+			if err := state.Indexer.ScanLibrariesContext(state.scanContext()); err != nil && !errors.Is(err, gocontext.Canceled) {
+				log.Error().Err(err).Msg("Failed to scan library roots")
+			}
+			republishOnIndexChange(context, "")
+			refreshCodeLenses(context)
 		}()
 	}
 
 	return nil
 }
 
-func shutdown(context *glsp.Context) error {
-	protocol.SetTraceValue(protocol.TraceValueOff)
+// resolveLibraryRoots makes configured library roots absolute, resolving
+// relative entries against the workspace root.
+func resolveLibraryRoots(rootPath string, roots []string) []string {
+	var resolved []string
+	for _, root := range roots {
+		if !filepath.IsAbs(root) {
+			if rootPath == "" {
+				log.Warn().Str("root", root).Msg("Ignoring relative library root without a workspace root")
+				continue
+			}
+			root = filepath.Join(rootPath, root)
+		}
+		resolved = append(resolved, root)
+	}
+	return resolved
+}
+
+// cancelRequest cancels the context of the work still running for the
+// cancelled request, so that it stops early.
+func cancelRequest(context *glsp.Context, params *protocol.CancelParams) error {
+	id, err := requestID(context.Params)
+	if err != nil {
+		return err
+	}
+	if n := inflight.cancel(id); n > 0 {
+		log.Debug().Str("id", id).Int("calls", n).Msg("Cancelled running request")
+	}
 	return nil
 }
 
@@ -177,90 +478,206 @@ func setTrace(context *glsp.Context, params *protocol.SetTraceParams) error {
 }
 
 func textDocumentDidOpen(context *glsp.Context, params *protocol.DidOpenTextDocumentParams) error {
-	state.Documents[params.TextDocument.URI] = params.TextDocument.Text
+	state.Documents.Set(params.TextDocument.URI, params.TextDocument.Text, params.TextDocument.Version)
+	if state.Changes != nil {
+		state.Changes.Cancel(params.TextDocument.URI)
+	}
 
 	// Index the content to support dynamic updates (e.g. new CRDs)
-	path := uriToPath(params.TextDocument.URI)
-	state.Indexer.IndexContent(path, params.TextDocument.Text)
-
-	go publishDiagnostics(context, params.TextDocument.URI, params.TextDocument.Text)
+	applyDocument(context, params.TextDocument.URI)
 	return nil
 }
 
 func textDocumentDidChange(context *glsp.Context, params *protocol.DidChangeTextDocumentParams) error {
-	// Since we use Full sync, ContentChanges has one element with the full text
-	if len(params.ContentChanges) > 0 {
-		change, ok := params.ContentChanges[0].(protocol.TextDocumentContentChangeEvent)
-		if ok {
-			state.Documents[params.TextDocument.URI] = change.Text
+	if len(params.ContentChanges) == 0 {
+		return nil
+	}
 
-			// Index the content
-			path := uriToPath(params.TextDocument.URI)
-			state.Indexer.IndexContent(path, change.Text)
+	// Changes are either ranged (incremental sync) or carry the whole text.
+	uri := params.TextDocument.URI
+	state.Documents.Update(uri, params.TextDocument.Version, func(current string) string {
+		return applyContentChanges(current, params.ContentChanges)
+	})
 
-			go publishDiagnostics(context, params.TextDocument.URI, change.Text)
-		} else {
-			// Fallback or log error if type assertion fails
-			// In some versions it might be TextDocumentContentChangeEventWhole
-			if changeWhole, ok := params.ContentChanges[0].(protocol.TextDocumentContentChangeEventWhole); ok {
-				state.Documents[params.TextDocument.URI] = changeWhole.Text
+	if state.Changes == nil {
+		applyDocument(context, uri)
+		return nil
+	}
+	state.Changes.Schedule(uri)
+	return nil
+}
 
-				// Index the content
-				path := uriToPath(params.TextDocument.URI)
-				state.Indexer.IndexContent(path, changeWhole.Text)
+// applyDocument indexes and validates the latest content of an open
+// document.
+func applyDocument(context *glsp.Context, uri string) {
+	content, ok := state.Documents.Get(uri)
+	if !ok {
+		return
+	}
+	state.Indexer.IndexContent(uriToPath(uri), content)
+	state.Documents.MarkApplied(uri, content)
 
-				go publishDiagnostics(context, params.TextDocument.URI, changeWhole.Text)
-			}
+	publishInBackground(context, map[string]string{uri: content})
+	republishOnIndexChange(context, uri)
+	refreshCodeLenses(context)
+}
+
+// republishOnIndexChange re-validates the open documents other than except
+// when resources were added to or removed from the index, so creating a
+// missing ConfigMap clears the warning in the document referencing it.
+// Edits that leave the indexed names alone cost nothing.
+func republishOnIndexChange(context *glsp.Context, except string) {
+	keys := state.Store.KeysVersion()
+	if state.indexKeys.Swap(keys) == keys {
+		return
+	}
+	scheduleWorkspaceValidation()
+	docs := state.Documents.All()
+	delete(docs, except)
+	if len(docs) == 0 {
+		return
+	}
+	log.Debug().Int("documents", len(docs)).Msg("Index changed, re-publishing diagnostics")
+	publishInBackground(context, docs)
+}
+
+// publishing tracks diagnostics being published in the background, so
+// shutdown (and tests) can wait for them.
+var publishing sync.WaitGroup
+
+// publishInBackground publishes diagnostics for docs (uri -> content)
+// without blocking the handler.
+func publishInBackground(context *glsp.Context, docs map[string]string) {
+	if state.DiagnosticPull {
+		refreshDiagnostics(context)
+		return
+	}
+	publishing.Add(1)
+	go func() {
+		defer publishing.Done()
+		configMu.RLock()
+		defer configMu.RUnlock()
+		for uri, content := range docs {
+			publishDiagnostics(context, uri, content)
 		}
+	}()
+}
+
+func textDocumentDidClose(context *glsp.Context, params *protocol.DidCloseTextDocumentParams) error {
+	if state.Changes != nil {
+		state.Changes.Cancel(params.TextDocument.URI)
 	}
+	state.Documents.Delete(params.TextDocument.URI)
+	// The file is reported along with the rest of the workspace again.
+	scheduleWorkspaceValidation()
 	return nil
 }
 
+// textDocumentDidSave re-applies a document as saved. The saved text comes
+// with the notification when the client includes it, and is otherwise read
+// back from disk, so a formatter rewriting the file on save or a checkout
+// reverting it does not leave the in-memory copy behind. Saving also applies
+// edits still waiting out the change debounce; when the text is what was
+// last indexed, nothing is done.
 func textDocumentDidSave(context *glsp.Context, params *protocol.DidSaveTextDocumentParams) error {
-	log.Debug().Str("uri", params.TextDocument.URI).Msg("Document saved")
+	uri := params.TextDocument.URI
+	log.Debug().Str("uri", uri).Bool("includesText", params.Text != nil).Msg("Document saved")
+
+	if state.Changes != nil {
+		state.Changes.Cancel(uri)
+	}
+	current, version, open := state.Documents.GetWithVersion(uri)
+	if !open {
+		return nil
+	}
+
+	if saved, ok := savedText(params); ok && saved != current {
+		log.Info().Str("uri", uri).Msg("Saved file differs from the open document, reloading it")
+		state.Documents.Set(uri, saved, version)
+		current = saved
+	}
+
+	if state.Documents.Applied(uri, current) {
+		return nil
+	}
+	applyDocument(context, uri)
 	return nil
 }
 
+// savedText is the text of a saved document: what the client sent along,
+// or else the file on disk.
+func savedText(params *protocol.DidSaveTextDocumentParams) (string, bool) {
+	if params.Text != nil {
+		return *params.Text, true
+	}
+	return loadFromDisk(params.TextDocument.URI)
+}
+
 func workspaceDidChangeWatchedFiles(context *glsp.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	var changes []WatchedChange
 	for _, change := range params.Changes {
 		log.Debug().Str("uri", change.URI).Int("type", int(change.Type)).Msg("Watched file changed")
-		// TODO: Handle file events (Created, Changed, Deleted)
-		// For now, we just log.
-		// If we wanted to be correct, we should:
-		// 1. If Created/Changed: IndexFile(uriToPath(change.URI))
-		// 2. If Deleted: Remove resources from store (requires Store update to track by file)
+		changes = append(changes, WatchedChange{Path: uriToPath(change.URI), Type: change.Type})
+	}
+	if state.Watcher == nil {
+		applyWatchedChanges(context, changes)
+		return nil
+	}
+	for _, change := range changes {
+		state.Watcher.Add(change.Path, change.Type)
 	}
 	return nil
 }
 
-func uriToPath(uri string) string {
-	parsed, err := url.Parse(uri)
-	if err == nil && parsed.Scheme == "file" {
-		return parsed.Path
+// applyWatchedChanges re-indexes or removes each changed file, then
+// re-publishes diagnostics for the open documents in one pass.
+func applyWatchedChanges(context *glsp.Context, changes []WatchedChange) {
+	for _, change := range changes {
+		switch change.Type {
+		case protocol.FileChangeTypeCreated, protocol.FileChangeTypeChanged:
+			if indexer.IsManifestPath(change.Path) {
+				state.Indexer.IndexFile(change.Path)
+			}
+		case protocol.FileChangeTypeDeleted:
+			state.Indexer.RemoveFile(change.Path)
+		}
+	}
+	log.Debug().Int("files", len(changes)).Msg("Applied watched file changes")
+	saveIndexCache(indexCacheFlushChanges)
+
+	state.indexKeys.Store(state.Store.KeysVersion())
+	for uri, content := range state.Documents.All() {
+		publishDiagnostics(context, uri, content)
 	}
-	return uri
+	scheduleWorkspaceValidation()
+	refreshCodeLenses(context)
+}
+
+// uriToPath returns the path of a file:// URI, or the URI itself for other
+// schemes, so that unsaved documents still get a key of their own.
+func uriToPath(documentURI string) string {
+	if path, err := uri.ToPath(documentURI); err == nil {
+		return path
+	}
+	return documentURI
 }
 
 func textDocumentDefinition(context *glsp.Context, params *protocol.DefinitionParams) (any, error) {
 	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received definition request")
 
 	uri := params.TextDocument.URI
-	log.Debug().Str("uri", uri).Msg("Looking up document content")
-	content, ok := state.Documents[uri]
-	log.Debug().Bool("foundInMemory", ok).Msg("Document content lookup result")
-	if !ok {
-		// Try to read from file if not in memory (e.g. not opened yet but requested?)
-		// Usually client opens before requesting definition.
-		// But let's try to read from file path if possible.
-		parsed, err := url.Parse(uri)
-		if err == nil && parsed.Scheme == "file" {
-			bytes, err := os.ReadFile(parsed.Path)
-			if err == nil {
-				content = string(bytes)
-				state.Documents[uri] = content
-			}
+	if strings.HasPrefix(uri, "k8s-embedded:") {
+		// Embedded files aren't synced; they are read from their source.
+		content, err := embeddedFileContent(uri)
+		if err != nil {
+			log.Debug().Err(err).Str("uri", uri).Msg("Failed to read embedded file")
+			return nil, nil
 		}
+		return state.Resolver.DotenvDefinition(content, uri, int(params.Position.Line), int(params.Position.Character)), nil
 	}
+	log.Debug().Str("uri", uri).Msg("Looking up document content")
+	content, ok := state.Documents.GetOrLoadFromDisk(uri)
+	log.Debug().Bool("found", ok).Msg("Document content lookup result")
 	log.Debug().Bool("contentAvailable", content != "").Msg("Document content availability")
 
 	if content == "" {
@@ -270,7 +687,9 @@ func textDocumentDefinition(context *glsp.Context, params *protocol.DefinitionPa
 	log.Debug().Str("uri", uri).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Resolving definition")
 	log.Debug().Str("content", content).Msg("Document content for definition")
 
-	locs, err := state.Resolver.ResolveDefinition(content, uri, int(params.Position.Line), int(params.Position.Character))
+	locs, err := withinBudgetContext(context, "definition", analysisBudget(), func(ctx gocontext.Context) ([]protocol.LocationLink, error) {
+		return state.Resolver.ResolveDefinition(ctx, content, uri, int(params.Position.Line), int(params.Position.Character))
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to resolve definition")
 		return nil, nil
@@ -284,23 +703,19 @@ func textDocumentReferences(context *glsp.Context, params *protocol.ReferencePar
 	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received references request")
 
 	uri := params.TextDocument.URI
-	content, ok := state.Documents[uri]
-	if !ok {
-		parsed, err := url.Parse(uri)
-		if err == nil && parsed.Scheme == "file" {
-			bytes, err := os.ReadFile(parsed.Path)
-			if err == nil {
-				content = string(bytes)
-				state.Documents[uri] = content
-			}
-		}
-	}
+	content, _ := state.Documents.GetOrLoadFromDisk(uri)
 
 	if content == "" {
 		return nil, nil
 	}
 
-	locs, err := state.Resolver.ResolveReferences(content, uri, int(params.Position.Line), int(params.Position.Character))
+	if params.PartialResultToken != nil {
+		return streamReferences(context, params, content), nil
+	}
+
+	locs, err := withinBudgetContext(context, "references", analysisBudget(), func(ctx gocontext.Context) ([]protocol.Location, error) {
+		return state.Resolver.ResolveReferences(ctx, content, uri, int(params.Position.Line), int(params.Position.Character))
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to resolve references")
 		return nil, nil
@@ -309,27 +724,45 @@ func textDocumentReferences(context *glsp.Context, params *protocol.ReferencePar
 	return locs, nil
 }
 
+// streamReferences sends the references as partial results on the
+// client's partialResultToken while the Store is scanned, and returns the
+// empty final result the protocol then expects. Batches still coming in
+// after the budget ran out are dropped, so nothing follows the reply.
+func streamReferences(context *glsp.Context, params *protocol.ReferenceParams, content string) []protocol.Location {
+	progress := newProgressReporter(context, params.PartialResultToken)
+	var mu sync.Mutex
+	replied := false
+	_, err := withinBudgetContext(context, "references", analysisBudget(), func(ctx gocontext.Context) (struct{}, error) {
+		return struct{}{}, state.Resolver.StreamReferences(ctx, content, params.TextDocument.URI, int(params.Position.Line), int(params.Position.Character), func(locs []protocol.Location) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !replied {
+				progress.notify(locs)
+			}
+		})
+	})
+	mu.Lock()
+	replied = true
+	mu.Unlock()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve references")
+	}
+	return []protocol.Location{}
+}
+
 func textDocumentCompletion(context *glsp.Context, params *protocol.CompletionParams) (any, error) {
 	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received completion request")
 
 	uri := params.TextDocument.URI
-	content, ok := state.Documents[uri]
-	if !ok {
-		parsed, err := url.Parse(uri)
-		if err == nil && parsed.Scheme == "file" {
-			bytes, err := os.ReadFile(parsed.Path)
-			if err == nil {
-				content = string(bytes)
-				state.Documents[uri] = content
-			}
-		}
-	}
+	content, _ := state.Documents.GetOrLoadFromDisk(uri)
 
 	if content == "" {
 		return nil, nil
 	}
 
-	items, err := state.Resolver.Completion(content, int(params.Position.Line), int(params.Position.Character))
+	items, err := withinBudgetContext(context, "completion", analysisBudget(), func(ctx gocontext.Context) ([]protocol.CompletionItem, error) {
+		return state.Resolver.Completion(ctx, content, int(params.Position.Line), int(params.Position.Character))
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to resolve completion")
 		return nil, nil
@@ -338,14 +771,157 @@ func textDocumentCompletion(context *glsp.Context, params *protocol.CompletionPa
 	return items, nil
 }
 
-func publishDiagnostics(context *glsp.Context, uri string, content string) {
+// completionItemResolve adds the documentation completion leaves out to
+// keep the list fast.
+func completionItemResolve(context *glsp.Context, params *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	item := state.Resolver.ResolveCompletionItem(*params)
+	return &item, nil
+}
+
+func workspaceSymbol(context *glsp.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	log.Debug().Str("query", params.Query).Msg("Received workspace symbol request")
+	return withinBudget(context, "workspaceSymbol", analysisBudget(), func() ([]protocol.SymbolInformation, error) {
+		return state.Resolver.WorkspaceSymbols(params.Query), nil
+	})
+}
+
+func textDocumentDocumentSymbol(context *glsp.Context, params *protocol.DocumentSymbolParams) (any, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Msg("Received document symbol request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	return withinBudget(context, "documentSymbol", analysisBudget(), func() ([]protocol.DocumentSymbol, error) {
+		return state.Resolver.DocumentSymbols(content), nil
+	})
+}
+
+func textDocumentPrepareRename(context *glsp.Context, params *protocol.PrepareRenameParams) (any, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received prepare rename request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	rng, err := state.Resolver.PrepareRename(content, params.TextDocument.URI, int(params.Position.Line), int(params.Position.Character))
+	if err != nil || rng == nil {
+		return nil, err
+	}
+	return rng, nil
+}
+
+func textDocumentRename(context *glsp.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Str("newName", params.NewName).Msg("Received rename request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	edits, err := state.Resolver.Rename(content, params.TextDocument.URI, int(params.Position.Line), int(params.Position.Character), params.NewName)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := resolver.BuildVerifiedEdit(edits, documentSource)
+	if len(edit.Warnings) > 0 {
+		log.Warn().Strs("warnings", edit.Warnings).Msg("Some rename edits were withheld")
+		// The client applies the rest, so tell the user what it left out.
+		context.Notify(string(protocol.ServerWindowShowMessage), protocol.ShowMessageParams{
+			Type:    protocol.MessageTypeWarning,
+			Message: "Rename left out edits of changed documents:\n" + strings.Join(edit.Warnings, "\n"),
+		})
+	}
+	return &edit.WorkspaceEdit, nil
+}
+
+func textDocumentCodeAction(context *glsp.Context, params *protocol.CodeActionParams) (any, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Int("diagnostics", len(params.Context.Diagnostics)).Msg("Received code action request")
+
 	if state.Validator == nil {
+		return nil, nil
+	}
+	return state.Validator.QuickFixes(params.TextDocument.URI, params.Context.Diagnostics, documentSource), nil
+}
+
+func textDocumentCodeLens(context *glsp.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Msg("Received code lens request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	return withinBudget(context, "codeLens", analysisBudget(), func() ([]protocol.CodeLens, error) {
+		return state.Resolver.CodeLenses(content, params.TextDocument.URI), nil
+	})
+}
+
+// refreshCodeLenses asks the client to re-request code lenses, since
+// reference counts change whenever any file is re-indexed.
+func refreshCodeLenses(context *glsp.Context) {
+	if !state.CodeLensRefresh {
+		return
+	}
+	go context.Call(string(protocol.ServerWorkspaceCodeLensRefresh), nil, nil)
+}
+
+func textDocumentPrepareCallHierarchy(context *glsp.Context, params *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received prepare call hierarchy request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	return state.Resolver.PrepareCallHierarchy(content, params.TextDocument.URI, int(params.Position.Line), int(params.Position.Character))
+}
+
+func callHierarchyIncomingCalls(context *glsp.Context, params *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	log.Debug().Str("item", params.Item.Name).Msg("Received incoming calls request")
+	return state.Resolver.IncomingCalls(params.Item), nil
+}
+
+func callHierarchyOutgoingCalls(context *glsp.Context, params *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	log.Debug().Str("item", params.Item.Name).Msg("Received outgoing calls request")
+	return state.Resolver.OutgoingCalls(params.Item), nil
+}
+
+func textDocumentDocumentLink(context *glsp.Context, params *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Msg("Received document link request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	return withinBudget(context, "documentLink", analysisBudget(), func() ([]protocol.DocumentLink, error) {
+		return state.Resolver.DocumentLinks(content), nil
+	})
+}
+
+func textDocumentFoldingRange(context *glsp.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Msg("Received folding range request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	return withinBudget(context, "foldingRange", analysisBudget(), func() ([]protocol.FoldingRange, error) {
+		return state.Resolver.FoldingRanges(content), nil
+	})
+}
+
+func publishDiagnostics(context *glsp.Context, uri string, content string) {
+	if state.Validator == nil || state.DiagnosticPull {
 		return
 	}
 
-	diagnostics := state.Validator.Validate(uri, content)
-	if diagnostics == nil {
-		diagnostics = []protocol.Diagnostic{}
+	diagnostics := documentDiagnostics(uri, content)
+	// Drop results for content that has been edited since; the newer
+	// version publishes its own.
+	if current, ok := state.Documents.Get(uri); ok && current != content {
+		log.Debug().Str("uri", uri).Msg("Dropping diagnostics for an outdated version")
+		return
 	}
 
 	context.Notify("textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
@@ -358,27 +934,24 @@ func textDocumentHover(context *glsp.Context, params *protocol.HoverParams) (*pr
 	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received hover request")
 
 	uri := params.TextDocument.URI
-	content, ok := state.Documents[uri]
-	if !ok {
-		parsed, err := url.Parse(uri)
-		if err == nil && parsed.Scheme == "file" {
-			bytes, err := os.ReadFile(parsed.Path)
-			if err == nil {
-				content = string(bytes)
-				state.Documents[uri] = content
-			}
-		}
-	}
+	content, _ := state.Documents.GetOrLoadFromDisk(uri)
 
 	if content == "" {
 		return nil, nil
 	}
 
-	hover, err := state.Resolver.ResolveHover(content, uri, int(params.Position.Line), int(params.Position.Character))
+	hover, err := withinBudgetContext(context, "hover", analysisBudget(), func(ctx gocontext.Context) (*protocol.Hover, error) {
+		return state.Resolver.ResolveHover(ctx, content, uri, int(params.Position.Line), int(params.Position.Character))
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to resolve hover")
 		return nil, nil
 	}
+	if hover != nil && state.HoverPlainText {
+		if markup, ok := hover.Contents.(protocol.MarkupContent); ok && markup.Kind == protocol.MarkupKindMarkdown {
+			hover.Contents = protocol.MarkupContent{Kind: protocol.MarkupKindPlainText, Value: markdownToPlainText(markup.Value)}
+		}
+	}
 
 	return hover, nil
 }
@@ -398,6 +971,78 @@ func workspaceExecuteCommand(context *glsp.Context, params *protocol.ExecuteComm
 
 			return handleEmbeddedContent(context, &embeddedParams)
 		}
+	} else if params.Command == "k8s.refreshCRDs" {
+		return nil, handleRefreshCRDs(context)
+	} else if params.Command == "k8s.rescanWorkspace" {
+		return nil, handleRescanWorkspace(context, params.WorkDoneToken)
+	} else if params.Command == "k8s.explainPosition" {
+		if len(params.Arguments) > 0 {
+			argBytes, err := json.Marshal(params.Arguments[0])
+			if err != nil {
+				return nil, err
+			}
+
+			var explainParams protocol.TextDocumentPositionParams
+			if err := json.Unmarshal(argBytes, &explainParams); err != nil {
+				return nil, err
+			}
+
+			return handleExplainPosition(context, &explainParams)
+		}
+	} else if params.Command == "k8s.dumpIndex" {
+		var dumpParams DumpIndexParams
+		if len(params.Arguments) > 0 {
+			argBytes, err := json.Marshal(params.Arguments[0])
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(argBytes, &dumpParams); err != nil {
+				return nil, err
+			}
+		}
+		return handleDumpIndex(context, &dumpParams)
+	} else if params.Command == "k8s.embeddedDiff" {
+		if len(params.Arguments) > 0 {
+			argBytes, err := json.Marshal(params.Arguments[0])
+			if err != nil {
+				return nil, err
+			}
+
+			var diffParams EmbeddedDiffParams
+			if err := json.Unmarshal(argBytes, &diffParams); err != nil {
+				return nil, err
+			}
+
+			return handleEmbeddedDiff(context, &diffParams)
+		}
+	} else if params.Command == "k8s.detailedReferences" {
+		if len(params.Arguments) > 0 {
+			argBytes, err := json.Marshal(params.Arguments[0])
+			if err != nil {
+				return nil, err
+			}
+
+			var detailedParams DetailedReferencesParams
+			if err := json.Unmarshal(argBytes, &detailedParams); err != nil {
+				return nil, err
+			}
+
+			return handleDetailedReferences(context, &detailedParams)
+		}
+	} else if params.Command == "k8s.envVarUsages" {
+		if len(params.Arguments) > 0 {
+			argBytes, err := json.Marshal(params.Arguments[0])
+			if err != nil {
+				return nil, err
+			}
+
+			var usagesParams EnvVarUsagesParams
+			if err := json.Unmarshal(argBytes, &usagesParams); err != nil {
+				return nil, err
+			}
+
+			return handleEnvVarUsages(context, &usagesParams)
+		}
 	} else if params.Command == "k8s.saveEmbeddedContent" {
 		if len(params.Arguments) > 0 {
 			argBytes, err := json.Marshal(params.Arguments[0])
@@ -416,6 +1061,53 @@ func workspaceExecuteCommand(context *glsp.Context, params *protocol.ExecuteComm
 	return nil, nil
 }
 
+func handleExplainPosition(context *glsp.Context, params *protocol.TextDocumentPositionParams) (any, error) {
+	log.Debug().Str("uri", params.TextDocument.URI).Int("line", int(params.Position.Line)).Int("char", int(params.Position.Character)).Msg("Received explain position request")
+
+	content, ok := state.Documents.GetOrLoadFromDisk(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	return state.Resolver.ExplainPosition(content, int(params.Position.Line), int(params.Position.Character))
+}
+
+// DetailedReferencesParams is the argument of k8s.detailedReferences.
+// ExactOnly leaves out references that only resolve through a fallback.
+type DetailedReferencesParams struct {
+	protocol.TextDocumentPositionParams
+	ExactOnly bool `json:"exactOnly"`
+}
+
+// handleDetailedReferences resolves the reference or resource name at a
+// position and the references to it, each labeled with the confidence of
+// its match.
+func handleDetailedReferences(context *glsp.Context, params *DetailedReferencesParams) (*resolver.DetailedReferences, error) {
+	return withinBudgetContext(context, "detailedReferences", analysisBudget(), func(ctx gocontext.Context) (*resolver.DetailedReferences, error) {
+		return state.Resolver.DetailedReferences(ctx, params.TextDocument.URI, int(params.Position.Line), int(params.Position.Character), params.ExactOnly), nil
+	})
+}
+
+// EnvVarUsagesParams is the argument of k8s.envVarUsages: a ConfigMap key.
+type EnvVarUsagesParams struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// handleEnvVarUsages lists the containers consuming a ConfigMap key, with
+// the environment variable or file each one sees it as.
+func handleEnvVarUsages(context *glsp.Context, params *EnvVarUsagesParams) ([]resolver.EnvVarUsage, error) {
+	log.Debug().Str("namespace", params.Namespace).Str("name", params.Name).Str("key", params.Key).Msg("Received env var usages request")
+
+	if params.Name == "" || params.Key == "" {
+		return nil, fmt.Errorf("missing name or key")
+	}
+	return withinBudgetContext(context, "envVarUsages", analysisBudget(), func(ctx gocontext.Context) ([]resolver.EnvVarUsage, error) {
+		return state.Resolver.EnvVarUsages(ctx, params.Namespace, params.Name, params.Key), nil
+	})
+}
+
 type EmbeddedContentParams struct {
 	URI string `json:"uri"`
 }
@@ -425,6 +1117,29 @@ type SaveEmbeddedContentParams struct {
 	Content string `json:"content"`
 }
 
+// EmbeddedDiffParams names a ConfigMap or Secret entry by its document and
+// key, with the content proposed for it.
+type EmbeddedDiffParams struct {
+	Source  string `json:"source"`
+	Key     string `json:"key"`
+	Content string `json:"content"`
+}
+
+// handleEmbeddedDiff previews k8s.saveEmbeddedContent: the unified diff
+// from the entry's current decoded value to the proposed content.
+func handleEmbeddedDiff(context *glsp.Context, params *EmbeddedDiffParams) (string, error) {
+	log.Debug().Str("source", params.Source).Str("key", params.Key).Msg("Received embedded diff request")
+
+	if params.Source == "" || params.Key == "" {
+		return "", fmt.Errorf("missing source or key")
+	}
+	content, _ := state.Documents.GetOrLoadFromDisk(params.Source)
+	if content == "" {
+		return "", fmt.Errorf("document not found: %s", params.Source)
+	}
+	return state.Resolver.EmbeddedDiff(content, params.Key, params.Content)
+}
+
 func handleSaveEmbeddedContent(context *glsp.Context, params *SaveEmbeddedContentParams) (any, error) {
 	log.Debug().Str("uri", params.URI).Msg("Received save embedded content request")
 
@@ -453,18 +1168,12 @@ func handleSaveEmbeddedContent(context *glsp.Context, params *SaveEmbeddedConten
 	}
 	key := string(keyBytes)
 
-	content, ok := state.Documents[sourceURI]
-	if !ok {
-		parsed, err := url.Parse(sourceURI)
-		if err == nil && parsed.Scheme == "file" {
-			bytes, err := os.ReadFile(parsed.Path)
-			if err == nil {
-				content = string(bytes)
-				state.Documents[sourceURI] = content
-			}
-		}
+	if state.Indexer.IsLibraryPath(uriToPath(sourceURI)) {
+		return nil, fmt.Errorf("%s belongs to a read-only library root", sourceURI)
 	}
 
+	content, _ := state.Documents.GetOrLoadFromDisk(sourceURI)
+
 	if content == "" {
 		return nil, fmt.Errorf("document not found: %s", sourceURI)
 	}
@@ -484,27 +1193,43 @@ func handleSaveEmbeddedContent(context *glsp.Context, params *SaveEmbeddedConten
 		endChar = len(lines[endLine-1])
 	}
 
-	edit := protocol.WorkspaceEdit{
-		Changes: map[string][]protocol.TextEdit{
-			sourceURI: {
-				{
-					Range: protocol.Range{
-						Start: protocol.Position{Line: 0, Character: 0},
-						End:   protocol.Position{Line: uint32(endLine), Character: uint32(endChar)},
-					},
-					NewText: newDocContent,
-				},
-			},
+	edit := resolver.BuildVerifiedEdit([]resolver.PendingEdit{{
+		URI: sourceURI,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: uint32(endLine), Character: uint32(endChar)},
 		},
+		OldText: content,
+		NewText: newDocContent,
+	}}, documentSource)
+	if len(edit.Warnings) > 0 {
+		log.Warn().Strs("warnings", edit.Warnings).Msg("Embedded content edit was withheld")
 	}
 
 	return edit, nil
 }
 
+// documentSource provides the text that workspace edits are verified against:
+// the client's copy (with its version) for open documents, the file on disk
+// otherwise.
+func documentSource(uri string) (string, *protocol.Integer, bool) {
+	if content, version, ok := state.Documents.GetWithVersion(uri); ok {
+		return content, &version, true
+	}
+
+	content, ok := state.Documents.GetOrLoadFromDisk(uri)
+	return content, nil, ok
+}
+
 func handleEmbeddedContent(context *glsp.Context, params *EmbeddedContentParams) (string, error) {
 	log.Debug().Str("uri", params.URI).Msg("Received embedded content request")
+	return embeddedFileContent(params.URI)
+}
 
-	u, err := url.Parse(params.URI)
+// embeddedFileContent returns the content of a k8s-embedded:// file, read
+// from the data entry of the document its URI names.
+func embeddedFileContent(uri string) (string, error) {
+	u, err := url.Parse(uri)
 	if err != nil {
 		return "", err
 	}
@@ -535,18 +1260,7 @@ func handleEmbeddedContent(context *glsp.Context, params *EmbeddedContentParams)
 
 	log.Debug().Str("source", sourceURI).Str("key", key).Msg("Decoded params")
 
-	content, ok := state.Documents[sourceURI]
-	if !ok {
-		// Try to read from disk
-		parsed, err := url.Parse(sourceURI)
-		if err == nil && parsed.Scheme == "file" {
-			bytes, err := os.ReadFile(parsed.Path)
-			if err == nil {
-				content = string(bytes)
-				state.Documents[sourceURI] = content
-			}
-		}
-	}
+	content, _ := state.Documents.GetOrLoadFromDisk(sourceURI)
 
 	if content == "" {
 		return "", fmt.Errorf("document not found: %s", sourceURI)
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ServerOptions is the initializationOptions, or k8sLsp settings section,
+// a client may send, e.g.
+//
+//	{
+//	  "rulesPath": "/etc/k8s-lsp/rules",
+//	  "crdSources": ["https://example.com/crds.yaml"],
+//	  "excludeGlobs": ["vendor", "charts/*/templates"],
+//	  "logLevel": "info",
+//	  "validation": false,
+//	  "inlayHints": false,
+//	  "indexCache": false,
+//	  "severityOverrides": {"namespace-fallback": "off"},
+//	  "workspaceDiagnostics": "references"
+//	}
+//
+// Omitted fields keep their defaults.
+type ServerOptions struct {
+	// RulesPath is the directory holding k8s.yaml and validation.yaml.
+	// Relative paths are resolved against the first workspace folder.
+	RulesPath string `json:"rulesPath,omitempty"`
+
+	// CRDSources replaces the crdSources setting of the rules.
+	CRDSources []string `json:"crdSources,omitempty"`
+
+	// ExcludeGlobs are skipped by the workspace scan; see
+	// Indexer.SetExcludeGlobs.
+	ExcludeGlobs []string `json:"excludeGlobs,omitempty"`
+
+	// LogLevel is a zerolog level: trace, debug, info, warn or error.
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Validation turns diagnostics off when false.
+	Validation *bool `json:"validation,omitempty"`
+
+	// InlayHints turns the resolved-target inlay hints off when false.
+	InlayHints *bool `json:"inlayHints,omitempty"`
+
+	// IndexCache turns off the on-disk cache that lets a workspace scan
+	// skip files unchanged since the last session when false. It is read
+	// before the first scan.
+	IndexCache *bool `json:"indexCache,omitempty"`
+
+	// SeverityOverrides maps a diagnostic code to error, warning,
+	// information, hint or off.
+	SeverityOverrides map[string]string `json:"severityOverrides,omitempty"`
+
+	// WorkspaceDiagnostics publishes diagnostics for indexed files that
+	// aren't open: "references" for files making at least one reference,
+	// "all" for every manifest, or "off" (the default).
+	WorkspaceDiagnostics string `json:"workspaceDiagnostics,omitempty"`
+}
+
+const (
+	workspaceDiagnosticsOff        = "off"
+	workspaceDiagnosticsReferences = "references"
+	workspaceDiagnosticsAll        = "all"
+)
+
+// defaultOptions describe the server without initializationOptions: rules
+// next to the executable and the log level of the command line (both set
+// in main).
+var defaultOptions = ServerOptions{LogLevel: "info"}
+
+// parseServerOptions decodes raw initializationOptions. Unknown fields are
+// returned rather than rejected so initialization never fails on them.
+func parseServerOptions(raw any) (ServerOptions, []string, error) {
+	var opts ServerOptions
+	if raw == nil {
+		return opts, nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return opts, nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return opts, nil, fmt.Errorf("initializationOptions must be an object: %w", err)
+	}
+	known := make(map[string]bool)
+	t := reflect.TypeOf(opts)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		known[name] = true
+	}
+	var unknown []string
+	for name := range fields {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return ServerOptions{}, unknown, err
+	}
+	return opts, unknown, nil
+}
+
+// merge returns o with every field set in over replacing its own.
+func (o ServerOptions) merge(over ServerOptions) ServerOptions {
+	if over.RulesPath != "" {
+		o.RulesPath = over.RulesPath
+	}
+	if over.CRDSources != nil {
+		o.CRDSources = over.CRDSources
+	}
+	if over.ExcludeGlobs != nil {
+		o.ExcludeGlobs = over.ExcludeGlobs
+	}
+	if over.LogLevel != "" {
+		o.LogLevel = over.LogLevel
+	}
+	if over.Validation != nil {
+		o.Validation = over.Validation
+	}
+	if over.InlayHints != nil {
+		o.InlayHints = over.InlayHints
+	}
+	if over.SeverityOverrides != nil {
+		o.SeverityOverrides = over.SeverityOverrides
+	}
+	if over.WorkspaceDiagnostics != "" {
+		o.WorkspaceDiagnostics = over.WorkspaceDiagnostics
+	}
+	return o
+}
+
+func (o ServerOptions) validationOff() bool {
+	return o.Validation != nil && !*o.Validation
+}
+
+func (o ServerOptions) inlayHintsOff() bool {
+	return o.InlayHints != nil && !*o.InlayHints
+}
+
+func (o ServerOptions) indexCacheOff() bool {
+	return o.IndexCache != nil && !*o.IndexCache
+}
+
+// workspaceDiagnostics is the WorkspaceDiagnostics mode, off when unset or
+// unknown.
+func (o ServerOptions) workspaceDiagnostics() string {
+	switch o.WorkspaceDiagnostics {
+	case workspaceDiagnosticsReferences, workspaceDiagnosticsAll:
+		return o.WorkspaceDiagnostics
+	case "", workspaceDiagnosticsOff:
+	default:
+		log.Warn().Str("workspaceDiagnostics", o.WorkspaceDiagnostics).Msg("Ignoring unknown workspace diagnostics mode")
+	}
+	return workspaceDiagnosticsOff
+}
+
+// mergeServerOptions decodes raw options, from initializationOptions or
+// the k8sLsp settings section, and merges them over base. Problems are
+// logged rather than returned so a bad field never stops the server.
+func mergeServerOptions(base ServerOptions, raw any) ServerOptions {
+	over, unknown, err := parseServerOptions(raw)
+	for _, name := range unknown {
+		log.Warn().Str("option", name).Msg("Ignoring unknown option")
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Ignoring invalid options")
+	}
+	return base.merge(over)
+}
+
+// applyServerOptions configures state from the client's
+// initializationOptions merged over the defaults.
+func applyServerOptions(raw any, roots []string) {
+	applyOptions(mergeServerOptions(defaultOptions, raw), roots)
+}
+
+// applyOptions brings state in line with opts. A different rules directory
+// reloads the rules and rebuilds the resolver and validator around the
+// existing Store. Outside of initialize, callers hold configMu.
+func applyOptions(opts ServerOptions, roots []string) {
+	if level, err := zerolog.ParseLevel(opts.LogLevel); err != nil || opts.LogLevel == "" {
+		log.Warn().Str("logLevel", opts.LogLevel).Msg("Ignoring unknown log level")
+	} else {
+		zerolog.SetGlobalLevel(level)
+	}
+
+	reloaded := false
+	if rulesPath := opts.RulesPath; rulesPath != "" {
+		if !filepath.IsAbs(rulesPath) && len(roots) > 0 {
+			rulesPath = filepath.Join(roots[0], rulesPath)
+		}
+		if filepath.Clean(rulesPath) != filepath.Clean(state.rulesDir) {
+			log.Info().Str("path", rulesPath).Msg("Loading rules")
+			reloadRules(rulesPath)
+			reloaded = true
+		}
+	}
+
+	state.CRDSources = state.Indexer.Config.Settings.CRDSources
+	if opts.CRDSources != nil {
+		state.CRDSources = opts.CRDSources
+	}
+	state.Indexer.SetExcludeGlobs(opts.ExcludeGlobs)
+	if opts.validationOff() {
+		state.Validator = nil
+	} else if state.options.validationOff() && !reloaded {
+		state.Validator = loadValidator(state.rulesDir, state.Store, state.Indexer.Config)
+	}
+	state.SeverityOverrides = opts.SeverityOverrides
+	state.options = opts
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseServerOptionsPartial(t *testing.T) {
+	raw := map[string]any{
+		"logLevel":     "info",
+		"excludeGlobs": []any{"vendor"},
+		"colour":       "blue",
+	}
+	opts, unknown, err := parseServerOptions(raw)
+	if err != nil {
+		t.Fatalf("parseServerOptions failed: %v", err)
+	}
+	if !reflect.DeepEqual(unknown, []string{"colour"}) {
+		t.Errorf("Expected colour to be reported as unknown, got %v", unknown)
+	}
+	if opts.LogLevel != "info" || !reflect.DeepEqual(opts.ExcludeGlobs, []string{"vendor"}) {
+		t.Errorf("Expected logLevel and excludeGlobs, got %+v", opts)
+	}
+	if opts.RulesPath != "" || opts.CRDSources != nil || opts.Validation != nil {
+		t.Errorf("Expected omitted fields to stay unset, got %+v", opts)
+	}
+
+	defaults := ServerOptions{RulesPath: "/opt/k8s-lsp/rules", CRDSources: []string{"https://example.com/crds.yaml"}, LogLevel: "debug"}
+	merged := defaults.merge(opts)
+	if merged.RulesPath != defaults.RulesPath || !reflect.DeepEqual(merged.CRDSources, defaults.CRDSources) {
+		t.Errorf("Expected defaults to survive the merge, got %+v", merged)
+	}
+	if merged.LogLevel != "info" || !reflect.DeepEqual(merged.ExcludeGlobs, []string{"vendor"}) {
+		t.Errorf("Expected the client's options to win, got %+v", merged)
+	}
+}
+
+func TestParseServerOptionsInvalid(t *testing.T) {
+	if opts, unknown, err := parseServerOptions(nil); err != nil || unknown != nil || !reflect.DeepEqual(opts, ServerOptions{}) {
+		t.Errorf("Expected no options without initializationOptions, got %+v %v %v", opts, unknown, err)
+	}
+	if _, _, err := parseServerOptions("verbose"); err == nil {
+		t.Error("Expected an error for non-object options")
+	}
+	if _, _, err := parseServerOptions(map[string]any{"validation": "yes"}); err == nil {
+		t.Error("Expected an error for a mistyped field")
+	}
+}
+
+func TestApplyServerOptionsLoadsRules(t *testing.T) {
+	root := t.TempDir()
+	rulesDir := filepath.Join(root, "k8s-rules")
+	if err := os.MkdirAll(rulesDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rulesDir, "k8s.yaml"), []byte(`settings:
+  crdSources: ["https://example.com/from-rules.yaml"]
+symbols:
+  - name: k8s.resource.name
+    definitions:
+      - kinds: ["ConfigMap"]
+        path: "metadata.name"
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rulesDir, "validation.yaml"), []byte("rules: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	state = newServerState(filepath.Join(t.TempDir(), "missing"))
+	applyServerOptions(map[string]any{"rulesPath": "k8s-rules"}, []string{root})
+	if len(state.Indexer.Config.Symbols) != 1 || state.Validator == nil {
+		t.Fatalf("Expected the rules from %s, got %+v", rulesDir, state.Indexer.Config)
+	}
+	if !reflect.DeepEqual(state.CRDSources, []string{"https://example.com/from-rules.yaml"}) {
+		t.Errorf("Expected the rules' crdSources, got %v", state.CRDSources)
+	}
+
+	applyServerOptions(map[string]any{
+		"rulesPath":  rulesDir,
+		"crdSources": []any{"https://example.com/crds.yaml"},
+		"validation": false,
+	}, nil)
+	if state.Validator != nil {
+		t.Error("Expected validation to be turned off")
+	}
+	if !reflect.DeepEqual(state.CRDSources, []string{"https://example.com/crds.yaml"}) {
+		t.Errorf("Expected the client's crdSources, got %v", state.CRDSources)
+	}
+}
+
+func TestApplyServerOptionsKeepsCommandLineLogLevel(t *testing.T) {
+	defer func(opts ServerOptions, level zerolog.Level) {
+		defaultOptions = opts
+		zerolog.SetGlobalLevel(level)
+	}(defaultOptions, zerolog.GlobalLevel())
+	setDefaultOptions(cliOptions{LogLevel: zerolog.WarnLevel, RulesDir: "rules"})
+
+	state = newServerState(defaultOptions.RulesPath)
+	applyServerOptions(nil, nil)
+	if level := zerolog.GlobalLevel(); level != zerolog.WarnLevel {
+		t.Errorf("Expected --log-level warn to be kept, got %s", level)
+	}
+	applyServerOptions(map[string]any{"logLevel": "error"}, nil)
+	if level := zerolog.GlobalLevel(); level != zerolog.ErrorLevel {
+		t.Errorf("Expected the client's logLevel, got %s", level)
+	}
+}
@@ -3,26 +3,357 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Version    int         `yaml:"version"`
+	Settings   Settings    `yaml:"settings"`
 	Symbols    []Symbol    `yaml:"symbols"`
 	References []Reference `yaml:"references"`
 }
 
+// Settings toggles optional server behavior. Every rules file may carry a
+// `settings` section; they are merged in load order.
+type Settings struct {
+	// CaseInsensitiveKinds compares resource kinds ignoring case (e.g. a
+	// reference to "deployment" resolves to a "Deployment"). Names are always
+	// matched exactly.
+	CaseInsensitiveKinds bool `yaml:"caseInsensitiveKinds"`
+
+	// OrphanedPersistentVolumes reports PersistentVolumes that no
+	// PersistentVolumeClaim binds via spec.volumeName. Off by default since
+	// PVs are often bound dynamically.
+	OrphanedPersistentVolumes bool `yaml:"orphanedPersistentVolumes"`
+
+	// NamespaceFallback reports references that only resolve because the
+	// lookup falls back to the "default" namespace, which usually means the
+	// target was created in the wrong namespace.
+	NamespaceFallback bool `yaml:"namespaceFallback"`
+
+	// HostAliasLinks adds hovers summarizing pod hostAliases and DNS config,
+	// and links hostAliases entries to the Services whose clusterIP or
+	// externalName they name. Off by default.
+	HostAliasLinks bool `yaml:"hostAliasLinks"`
+
+	// BuiltinFeatures switches individual hard-coded special cases on or off
+	// (feature name -> enabled). Features that are not listed stay enabled:
+	//   volumeMountNavigation - definition from volumeMounts[].name to volumes[].name
+	//   subPathTargets        - references on volumeMounts[].subPath list the
+	//                           ConfigMap/Secret key and the embedded file
+	//   embeddedFiles         - hover links, definition and usages for
+	//                           file-like ConfigMap data entries
+	//   pvcClaimUsages        - references on persistentVolumeClaim.claimName
+	//                           list the volumeMounts that use the claim
+	BuiltinFeatures map[string]bool `yaml:"builtinFeatures"`
+
+	// LibraryRoots lists extra directories (e.g. a shared manifests repo)
+	// indexed at startup next to the workspace. Relative paths are resolved
+	// against the workspace root. Library resources are read-only.
+	LibraryRoots []string `yaml:"libraryRoots"`
+
+	// Exclude lists globs the workspace scan skips, in gitignore syntax
+	// (e.g. "**/charts/**", "dist/"). .gitignore files are honored too.
+	Exclude []string `yaml:"exclude"`
+
+	// PolicyPacks enables opt-in groups of semantic validations:
+	//   autoscaling - HorizontalPodAutoscaler replica bounds against the
+	//                 target workload and PodDisruptionBudgets
+	PolicyPacks []string `yaml:"policyPacks"`
+
+	// CRDSources lists URLs serving CustomResourceDefinition manifests that
+	// are downloaded at startup (and on k8s.refreshCRDs) to learn extra kinds.
+	CRDSources []string `yaml:"crdSources"`
+
+	// NamespaceAliases maps alias namespaces to the namespace they stand for
+	// (alias -> canonical), so "a-alias/app" and "a/app" are the same
+	// resource when resolving references.
+	NamespaceAliases map[string]string `yaml:"namespaceAliases"`
+
+	// ImageBuildAnnotation names an annotation (e.g. the one Skaffold or
+	// Tilt sets) whose value is the image a resource builds. When set,
+	// go-to-definition on a container image jumps to the resources declaring
+	// it. Empty, the default, turns the link off.
+	ImageBuildAnnotation string `yaml:"imageBuildAnnotation"`
+
+	// ImageRegistryURLs maps registry hosts to the web page linked from
+	// container images. "{repository}" is replaced by the image path without
+	// registry, tag or digest. Entries extend or override the built-in
+	// docker.io, ghcr.io, quay.io and gcr.io mappings; other registries link
+	// to https://<registry>.
+	ImageRegistryURLs map[string]string `yaml:"imageRegistryURLs"`
+
+	// DocumentLimits guards against pathological YAML such as deep nesting
+	// or billion-laughs anchors.
+	DocumentLimits DocumentLimits `yaml:"documentLimits"`
+
+	// WatchDebounceMs is how long watched-file events are collected before
+	// the affected files are re-indexed once each (default 200).
+	WatchDebounceMs int `yaml:"watchDebounceMs"`
+
+	// ScanWorkers is how many files the workspace scan indexes concurrently
+	// (default: the number of CPUs).
+	ScanWorkers int `yaml:"scanWorkers"`
+
+	// ChangeDebounceMs is how long an edited document must stay unchanged
+	// before it is re-indexed and validated (default 300). Opening and
+	// saving a document apply immediately.
+	ChangeDebounceMs int `yaml:"changeDebounceMs"`
+
+	// Interpolation resolves $(var) and ${var} in reference values before
+	// they are looked up, for CRDs whose controllers substitute variables.
+	Interpolation Interpolation `yaml:"interpolation"`
+}
+
+// Interpolation is the variable set references are resolved against.
+// Values that stay unresolved are never reported as missing.
+type Interpolation struct {
+	// Values are variables available to every manifest.
+	Values map[string]string `yaml:"values"`
+	// ValuesFile names a flat YAML mapping of variables read from each
+	// manifest's directory, e.g. "values.yaml". It overrides Values.
+	ValuesFile string `yaml:"valuesFile"`
+}
+
+// Enabled reports whether any variables are configured.
+func (i Interpolation) Enabled() bool {
+	return len(i.Values) > 0 || i.ValuesFile != ""
+}
+
+// DocumentLimits bounds the analysis of a single YAML document. Zero values
+// use the defaults.
+type DocumentLimits struct {
+	// MaxDepth is the deepest node nesting analyzed (default 100).
+	MaxDepth int `yaml:"maxDepth"`
+	// MaxNodes is the most nodes analyzed per document, counting each alias
+	// as a copy of its anchor (default 100000).
+	MaxNodes int `yaml:"maxNodes"`
+	// TimeoutMs is the wall-clock budget of a single request in
+	// milliseconds (default 5000).
+	TimeoutMs int `yaml:"timeoutMs"`
+	// MaxEmbeddedBytes is the largest ConfigMap or Secret value opened or
+	// saved as an embedded file (default 1048576, the size limit of a
+	// ConfigMap).
+	MaxEmbeddedBytes int `yaml:"maxEmbeddedBytes"`
+}
+
+const (
+	defaultMaxDepth         = 100
+	defaultMaxNodes         = 100000
+	defaultTimeoutMs        = 5000
+	defaultMaxEmbeddedBytes = 1 << 20
+	defaultWatchDebounceMs  = 200
+	defaultChangeDebounceMs = 300
+)
+
+// Depth returns MaxDepth or its default.
+func (l DocumentLimits) Depth() int {
+	if l.MaxDepth > 0 {
+		return l.MaxDepth
+	}
+	return defaultMaxDepth
+}
+
+// Nodes returns MaxNodes or its default.
+func (l DocumentLimits) Nodes() int {
+	if l.MaxNodes > 0 {
+		return l.MaxNodes
+	}
+	return defaultMaxNodes
+}
+
+// Timeout returns TimeoutMs, or its default, as a duration.
+func (l DocumentLimits) Timeout() time.Duration {
+	if l.TimeoutMs > 0 {
+		return time.Duration(l.TimeoutMs) * time.Millisecond
+	}
+	return defaultTimeoutMs * time.Millisecond
+}
+
+// EmbeddedBytes returns MaxEmbeddedBytes or its default.
+func (l DocumentLimits) EmbeddedBytes() int {
+	if l.MaxEmbeddedBytes > 0 {
+		return l.MaxEmbeddedBytes
+	}
+	return defaultMaxEmbeddedBytes
+}
+
+// WatchDebounce returns WatchDebounceMs, or its default, as a duration.
+func (s Settings) WatchDebounce() time.Duration {
+	if s.WatchDebounceMs > 0 {
+		return time.Duration(s.WatchDebounceMs) * time.Millisecond
+	}
+	return defaultWatchDebounceMs * time.Millisecond
+}
+
+// ChangeDebounce returns ChangeDebounceMs, or its default, as a duration.
+func (s Settings) ChangeDebounce() time.Duration {
+	if s.ChangeDebounceMs > 0 {
+		return time.Duration(s.ChangeDebounceMs) * time.Millisecond
+	}
+	return defaultChangeDebounceMs * time.Millisecond
+}
+
+// ScanWorkerCount returns ScanWorkers, or the number of CPUs when unset.
+func (s Settings) ScanWorkerCount() int {
+	if s.ScanWorkers > 0 {
+		return s.ScanWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// CanonicalNamespace resolves ns through NamespaceAliases. An empty namespace
+// is treated as "default".
+func (s Settings) CanonicalNamespace(ns string) string {
+	if ns == "" {
+		ns = "default"
+	}
+	if canonical, ok := s.NamespaceAliases[ns]; ok && canonical != "" {
+		return canonical
+	}
+	return ns
+}
+
+// Policy pack names accepted in Settings.PolicyPacks.
+const (
+	PolicyPackAutoscaling = "autoscaling"
+)
+
+// PolicyPackEnabled reports whether the named policy pack is enabled.
+func (s Settings) PolicyPackEnabled(name string) bool {
+	for _, p := range s.PolicyPacks {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in feature names accepted in Settings.BuiltinFeatures.
+const (
+	FeatureVolumeMountNavigation = "volumeMountNavigation"
+	FeatureSubPathTargets        = "subPathTargets"
+	FeatureEmbeddedFiles         = "embeddedFiles"
+	FeaturePVCClaimUsages        = "pvcClaimUsages"
+)
+
+// FeatureEnabled reports whether a built-in feature is enabled. Features
+// default to enabled unless explicitly set to false.
+func (s Settings) FeatureEnabled(name string) bool {
+	enabled, ok := s.BuiltinFeatures[name]
+	return !ok || enabled
+}
+
+func (s *Settings) merge(other Settings) {
+	if other.CaseInsensitiveKinds {
+		s.CaseInsensitiveKinds = true
+	}
+	if other.OrphanedPersistentVolumes {
+		s.OrphanedPersistentVolumes = true
+	}
+	if other.NamespaceFallback {
+		s.NamespaceFallback = true
+	}
+	if other.HostAliasLinks {
+		s.HostAliasLinks = true
+	}
+	if other.DocumentLimits.MaxDepth > 0 {
+		s.DocumentLimits.MaxDepth = other.DocumentLimits.MaxDepth
+	}
+	if other.DocumentLimits.MaxNodes > 0 {
+		s.DocumentLimits.MaxNodes = other.DocumentLimits.MaxNodes
+	}
+	if other.DocumentLimits.TimeoutMs > 0 {
+		s.DocumentLimits.TimeoutMs = other.DocumentLimits.TimeoutMs
+	}
+	if other.DocumentLimits.MaxEmbeddedBytes > 0 {
+		s.DocumentLimits.MaxEmbeddedBytes = other.DocumentLimits.MaxEmbeddedBytes
+	}
+	if other.ImageBuildAnnotation != "" {
+		s.ImageBuildAnnotation = other.ImageBuildAnnotation
+	}
+	if other.WatchDebounceMs > 0 {
+		s.WatchDebounceMs = other.WatchDebounceMs
+	}
+	if other.ChangeDebounceMs > 0 {
+		s.ChangeDebounceMs = other.ChangeDebounceMs
+	}
+	if other.ScanWorkers > 0 {
+		s.ScanWorkers = other.ScanWorkers
+	}
+	if other.Interpolation.ValuesFile != "" {
+		s.Interpolation.ValuesFile = other.Interpolation.ValuesFile
+	}
+	for name, value := range other.Interpolation.Values {
+		if s.Interpolation.Values == nil {
+			s.Interpolation.Values = make(map[string]string)
+		}
+		s.Interpolation.Values[name] = value
+	}
+	s.LibraryRoots = append(s.LibraryRoots, other.LibraryRoots...)
+	s.Exclude = append(s.Exclude, other.Exclude...)
+	s.PolicyPacks = append(s.PolicyPacks, other.PolicyPacks...)
+	s.CRDSources = append(s.CRDSources, other.CRDSources...)
+	for registry, url := range other.ImageRegistryURLs {
+		if s.ImageRegistryURLs == nil {
+			s.ImageRegistryURLs = make(map[string]string)
+		}
+		s.ImageRegistryURLs[registry] = url
+	}
+	for alias, canonical := range other.NamespaceAliases {
+		if s.NamespaceAliases == nil {
+			s.NamespaceAliases = make(map[string]string)
+		}
+		s.NamespaceAliases[alias] = canonical
+	}
+	for name, enabled := range other.BuiltinFeatures {
+		if s.BuiltinFeatures == nil {
+			s.BuiltinFeatures = make(map[string]bool)
+		}
+		s.BuiltinFeatures[name] = enabled
+	}
+}
+
 type Symbol struct {
-	Name        string             `yaml:"name"`
-	Description string             `yaml:"description"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// KeyTemplate is a text/template building the store key of the
+	// resources the symbol defines, e.g. "{{ .kind }}/{{ .namespace }}/{{ .name }}";
+	// see indexer.Store.KeyVars for its variables. Only k8s.resource.name
+	// keys resources, so other symbols' templates are ignored.
 	KeyTemplate string             `yaml:"keyTemplate"`
 	Definitions []SymbolDefinition `yaml:"definitions"`
 }
 
+// ResourceKeyTemplate is the key template of the k8s.resource.name symbol,
+// or "" for the default Kind/Namespace/Name keys.
+func (c *Config) ResourceKeyTemplate() string {
+	for _, sym := range c.Symbols {
+		if sym.Name == "k8s.resource.name" && sym.KeyTemplate != "" {
+			return sym.KeyTemplate
+		}
+	}
+	return ""
+}
+
 type SymbolDefinition struct {
 	Kinds []string `yaml:"kinds"`
-	Path  string   `yaml:"path"`
+	// ApiGroups limits the definition to documents whose apiVersion is in
+	// one of these API groups; see ReferenceMatch.ApiGroups.
+	ApiGroups []string `yaml:"apiGroups"`
+	Path      string   `yaml:"path"`
+	// AliasKind makes the value at Path define a resource of this kind with
+	// that name, e.g. a Certificate's spec.secretName declares the Secret
+	// cert-manager will create. Only used with k8s.resource.name.
+	AliasKind string `yaml:"aliasKind"`
+	// Field stores the scalar value at Path in the field index under this
+	// name, so k8s.field references can select the resource by it. Only
+	// used with k8s.field.
+	Field string `yaml:"field"`
 }
 
 type Reference struct {
@@ -30,23 +361,76 @@ type Reference struct {
 	Symbol     string         `yaml:"symbol"`
 	TargetKind string         `yaml:"targetKind"`
 	Match      ReferenceMatch `yaml:"match"`
+
+	// CompositePath describes values that pack the target's namespace and
+	// name into one string, e.g. "namespace/name" for "foo-ns/my-secret".
+	CompositePath string `yaml:"compositePath"`
+
+	// Field makes a k8s.field reference a field selector: it targets every
+	// TargetKind resource whose indexed Field equals the value at
+	// Match.Path.
+	Field string `yaml:"field"`
 }
 
 type ReferenceMatch struct {
 	Kinds []string `yaml:"kinds"`
-	Path  string   `yaml:"path"`
+	// ApiGroups limits the rule to documents whose apiVersion is in one of
+	// these API groups, e.g. ["example.com"], for kinds that several CRDs
+	// share. "" (or "core") is the core group of "v1". Empty matches any
+	// apiVersion.
+	ApiGroups []string `yaml:"apiGroups"`
+	Path      string   `yaml:"path"`
+}
+
+// MatchesAPIVersion reports whether the rule applies to a document with
+// apiVersion.
+func (m ReferenceMatch) MatchesAPIVersion(apiVersion string) bool {
+	return inAPIGroups(m.ApiGroups, apiVersion)
+}
+
+// MatchesAPIVersion reports whether the definition applies to a document
+// with apiVersion.
+func (d SymbolDefinition) MatchesAPIVersion(apiVersion string) bool {
+	return inAPIGroups(d.ApiGroups, apiVersion)
+}
+
+// APIGroup returns the group of apiVersion: "apps" for "apps/v1" and ""
+// for the core "v1".
+func APIGroup(apiVersion string) string {
+	group, _, found := strings.Cut(apiVersion, "/")
+	if !found {
+		return ""
+	}
+	return group
+}
+
+func inAPIGroups(groups []string, apiVersion string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	group := APIGroup(apiVersion)
+	for _, g := range groups {
+		if g == group || (g == "core" && group == "") {
+			return true
+		}
+	}
+	return false
 }
 
 func Load(rootPath string) (*Config, error) {
+	return LoadDir(filepath.Join(rootPath, "rules"))
+}
+
+// LoadDir reads every rules file in rulesDir, e.g. one a client configured
+// instead of the rules shipped next to the executable.
+func LoadDir(rulesDir string) (*Config, error) {
 	cfg := &Config{}
 
-	// Walk through rules directory
-	rulesDir := filepath.Join(rootPath, "rules")
 	err := filepath.Walk(rulesDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && (filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml") {
+		if !info.IsDir() && isRulesFile(path) {
 			f, err := os.Open(path)
 			if err != nil {
 				return err
@@ -58,6 +442,7 @@ func Load(rootPath string) (*Config, error) {
 				return err
 			}
 
+			cfg.Settings.merge(c.Settings)
 			cfg.Symbols = append(cfg.Symbols, c.Symbols...)
 			cfg.References = append(cfg.References, c.References...)
 		}
@@ -75,3 +460,7 @@ func Load(rootPath string) (*Config, error) {
 
 	return cfg, nil
 }
+
+func isRulesFile(path string) bool {
+	return filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml"
+}
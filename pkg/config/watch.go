@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WatchInterval is how often the server checks its rules for changes.
+const WatchInterval = 2 * time.Second
+
+// Watch reloads the rules in rulesDir whenever a rules file is added,
+// removed or modified, and hands the new Config to onReload. Modification
+// times are polled every interval until ctx is done. Rules that fail to
+// load, or a directory left without rules files, are logged and skipped, so
+// the rules loaded before stay in effect until the files are fixed.
+func Watch(ctx context.Context, rulesDir string, interval time.Duration, onReload func(*Config)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := rulesStamp(rulesDir)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stamp := rulesStamp(rulesDir)
+		if stamp == last {
+			continue
+		}
+		last = stamp
+		if stamp == "" {
+			// A rules directory that disappeared mid-checkout would
+			// otherwise load as no rules at all.
+			log.Warn().Str("path", rulesDir).Msg("No rules files left; keeping the previous rules")
+			continue
+		}
+		cfg, err := LoadDir(rulesDir)
+		if err != nil {
+			log.Error().Err(err).Str("path", rulesDir).Msg("Failed to reload rules; keeping the previous ones")
+			continue
+		}
+		log.Info().Str("path", rulesDir).Msg("Reloaded rules")
+		onReload(cfg)
+	}
+}
+
+// rulesStamp lists the path, size and modification time of every rules
+// file in rulesDir, so that any change to them changes the stamp.
+func rulesStamp(rulesDir string) string {
+	var b strings.Builder
+	filepath.Walk(rulesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isRulesFile(path) {
+			return nil
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package crd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"k8s-lsp/pkg/indexer"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Downloader fetches CustomResourceDefinition manifests from remote sources
+// and indexes them so their kinds become known to the indexer. Responses are
+// cached by ETag, so refreshing only transfers sources that changed.
+type Downloader struct {
+	Client *http.Client
+	// MaxBytes is the largest source read; 0 means DefaultMaxBytes.
+	MaxBytes int64
+
+	mu    sync.Mutex
+	cache map[string]cachedSource // URL -> last successful response
+}
+
+// DefaultMaxBytes bounds a CRD source, so a misconfigured URL can't make
+// the server read an arbitrarily large response.
+const DefaultMaxBytes = 32 << 20
+
+type cachedSource struct {
+	etag string
+	body string
+}
+
+func NewDownloader(client *http.Client) *Downloader {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Downloader{
+		Client: client,
+		cache:  make(map[string]cachedSource),
+	}
+}
+
+// DownloadAndIndex fetches every source and indexes the CRDs it serves.
+// Unchanged sources (HTTP 304) are re-indexed from the cache. Registering a
+// kind that is already known is a no-op, so this can be called repeatedly.
+// Failing sources are skipped; the first error is returned.
+func (d *Downloader) DownloadAndIndex(ctx context.Context, sources []string, idx *indexer.Indexer) error {
+	var firstErr error
+	for _, url := range sources {
+		body, err := d.fetch(ctx, url)
+		if err != nil {
+			log.Error().Err(err).Str("url", url).Msg("Failed to download CRDs")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		idx.IndexContent(url, body)
+	}
+	return firstErr
+}
+
+func (d *Downloader) fetch(ctx context.Context, url string) (string, error) {
+	d.mu.Lock()
+	cached, hasCache := d.cache[url]
+	d.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if hasCache && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if !hasCache {
+			return "", fmt.Errorf("%s: not modified but nothing cached", url)
+		}
+		log.Debug().Str("url", url).Msg("CRD source not modified")
+		return cached.body, nil
+	case http.StatusOK:
+	default:
+		return "", fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+
+	limit := d.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("%s: larger than %d bytes", url, limit)
+	}
+	body := string(data)
+
+	d.mu.Lock()
+	d.cache[url] = cachedSource{etag: resp.Header.Get("ETag"), body: body}
+	d.mu.Unlock()
+
+	log.Info().Str("url", url).Msg("Downloaded CRDs")
+	return body, nil
+}
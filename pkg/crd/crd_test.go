@@ -0,0 +1,154 @@
+package crd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+const widgetCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+`
+
+const gadgetCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gadget
+`
+
+func isRegistered(cfg *config.Config, kind string) bool {
+	for _, sym := range cfg.Symbols {
+		if sym.Name != "k8s.resource.name" {
+			continue
+		}
+		for _, def := range sym.Definitions {
+			for _, k := range def.Kinds {
+				if k == kind {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestRefreshPicksUpNewKind(t *testing.T) {
+	var mu sync.Mutex
+	body, etag := widgetCRD, `"v1"`
+	var notModified int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	idx := indexer.NewIndexer(indexer.NewStore(), cfg)
+	d := NewDownloader(server.Client())
+	sources := []string{server.URL + "/crds.yaml"}
+
+	if err := d.DownloadAndIndex(context.Background(), sources, idx); err != nil {
+		t.Fatalf("DownloadAndIndex failed: %v", err)
+	}
+	if !isRegistered(cfg, "Widget") {
+		t.Fatal("Expected Widget to be registered")
+	}
+
+	// Unchanged source: served from cache, registration stays idempotent.
+	if err := d.DownloadAndIndex(context.Background(), sources, idx); err != nil {
+		t.Fatalf("DownloadAndIndex failed: %v", err)
+	}
+	if notModified != 1 {
+		t.Fatalf("Expected a conditional request answered with 304, got %d", notModified)
+	}
+	widgetCount := 0
+	for _, def := range cfg.Symbols[0].Definitions {
+		for _, k := range def.Kinds {
+			if k == "Widget" {
+				widgetCount++
+			}
+		}
+	}
+	if widgetCount != 1 {
+		t.Fatalf("Expected Widget to be registered once, got %d", widgetCount)
+	}
+
+	mu.Lock()
+	body, etag = widgetCRD+"---\n"+gadgetCRD, `"v2"`
+	mu.Unlock()
+
+	if err := d.DownloadAndIndex(context.Background(), sources, idx); err != nil {
+		t.Fatalf("DownloadAndIndex failed: %v", err)
+	}
+	if !isRegistered(cfg, "Gadget") {
+		t.Fatal("Expected refresh to register the newly added Gadget kind")
+	}
+}
+
+func TestOversizedSourceIsSkipped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(widgetCRD))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	idx := indexer.NewIndexer(indexer.NewStore(), cfg)
+	d := NewDownloader(server.Client())
+	d.MaxBytes = int64(len(widgetCRD) - 1)
+
+	if err := d.DownloadAndIndex(context.Background(), []string{server.URL + "/crds.yaml"}, idx); err == nil {
+		t.Fatal("Expected a source over MaxBytes to fail")
+	}
+	if isRegistered(cfg, "Widget") {
+		t.Error("Expected nothing to be read from the oversized source")
+	}
+
+	d.MaxBytes = int64(len(widgetCRD))
+	if err := d.DownloadAndIndex(context.Background(), []string{server.URL + "/crds.yaml"}, idx); err != nil {
+		t.Fatalf("Expected a source of exactly MaxBytes to be read, got %v", err)
+	}
+	if !isRegistered(cfg, "Widget") {
+		t.Error("Expected Widget to be registered")
+	}
+}
@@ -0,0 +1,378 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s-lsp/pkg/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+// indexCacheVersion is bumped whenever the cached form of K8sResource or
+// of an entry changes, so older caches are discarded rather than misread.
+const indexCacheVersion = 3
+
+// IndexCache keeps the resources indexed from workspace files between
+// sessions. Each file's entry is keyed by its modification time, size and
+// content hash; a scan reuses the entry of a file that still matches and
+// indexes the rest. Entries are only valid for the rules they were produced
+// with, so the cache records a fingerprint of those and is emptied when
+// they change. An entry also records the kinds that CRDs registered or
+// didn't when it was produced; a scan indexes the file again once it has
+// seen that this is no longer so.
+type IndexCache struct {
+	path string
+
+	mu      sync.Mutex
+	rules   string
+	entries map[string]*cacheEntry
+	changes int // since the cache was loaded or last saved
+}
+
+type cacheEntry struct {
+	ModTime   int64           `json:"modTime"` // UnixNano
+	Size      int64           `json:"size"`
+	Hash      string          `json:"hash"` // SHA-256 of the content
+	Resources json.RawMessage `json:"resources"`
+	Kinds     kindUse         `json:"kinds"`
+}
+
+type cacheFile struct {
+	Version int                    `json:"version"`
+	Rules   string                 `json:"rules"`
+	Files   map[string]*cacheEntry `json:"files"`
+}
+
+// LoadIndexCache reads the cache stored at path. A missing file gives an
+// empty cache, and so does an unreadable or corrupt one, which is logged
+// and replaced on the next Save.
+func LoadIndexCache(path string) *IndexCache {
+	c := &IndexCache{path: path, entries: make(map[string]*cacheEntry)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("path", path).Msg("Ignoring unreadable index cache")
+		}
+		return c
+	}
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Ignoring corrupt index cache")
+		c.changes++
+		return c
+	}
+	if file.Version != indexCacheVersion {
+		log.Info().Int("version", file.Version).Str("path", path).Msg("Ignoring index cache of another version")
+		c.changes++
+		return c
+	}
+	c.rules = file.Rules
+	for path, entry := range file.Files {
+		if entry != nil {
+			c.entries[path] = entry
+		}
+	}
+	log.Info().Int("files", len(c.entries)).Str("path", path).Msg("Loaded index cache")
+	return c
+}
+
+// Path returns where the cache is stored.
+func (c *IndexCache) Path() string {
+	return c.path
+}
+
+// Len returns the number of files the cache has entries for.
+func (c *IndexCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Changes returns how many entries changed since the cache was loaded or
+// last saved.
+func (c *IndexCache) Changes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.changes
+}
+
+// Save writes the cache if anything changed, through a temporary file
+// renamed over the old one so a crash never leaves half a cache behind.
+func (c *IndexCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.changes == 0 {
+		return nil
+	}
+	data, err := json.Marshal(cacheFile{Version: indexCacheVersion, Rules: c.rules, Files: c.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	log.Debug().Int("files", len(c.entries)).Str("path", c.path).Msg("Saved index cache")
+	c.changes = 0
+	return nil
+}
+
+// useRules empties the cache unless its entries were produced with the
+// rules of fingerprint.
+func (c *IndexCache) useRules(fingerprint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rules == fingerprint {
+		return
+	}
+	if len(c.entries) > 0 {
+		log.Info().Int("files", len(c.entries)).Msg("Rules changed; discarding index cache")
+	}
+	c.rules = fingerprint
+	clear(c.entries)
+	c.changes++
+}
+
+// reset drops every entry.
+func (c *IndexCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.changes++
+}
+
+// lookup returns the resources cached for path if info still matches its
+// entry. A file whose modification time changed but whose size didn't is
+// read and compared by hash, since checkouts touch files without changing
+// them.
+func (c *IndexCache) lookup(path string, info os.FileInfo) ([]*K8sResource, kindUse, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+	if !ok || entry.Size != info.Size() {
+		return nil, kindUse{}, false
+	}
+	if entry.ModTime != info.ModTime().UnixNano() {
+		data, err := os.ReadFile(path)
+		if err != nil || contentHash(data) != entry.Hash {
+			return nil, kindUse{}, false
+		}
+		c.mu.Lock()
+		if c.entries[path] == entry {
+			touched := *entry
+			touched.ModTime = info.ModTime().UnixNano()
+			c.entries[path] = &touched
+			c.changes++
+		}
+		c.mu.Unlock()
+	}
+
+	var resources []*K8sResource
+	if err := json.Unmarshal(entry.Resources, &resources); err != nil {
+		c.forget(path)
+		return nil, kindUse{}, false
+	}
+	var all []*K8sResource
+	for _, res := range resources {
+		if res == nil {
+			continue
+		}
+		all = append(all, res)
+		for _, declared := range res.Declared {
+			declared.DeclaredBy = res
+			all = append(all, declared)
+		}
+	}
+	return all, entry.Kinds, true
+}
+
+// put records the resources indexed from path, as read when it had info
+// and content, and the kinds they used. Resources declared by others are
+// cached with them.
+func (c *IndexCache) put(path string, info os.FileInfo, content []byte, resources []*K8sResource, kinds kindUse) {
+	var top []*K8sResource
+	for _, res := range resources {
+		if res.DeclaredBy == nil {
+			top = append(top, res)
+		}
+	}
+	data, err := json.Marshal(top)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to cache indexed resources")
+		c.forget(path)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = &cacheEntry{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Hash: contentHash(content), Resources: data, Kinds: kinds}
+	c.changes++
+}
+
+// forget drops the entry of path.
+func (c *IndexCache) forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[path]; ok {
+		delete(c.entries, path)
+		c.changes++
+	}
+}
+
+// forgetUnder drops the entries of files below dir that keep does not
+// report as present.
+func (c *IndexCache) forgetUnder(dir string, keep func(path string) bool) {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.entries {
+		if strings.HasPrefix(path, prefix) && (keep == nil || !keep(path)) {
+			delete(c.entries, path)
+			c.changes++
+		}
+	}
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheKey names the cache of a workspace with the given roots.
+func CacheKey(roots []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(roots, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SetCache makes scans reuse the entries of cache for files that haven't
+// changed, and record in it what they index. nil turns caching off.
+func (i *Indexer) SetCache(cache *IndexCache) {
+	i.cfgMu.RLock()
+	defer i.cfgMu.RUnlock()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.cache = cache
+	i.checkCacheRules()
+}
+
+// SaveCache saves the cache once at least minChanges entries changed since
+// it was last saved.
+func (i *Indexer) SaveCache(minChanges int) error {
+	i.mu.RLock()
+	cache := i.cache
+	i.mu.RUnlock()
+	if cache == nil || cache.Changes() < max(minChanges, 1) {
+		return nil
+	}
+	return cache.Save()
+}
+
+// ResetCache drops every cached file, so the next scan indexes them all.
+func (i *Indexer) ResetCache() {
+	i.mu.RLock()
+	cache := i.cache
+	i.mu.RUnlock()
+	if cache != nil {
+		cache.reset()
+	}
+}
+
+// checkCacheRules empties the cache if the rules changed since its entries
+// were produced; cfgMu and mu must be held.
+func (i *Indexer) checkCacheRules() {
+	if i.cache == nil {
+		return
+	}
+	data, err := json.Marshal(struct {
+		Config       *config.Config
+		LibraryRoots []string
+	}{i.Config, i.libraryRoots})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fingerprint rules; discarding index cache")
+		i.cache.reset()
+		return
+	}
+	i.cache.useRules(contentHash(data))
+}
+
+// restoreCached puts the cached resources of path in the Store if the file
+// hasn't changed since they were indexed. ok reports whether it had, and
+// indexed whether the file holds any resources.
+func (i *Indexer) restoreCached(path string) (indexed, ok bool) {
+	i.mu.RLock()
+	cache := i.cache
+	i.mu.RUnlock()
+	if cache == nil {
+		return false, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, false
+	}
+	resources, kinds, ok := cache.lookup(path, info)
+	if !ok {
+		return false, false
+	}
+
+	i.cfgMu.RLock()
+	defer i.cfgMu.RUnlock()
+	// Cached files declare no CRDs (see cacheFile), whatever path held
+	// before.
+	i.settleCRDKinds(path, true)
+	i.mu.Lock()
+	i.setFileKinds(path, kinds)
+	i.mu.Unlock()
+	i.Store.SetSchemas(path, nil, true)
+	i.Store.ReplaceFile(path, resources)
+	return len(resources) > 0, true
+}
+
+// cacheFile records what indexing path, read with info and content,
+// produced. Files declaring CRDs are left out so that their kinds are
+// registered by indexing them, as are files that could only be read in
+// part.
+func (i *Indexer) cacheFile(path string, info os.FileInfo, content []byte, resources []*K8sResource, complete bool) {
+	i.mu.RLock()
+	cache := i.cache
+	declaresKinds := len(i.fileCRDKinds[path]) > 0
+	kinds := i.fileKinds[path]
+	i.mu.RUnlock()
+	if cache == nil {
+		return
+	}
+	if !complete || declaresKinds {
+		cache.forget(path)
+		return
+	}
+	cache.put(path, info, content, resources, kinds)
+}
+
+// pruneCache drops the entries of files under root that a complete scan of
+// it didn't find, as they were deleted or are excluded now.
+func (i *Indexer) pruneCache(root string, found map[string]bool) {
+	i.mu.RLock()
+	cache := i.cache
+	i.mu.RUnlock()
+	if cache != nil {
+		cache.forgetUnder(root, func(path string) bool { return found[path] })
+	}
+}
@@ -0,0 +1,229 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s-lsp/pkg/config"
+)
+
+const cachedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: web-config
+`
+
+func loadRules(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadDir("../../rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// scanWithCache runs a session: a fresh Store indexing root with the cache
+// at path, saved afterwards.
+func scanWithCache(t *testing.T, cfg *config.Config, root, path string) (*Store, *IndexCache) {
+	t.Helper()
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+	cache := LoadIndexCache(path)
+	idx.SetCache(cache)
+	if _, err := idx.ScanWorkspaceCount(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.SaveCache(1); err != nil {
+		t.Fatal(err)
+	}
+	return store, cache
+}
+
+func TestIndexCacheReusesUnchangedFiles(t *testing.T) {
+	root := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "index", CacheKey([]string{root})+".json")
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	deploy := write("deploy.yaml", cachedDeployment)
+	write("cm.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web-config\n")
+	write("old.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: old\n")
+	write("crd.yaml", `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+`)
+	write("widget.yaml", "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: spinner\n")
+	write("broken.yaml", "kind: [unterminated\n")
+
+	first, cache := scanWithCache(t, loadRules(t), root, cachePath)
+	// The CRD is indexed again each session to register its kind, and the
+	// broken file to retry it.
+	if n := cache.Len(); n != 4 {
+		t.Fatalf("Expected deploy, cm, old and widget to be cached, got %d entries", n)
+	}
+	web := first.ListByKind("Deployment")
+	if len(web) != 1 || len(web[0].References) == 0 || web[0].Labels["app"] != "web" {
+		t.Fatalf("Expected the Deployment with its labels and references, got %v", web)
+	}
+
+	// An edit of the same size with the modification time put back looks
+	// unchanged, so only a reused entry still has the old label.
+	info, err := os.Stat(deploy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("deploy.yaml", cachedDeployment[:len(cachedDeployment)-len("web-config\n")]+"xyz-config\n")
+	if err := os.Chtimes(deploy, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	write("cm.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: renamed-config\n")
+	if err := os.Remove(filepath.Join(root, "old.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	second, cache := scanWithCache(t, loadRules(t), root, cachePath)
+	reused := second.ListByKind("Deployment")
+	if len(reused) != 1 || !reflect.DeepEqual(reused[0].Labels, web[0].Labels) || !reflect.DeepEqual(reused[0].References, web[0].References) {
+		t.Fatalf("Expected the cached Deployment, got %v", reused)
+	}
+	if cms := second.ListByKind("ConfigMap"); len(cms) != 1 || cms[0].Name != "renamed-config" {
+		t.Errorf("Expected the changed ConfigMap to be indexed again, got %v", cms)
+	}
+	if widgets := second.ListByKind("Widget"); len(widgets) != 1 {
+		t.Errorf("Expected the cached Widget, got %v", widgets)
+	}
+	if n := cache.Len(); n != 3 {
+		t.Errorf("Expected the deleted file's entry to be dropped, got %d entries", n)
+	}
+
+	// A file touched without changing what was cached is reused by hash,
+	// taking the new modification time.
+	write("deploy.yaml", cachedDeployment)
+	later := info.ModTime().Add(time.Hour)
+	if err := os.Chtimes(deploy, later, later); err != nil {
+		t.Fatal(err)
+	}
+	third, _ := scanWithCache(t, loadRules(t), root, cachePath)
+	if got := third.ListByKind("Deployment"); len(got) != 1 || !reflect.DeepEqual(got[0].References, web[0].References) {
+		t.Errorf("Expected the cached Deployment, got %v", got)
+	}
+	if entry := LoadIndexCache(cachePath).entries[deploy]; entry == nil || entry.ModTime != later.UnixNano() {
+		t.Errorf("Expected the entry to be kept with the new modification time, got %+v", entry)
+	}
+
+	// Other rules produce other resources, so nothing is reused.
+	cfg := loadRules(t)
+	cfg.Settings.ScanWorkers = 3
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+	cache = LoadIndexCache(cachePath)
+	idx.SetCache(cache)
+	if n := cache.Len(); n != 0 {
+		t.Fatalf("Expected changed rules to discard the cache, got %d entries", n)
+	}
+	if _, err := idx.ScanWorkspaceCount(root); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.ListByKind("Deployment"); len(got) != 1 {
+		t.Errorf("Expected the Deployment to be indexed from disk, got %v", got)
+	}
+	if n := cache.Len(); n != 3 {
+		t.Errorf("Expected the files to be cached under the new rules, got %d entries", n)
+	}
+}
+
+func TestIndexCacheCorrupt(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cm.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "index.json")
+
+	for _, corrupt := range []string{"not json", `{"version": 1, "files": {"x": {"resources": `, `{"version": 99, "files": {}}`} {
+		if err := os.WriteFile(cachePath, []byte(corrupt), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		store, cache := scanWithCache(t, loadRules(t), root, cachePath)
+		if len(store.ListByKind("ConfigMap")) != 1 {
+			t.Fatalf("Expected the scan to index without the cache for %q", corrupt)
+		}
+		if reloaded := LoadIndexCache(cachePath); reloaded.Len() != 1 || cache.Len() != 1 {
+			t.Errorf("Expected %q to be replaced by a working cache", corrupt)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %v", entries)
+	}
+}
+
+func TestIndexCacheFollowsCRDRegistrations(t *testing.T) {
+	root := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "index.json")
+	crd := filepath.Join(root, "a-crd.yaml")
+	if err := os.WriteFile(filepath.Join(root, "b-cr.yaml"), []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without its CRD the Widget isn't indexed, and is cached that way.
+	store, _ := scanWithCache(t, loadRules(t), root, cachePath)
+	if got := store.Get("Widget", "default", "w1"); got != nil {
+		t.Fatalf("Expected no Widget without its CRD, got %v", got)
+	}
+
+	if err := os.WriteFile(crd, []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, _ = scanWithCache(t, loadRules(t), root, cachePath)
+	if got := store.Get("Widget", "default", "w1"); got == nil {
+		t.Fatal("Expected the Widget once a CRD registers its kind")
+	}
+	// The next session restores it from the cache.
+	store, _ = scanWithCache(t, loadRules(t), root, cachePath)
+	if got := store.Get("Widget", "default", "w1"); got == nil {
+		t.Fatal("Expected the cached Widget")
+	}
+
+	if err := os.Remove(crd); err != nil {
+		t.Fatal(err)
+	}
+	store, _ = scanWithCache(t, loadRules(t), root, cachePath)
+	if got := store.Get("Widget", "default", "w1"); got != nil {
+		t.Errorf("Expected the Widget to go with its CRD, got %v", got)
+	}
+}
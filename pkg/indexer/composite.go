@@ -0,0 +1,31 @@
+package indexer
+
+import "strings"
+
+// SplitComposite splits a reference value that packs several parts into one
+// string, laid out as described by a rule's compositePath (e.g.
+// "namespace/name" for "foo-ns/my-secret"). It returns the namespace and
+// name parts and the byte offset of the name within value. A value without
+// separators is taken as just the name. ok is false when value has a
+// different number of parts than format.
+func SplitComposite(format, value string) (namespace, name string, nameOffset int, ok bool) {
+	if !strings.Contains(value, "/") {
+		return "", value, 0, value != ""
+	}
+	parts := strings.Split(format, "/")
+	values := strings.Split(value, "/")
+	if len(values) != len(parts) {
+		return "", "", 0, false
+	}
+	offset := 0
+	for i, part := range parts {
+		switch part {
+		case "namespace":
+			namespace = values[i]
+		case "name":
+			name, nameOffset = values[i], offset
+		}
+		offset += len(values[i]) + 1
+	}
+	return namespace, name, nameOffset, name != ""
+}
@@ -0,0 +1,73 @@
+package indexer
+
+import "gopkg.in/yaml.v3"
+
+// EnvFromSource is an envFrom entry of a container: the ConfigMap or Secret
+// whose keys become environment variables, each behind Prefix.
+type EnvFromSource struct {
+	Kind       string // ConfigMap or Secret
+	Name       string
+	Prefix     string
+	NameNode   *yaml.Node
+	PrefixNode *yaml.Node // nil without a prefix
+}
+
+// EnvFromVar is an environment variable an envFrom source produces.
+type EnvFromVar struct {
+	Name   string // Source.Prefix + Key
+	Key    string
+	Source EnvFromSource
+}
+
+// ContainerEnvFrom returns the envFrom sources of every container of the
+// pod spec in root, one slice per container in document order.
+func ContainerEnvFrom(root *yaml.Node, kind string) [][]EnvFromSource {
+	podSpec := findPodSpecNode(root, kind)
+	if podSpec == nil {
+		return nil
+	}
+	var containers [][]EnvFromSource
+	for _, container := range findContainers(podSpec) {
+		var sources []EnvFromSource
+		for _, item := range asSequence(getMapValue(container, "envFrom")) {
+			source := EnvFromSource{PrefixNode: getMapValue(item, "prefix")}
+			if source.PrefixNode != nil {
+				source.Prefix = source.PrefixNode.Value
+			}
+			for _, ref := range []struct{ field, kind string }{{"configMapRef", "ConfigMap"}, {"secretRef", "Secret"}} {
+				nameNode := getMapValue(getMapValue(item, ref.field), "name")
+				if nameNode != nil && nameNode.Kind == yaml.ScalarNode && nameNode.Value != "" {
+					source.Kind, source.Name, source.NameNode = ref.kind, nameNode.Value, nameNode
+				}
+			}
+			if source.NameNode != nil {
+				sources = append(sources, source)
+			}
+		}
+		containers = append(containers, sources)
+	}
+	return containers
+}
+
+// EnvFromVars expands sources into the variables they produce, in order,
+// from the data keys of the indexed ConfigMaps and Secrets in namespace.
+// When two sources produce the same name Kubernetes keeps the later one.
+// Sources that aren't indexed produce nothing.
+func (s *Store) EnvFromVars(sources []EnvFromSource, namespace string) []EnvFromVar {
+	var vars []EnvFromVar
+	for _, source := range sources {
+		target := s.Get(source.Kind, namespace, source.Name)
+		if target == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, key := range target.DataKeys {
+			if seen[key.Key] {
+				continue
+			}
+			seen[key.Key] = true
+			vars = append(vars, EnvFromVar{Name: source.Prefix + key.Key, Key: key.Key, Source: source})
+		}
+	}
+	return vars
+}
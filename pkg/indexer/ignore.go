@@ -0,0 +1,192 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a line of a .gitignore file, or an exclude glob, compiled
+// to match paths below the directory it applies to.
+type ignoreRule struct {
+	base    string // directory the rule applies below, slash separated, "" for the root
+	negate  bool   // "!pattern" re-includes what an earlier rule excluded
+	dirOnly bool   // "pattern/" only matches directories
+	re      *regexp.Regexp
+}
+
+// parseIgnoreRule parses a line with gitignore syntax relative to base. It
+// reports false for blank lines and comments.
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped.
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	line = trimmed
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash anywhere but at the end anchors the pattern to base;
+	// otherwise it matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	re, err := regexp.Compile(globPattern(line, anchored))
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globPattern translates a gitignore glob into a regular expression over
+// slash separated paths: "*" and "?" stay within one segment, "**" as a
+// whole segment spans any number of them, and [...] is a character class.
+func globPattern(glob string, anchored bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*' && (i == 0 || glob[i-1] == '/') && (i+2 == len(glob) || glob[i+2] == '/'):
+			if i+2 == len(glob) {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			b.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i++
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// matches reports whether the rule covers rel, a path relative to the
+// scanned root.
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	return r.re.MatchString(rel)
+}
+
+// ignoreMatcher decides which paths a workspace scan skips. Each
+// directory's .gitignore applies below it, and the exclude globs apply
+// everywhere after them. The last matching rule wins, so a negated pattern
+// re-includes what a broader one excluded, except inside an excluded
+// directory, which isn't entered at all.
+type ignoreMatcher struct {
+	root     string
+	globs    []ignoreRule
+	dirRules map[string][]ignoreRule
+}
+
+func newIgnoreMatcher(root string, globs []string) *ignoreMatcher {
+	m := &ignoreMatcher{root: root, dirRules: make(map[string][]ignoreRule)}
+	for _, glob := range globs {
+		if rule, ok := parseIgnoreRule("", glob); ok {
+			m.globs = append(m.globs, rule)
+		}
+	}
+	return m
+}
+
+// rel returns path relative to the root, slash separated, "" for the root
+// itself.
+func (m *ignoreMatcher) rel(path string) string {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// enter reads the .gitignore of a directory about to be walked.
+func (m *ignoreMatcher) enter(dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	rel := m.rel(dir)
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		if rule, ok := parseIgnoreRule(rel, line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	m.dirRules[rel] = rules
+}
+
+// ignored reports whether the scan skips path.
+func (m *ignoreMatcher) ignored(path string, isDir bool) bool {
+	rel := m.rel(path)
+	if rel == "" {
+		return false
+	}
+	ignored := false
+	apply := func(rules []ignoreRule) {
+		for _, rule := range rules {
+			if rule.matches(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	// The .gitignore files of the root and each parent directory, outermost
+	// first.
+	apply(m.dirRules[""])
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' {
+			apply(m.dirRules[rel[:i]])
+		}
+	}
+	apply(m.globs)
+	return ignored
+}
@@ -0,0 +1,130 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+)
+
+func TestIgnoreRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Names without a slash match at any depth.
+		{"vendor", "", "vendor", true, true},
+		{"vendor", "", "app/vendor", true, true},
+		{"*.tmpl.yaml", "", "app/deploy.tmpl.yaml", false, true},
+		{"*.tmpl.yaml", "", "app/deploy.yaml", false, false},
+		// A slash anchors the pattern to the .gitignore's directory.
+		{"/dist", "", "dist", true, true},
+		{"/dist", "", "app/dist", true, false},
+		{"charts/*/templates", "", "charts/web/templates", true, true},
+		{"charts/*/templates", "", "charts/web/sub/templates", true, false},
+		{"dist", "app", "app/dist", true, true},
+		{"dist", "app", "dist", true, false},
+		{"/out", "app", "app/out", true, true},
+		{"/out", "app", "app/web/out", true, false},
+		// A trailing slash only matches directories.
+		{"build/", "", "build", true, true},
+		{"build/", "", "build", false, false},
+		// ** spans any number of directories.
+		{"**/charts/**", "", "charts/web/values.yaml", false, true},
+		{"**/charts/**", "", "deploy/charts/web", true, true},
+		{"**/charts/**", "", "charts", true, false},
+		{"**/.terraform/**", "", "infra/.terraform/modules/x.json", false, true},
+		{"a/**/b", "", "a/b", true, true},
+		{"a/**/b", "", "a/x/y/b", true, true},
+		{"a/**/b", "", "ab", true, false},
+		{"**/generated.yaml", "", "generated.yaml", false, true},
+		// Character classes, single characters and escapes.
+		{"v[0-9].yaml", "", "api/v1.yaml", false, true},
+		{"v[!0-9].yaml", "", "api/v1.yaml", false, false},
+		{"v?.yaml", "", "v2.yaml", false, true},
+		{"v?.yaml", "", "v10.yaml", false, false},
+		{`\#notes`, "", "#notes", false, true},
+		{"*", "", "a/b.yaml", false, true},
+		{"*.yaml", "", "a.yaml.bak", false, false},
+	}
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule(tt.base, tt.pattern)
+		if !ok {
+			t.Errorf("Expected %q to parse", tt.pattern)
+			continue
+		}
+		if got := rule.matches(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q (in %q) matching %q (dir %v): got %v, want %v", tt.pattern, tt.base, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "/", "!"} {
+		if _, ok := parseIgnoreRule("", line); ok {
+			t.Errorf("Expected %q to be skipped", line)
+		}
+	}
+	if rule, ok := parseIgnoreRule("", "!keep.yaml"); !ok || !rule.negate || !rule.matches("keep.yaml", false) {
+		t.Errorf("Expected a negated rule, got %+v", rule)
+	}
+	if rule, ok := parseIgnoreRule("", `trailing\ `); !ok || !rule.matches("trailing ", false) {
+		t.Errorf("Expected an escaped trailing space to be kept, got %+v", rule)
+	}
+}
+
+func TestScanWorkspaceHonorsGitignore(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+		Settings: config.Settings{Exclude: []string{"**/charts/**"}},
+	}
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{
+		"app", "dist/rendered", "node_modules/pkg/cm", "app/skip.local", "app/keep.local",
+		"app/generated/out", "infra/generated/out", "deploy/charts/web/cm",
+	} {
+		write(name+".yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: "+name+"\n")
+	}
+	write(".gitignore", "# build output\ndist/\nnode_modules\n*.local.yaml\n!keep.local.yaml\n")
+	// A nested .gitignore only applies below its directory.
+	write("app/.gitignore", "/generated\n")
+
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+	found, err := idx.ScanWorkspaceCount(root)
+	if err != nil {
+		t.Fatalf("ScanWorkspace failed: %v", err)
+	}
+
+	for _, name := range []string{"app", "app/keep.local", "infra/generated/out"} {
+		if store.Get("ConfigMap", "", name) == nil {
+			t.Errorf("Expected %s to be indexed", name)
+		}
+	}
+	for _, name := range []string{"dist/rendered", "node_modules/pkg/cm", "app/skip.local", "app/generated/out", "deploy/charts/web/cm"} {
+		if store.Get("ConfigMap", "", name) != nil {
+			t.Errorf("Expected %s to be skipped", name)
+		}
+	}
+	if found != 3 {
+		t.Errorf("Expected 3 manifest files found, got %d", found)
+	}
+}
@@ -1,23 +1,50 @@
 package indexer
 
 import (
-	"io"
+	"context"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/position"
 
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 )
 
 type Indexer struct {
-	Store  *Store
-	Config *config.Config
-	mu     sync.RWMutex
+	Store        *Store
+	Config       *config.Config
+	libraryRoots []string
+	excludeGlobs []string
+	mu           sync.RWMutex
+
+	// crdFiles maps each kind registered from a CRD to the files whose CRDs
+	// declare it, and fileCRDKinds the other way round, so that a kind goes
+	// once no CRD declares it anymore. pendingCRDKinds collects the kinds
+	// of a file while it is being indexed. All are guarded by mu.
+	crdFiles        map[string]map[string]bool
+	fileCRDKinds    map[string][]string
+	pendingCRDKinds map[string][]string
+	// pendingSchemas collects the CRD schemas of a file while it is being
+	// indexed, for Store.SetSchemas; guarded by mu.
+	pendingSchemas map[string]map[string]*Schema
+	// fileKinds records which kinds of each file depend on CRD
+	// registrations, so the file is indexed again when those change;
+	// guarded by mu.
+	fileKinds map[string]kindUse
+
+	// cache, if set, keeps indexed files between sessions; guarded by mu.
+	cache *IndexCache
+
+	// cfgMu keeps Config fixed while a file is indexed.
+	cfgMu sync.RWMutex
 }
 
 func NewIndexer(store *Store, cfg *config.Config) *Indexer {
@@ -25,51 +52,237 @@ func NewIndexer(store *Store, cfg *config.Config) *Indexer {
 }
 
 func (i *Indexer) ScanWorkspace(rootPath string) error {
+	_, err := i.ScanWorkspaceCount(rootPath)
+	return err
+}
+
+// ScanWorkspaceCount is ScanWorkspace, also returning the number of
+// manifest files it found.
+func (i *Indexer) ScanWorkspaceCount(rootPath string) (int, error) {
+	return i.ScanWorkspaceContext(context.Background(), rootPath)
+}
+
+// ScanWorkspaceContext is ScanWorkspaceCount, stopping with ctx's error
+// once ctx is done. Files indexed before then stay in the Store. The walk
+// feeds the manifests it finds to settings.scanWorkers goroutines that
+// index them concurrently; a directory or file that can't be read is
+// logged and skipped.
+func (i *Indexer) ScanWorkspaceContext(ctx context.Context, rootPath string) (int, error) {
 	log.Info().Str("root", rootPath).Msg("Scanning workspace...")
-	count := 0
+	ignore := newIgnoreMatcher(rootPath, i.scanExcludes())
 	filesFound := 0
+	skippedFiles, skippedDirs := 0, 0
+	var indexed atomic.Int64
+	seen := make(map[string]bool)
+
+	paths := make(chan string, 64)
+	var wg sync.WaitGroup
+	for range i.scanWorkers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+				if restored, ok := i.restoreCached(path); ok {
+					if restored {
+						indexed.Add(1)
+					}
+					continue
+				}
+				if i.IndexFile(path) {
+					indexed.Add(1)
+				}
+			}
+		}()
+	}
+
 	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			if path == rootPath {
+				return err
+			}
+			log.Warn().Err(err).Str("path", path).Msg("Skipping unreadable path")
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 		if info.IsDir() {
 			if strings.HasPrefix(info.Name(), ".") && info.Name() != "." {
 				return filepath.SkipDir // Skip hidden dirs like .git, but not the root itself if it starts with .
 			}
+			if path != rootPath && ignore.ignored(path, true) {
+				skippedDirs++
+				return filepath.SkipDir
+			}
+			ignore.enter(path)
+			return nil
+		}
+		if ignore.ignored(path, false) {
+			skippedFiles++
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext == ".yaml" || ext == ".yml" {
+		if IsManifestPath(path) {
 			filesFound++
-			if i.IndexFile(path) {
-				count++
-			}
+			seen[path] = true
+			paths <- path
 		}
 		return nil
 	})
-	log.Info().Int("filesFound", filesFound).Int("indexedCount", count).Msg("Workspace scan completed")
-	return err
+	close(paths)
+	wg.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		// A file indexed (or restored from the cache) before a CRD it
+		// depends on was registered, or after one was dropped, is indexed
+		// again now that the scan has seen every CRD.
+		for _, path := range i.staleKindFiles(seen) {
+			log.Debug().Str("path", path).Msg("Indexing again for kinds registered during the scan")
+			i.IndexFile(path)
+		}
+		i.pruneCache(rootPath, seen)
+	}
+
+	log.Info().Int("filesFound", filesFound).Int64("indexedCount", indexed.Load()).Int("skippedFiles", skippedFiles).Int("skippedDirs", skippedDirs).Msg("Workspace scan completed")
+	return filesFound, err
+}
+
+// scanWorkers returns how many files a workspace scan indexes at once.
+func (i *Indexer) scanWorkers() int {
+	i.cfgMu.RLock()
+	defer i.cfgMu.RUnlock()
+	return i.Config.Settings.ScanWorkerCount()
+}
+
+// IsManifestPath reports whether path has an extension manifests are
+// indexed from: .yaml, .yml or .json. yaml.v3 reads JSON as YAML, so JSON
+// manifests are indexed like any other.
+func IsManifestPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// SetLibraryRoots configures read-only directories whose resources can be
+// referenced from the workspace but must never be edited.
+func (i *Indexer) SetLibraryRoots(roots []string) {
+	i.cfgMu.RLock()
+	defer i.cfgMu.RUnlock()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.libraryRoots = nil
+	for _, root := range roots {
+		i.libraryRoots = append(i.libraryRoots, filepath.Clean(root))
+	}
+	i.checkCacheRules()
+}
+
+// SetExcludeGlobs configures paths ScanWorkspace skips on top of the
+// rules' exclude setting and .gitignore files. Globs use gitignore syntax
+// relative to the scanned root: "vendor" or "*.tmpl.yaml" match a name at
+// any depth, "charts/*/templates" a path from the root, "**/charts/**"
+// everything under any charts directory, and "!keep.yaml" re-includes.
+func (i *Indexer) SetExcludeGlobs(globs []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.excludeGlobs = append([]string(nil), globs...)
+}
+
+// scanExcludes returns the rules' exclude globs followed by the ones set
+// through SetExcludeGlobs.
+func (i *Indexer) scanExcludes() []string {
+	i.cfgMu.RLock()
+	globs := append([]string(nil), i.Config.Settings.Exclude...)
+	i.cfgMu.RUnlock()
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append(globs, i.excludeGlobs...)
+}
+
+// ScanLibraries indexes every configured library root.
+func (i *Indexer) ScanLibraries() error {
+	return i.ScanLibrariesContext(context.Background())
+}
+
+// ScanLibrariesContext is ScanLibraries, stopping once ctx is done.
+func (i *Indexer) ScanLibrariesContext(ctx context.Context) error {
+	i.mu.RLock()
+	roots := append([]string(nil), i.libraryRoots...)
+	i.mu.RUnlock()
+
+	for _, root := range roots {
+		if _, err := i.ScanWorkspaceContext(ctx, root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsLibraryPath reports whether path lies inside a library root.
+func (i *Indexer) IsLibraryPath(path string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	path = filepath.Clean(path)
+	for _, root := range i.libraryRoots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
 }
 
 func (i *Indexer) IndexFile(path string) bool {
-	f, err := os.Open(path)
+	// Stat before reading, so a change in between makes the cache entry
+	// look stale rather than current.
+	info, statErr := os.Stat(path)
+	content, err := os.ReadFile(path)
 	if err != nil {
 		log.Error().Err(err).Str("path", path).Msg("Failed to open file")
 		return false
 	}
-	defer f.Close()
-
-	return i.indexReader(f, path)
+	resources, complete := i.indexContent(string(content), path)
+	if statErr == nil {
+		i.cacheFile(path, info, content, resources, complete)
+	}
+	return len(resources) > 0
 }
 
 func (i *Indexer) IndexContent(path, content string) bool {
-	return i.indexReader(strings.NewReader(content), path)
+	resources, _ := i.indexContent(content, path)
+	return len(resources) > 0
 }
 
-func (i *Indexer) indexReader(r io.Reader, path string) bool {
-	decoder := yaml.NewDecoder(r)
-	indexed := false
+// SetConfig replaces the rules used for files indexed from now on, e.g.
+// after the client changed the rules path. Resources already in the Store
+// are kept.
+func (i *Indexer) SetConfig(cfg *config.Config) {
+	i.cfgMu.Lock()
+	defer i.cfgMu.Unlock()
+	i.Config = cfg
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.checkCacheRules()
+}
+
+// indexContent indexes the resources of content as the file at path,
+// returning them and whether the whole content could be read.
+func (i *Indexer) indexContent(content, path string) ([]*K8sResource, bool) {
+	i.cfgMu.RLock()
+	defer i.cfgMu.RUnlock()
+	decoder := position.NewDecoder(content)
+	var resources []*K8sResource
+	var skippedKinds []string
+	complete := true
 	for {
 		var node yaml.Node
 		if err := decoder.Decode(&node); err != nil {
@@ -78,17 +291,73 @@ func (i *Indexer) indexReader(r io.Reader, path string) bool {
 			}
 			// Log warning but continue if possible
 			log.Warn().Err(err).Str("path", path).Msg("Failed to decode YAML")
+			complete = false
 			break
 		}
 
-		res := i.parseK8sResource(&node, path)
-		if res != nil {
+		if err := CheckDocumentLimits(&node, i.Config.Settings.DocumentLimits); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Skipping document")
+			continue
+		}
+
+		for _, doc := range ListItems(&node) {
+			res := i.parseK8sResource(doc, path)
+			if res != nil {
+				resources = append(resources, res)
+				resources = append(resources, res.Declared...)
+				log.Debug().Str("kind", res.Kind).Str("name", res.Name).Str("path", path).Msg("Indexed resource")
+			} else if kind := documentKind(doc); kind != "" {
+				skippedKinds = append(skippedKinds, kind)
+			}
+		}
+	}
+
+	i.settleCRDKinds(path, complete)
+	i.recordKinds(path, resources, skippedKinds, complete)
+	i.Store.SetSchemas(path, i.takeSchemas(path), complete)
+	if complete {
+		i.Store.ReplaceFile(path, resources)
+	} else {
+		// The rest of the file couldn't be read (often mid-edit); keep what
+		// it produced before rather than evicting it.
+		for _, res := range resources {
 			i.Store.Add(res)
-			log.Debug().Str("kind", res.Kind).Str("name", res.Name).Str("path", path).Msg("Indexed resource")
-			indexed = true
 		}
 	}
-	return indexed
+	return resources, complete
+}
+
+// ListItems unwraps a kind: List (or an API list kind such as PodList, as
+// printed by kubectl get -o yaml) into one document per element of items[].
+// Any other document is returned as is. The elements keep their positions
+// in the file, so resources indexed from them point at the right lines and
+// features that read a resource back find it where it was indexed.
+func ListItems(node *yaml.Node) []*yaml.Node {
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return []*yaml.Node{node}
+	}
+	root := node.Content[0]
+	kind := getMapValue(root, "kind")
+	items := getMapValue(root, "items")
+	if kind == nil || !strings.HasSuffix(kind.Value, "List") || items == nil || items.Kind != yaml.SequenceNode {
+		return []*yaml.Node{node}
+	}
+	docs := make([]*yaml.Node, 0, len(items.Content))
+	for _, item := range items.Content {
+		docs = append(docs, &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{item}, Line: item.Line, Column: item.Column})
+	}
+	return docs
+}
+
+// documentKind returns the kind of a document, or "" if it has none.
+func documentKind(node *yaml.Node) string {
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return ""
+	}
+	if kind := getMapValue(node.Content[0], "kind"); kind != nil && kind.Kind == yaml.ScalarNode {
+		return kind.Value
+	}
+	return ""
 }
 
 func (i *Indexer) parseK8sResource(node *yaml.Node, path string) *K8sResource {
@@ -117,7 +386,7 @@ func (i *Indexer) parseK8sResource(node *yaml.Node, path string) *K8sResource {
 
 		// Handle CRD registration
 		if kind == "CustomResourceDefinition" {
-			i.handleCRD(root)
+			i.handleCRD(root, path)
 		}
 
 		res := &K8sResource{
@@ -125,24 +394,40 @@ func (i *Indexer) parseK8sResource(node *yaml.Node, path string) *K8sResource {
 			Kind:       kind,
 			FilePath:   path,
 			Labels:     make(map[string]string),
+			Library:    i.IsLibraryPath(path),
 		}
 
 		i.mu.RLock()
 		defer i.mu.RUnlock()
 
-		i.traverse(node, []string{}, func(n *yaml.Node, p []string) {
+		foldKinds := i.Config.Settings.CaseInsensitiveKinds
+		var aliases []*yaml.Node
+		var aliasKinds []string
+		i.traverse(node, nil, []string{}, nil, func(n *yaml.Node, parent *yaml.Node, p []string, idx []int) {
 			// Check definitions
 			for _, sym := range i.Config.Symbols {
 				for _, def := range sym.Definitions {
-					if contains(def.Kinds, kind) && matchPath(p, def.Path) {
-						if sym.Name == "k8s.resource.name" {
+					if containsKind(def.Kinds, kind, foldKinds) && def.MatchesAPIVersion(apiVersion) && matchPath(p, idx, def.Path) {
+						if def.AliasKind != "" {
+							if sym.Name == "k8s.resource.name" && n.Kind == yaml.ScalarNode && n.Value != "" {
+								aliases = append(aliases, n)
+								aliasKinds = append(aliasKinds, def.AliasKind)
+							}
+						} else if sym.Name == "k8s.resource.name" {
 							res.Name = n.Value
 							res.Line = n.Line - 1
-							res.Col = n.Column - 1
+							res.Col = scalarCol(n)
 							// Also try to find namespace if we are at metadata.name
 							// But namespace is at metadata.namespace.
 							// We can't easily look sideways in this traversal without parent pointer.
 							// But we can capture namespace when we visit metadata.namespace.
+						} else if sym.Name == "k8s.field" {
+							if def.Field != "" && n.Kind == yaml.ScalarNode {
+								if res.Fields == nil {
+									res.Fields = make(map[string]string)
+								}
+								res.Fields[def.Field] = n.Value
+							}
 						} else if sym.Name == "k8s.label" {
 							// n is the map node for labels
 							if n.Kind == yaml.MappingNode {
@@ -158,23 +443,29 @@ func (i *Indexer) parseK8sResource(node *yaml.Node, path string) *K8sResource {
 			}
 
 			// Special case for Namespace: if we visit metadata.namespace, capture it
-			if matchPath(p, "metadata.namespace") {
+			if matchPath(p, nil, "metadata.namespace") {
 				res.Namespace = n.Value
 			}
 
 			// Check references
 			for _, refRule := range i.Config.References {
-				if matchesKind(refRule.Match.Kinds, kind) && matchPath(p, refRule.Match.Path) {
+				if matchesKind(refRule.Match.Kinds, kind, foldKinds) && refRule.Match.MatchesAPIVersion(apiVersion) && matchPath(p, idx, refRule.Match.Path) {
 					// Special handling for label selectors (Map)
 					if refRule.Symbol == "k8s.label" && n.Kind == yaml.MappingNode {
+						// A LabelSelector (PodDisruptionBudget, NetworkPolicy)
+						// nests its labels under matchLabels.
+						if matchLabels := getMapValue(n, "matchLabels"); matchLabels != nil && matchLabels.Kind == yaml.MappingNode {
+							n = matchLabels
+						}
 						for k := 0; k < len(n.Content); k += 2 {
-							_ = n.Content[k] // lKey unused
+							lKey := n.Content[k]
 							lVal := n.Content[k+1]
 							res.References = append(res.References, Reference{
 								Name:   lVal.Value,
+								Key:    lKey.Value,
 								Symbol: refRule.Symbol,
 								Line:   lVal.Line - 1,
-								Col:    lVal.Column - 1,
+								Col:    scalarCol(lVal),
 								Kind:   refRule.TargetKind,
 							})
 						}
@@ -186,9 +477,28 @@ func (i *Indexer) parseK8sResource(node *yaml.Node, path string) *K8sResource {
 						Name:   n.Value,
 						Symbol: refRule.Symbol,
 						Line:   n.Line - 1,
-						Col:    n.Column - 1,
+						Col:    scalarCol(n),
 						Kind:   refRule.TargetKind,
 					}
+					if refRule.CompositePath != "" {
+						namespace, name, offset, ok := SplitComposite(refRule.CompositePath, n.Value)
+						if !ok {
+							continue
+						}
+						ref.Name, ref.Namespace = name, namespace
+						ref.Col += offset
+					}
+					// Field selectors carry the field they match in Key.
+					if refRule.Symbol == "k8s.field" {
+						ref.Key = refRule.Field
+					}
+					// References like webhooks[].clientConfig.service.name carry
+					// their target namespace in a sibling field.
+					if ref.Namespace == "" && p[len(p)-1] != "namespace" {
+						if nsNode := getMapValue(parent, "namespace"); nsNode != nil && nsNode.Kind == yaml.ScalarNode {
+							ref.Namespace = nsNode.Value
+						}
+					}
 					res.References = append(res.References, ref)
 				}
 			}
@@ -198,15 +508,105 @@ func (i *Indexer) parseK8sResource(node *yaml.Node, path string) *K8sResource {
 		// (e.g. configMapKeyRef.name + configMapKeyRef.key).
 		// This is intentionally not driven by rules because we need to correlate fields.
 		res.References = append(res.References, extractConfigMapReferences(root, kind, normalizeNamespace(res.Namespace))...)
+		res.References = append(res.References, extractSecretReferences(root, kind, normalizeNamespace(res.Namespace))...)
+		res.References = append(res.References, extractTLSSecretReferences(root, kind, normalizeNamespace(res.Namespace))...)
+		res.References = append(res.References, extractServiceAccountReferences(root, kind, normalizeNamespace(res.Namespace))...)
+		res.References = append(res.References, extractPodClassReferences(root, kind)...)
+		res.References = append(res.References, extractInjectCAFromReference(root, res.Namespace)...)
+		res.References = append(res.References, extractOwnerReferences(root, normalizeNamespace(res.Namespace))...)
+		res.References = append(res.References, extractRoleRefReference(root, kind, normalizeNamespace(res.Namespace))...)
+		res.References = append(res.References, extractSubjectReferences(root, kind, normalizeNamespace(res.Namespace))...)
 		res.References = dedupeReferences(res.References)
+		if uidNode := getMapValue(getMapValue(root, "metadata"), "uid"); uidNode != nil && uidNode.Kind == yaml.ScalarNode {
+			res.UID = uidNode.Value
+		}
+		if kind == "ConfigMap" || kind == "Secret" {
+			res.DataKeys = extractDataKeys(root)
+		}
+		if kind == "Service" {
+			spec := getMapValue(root, "spec")
+			if clusterIP := getMapValue(spec, "clusterIP"); clusterIP != nil && clusterIP.Kind == yaml.ScalarNode && clusterIP.Value != "None" {
+				res.ClusterIP = clusterIP.Value
+			}
+			if externalName := getMapValue(spec, "externalName"); externalName != nil && externalName.Kind == yaml.ScalarNode {
+				res.ExternalName = strings.TrimSuffix(externalName.Value, ".")
+			}
+		}
+		if annotation := i.Config.Settings.ImageBuildAnnotation; annotation != "" {
+			annotations := getMapValue(getMapValue(root, "metadata"), "annotations")
+			if valNode := getMapValue(annotations, annotation); valNode != nil && valNode.Kind == yaml.ScalarNode && valNode.Value != "" {
+				res.BuildImage = valNode.Value
+				res.BuildImageLine = valNode.Line - 1
+				res.BuildImageCol = scalarCol(valNode)
+			}
+		}
+
+		// Objects created from a generateName have no name until the API
+		// server assigns one; index them under the prefix.
+		if res.Name == "" && i.definesResourceName(kind, apiVersion) {
+			generateName := getMapValue(getMapValue(root, "metadata"), "generateName")
+			if generateName != nil && generateName.Kind == yaml.ScalarNode && generateName.Value != "" {
+				res.Name = generateName.Value
+				res.Line = generateName.Line - 1
+				res.Col = scalarCol(generateName)
+				res.GenerateName = true
+			}
+		}
 
 		if res.Name != "" {
+			for j, n := range aliases {
+				res.Declared = append(res.Declared, &K8sResource{
+					Kind:       aliasKinds[j],
+					Name:       n.Value,
+					Namespace:  res.Namespace,
+					FilePath:   path,
+					Line:       n.Line - 1,
+					Col:        scalarCol(n),
+					Labels:     make(map[string]string),
+					Library:    res.Library,
+					DeclaredBy: res,
+				})
+			}
 			return res
 		}
 	}
 	return nil
 }
 
+// definesResourceName reports whether the k8s.resource.name symbol covers
+// kind in apiVersion. Callers hold i.mu.
+func (i *Indexer) definesResourceName(kind, apiVersion string) bool {
+	for _, sym := range i.Config.Symbols {
+		if sym.Name != "k8s.resource.name" {
+			continue
+		}
+		for _, def := range sym.Definitions {
+			if def.AliasKind == "" && containsKind(def.Kinds, kind, i.Config.Settings.CaseInsensitiveKinds) && def.MatchesAPIVersion(apiVersion) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"PersistentVolume":               true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"StorageClass":                   true,
+	"PriorityClass":                  true,
+	"RuntimeClass":                   true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+}
+
+// IsClusterScoped reports whether resources of kind live outside namespaces,
+// so they are matched by name alone.
+func IsClusterScoped(kind string) bool {
+	return clusterScopedKinds[kind]
+}
+
 func normalizeNamespace(ns string) string {
 	if ns == "" {
 		return "default"
@@ -237,7 +637,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 				Name:      cmNameNode.Value,
 				Namespace: resourceNamespace,
 				Line:      cmNameNode.Line - 1,
-				Col:       cmNameNode.Column - 1,
+				Col:       scalarCol(cmNameNode),
 			})
 
 			items := getMapValue(cm, "items")
@@ -250,7 +650,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 						Key:       keyNode.Value,
 						Namespace: resourceNamespace,
 						Line:      keyNode.Line - 1,
-						Col:       keyNode.Column - 1,
+						Col:       scalarCol(keyNode),
 					})
 				}
 			}
@@ -268,7 +668,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 					Name:      cm2NameNode.Value,
 					Namespace: resourceNamespace,
 					Line:      cm2NameNode.Line - 1,
-					Col:       cm2NameNode.Column - 1,
+					Col:       scalarCol(cm2NameNode),
 				})
 
 				items := getMapValue(cm2, "items")
@@ -281,7 +681,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 							Key:       keyNode.Value,
 							Namespace: resourceNamespace,
 							Line:      keyNode.Line - 1,
-							Col:       keyNode.Column - 1,
+							Col:       scalarCol(keyNode),
 						})
 					}
 				}
@@ -301,7 +701,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 					Name:      nameNode.Value,
 					Namespace: resourceNamespace,
 					Line:      nameNode.Line - 1,
-					Col:       nameNode.Column - 1,
+					Col:       scalarCol(nameNode),
 				})
 			}
 		}
@@ -319,7 +719,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 					Name:      nameNode.Value,
 					Namespace: resourceNamespace,
 					Line:      nameNode.Line - 1,
-					Col:       nameNode.Column - 1,
+					Col:       scalarCol(nameNode),
 				})
 			}
 			if nameNode != nil && nameNode.Kind == yaml.ScalarNode && keyNode != nil && keyNode.Kind == yaml.ScalarNode {
@@ -329,7 +729,7 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 					Key:       keyNode.Value,
 					Namespace: resourceNamespace,
 					Line:      keyNode.Line - 1,
-					Col:       keyNode.Column - 1,
+					Col:       scalarCol(keyNode),
 				})
 			}
 		}
@@ -338,11 +738,297 @@ func extractConfigMapReferences(root *yaml.Node, kind string, resourceNamespace
 	return refs
 }
 
+func extractSecretReferences(root *yaml.Node, kind string, resourceNamespace string) []Reference {
+	// Only pod-spec-bearing resources can reference Secrets this way.
+	if !(kind == "Pod" || kind == "Deployment" || kind == "StatefulSet" || kind == "DaemonSet" || kind == "Job" || kind == "CronJob") {
+		return nil
+	}
+
+	podSpec := findPodSpecNode(root, kind)
+	if podSpec == nil {
+		return nil
+	}
+
+	var refs []Reference
+
+	// containers[].env[].valueFrom.secretKeyRef.{name,key}
+	for _, container := range findContainers(podSpec) {
+		env := getMapValue(container, "env")
+		for _, envItem := range asSequence(env) {
+			valueFrom := getMapValue(envItem, "valueFrom")
+			secretKeyRef := getMapValue(valueFrom, "secretKeyRef")
+			nameNode := getMapValue(secretKeyRef, "name")
+			keyNode := getMapValue(secretKeyRef, "key")
+			if nameNode != nil && nameNode.Kind == yaml.ScalarNode {
+				refs = append(refs, Reference{
+					Kind:      "Secret",
+					Name:      nameNode.Value,
+					Namespace: resourceNamespace,
+					Line:      nameNode.Line - 1,
+					Col:       scalarCol(nameNode),
+				})
+			}
+			if nameNode != nil && nameNode.Kind == yaml.ScalarNode && keyNode != nil && keyNode.Kind == yaml.ScalarNode {
+				refs = append(refs, Reference{
+					Kind:      "Secret",
+					Name:      nameNode.Value,
+					Key:       keyNode.Value,
+					Namespace: resourceNamespace,
+					Line:      keyNode.Line - 1,
+					Col:       scalarCol(keyNode),
+				})
+			}
+		}
+	}
+
+	// containers[].envFrom[].secretRef.name (whole Secret)
+	for _, container := range findContainers(podSpec) {
+		envFrom := getMapValue(container, "envFrom")
+		for _, envFromItem := range asSequence(envFrom) {
+			secretRef := getMapValue(envFromItem, "secretRef")
+			nameNode := getMapValue(secretRef, "name")
+			if nameNode != nil && nameNode.Kind == yaml.ScalarNode {
+				refs = append(refs, Reference{
+					Kind:      "Secret",
+					Name:      nameNode.Value,
+					Namespace: resourceNamespace,
+					Line:      nameNode.Line - 1,
+					Col:       scalarCol(nameNode),
+				})
+			}
+		}
+	}
+
+	return refs
+}
+
+// extractServiceAccountReferences indexes spec.serviceAccountName and the
+// deprecated spec.serviceAccount of the pod spec, wherever the kind puts it.
+func extractServiceAccountReferences(root *yaml.Node, kind string, resourceNamespace string) []Reference {
+	if !(kind == "Pod" || kind == "Deployment" || kind == "StatefulSet" || kind == "DaemonSet" || kind == "Job" || kind == "CronJob") {
+		return nil
+	}
+
+	podSpec := findPodSpecNode(root, kind)
+	if podSpec == nil {
+		return nil
+	}
+
+	var refs []Reference
+	for _, field := range []string{"serviceAccountName", "serviceAccount"} {
+		nameNode := getMapValue(podSpec, field)
+		if nameNode != nil && nameNode.Kind == yaml.ScalarNode && nameNode.Value != "" {
+			refs = append(refs, Reference{
+				Kind:      "ServiceAccount",
+				Name:      nameNode.Value,
+				Namespace: resourceNamespace,
+				Line:      nameNode.Line - 1,
+				Col:       scalarCol(nameNode),
+			})
+		}
+	}
+	return refs
+}
+
+// extractOwnerReferences indexes metadata.ownerReferences[] by the owner's
+// kind and exact name, and its uid when exported manifests carry one.
+// Owners live in the same namespace, or are cluster-scoped. The references
+// carry the k8s.owner symbol, as only they may name a generateName owner by
+// the name the cluster gave it.
+func extractOwnerReferences(root *yaml.Node, resourceNamespace string) []Reference {
+	var refs []Reference
+	for _, owner := range asSequence(getMapValue(getMapValue(root, "metadata"), "ownerReferences")) {
+		kindNode := getMapValue(owner, "kind")
+		nameNode := getMapValue(owner, "name")
+		if kindNode == nil || kindNode.Kind != yaml.ScalarNode || kindNode.Value == "" ||
+			nameNode == nil || nameNode.Kind != yaml.ScalarNode || nameNode.Value == "" {
+			continue
+		}
+		ref := Reference{
+			Kind:      kindNode.Value,
+			Name:      nameNode.Value,
+			Namespace: resourceNamespace,
+			Symbol:    "k8s.owner",
+			Line:      nameNode.Line - 1,
+			Col:       scalarCol(nameNode),
+		}
+		if uidNode := getMapValue(owner, "uid"); uidNode != nil && uidNode.Kind == yaml.ScalarNode {
+			ref.UID = uidNode.Value
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// extractRoleRefReference indexes the roleRef of RoleBindings and
+// ClusterRoleBindings by roleRef.kind: a Role in the binding's namespace, or
+// a cluster-scoped ClusterRole.
+func extractRoleRefReference(root *yaml.Node, kind string, resourceNamespace string) []Reference {
+	if kind != "RoleBinding" && kind != "ClusterRoleBinding" {
+		return nil
+	}
+	roleRef := getMapValue(root, "roleRef")
+	kindNode := getMapValue(roleRef, "kind")
+	nameNode := getMapValue(roleRef, "name")
+	if kindNode == nil || kindNode.Kind != yaml.ScalarNode || kindNode.Value == "" ||
+		nameNode == nil || nameNode.Kind != yaml.ScalarNode || nameNode.Value == "" {
+		return nil
+	}
+	namespace := resourceNamespace
+	if IsClusterScoped(kindNode.Value) {
+		namespace = ""
+	}
+	return []Reference{{
+		Kind:      kindNode.Value,
+		Name:      nameNode.Value,
+		Namespace: namespace,
+		Line:      nameNode.Line - 1,
+		Col:       scalarCol(nameNode),
+	}}
+}
+
+// extractSubjectReferences indexes the ServiceAccount subjects of
+// RoleBindings and ClusterRoleBindings. A subject's own namespace field wins
+// over the binding's, which ClusterRoleBindings don't have.
+func extractSubjectReferences(root *yaml.Node, kind string, resourceNamespace string) []Reference {
+	if kind != "RoleBinding" && kind != "ClusterRoleBinding" {
+		return nil
+	}
+	var refs []Reference
+	for _, subject := range asSequence(getMapValue(root, "subjects")) {
+		kindNode := getMapValue(subject, "kind")
+		nameNode := getMapValue(subject, "name")
+		if kindNode == nil || kindNode.Value != "ServiceAccount" ||
+			nameNode == nil || nameNode.Kind != yaml.ScalarNode || nameNode.Value == "" {
+			continue
+		}
+		namespace := resourceNamespace
+		if nsNode := getMapValue(subject, "namespace"); nsNode != nil && nsNode.Kind == yaml.ScalarNode && nsNode.Value != "" {
+			namespace = nsNode.Value
+		}
+		refs = append(refs, Reference{
+			Kind:      "ServiceAccount",
+			Name:      nameNode.Value,
+			Namespace: namespace,
+			Line:      nameNode.Line - 1,
+			Col:       scalarCol(nameNode),
+		})
+	}
+	return refs
+}
+
+// extractDataKeys lists the data, binaryData and stringData keys of a
+// ConfigMap or Secret.
+func extractDataKeys(root *yaml.Node) []DataKey {
+	var keys []DataKey
+	for _, field := range []string{"data", "binaryData", "stringData"} {
+		data := getMapValue(root, field)
+		if data == nil || data.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j < len(data.Content); j += 2 {
+			keyNode := data.Content[j]
+			keys = append(keys, DataKey{Key: keyNode.Value, Line: keyNode.Line - 1, Col: scalarCol(keyNode)})
+		}
+	}
+	return keys
+}
+
+// ImageRepository strips the tag and digest from an image reference, so
+// "registry:5000/app:v1" and "registry:5000/app@sha256:..." both become
+// "registry:5000/app".
+func ImageRepository(image string) string {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	// A colon after the last slash starts the tag; earlier ones are ports.
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		image = image[:idx]
+	}
+	return image
+}
+
+// ParseImage splits an image reference into its registry host and its
+// repository path, dropping tag and digest. Images without a registry are on
+// Docker Hub ("docker.io"), where single-name images live under "library/".
+func ParseImage(image string) (registry, repository string) {
+	repository = ImageRepository(image)
+	if first, rest, ok := strings.Cut(repository, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, repository = first, rest
+	}
+	switch registry {
+	case "", "index.docker.io", "registry-1.docker.io":
+		registry = "docker.io"
+	}
+	if registry == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return registry, repository
+}
+
+// InjectCAFromAnnotation is the cert-manager annotation that points a webhook
+// configuration, CRD or APIService at the Certificate whose CA is injected
+// into its caBundle. The value has the form "namespace/certificate-name".
+const InjectCAFromAnnotation = "cert-manager.io/inject-ca-from"
+
+// ParseInjectCAFrom splits an inject-ca-from value into namespace and name.
+// offset is the byte offset of the name within value. A value without a
+// namespace part falls back to defaultNamespace.
+func ParseInjectCAFrom(value, defaultNamespace string) (namespace, name string, offset int, ok bool) {
+	if idx := strings.Index(value, "/"); idx >= 0 {
+		namespace, name, offset = value[:idx], value[idx+1:], idx+1
+	} else {
+		namespace, name = defaultNamespace, value
+	}
+	if name == "" {
+		return "", "", 0, false
+	}
+	return namespace, name, offset, true
+}
+
+// extractInjectCAFromReference indexes the cert-manager inject-ca-from
+// annotation. It can't be expressed as a rule because the annotation key
+// itself contains dots.
+func extractInjectCAFromReference(root *yaml.Node, resourceNamespace string) []Reference {
+	annotations := getMapValue(getMapValue(root, "metadata"), "annotations")
+	valNode := getMapValue(annotations, InjectCAFromAnnotation)
+	if valNode == nil || valNode.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	ns, name, offset, ok := ParseInjectCAFrom(valNode.Value, resourceNamespace)
+	if !ok {
+		return nil
+	}
+
+	return []Reference{{
+		Kind:      "Certificate",
+		Name:      name,
+		Namespace: ns,
+		Symbol:    "k8s.resource.name",
+		Line:      valNode.Line - 1,
+		Col:       scalarCol(valNode) + offset,
+	}}
+}
+
+// scalarCol returns the 0-based column where n's value text starts, past the
+// opening quote of quoted scalars, so Col+len(Value) ends on the last
+// character whether the scalar sits in a block or a flow collection.
+func scalarCol(n *yaml.Node) int {
+	if n.Style == yaml.DoubleQuotedStyle || n.Style == yaml.SingleQuotedStyle {
+		return n.Column
+	}
+	return n.Column - 1
+}
+
+// dedupeReferences drops references to the same target at the same position,
+// keeping the first. Rules and the built-in extractors may both index a field
+// (e.g. secretKeyRef.name); the rule's reference comes first.
 func dedupeReferences(refs []Reference) []Reference {
 	seen := make(map[string]struct{}, len(refs))
 	out := make([]Reference, 0, len(refs))
 	for _, r := range refs {
-		k := r.Kind + "|" + r.Namespace + "|" + r.Name + "|" + r.Key + "|" + r.Symbol + "|" + fmtInt(r.Line) + "|" + fmtInt(r.Col)
+		k := r.Kind + "|" + r.Name + "|" + r.Key + "|" + fmtInt(r.Line) + "|" + fmtInt(r.Col)
 		if _, ok := seen[k]; ok {
 			continue
 		}
@@ -418,12 +1104,17 @@ func findVolumes(podSpec *yaml.Node) []*yaml.Node {
 	return asSequence(vols)
 }
 
+// findContainers returns the initContainers, containers and
+// ephemeralContainers of a pod spec.
 func findContainers(podSpec *yaml.Node) []*yaml.Node {
-	containers := getMapValue(podSpec, "containers")
-	return asSequence(containers)
+	var containers []*yaml.Node
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		containers = append(containers, asSequence(getMapValue(podSpec, field))...)
+	}
+	return containers
 }
 
-func (i *Indexer) handleCRD(root *yaml.Node) {
+func (i *Indexer) handleCRD(root *yaml.Node, path string) {
 	// We need to find spec.names.kind
 	// root is the MappingNode of the document
 	var specNode *yaml.Node
@@ -459,28 +1150,62 @@ func (i *Indexer) handleCRD(root *yaml.Node) {
 	}
 
 	if kindName != "" {
-		i.registerKind(kindName)
+		i.registerKind(kindName, path)
+	}
+	if schemas := crdSchemas(root); len(schemas) > 0 {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if i.pendingSchemas == nil {
+			i.pendingSchemas = make(map[string]map[string]*Schema)
+		}
+		if i.pendingSchemas[path] == nil {
+			i.pendingSchemas[path] = make(map[string]*Schema)
+		}
+		maps.Copy(i.pendingSchemas[path], schemas)
 	}
 }
 
-func (i *Indexer) registerKind(kind string) {
+// takeSchemas returns the CRD schemas collected while path was indexed.
+func (i *Indexer) takeSchemas(path string) map[string]*Schema {
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	schemas := i.pendingSchemas[path]
+	delete(i.pendingSchemas, path)
+	return schemas
+}
+
+// registerKind makes kind, declared by a CRD in path, indexable by name.
+func (i *Indexer) registerKind(kind, path string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.pendingCRDKinds == nil {
+		i.pendingCRDKinds = make(map[string][]string)
+	}
+	i.pendingCRDKinds[path] = append(i.pendingCRDKinds[path], kind)
+	if files, ok := i.crdFiles[kind]; ok {
+		files[path] = true
+		return
+	}
 
 	// Find k8s.resource.name symbol
 	for idx, sym := range i.Config.Symbols {
 		if sym.Name == "k8s.resource.name" {
 			// Check if already registered
 			for _, def := range sym.Definitions {
-				if contains(def.Kinds, kind) {
-					return // Already registered
+				if def.AliasKind == "" && contains(def.Kinds, kind) {
+					return // Already registered by the rules
 				}
 			}
+			if i.crdFiles == nil {
+				i.crdFiles = make(map[string]map[string]bool)
+			}
+			i.crdFiles[kind] = map[string]bool{path: true}
 
 			// Add to the first definition that uses metadata.name
 			found := false
 			for dIdx, def := range sym.Definitions {
-				if def.Path == "metadata.name" {
+				if def.Path == "metadata.name" && def.AliasKind == "" {
 					i.Config.Symbols[idx].Definitions[dIdx].Kinds = append(i.Config.Symbols[idx].Definitions[dIdx].Kinds, kind)
 					log.Info().Str("kind", kind).Msg("Registered new dynamic kind from CRD")
 					found = true
@@ -501,12 +1226,210 @@ func (i *Indexer) registerKind(kind string) {
 	}
 }
 
-func (i *Indexer) traverse(node *yaml.Node, path []string, visitor func(*yaml.Node, []string)) {
-	visitor(node, path)
+// settleCRDKinds records the kinds the CRDs in path declared while it was
+// indexed, unregistering those it declared before and no longer does when
+// no other file declares them. A file that couldn't be read completely
+// only adds kinds.
+func (i *Indexer) settleCRDKinds(path string, complete bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	kinds := i.pendingCRDKinds[path]
+	delete(i.pendingCRDKinds, path)
+	if !complete {
+		for _, kind := range i.fileCRDKinds[path] {
+			if !contains(kinds, kind) {
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	i.setFileCRDKinds(path, kinds)
+}
+
+// setFileCRDKinds replaces the kinds path declares; mu must be held.
+func (i *Indexer) setFileCRDKinds(path string, kinds []string) {
+	for _, kind := range i.fileCRDKinds[path] {
+		if contains(kinds, kind) {
+			continue
+		}
+		files, ok := i.crdFiles[kind]
+		if !ok {
+			continue
+		}
+		delete(files, path)
+		if len(files) == 0 {
+			delete(i.crdFiles, kind)
+			i.unregisterKind(kind)
+		}
+	}
+	if len(kinds) == 0 {
+		delete(i.fileCRDKinds, path)
+		return
+	}
+	if i.fileCRDKinds == nil {
+		i.fileCRDKinds = make(map[string][]string)
+	}
+	i.fileCRDKinds[path] = kinds
+}
+
+// kindUse lists the kinds of a file whose indexing depends on CRDs:
+// Skipped those of documents that produced no resource while no CRD
+// registered them, Dynamic those of resources indexed only because a CRD
+// did.
+type kindUse struct {
+	Skipped []string `json:"skipped,omitempty"`
+	Dynamic []string `json:"dynamic,omitempty"`
+}
+
+// stale reports whether the kinds registered from CRDs changed in a way
+// that would index use's file differently; mu must be held.
+func (i *Indexer) stale(use kindUse) bool {
+	for _, kind := range use.Skipped {
+		if i.crdKind(kind) {
+			return true
+		}
+	}
+	for _, kind := range use.Dynamic {
+		if !i.crdKind(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// crdKind reports whether kind is registered from a CRD; mu must be held.
+func (i *Indexer) crdKind(kind string) bool {
+	if _, ok := i.crdFiles[kind]; ok {
+		return true
+	}
+	if i.Config.Settings.CaseInsensitiveKinds {
+		for registered := range i.crdFiles {
+			if strings.EqualFold(registered, kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordKinds records the kinds path's resources and skipped documents
+// used. A file that couldn't be read completely only adds kinds.
+func (i *Indexer) recordKinds(path string, resources []*K8sResource, skipped []string, complete bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	var use kindUse
+	if !complete {
+		use = i.fileKinds[path]
+	}
+	for _, kind := range skipped {
+		if !i.crdKind(kind) && !contains(use.Skipped, kind) {
+			use.Skipped = append(use.Skipped, kind)
+		}
+	}
+	for _, res := range resources {
+		if res.DeclaredBy == nil && i.crdKind(res.Kind) && !contains(use.Dynamic, res.Kind) {
+			use.Dynamic = append(use.Dynamic, res.Kind)
+		}
+	}
+	i.setFileKinds(path, use)
+}
+
+// setFileKinds replaces the kinds recorded for path; mu must be held.
+func (i *Indexer) setFileKinds(path string, use kindUse) {
+	if len(use.Skipped) == 0 && len(use.Dynamic) == 0 {
+		delete(i.fileKinds, path)
+		return
+	}
+	if i.fileKinds == nil {
+		i.fileKinds = make(map[string]kindUse)
+	}
+	i.fileKinds[path] = use
+}
+
+// staleKindFiles returns the files among paths whose recorded kinds are
+// stale, in order.
+func (i *Indexer) staleKindFiles(paths map[string]bool) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var stale []string
+	for path, use := range i.fileKinds {
+		if paths[path] && i.stale(use) {
+			stale = append(stale, path)
+		}
+	}
+	slices.Sort(stale)
+	return stale
+}
+
+// unregisterKind undoes registerKind; mu must be held. The definitions are
+// replaced rather than edited in place, as resolvers may still be reading
+// them.
+func (i *Indexer) unregisterKind(kind string) {
+	for idx, sym := range i.Config.Symbols {
+		if sym.Name != "k8s.resource.name" {
+			continue
+		}
+		var defs []config.SymbolDefinition
+		for _, def := range sym.Definitions {
+			if def.Path == "metadata.name" && def.AliasKind == "" && contains(def.Kinds, kind) {
+				def.Kinds = slices.DeleteFunc(slices.Clone(def.Kinds), func(k string) bool { return k == kind })
+				if len(def.Kinds) == 0 {
+					continue
+				}
+			}
+			defs = append(defs, def)
+		}
+		i.Config.Symbols[idx].Definitions = defs
+		log.Info().Str("kind", kind).Msg("Unregistered dynamic kind no CRD declares anymore")
+		return
+	}
+}
+
+// RemoveFile drops everything indexed from path, including the kinds its
+// CRDs registered.
+func (i *Indexer) RemoveFile(path string) {
+	i.Store.RemoveByFile(path)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.setFileCRDKinds(path, nil)
+	delete(i.fileKinds, path)
+	if i.cache != nil {
+		i.cache.forget(path)
+	}
+}
+
+// RemovePathPrefix is RemoveFile for every file under dir. It returns the
+// number of files dropped from the Store.
+func (i *Indexer) RemovePathPrefix(dir string) int {
+	files := i.Store.RemoveByPathPrefix(dir)
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for path := range i.fileCRDKinds {
+		if strings.HasPrefix(path, prefix) {
+			i.setFileCRDKinds(path, nil)
+		}
+	}
+	for path := range i.fileKinds {
+		if strings.HasPrefix(path, prefix) {
+			delete(i.fileKinds, path)
+		}
+	}
+	if i.cache != nil {
+		i.cache.forgetUnder(dir, nil)
+	}
+	return files
+}
+
+// traverse visits node and everything under it with the path of mapping
+// keys leading there. indices runs parallel to path: the position of the
+// node within the sequence under that key, or -1 outside of one. Nested
+// sequences only record the outermost position.
+func (i *Indexer) traverse(node *yaml.Node, parent *yaml.Node, path []string, indices []int, visitor func(*yaml.Node, *yaml.Node, []string, []int)) {
+	visitor(node, parent, path, indices)
 
 	if node.Kind == yaml.DocumentNode {
 		for _, child := range node.Content {
-			i.traverse(child, path, visitor)
+			i.traverse(child, node, path, indices, visitor)
 		}
 	} else if node.Kind == yaml.MappingNode {
 		for j := 0; j < len(node.Content); j += 2 {
@@ -516,30 +1439,65 @@ func (i *Indexer) traverse(node *yaml.Node, path []string, visitor func(*yaml.No
 			newPath := make([]string, len(path)+1)
 			copy(newPath, path)
 			newPath[len(path)] = keyNode.Value
+			newIndices := make([]int, len(indices)+1)
+			copy(newIndices, indices)
+			newIndices[len(indices)] = -1
 
-			i.traverse(valNode, newPath, visitor)
+			i.traverse(valNode, node, newPath, newIndices, visitor)
 		}
 	} else if node.Kind == yaml.SequenceNode {
-		for _, child := range node.Content {
-			i.traverse(child, path, visitor)
+		for j, child := range node.Content {
+			childIndices := indices
+			if n := len(indices); n > 0 && indices[n-1] == -1 {
+				childIndices = make([]int, n)
+				copy(childIndices, indices)
+				childIndices[n-1] = j
+			}
+			i.traverse(child, node, path, childIndices, visitor)
 		}
 	}
 }
 
-func matchPath(current []string, pattern string) bool {
+// matchPath reports whether current matches the dotted pattern, where a *
+// segment matches any one key. A segment may end in [] to mark a list,
+// which matches any item like the bare key does, or in [N] to only match
+// the item at index N. indices are the
+// positions traverse tracks; nil means unknown, which no [N] matches.
+func matchPath(current []string, indices []int, pattern string) bool {
 	parts := strings.Split(pattern, ".")
 	if len(parts) != len(current) {
 		return false
 	}
 	for i, part := range parts {
-		cleanPart := strings.TrimSuffix(part, "[]")
-		if cleanPart != current[i] {
+		key, index := pathSegment(part)
+		if key != "*" && key != current[i] {
+			return false
+		}
+		if index >= 0 && (i >= len(indices) || indices[i] != index) {
 			return false
 		}
 	}
 	return true
 }
 
+// pathSegment splits a rule path segment into its key and the list index
+// it selects, -1 for "key" and "key[]".
+func pathSegment(part string) (string, int) {
+	open := strings.LastIndexByte(part, '[')
+	if open < 0 || !strings.HasSuffix(part, "]") {
+		return part, -1
+	}
+	inner := part[open+1 : len(part)-1]
+	if inner == "" {
+		return part[:open], -1
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return part, -1
+	}
+	return part[:open], index
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -549,9 +1507,19 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func matchesKind(ruleKinds []string, currentKind string) bool {
+func matchesKind(ruleKinds []string, currentKind string, foldCase bool) bool {
 	for _, k := range ruleKinds {
-		if k == "*" || k == currentKind {
+		if k == "*" || k == currentKind || (foldCase && strings.EqualFold(k, currentKind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsKind reports whether kind is listed in kinds, optionally ignoring case.
+func containsKind(kinds []string, kind string, foldCase bool) bool {
+	for _, k := range kinds {
+		if k == kind || (foldCase && strings.EqualFold(k, kind)) {
 			return true
 		}
 	}
@@ -1,7 +1,11 @@
 package indexer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -127,6 +131,66 @@ metadata:
 	}
 }
 
+func TestIndexListItems(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service", "ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	content := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: web
+      namespace: prod
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: settings
+`
+	if !idx.IndexContent("list.yaml", content) {
+		t.Fatal("Expected the List items to be indexed")
+	}
+
+	svc := store.Get("Service", "prod", "web")
+	if svc == nil {
+		t.Fatal("Service was not indexed from the List")
+	}
+	if svc.Line != 6 || svc.Col != 12 {
+		t.Errorf("Expected the Service name at 6:12, got %d:%d", svc.Line, svc.Col)
+	}
+	cm := store.Get("ConfigMap", "default", "settings")
+	if cm == nil {
+		t.Fatal("ConfigMap was not indexed from the List")
+	}
+	if cm.Line != 11 || cm.Col != 12 {
+		t.Errorf("Expected the ConfigMap name at 11:12, got %d:%d", cm.Line, cm.Col)
+	}
+
+	// API list kinds from kubectl get -o yaml are unwrapped the same way.
+	idx.IndexContent("services.yaml", `apiVersion: v1
+kind: ServiceList
+items:
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: api
+`)
+	if store.Get("Service", "default", "api") == nil {
+		t.Error("Service was not indexed from the ServiceList")
+	}
+}
+
 func TestConcurrentAccess(t *testing.T) {
 	cfg := &config.Config{
 		Symbols: []config.Symbol{
@@ -189,3 +253,898 @@ spec:
 		t.Errorf("Expected 10 dynamic kinds, got %d", count)
 	}
 }
+
+func TestReindexAfterRenameEvictsOldKey(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	idx.IndexContent("/repo/app.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`)
+	idx.IndexContent("/repo/app.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings-v2
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`)
+
+	if store.Get("ConfigMap", "default", "settings") != nil {
+		t.Fatal("Expected the old key to be evicted after renaming")
+	}
+	if store.Get("ConfigMap", "default", "settings-v2") == nil || store.Get("Deployment", "default", "web") == nil {
+		t.Fatal("Expected the re-indexed resources to be present")
+	}
+
+	// A document that fails to parse mid-edit must not evict what was indexed.
+	idx.IndexContent("/repo/app.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings-v2
+---
+kind: [unterminated
+`)
+	if store.Get("Deployment", "default", "web") == nil {
+		t.Fatal("Expected resources to survive a partial parse")
+	}
+}
+
+func TestReindexDropsRemovedDocument(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment", "Service"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`
+	service := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte(deployment+"---\n"+service), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx.IndexFile(path)
+	if store.Get("Service", "default", "web") == nil {
+		t.Fatal("Expected the Service to be indexed")
+	}
+
+	// Deleting the second document on disk drops the Service.
+	if err := os.WriteFile(path, []byte(deployment), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx.IndexFile(path)
+	if store.Get("Service", "default", "web") != nil {
+		t.Fatal("Expected the removed Service to no longer resolve")
+	}
+	if store.Get("Deployment", "default", "web") == nil || len(store.FindByFile(path)) != 1 {
+		t.Fatalf("Expected only the Deployment to remain, got %v", store.FindByFile(path))
+	}
+
+	// The same goes for unsaved content, and for the deleted file.
+	idx.IndexContent(path, deployment+"---\n"+service)
+	idx.IndexContent(path, service)
+	if store.Get("Deployment", "default", "web") != nil || store.Get("Service", "default", "web") == nil {
+		t.Fatal("Expected re-indexed content to replace the file's resources")
+	}
+	idx.RemoveFile(path)
+	if store.Get("Service", "default", "web") != nil || len(store.FindByFile(path)) != 0 {
+		t.Fatal("Expected nothing from a deleted file")
+	}
+}
+
+func TestEnvFromSecretReferences(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "workload.envfrom.secret",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Secret",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Deployment"},
+					Path:  "spec.template.spec.containers[].envFrom[].secretRef.name",
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	idx.IndexContent("/tmp/deploy.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          envFrom:
+            - secretRef:
+                name: db-creds
+      containers:
+        - name: app
+          envFrom:
+            - secretRef:
+                name: db-creds
+`)
+
+	res := store.Get("Deployment", "default", "web")
+	if res == nil {
+		t.Fatal("Deployment was not indexed")
+	}
+	var lines []int
+	for _, ref := range res.References {
+		if ref.Kind == "Secret" && ref.Name == "db-creds" {
+			lines = append(lines, ref.Line)
+		}
+	}
+	// The containers reference is matched by the rule too but counted once.
+	if len(lines) != 2 || lines[0] == lines[1] {
+		t.Fatalf("Expected one Secret reference per container, got %+v", res.References)
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                         "nginx",
+		"nginx:1.25":                    "nginx",
+		"registry:5000/app":             "registry:5000/app",
+		"registry:5000/app:v1":          "registry:5000/app",
+		"ghcr.io/org/app@sha256:abc123": "ghcr.io/org/app",
+	}
+	for image, want := range tests {
+		if got := ImageRepository(image); got != want {
+			t.Errorf("ImageRepository(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestInitContainerConfigMapReferences(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	idx.IndexContent("/tmp/pod.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  initContainers:
+    - name: init
+      env:
+        - name: MODE
+          valueFrom:
+            configMapKeyRef:
+              name: settings
+              key: mode
+  containers:
+    - name: app
+  ephemeralContainers:
+    - name: debug
+      envFrom:
+        - configMapRef:
+            name: debug-env
+`)
+
+	res := store.Get("Pod", "default", "app")
+	if res == nil {
+		t.Fatal("Pod was not indexed")
+	}
+	var keyRef, ephemeralRef bool
+	for _, ref := range res.References {
+		// Line 12 is "              key: mode".
+		if ref.Kind == "ConfigMap" && ref.Name == "settings" && ref.Key == "mode" && ref.Line == 12 && ref.Col == 19 {
+			keyRef = true
+		}
+		if ref.Kind == "ConfigMap" && ref.Name == "debug-env" {
+			ephemeralRef = true
+		}
+	}
+	if !keyRef || !ephemeralRef {
+		t.Fatalf("Expected initContainer and ephemeralContainer references, got %+v", res.References)
+	}
+}
+
+// deeplyNested returns a document nesting depth mappings under spec.
+func deeplyNested(depth int) string {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: deep\nspec:\n")
+	for d := 1; d <= depth; d++ {
+		b.WriteString(strings.Repeat(" ", d) + "a:\n")
+	}
+	return b.String()
+}
+
+func TestCheckDocumentLimits(t *testing.T) {
+	var deep yaml.Node
+	if err := yaml.Unmarshal([]byte(deeplyNested(2000)), &deep); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDocumentLimits(&deep, config.DocumentLimits{}); err != ErrDocumentTooComplex {
+		t.Errorf("Expected deep nesting to be refused, got %v", err)
+	}
+
+	laughs := "a: &a [x, x, x, x, x, x, x, x, x, x]\n"
+	for i, prev := 'b', 'a'; i <= 'j'; i, prev = i+1, i {
+		laughs += fmt.Sprintf("%c: &%c [*%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c]\n", i, i, prev, prev, prev, prev, prev, prev, prev, prev, prev, prev)
+	}
+	var bomb yaml.Node
+	if err := yaml.Unmarshal([]byte(laughs), &bomb); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDocumentLimits(&bomb, config.DocumentLimits{}); err != ErrDocumentTooComplex {
+		t.Errorf("Expected alias expansion to be refused, got %v", err)
+	}
+
+	var small yaml.Node
+	if err := yaml.Unmarshal([]byte(deeplyNested(5)), &small); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDocumentLimits(&small, config.DocumentLimits{}); err != nil {
+		t.Errorf("Expected a small document to pass, got %v", err)
+	}
+	if err := CheckDocumentLimits(&small, config.DocumentLimits{MaxDepth: 5}); err != ErrDocumentTooComplex {
+		t.Errorf("Expected a configured depth limit to apply, got %v", err)
+	}
+}
+
+func TestIndexContentSkipsTooComplexDocuments(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	idx.IndexContent("/tmp/deep.yaml", deeplyNested(2000)+`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shallow
+`)
+	if store.Get("ConfigMap", "default", "deep") != nil {
+		t.Error("Expected the deeply nested document to be skipped")
+	}
+	if store.Get("ConfigMap", "default", "shallow") == nil {
+		t.Error("Expected the following document to be indexed")
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		image, registry, repository string
+	}{
+		{"nginx", "docker.io", "library/nginx"},
+		{"bitnami/redis:7", "docker.io", "bitnami/redis"},
+		{"docker.io/nginx@sha256:abc", "docker.io", "library/nginx"},
+		{"ghcr.io/org/app:v1.2.3", "ghcr.io", "org/app"},
+		{"localhost/app", "localhost", "app"},
+		{"registry:5000/team/app:v1", "registry:5000", "team/app"},
+	}
+	for _, tt := range tests {
+		registry, repository := ParseImage(tt.image)
+		if registry != tt.registry || repository != tt.repository {
+			t.Errorf("ParseImage(%q) = %q, %q, want %q, %q", tt.image, registry, repository, tt.registry, tt.repository)
+		}
+	}
+}
+
+func TestDataKeys(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	idx.IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx-config
+data:
+  app.conf: |
+    server {}
+  "mime.types": text
+binaryData:
+  logo.png: aGVsbG8=
+`)
+
+	res := store.Get("ConfigMap", "default", "nginx-config")
+	if res == nil {
+		t.Fatal("ConfigMap was not indexed")
+	}
+	want := []DataKey{{Key: "app.conf", Line: 5, Col: 2}, {Key: "mime.types", Line: 7, Col: 3}, {Key: "logo.png", Line: 9, Col: 2}}
+	if fmt.Sprint(res.DataKeys) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, res.DataKeys)
+	}
+
+	if got := store.SearchDataKeys("nginx-config/logo"); len(got) != 1 {
+		t.Fatalf("Expected one match on name/key, got %d", len(got))
+	}
+	if got := store.SearchDataKeys("missing"); len(got) != 0 {
+		t.Fatalf("Expected no matches, got %d", len(got))
+	}
+}
+
+func TestGenerateNameAndOwnerReferences(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Pod", "ReplicaSet"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+
+	idx.IndexContent("/tmp/pod.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  generateName: web-7d9f8-
+  namespace: prod
+  ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: web-7d9f8
+`)
+
+	pod := store.Get("Pod", "prod", "web-7d9f8-")
+	if pod == nil || !pod.GenerateName || pod.Line != 3 || pod.Col != 16 {
+		t.Fatalf("Expected the Pod to be indexed under its generateName prefix, got %+v", pod)
+	}
+	if len(pod.References) != 1 || pod.References[0].Kind != "ReplicaSet" || pod.References[0].Name != "web-7d9f8" ||
+		pod.References[0].Namespace != "prod" || pod.References[0].Line != 8 {
+		t.Fatalf("Expected an owner reference to the ReplicaSet, got %+v", pod.References)
+	}
+
+	if got := store.FindGenerated("Pod", "prod", "web-7d9f8-x2k4q"); got != pod {
+		t.Fatalf("Expected generated name to match the prefix, got %+v", got)
+	}
+	if got := store.FindGenerated("Pod", "default", "web-7d9f8-x2k4q"); got != nil {
+		t.Fatalf("Expected no match in another namespace, got %+v", got)
+	}
+
+	// Kinds without a resource name definition are not indexed by prefix.
+	idx.IndexContent("/tmp/job.yaml", `apiVersion: batch/v1
+kind: Job
+metadata:
+  generateName: migrate-
+`)
+	if store.Get("Job", "default", "migrate-") != nil {
+		t.Fatal("Expected Job without a name definition to be skipped")
+	}
+}
+
+func TestScanWorkspaceExcludeGlobs(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	root := t.TempDir()
+	for _, name := range []string{"app", "vendor/lib", "charts/web/templates/cm", "skip.tmpl"} {
+		path := filepath.Join(root, name+".yaml")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		content := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + filepath.Base(name) + "\n"
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+	idx.SetExcludeGlobs([]string{"vendor", "charts/*/templates", "*.tmpl.yaml"})
+	if err := idx.ScanWorkspace(root); err != nil {
+		t.Fatalf("ScanWorkspace failed: %v", err)
+	}
+
+	if store.Get("ConfigMap", "", "app") == nil {
+		t.Error("Expected app to be indexed")
+	}
+	for _, name := range []string{"lib", "cm", "skip.tmpl"} {
+		if store.Get("ConfigMap", "", name) != nil {
+			t.Errorf("Expected %s to be excluded", name)
+		}
+	}
+}
+
+func TestScanWorkspaceIndexesJSON(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	root := t.TempDir()
+	content := `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {
+    "name": "web",
+    "namespace": "prod"
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "deploy.json"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "package.json"), []byte(`{"name": "tools"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+	found, err := idx.ScanWorkspaceCount(root)
+	if err != nil {
+		t.Fatalf("ScanWorkspace failed: %v", err)
+	}
+	if found != 2 {
+		t.Errorf("Expected both JSON files to be scanned, got %d", found)
+	}
+
+	res := store.Get("Deployment", "prod", "web")
+	if res == nil {
+		t.Fatal("Deployment was not indexed from JSON")
+	}
+	// The name is quoted, so it starts one column after the quote.
+	if res.Line != 4 || res.Col != 13 {
+		t.Errorf("Expected the name at 4:13, got %d:%d", res.Line, res.Col)
+	}
+}
+
+func TestEditedCRDUnregistersOldKind(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	idx := NewIndexer(NewStore(), cfg)
+	crd := func(kind string) string {
+		return fmt.Sprintf(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: %s
+`, kind)
+	}
+	registered := func(kind string) bool {
+		for _, def := range cfg.Symbols[0].Definitions {
+			if contains(def.Kinds, kind) {
+				return true
+			}
+		}
+		return false
+	}
+
+	idx.IndexContent("/ws/crd.yaml", crd("Widget"))
+	if !registered("Widget") {
+		t.Fatal("Expected Widget to be registered")
+	}
+
+	idx.IndexContent("/ws/crd.yaml", crd("Gadget"))
+	if registered("Widget") || !registered("Gadget") {
+		t.Errorf("Expected the edit to replace Widget with Gadget, got %+v", cfg.Symbols[0].Definitions)
+	}
+
+	// A kind another CRD file still declares stays, as do kinds from the rules.
+	idx.IndexContent("/ws/other.yaml", crd("Gadget"))
+	idx.IndexContent("/ws/service-crd.yaml", crd("Service"))
+	idx.IndexContent("/ws/crd.yaml", crd("Widget"))
+	idx.RemoveFile("/ws/service-crd.yaml")
+	if !registered("Gadget") || !registered("Widget") || !registered("Service") {
+		t.Errorf("Expected Gadget, Widget and Service to stay registered, got %+v", cfg.Symbols[0].Definitions)
+	}
+
+	idx.RemoveFile("/ws/other.yaml")
+	if registered("Gadget") {
+		t.Error("Expected Gadget to go with the last CRD declaring it")
+	}
+	idx.RemovePathPrefix("/ws")
+	if registered("Widget") {
+		t.Errorf("Expected removing the folder to drop Widget, got %+v", cfg.Symbols[0].Definitions)
+	}
+}
+
+func TestScanWorkspaceConcurrentCRDs(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+		Settings: config.Settings{ScanWorkers: 8},
+	}
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Every kind is declared by two CRD files, indexed concurrently with
+	// ConfigMaps and a file that fails to parse.
+	const kinds = 40
+	for n := 0; n < kinds; n++ {
+		for _, copy := range []string{"a", "b"} {
+			write(fmt.Sprintf("crd-%d-%s.yaml", n, copy), fmt.Sprintf(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets%d.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget%d
+`, n, n))
+		}
+		write(fmt.Sprintf("cm-%d.yaml", n), fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-%d\n", n))
+	}
+	write("broken.yaml", "kind: [unterminated\n")
+
+	store := NewStore()
+	idx := NewIndexer(store, cfg)
+	found, err := idx.ScanWorkspaceCount(root)
+	if err != nil {
+		t.Fatalf("ScanWorkspace failed: %v", err)
+	}
+	if found != 3*kinds+1 {
+		t.Errorf("Expected %d manifest files, got %d", 3*kinds+1, found)
+	}
+	if store.Len() != kinds {
+		t.Errorf("Expected %d ConfigMaps, got %d", kinds, store.Len())
+	}
+
+	registered := func(kind string) int {
+		count := 0
+		for _, def := range cfg.Symbols[0].Definitions {
+			for _, k := range def.Kinds {
+				if k == kind {
+					count++
+				}
+			}
+		}
+		return count
+	}
+	for n := 0; n < kinds; n++ {
+		if got := registered(fmt.Sprintf("Widget%d", n)); got != 1 {
+			t.Fatalf("Expected Widget%d to be registered once, got %d", n, got)
+		}
+	}
+
+	// Both declaring files were recorded: the kind outlives either one.
+	idx.RemoveFile(filepath.Join(root, "crd-0-a.yaml"))
+	if registered("Widget0") != 1 {
+		t.Error("Expected Widget0 to stay while crd-0-b.yaml declares it")
+	}
+	idx.RemoveFile(filepath.Join(root, "crd-0-b.yaml"))
+	if registered("Widget0") != 0 {
+		t.Error("Expected Widget0 to go with the last CRD declaring it")
+	}
+}
+
+func TestScanWorkspaceContextCancelled(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	root := t.TempDir()
+	content := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"
+	if err := os.WriteFile(filepath.Join(root, "cm.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err := NewIndexer(store, cfg).ScanWorkspaceContext(ctx, root)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the scan to stop with context.Canceled, got %v", err)
+	}
+	if n != 0 || store.Len() != 0 {
+		t.Errorf("Expected nothing indexed after cancellation, got %d files and %d resources", n, store.Len())
+	}
+}
+
+func TestIndexArrayIndexPaths(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Router"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "router.primary",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Service",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Router"},
+					Path:  "spec.backends[0].service",
+				},
+			},
+			{
+				Name:       "router.mirror",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Router"},
+					Path:  "spec.mirrors[].config",
+				},
+			},
+		},
+	}
+	store := NewStore()
+	NewIndexer(store, cfg).IndexContent("/tmp/router.yaml", `apiVersion: example.com/v1
+kind: Router
+metadata:
+  name: edge
+spec:
+  backends:
+    - service: primary
+    - service: fallback
+  mirrors:
+    - config: first
+    - config: second
+`)
+
+	res := store.Get("Router", "default", "edge")
+	if res == nil {
+		t.Fatal("Router was not indexed")
+	}
+	var got []string
+	for _, ref := range res.References {
+		got = append(got, ref.Kind+"/"+ref.Name)
+	}
+	expected := []string{"Service/primary", "ConfigMap/first", "ConfigMap/second"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected references %v, got %v", expected, got)
+	}
+}
+
+func TestPathSegment(t *testing.T) {
+	tests := []struct {
+		part  string
+		key   string
+		index int
+	}{
+		{"containers", "containers", -1},
+		{"containers[]", "containers", -1},
+		{"ports[0]", "ports", 0},
+		{"rules[12]", "rules", 12},
+		{"odd[x]", "odd[x]", -1},
+	}
+	for _, tt := range tests {
+		if key, index := pathSegment(tt.part); key != tt.key || index != tt.index {
+			t.Errorf("pathSegment(%q) = %q, %d, want %q, %d", tt.part, key, index, tt.key, tt.index)
+		}
+	}
+}
+
+func TestIndexWildcardPaths(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Pipeline"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "pipeline.stage.secret",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Secret",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Pipeline"},
+					Path:  "spec.*.secretRef.name",
+				},
+			},
+			{
+				Name:       "pipeline.configs",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Pipeline"},
+					Path:  "spec.configs.*",
+				},
+			},
+		},
+	}
+	store := NewStore()
+	NewIndexer(store, cfg).IndexContent("/tmp/pipeline.yaml", `apiVersion: example.com/v1
+kind: Pipeline
+metadata:
+  name: release
+spec:
+  build:
+    secretRef:
+      name: build-creds
+  deploy:
+    secretRef:
+      name: deploy-creds
+    extra:
+      secretRef:
+        name: too-deep
+  configs:
+    main: settings
+    debug: debug-settings
+`)
+
+	res := store.Get("Pipeline", "default", "release")
+	if res == nil {
+		t.Fatal("Pipeline was not indexed")
+	}
+	var got []string
+	for _, ref := range res.References {
+		got = append(got, ref.Kind+"/"+ref.Name)
+	}
+	expected := []string{"Secret/build-creds", "Secret/deploy-creds", "ConfigMap/settings", "ConfigMap/debug-settings"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected references %v, got %v", expected, got)
+	}
+}
+
+func TestCRDSchemaIndexed(t *testing.T) {
+	store := NewStore()
+	idx := NewIndexer(store, &config.Config{})
+
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: databases.example.com
+spec:
+  group: example.com
+  names:
+    kind: Database
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              required: [size]
+              properties:
+                size:
+                  type: integer
+                  description: Number of replicas.
+                engine:
+                  type: string
+                  enum: [postgres, mysql]
+                users:
+                  type: array
+                  items:
+                    type: object
+                    properties:
+                      name:
+                        type: string
+`
+	idx.IndexContent("/repo/crd.yaml", crd)
+
+	schema := store.Schema("example.com/v1", "Database")
+	if schema == nil {
+		t.Fatal("Expected the Database schema to be stored")
+	}
+	if store.Schema("example.com/v2", "Database") != nil {
+		t.Error("Expected no schema for an undeclared version")
+	}
+	spec := schema.Lookup([]string{"spec"})
+	if spec == nil || len(spec.Required) != 1 || spec.Required[0] != "size" {
+		t.Fatalf("Expected spec to require size, got %+v", spec)
+	}
+	if size := spec.Property("size"); size == nil || size.Type != "integer" || size.Description != "Number of replicas." {
+		t.Errorf("Expected an integer size, got %+v", size)
+	}
+	if engine := spec.Property("engine"); engine == nil || len(engine.Enum) != 2 {
+		t.Errorf("Expected engine's enum, got %+v", engine)
+	}
+	// Paths step through arrays the way the resolver's do.
+	if name := schema.Lookup([]string{"spec", "users", "name"}); name == nil || name.Type != "string" {
+		t.Errorf("Expected users[].name to be a string, got %+v", name)
+	}
+
+	idx.IndexContent("/repo/crd.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n")
+	if store.Schema("example.com/v1", "Database") != nil {
+		t.Error("Expected the schema to go with its CRD")
+	}
+}
@@ -0,0 +1,62 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"k8s-lsp/pkg/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// interpolationPattern matches $(var) and ${var}.
+var interpolationPattern = regexp.MustCompile(`\$\(([A-Za-z_][A-Za-z0-9_.]*)\)|\$\{([A-Za-z_][A-Za-z0-9_.]*)\}`)
+
+// HasInterpolation reports whether s contains a $(var) or ${var}.
+func HasInterpolation(s string) bool {
+	return interpolationPattern.MatchString(s)
+}
+
+// Interpolate substitutes the known variables in s. ok is false when a
+// variable is left unresolved.
+func Interpolate(s string, vars map[string]string) (result string, ok bool) {
+	ok = true
+	result = interpolationPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := interpolationPattern.FindStringSubmatch(match)
+		name := groups[1]
+		if name == "" {
+			name = groups[2]
+		}
+		if value, found := vars[name]; found {
+			return value
+		}
+		ok = false
+		return match
+	})
+	return result, ok
+}
+
+// InterpolationVars returns the variables for the manifest at path: the
+// configured values, overridden by the values file in its directory.
+func InterpolationVars(settings config.Interpolation, path string) map[string]string {
+	vars := make(map[string]string, len(settings.Values))
+	for name, value := range settings.Values {
+		vars[name] = value
+	}
+	if settings.ValuesFile == "" {
+		return vars
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path), settings.ValuesFile))
+	if err != nil {
+		return vars
+	}
+	var fileVars map[string]string
+	if err := yaml.Unmarshal(data, &fileVars); err != nil {
+		return vars
+	}
+	for name, value := range fileVars {
+		vars[name] = value
+	}
+	return vars
+}
@@ -0,0 +1,49 @@
+package indexer
+
+import (
+	"errors"
+
+	"k8s-lsp/pkg/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrDocumentTooComplex reports a document beyond the configured
+// DocumentLimits. Such documents are skipped rather than analyzed.
+var ErrDocumentTooComplex = errors.New("document too complex for analysis")
+
+// CheckDocumentLimits walks node without recursion and returns
+// ErrDocumentTooComplex when it nests deeper than limits.Depth() or has more
+// than limits.Nodes() nodes. Aliases are expanded while counting, so
+// billion-laughs style anchors stop at the node limit.
+func CheckDocumentLimits(node *yaml.Node, limits config.DocumentLimits) error {
+	type frame struct {
+		node  *yaml.Node
+		depth int
+	}
+	maxDepth, maxNodes := limits.Depth(), limits.Nodes()
+
+	stack := []frame{{node, 1}}
+	count := 0
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.node == nil {
+			continue
+		}
+
+		count++
+		if count > maxNodes || f.depth > maxDepth {
+			return ErrDocumentTooComplex
+		}
+
+		if f.node.Kind == yaml.AliasNode {
+			stack = append(stack, frame{f.node.Alias, f.depth})
+			continue
+		}
+		for _, child := range f.node.Content {
+			stack = append(stack, frame{child, f.depth + 1})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package indexer
+
+import "gopkg.in/yaml.v3"
+
+// podClassFields maps pod spec fields naming a cluster-scoped class to the
+// kind they reference.
+var podClassFields = []struct{ field, kind string }{
+	{"priorityClassName", "PriorityClass"},
+	{"runtimeClassName", "RuntimeClass"},
+}
+
+// extractPodClassReferences indexes the PriorityClass and RuntimeClass a
+// pod spec names. Both are cluster-scoped, so the references carry no
+// namespace.
+func extractPodClassReferences(root *yaml.Node, kind string) []Reference {
+	if !(kind == "Pod" || kind == "Deployment" || kind == "StatefulSet" || kind == "DaemonSet" || kind == "Job" || kind == "CronJob") {
+		return nil
+	}
+
+	podSpec := findPodSpecNode(root, kind)
+	if podSpec == nil {
+		return nil
+	}
+
+	var refs []Reference
+	for _, f := range podClassFields {
+		nameNode := getMapValue(podSpec, f.field)
+		if nameNode != nil && nameNode.Kind == yaml.ScalarNode && nameNode.Value != "" {
+			refs = append(refs, Reference{
+				Kind: f.kind,
+				Name: nameNode.Value,
+				Line: nameNode.Line - 1,
+				Col:  scalarCol(nameNode),
+			})
+		}
+	}
+	return refs
+}
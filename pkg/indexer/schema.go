@@ -0,0 +1,165 @@
+package indexer
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the part of a CRD's OpenAPI v3 schema that validation and
+// completion use.
+type Schema struct {
+	Type        string
+	Description string
+	Required    []string
+	Enum        []string
+	Properties  map[string]*Schema
+	// Items describes the elements of an array.
+	Items *Schema
+	// AdditionalProperties describes the values of a map without fixed
+	// properties.
+	AdditionalProperties *Schema
+	// PreserveUnknownFields (x-kubernetes-preserve-unknown-fields) allows
+	// fields the schema doesn't describe.
+	PreserveUnknownFields bool
+	// IntOrString (x-kubernetes-int-or-string) accepts both.
+	IntOrString bool
+}
+
+// maxSchemaDepth bounds how deeply nested a schema is parsed, which also
+// stops anchors that refer back to themselves.
+const maxSchemaDepth = 64
+
+// parseSchema reads an openAPIV3Schema mapping.
+func parseSchema(node *yaml.Node, depth int) *Schema {
+	if node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || node.Kind != yaml.MappingNode || depth > maxSchemaDepth {
+		return nil
+	}
+	s := &Schema{}
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		value := node.Content[j+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		if value == nil {
+			continue
+		}
+		switch node.Content[j].Value {
+		case "type":
+			s.Type = value.Value
+		case "description":
+			s.Description = value.Value
+		case "required":
+			for _, item := range value.Content {
+				s.Required = append(s.Required, item.Value)
+			}
+		case "enum":
+			for _, item := range value.Content {
+				if item.Kind == yaml.ScalarNode {
+					s.Enum = append(s.Enum, item.Value)
+				}
+			}
+		case "properties":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			s.Properties = make(map[string]*Schema, len(value.Content)/2)
+			for k := 0; k+1 < len(value.Content); k += 2 {
+				if prop := parseSchema(value.Content[k+1], depth+1); prop != nil {
+					s.Properties[value.Content[k].Value] = prop
+				}
+			}
+		case "items":
+			s.Items = parseSchema(value, depth+1)
+		case "additionalProperties":
+			if value.Kind == yaml.ScalarNode {
+				s.PreserveUnknownFields = s.PreserveUnknownFields || value.Value == "true"
+			} else {
+				s.AdditionalProperties = parseSchema(value, depth+1)
+			}
+		case "x-kubernetes-preserve-unknown-fields":
+			s.PreserveUnknownFields = s.PreserveUnknownFields || value.Value == "true"
+		case "x-kubernetes-int-or-string":
+			s.IntOrString = value.Value == "true"
+		}
+	}
+	return s
+}
+
+// Property returns the schema of the field name of an object, nil when the
+// schema doesn't describe it.
+func (s *Schema) Property(name string) *Schema {
+	if s == nil {
+		return nil
+	}
+	if prop, ok := s.Properties[name]; ok {
+		return prop
+	}
+	return s.AdditionalProperties
+}
+
+// Elem returns the schema of an array's elements, or s itself for anything
+// else.
+func (s *Schema) Elem() *Schema {
+	for s != nil && s.Type == "array" && s.Items != nil {
+		s = s.Items
+	}
+	return s
+}
+
+// Lookup follows a path of mapping keys, stepping through arrays on the
+// way, as paths from the resolver skip list positions.
+func (s *Schema) Lookup(path []string) *Schema {
+	for _, key := range path {
+		s = s.Elem().Property(key)
+		if s == nil {
+			return nil
+		}
+	}
+	return s
+}
+
+// SchemaKey identifies the schema of kind at apiVersion (group/version).
+func SchemaKey(apiVersion, kind string) string {
+	return apiVersion + "/" + kind
+}
+
+// crdSchemas returns the schemas a CustomResourceDefinition declares, per
+// SchemaKey: one per spec.versions[] entry, or the shared
+// spec.validation.openAPIV3Schema of apiextensions.k8s.io/v1beta1.
+func crdSchemas(root *yaml.Node) map[string]*Schema {
+	spec := getMapValue(root, "spec")
+	group := getMapValue(spec, "group")
+	kind := getMapValue(getMapValue(spec, "names"), "kind")
+	if group == nil || kind == nil || group.Value == "" || kind.Value == "" {
+		return nil
+	}
+
+	shared := parseSchema(getMapValue(getMapValue(spec, "validation"), "openAPIV3Schema"), 0)
+	schemas := make(map[string]*Schema)
+	add := func(version string, schema *Schema) {
+		key := SchemaKey(group.Value+"/"+version, kind.Value)
+		if _, ok := schemas[key]; version != "" && schema != nil && !ok {
+			schemas[key] = schema
+		}
+	}
+	for _, v := range asSequence(getMapValue(spec, "versions")) {
+		name := getMapValue(v, "name")
+		if name == nil {
+			continue
+		}
+		schema := parseSchema(getMapValue(getMapValue(v, "schema"), "openAPIV3Schema"), 0)
+		if schema == nil {
+			schema = shared
+		}
+		add(name.Value, schema)
+	}
+	if version := getMapValue(spec, "version"); version != nil {
+		add(version.Value, shared)
+	}
+	if len(schemas) == 0 {
+		return nil
+	}
+	return schemas
+}
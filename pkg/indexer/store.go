@@ -1,7 +1,15 @@
 package indexer
 
 import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"text/template"
+
+	"k8s-lsp/pkg/config"
 
 	"github.com/rs/zerolog/log"
 )
@@ -12,10 +20,18 @@ type Reference struct {
 	Key       string // Optional sub-key (e.g. ConfigMap data key)
 	Namespace string // Optional
 	Symbol    string // The symbol name (e.g. "k8s.resource.name")
+	UID       string // Optional, the target's metadata.uid (owner references)
 	Line      int
 	Col       int
 }
 
+// DataKey is a data, binaryData or stringData entry of a ConfigMap or Secret.
+type DataKey struct {
+	Key  string
+	Line int // 0-based line number of the key
+	Col  int // 0-based column number of the key
+}
+
 type K8sResource struct {
 	ApiVersion string
 	Kind       string
@@ -24,45 +40,717 @@ type K8sResource struct {
 	Labels     map[string]string
 	References []Reference
 	FilePath   string
-	Line       int // 0-based line number
-	Col        int // 0-based column number
+	Line       int  // 0-based line number
+	Col        int  // 0-based column number
+	Library    bool // Indexed from a read-only library root; never edited
+
+	// UID is metadata.uid, present in manifests exported from a cluster.
+	UID string
+
+	// GenerateName marks resources without a name, indexed under their
+	// metadata.generateName prefix.
+	GenerateName bool
+
+	// BuildImage is the image the resource declares as its build artifact
+	// through Settings.ImageBuildAnnotation; the position is of its value.
+	BuildImage     string
+	BuildImageLine int
+	BuildImageCol  int
+
+	DataKeys []DataKey // ConfigMap and Secret entries, in document order
+
+	// Fields are the values indexed through k8s.field symbol definitions,
+	// by field name.
+	Fields map[string]string
+
+	// ClusterIP and ExternalName are a Service's spec.clusterIP and
+	// spec.externalName, matched against pod hostAliases.
+	ClusterIP    string
+	ExternalName string
+
+	// Declared are the resources this one declares through aliasKind symbol
+	// definitions; each points back through DeclaredBy. They stand in for
+	// resources a controller creates, so real definitions take precedence.
+	// DeclaredBy is left out of JSON, which nests Declared instead; see
+	// IndexCache.
+	Declared   []*K8sResource
+	DeclaredBy *K8sResource `json:"-"`
 }
 
 type Store struct {
-	resources map[string]*K8sResource // Key: "Kind/Namespace/Name"
+	resources map[string]*K8sResource // Key: "Kind.group/Namespace/Name", or the key template
+	files     map[string][]string     // FilePath -> keys of resources defined in that file
+	uids      map[string]string       // metadata.uid -> key; checked against the resource on lookup
+	foldKinds bool
+	nsAliases map[string]string // alias namespace -> canonical namespace
+	keys      uint64            // bumped whenever a key is added or removed, or its duplicates change
 	mu        sync.RWMutex
+
+	// keyTemplate builds resource keys when set. Either way names maps
+	// each "Kind/Namespace/Name" to the keys of the resources carrying it,
+	// in the order they were added, so lookups by name still work.
+	keyTemplate     *template.Template
+	keyTemplateText string
+	names           map[string][]string
+
+	// duplicates holds, per key, the definitions from other files that the
+	// resource under the key shadows, by path. The resource under the key
+	// is the one from the first path, so the first duplicate takes over
+	// when its file goes away.
+	duplicates map[string][]*K8sResource
+
+	// shadowed holds, per key, the resources declared through aliasKind
+	// (a Certificate's Secret, say) that a definition under the key hides,
+	// by path. The first takes over once no definition is left.
+	shadowed map[string][]*K8sResource
+
+	// schemas holds the CRD schemas indexed from each file, per SchemaKey.
+	schemas map[string]map[string]*Schema
 }
 
 func NewStore() *Store {
 	return &Store{
-		resources: make(map[string]*K8sResource),
+		resources:  make(map[string]*K8sResource),
+		files:      make(map[string][]string),
+		uids:       make(map[string]string),
+		names:      make(map[string][]string),
+		duplicates: make(map[string][]*K8sResource),
+		shadowed:   make(map[string][]*K8sResource),
+		schemas:    make(map[string]map[string]*Schema),
 	}
 }
 
-// makeKey generates a unique key for the resource.
-// Format: Kind/Namespace/Name
-// If namespace is empty, it defaults to "default".
-func makeKey(kind, namespace, name string) string {
+// SetCaseInsensitiveKinds makes kind comparisons ignore case. Since that
+// changes how keys are built, resources already added are re-keyed.
+func (s *Store) SetCaseInsensitiveKinds(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.foldKinds == enabled {
+		return
+	}
+	s.foldKinds = enabled
+	s.rekey()
+}
+
+// SetNamespaceAliases makes aliased namespaces (alias -> canonical) address
+// the same resources. Like SetCaseInsensitiveKinds, it re-keys resources
+// already added.
+func (s *Store) SetNamespaceAliases(aliases map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if maps.Equal(s.nsAliases, aliases) {
+		return
+	}
+	s.nsAliases = aliases
+	s.rekey()
+}
+
+// SetKeyTemplate makes resources keyed by a text/template instead of
+// Kind/Namespace/Name, so resources sharing a name can be told apart; see
+// KeyVars for the variables it can use. An empty template restores the
+// default. Like SetCaseInsensitiveKinds, it re-keys resources already
+// added.
+func (s *Store) SetKeyTemplate(text string) error {
+	var tmpl *template.Template
+	if text != "" {
+		var err error
+		tmpl, err = template.New("key").Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid key template %q: %w", text, err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if text == s.keyTemplateText {
+		return nil
+	}
+	s.keyTemplate, s.keyTemplateText = tmpl, text
+	s.rekey()
+	return nil
+}
+
+// rekey adds every resource again under the key the current settings
+// give it; mu must be held. Each key's duplicates go first so that the
+// resource under it stays ahead of them, and the aliases it shadows last.
+func (s *Store) rekey() {
+	if len(s.resources) == 0 {
+		return
+	}
+	var all []*K8sResource
+	for _, key := range slices.Sorted(maps.Keys(s.resources)) {
+		all = append(all, s.duplicates[key]...)
+		all = append(all, s.resources[key])
+		all = append(all, s.shadowed[key]...)
+	}
+	s.resources = make(map[string]*K8sResource)
+	s.files = make(map[string][]string)
+	s.uids = make(map[string]string)
+	s.names = make(map[string][]string)
+	s.duplicates = make(map[string][]*K8sResource)
+	s.shadowed = make(map[string][]*K8sResource)
+	s.keys++
+	for _, res := range all {
+		s.add(res)
+	}
+}
+
+// KeyVars are the variables a key template is executed with:
+//
+//	.kind        the kind, lower-cased with case-insensitive kinds
+//	.group       the API group of the apiVersion, empty for the core group
+//	.namespace   the namespace, "default" when empty, aliases resolved
+//	.name        metadata.name
+//	.apiVersion  the apiVersion
+//	.labels      metadata.labels, e.g. {{ .labels.app }}
+//	.fields      values indexed by k8s.field definitions, e.g. {{ .fields.type }}
+//
+// A missing label or field renders as empty.
+func (s *Store) KeyVars(res *K8sResource) map[string]any {
+	kind, namespace := s.normalize(res.Kind, res.Namespace)
+	labels := res.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	fields := res.Fields
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	return map[string]any{
+		"kind":       kind,
+		"group":      config.APIGroup(res.ApiVersion),
+		"namespace":  namespace,
+		"name":       res.Name,
+		"apiVersion": res.ApiVersion,
+		"labels":     labels,
+		"fields":     fields,
+	}
+}
+
+// resourceKey is the key res is stored under: the key template's output,
+// or without one (or when it fails) makeKey with the kind qualified by its
+// API group, kubectl style (Certificate.cert-manager.io), so that kinds of
+// different groups sharing a name are kept apart.
+func (s *Store) resourceKey(res *K8sResource) string {
+	kind := res.Kind
+	if group := config.APIGroup(res.ApiVersion); group != "" {
+		kind += "." + group
+	}
+	groupKey := s.makeKey(kind, res.Namespace, res.Name)
+	if s.keyTemplate == nil {
+		return groupKey
+	}
+	var b strings.Builder
+	if err := s.keyTemplate.Execute(&b, s.KeyVars(res)); err != nil || b.Len() == 0 {
+		log.Warn().Err(err).Str("resource", groupKey).Msg("Failed to execute key template")
+		return groupKey
+	}
+	return b.String()
+}
+
+// lookup returns the resource named kind/namespace/name. Several may share
+// the name, from different API groups or keyed apart by the key template;
+// the one from the first path wins, or from the same file the first one
+// added.
+func (s *Store) lookup(kind, namespace, name string) *K8sResource {
+	nameKey := s.makeKey(kind, namespace, name)
+	var found *K8sResource
+	for _, key := range s.names[nameKey] {
+		if res, ok := s.resources[key]; ok && (found == nil || res.FilePath < found.FilePath) {
+			found = res
+		}
+	}
+	return found
+}
+
+// remove deletes the resource under key. A duplicate from another file
+// takes its place, the one from the first path, or failing that an alias
+// it shadowed.
+func (s *Store) remove(key string) {
+	res, ok := s.resources[key]
+	if !ok {
+		return
+	}
+	s.dropName(res, key)
+	if dups := s.duplicates[key]; len(dups) > 0 {
+		promoted := dups[0]
+		s.setDuplicates(key, dups[1:])
+		log.Debug().Str("key", key).Str("path", promoted.FilePath).Msg("Duplicate resource takes over")
+		s.resources[key] = promoted
+		s.addName(promoted, key)
+		s.addUID(promoted, key)
+		return
+	}
+	if aliases := s.shadowed[key]; len(aliases) > 0 {
+		restored := aliases[0]
+		s.setShadowed(key, aliases[1:])
+		log.Debug().Str("key", key).Str("path", restored.FilePath).Msg("Declared alias takes over")
+		s.resources[key] = restored
+		s.addName(restored, key)
+		s.addUID(restored, key)
+		s.keys++
+		return
+	}
+	delete(s.resources, key)
+	s.keys++
+}
+
+// addUID indexes the metadata.uid of res, now under key.
+func (s *Store) addUID(res *K8sResource, key string) {
+	if res.UID != "" {
+		s.uids[res.UID] = key
+	}
+}
+
+// removeFromFile drops path's definition of key, whether it is the
+// resource under the key or one of its duplicates.
+func (s *Store) removeFromFile(path, key string) {
+	s.removeDuplicate(key, path)
+	s.setShadowed(key, withoutFile(s.shadowed[key], path))
+	if res, ok := s.resources[key]; ok && res.FilePath == path {
+		log.Debug().Str("key", key).Str("path", path).Msg("Removing resource from store")
+		s.remove(key)
+	}
+}
+
+// removeDuplicate drops path's definition from the duplicates of key.
+func (s *Store) removeDuplicate(key, path string) {
+	s.setDuplicates(key, withoutFile(s.duplicates[key], path))
+}
+
+// setDuplicates replaces the duplicates of key. Every file involved shows
+// which definition is used, so a change bumps keys like a new key does.
+func (s *Store) setDuplicates(key string, dups []*K8sResource) {
+	if slices.Equal(s.duplicates[key], dups) {
+		return
+	}
+	s.keys++
+	if len(dups) == 0 {
+		delete(s.duplicates, key)
+	} else {
+		s.duplicates[key] = dups
+	}
+}
+
+// setShadowed replaces the aliases shadowed under key.
+func (s *Store) setShadowed(key string, aliases []*K8sResource) {
+	if len(aliases) == 0 {
+		delete(s.shadowed, key)
+	} else {
+		s.shadowed[key] = aliases
+	}
+}
+
+// sortedByPath sorts resources by file path, keeping the order of those
+// from the same file.
+func sortedByPath(resources []*K8sResource) []*K8sResource {
+	slices.SortStableFunc(resources, func(a, b *K8sResource) int {
+		return strings.Compare(a.FilePath, b.FilePath)
+	})
+	return resources
+}
+
+// withoutFile returns resources without those from path, leaving
+// resources as is.
+func withoutFile(resources []*K8sResource, path string) []*K8sResource {
+	return slices.DeleteFunc(slices.Clone(resources), func(res *K8sResource) bool {
+		return res.FilePath == path
+	})
+}
+
+// addName records key under res's name.
+func (s *Store) addName(res *K8sResource, key string) {
+	nameKey := s.makeKey(res.Kind, res.Namespace, res.Name)
+	if !containsString(s.names[nameKey], key) {
+		s.names[nameKey] = append(s.names[nameKey], key)
+	}
+}
+
+// dropName removes key from the keys carrying res's name.
+func (s *Store) dropName(res *K8sResource, key string) {
+	nameKey := s.makeKey(res.Kind, res.Namespace, res.Name)
+	if keys := slices.DeleteFunc(s.names[nameKey], func(k string) bool { return k == key }); len(keys) > 0 {
+		s.names[nameKey] = keys
+	} else {
+		delete(s.names, nameKey)
+	}
+}
+
+// normalize returns kind and namespace the way keys spell them.
+func (s *Store) normalize(kind, namespace string) (string, string) {
 	if namespace == "" {
 		namespace = "default"
 	}
+	if canonical, ok := s.nsAliases[namespace]; ok && canonical != "" {
+		namespace = canonical
+	}
+	if s.foldKinds {
+		kind = strings.ToLower(kind)
+	}
+	return kind, namespace
+}
+
+// makeKey generates the name key of a resource, which resources of
+// different API groups share.
+// Format: Kind/Namespace/Name
+// If namespace is empty, it defaults to "default". Aliased namespaces are
+// replaced by their canonical namespace.
+// With case-insensitive kinds the kind is lower-cased; the name never is.
+func (s *Store) makeKey(kind, namespace, name string) string {
+	kind, namespace = s.normalize(kind, namespace)
 	return kind + "/" + namespace + "/" + name
 }
 
+func (s *Store) sameKind(a, b string) bool {
+	if s.foldKinds {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
 func (s *Store) Add(res *K8sResource) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := makeKey(res.Kind, res.Namespace, res.Name)
+	s.add(res)
+}
+
+// ReplaceFile makes resources the complete set indexed from path. Keys the
+// file produced on a previous pass that are no longer present (e.g. after a
+// rename) are evicted.
+func (s *Store) ReplaceFile(path string, resources []*K8sResource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]bool, len(resources))
+	for _, res := range resources {
+		current[s.resourceKey(res)] = true
+	}
+	for _, key := range s.files[path] {
+		if !current[key] {
+			s.removeFromFile(path, key)
+		}
+	}
+	delete(s.files, path)
+
+	for _, res := range resources {
+		s.add(res)
+	}
+}
+
+func (s *Store) add(res *K8sResource) {
+	key := s.resourceKey(res)
 	log.Debug().Str("key", key).Msg("Adding resource to store")
+	prev, ok := s.resources[key]
+	if ok && prev.DeclaredBy == nil && res.DeclaredBy != nil {
+		log.Debug().Str("key", key).Msg("Keeping defined resource over declared alias")
+		s.shadow(key, res)
+		return
+	}
+	switch {
+	case !ok:
+		s.keys++
+	case prev.FilePath != res.FilePath && prev.DeclaredBy == nil && res.DeclaredBy == nil:
+		// Two files define the same resource. The one from the first path
+		// is used, whichever was indexed first, and the other kept as its
+		// duplicate.
+		dups := withoutFile(s.duplicates[key], res.FilePath)
+		if res.FilePath > prev.FilePath {
+			s.setDuplicates(key, sortedByPath(append(dups, res)))
+			if !containsString(s.files[res.FilePath], key) {
+				s.files[res.FilePath] = append(s.files[res.FilePath], key)
+			}
+			return
+		}
+		s.setDuplicates(key, sortedByPath(append(dups, prev)))
+	case prev.FilePath != res.FilePath && prev.DeclaredBy != nil:
+		// The alias comes back should res go away.
+		s.shadow(key, prev)
+	case prev.FilePath != res.FilePath:
+		s.forgetFileKey(prev.FilePath, key)
+	}
 	s.resources[key] = res
+	if ok {
+		// Re-added under the same key, the name may have changed too.
+		s.dropName(prev, key)
+	}
+	s.addName(res, key)
+	s.addUID(res, key)
+	if !containsString(s.files[res.FilePath], key) {
+		s.files[res.FilePath] = append(s.files[res.FilePath], key)
+	}
+}
+
+// shadow keeps the declared alias res under key, behind the resource there.
+func (s *Store) shadow(key string, res *K8sResource) {
+	s.setShadowed(key, sortedByPath(append(withoutFile(s.shadowed[key], res.FilePath), res)))
+	if !containsString(s.files[res.FilePath], key) {
+		s.files[res.FilePath] = append(s.files[res.FilePath], key)
+	}
+}
+
+// RemoveByFile removes every resource that was indexed from the given file.
+func (s *Store) RemoveByFile(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.files[path] {
+		s.removeFromFile(path, key)
+	}
+	delete(s.files, path)
+	delete(s.schemas, path)
+}
+
+// RemoveByPathPrefix removes every resource indexed from a file under dir,
+// e.g. when a workspace folder is closed. It returns the number of files
+// dropped.
+func (s *Store) RemoveByPathPrefix(dir string) int {
+	dir = filepath.Clean(dir)
+	prefix := dir + string(filepath.Separator)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for path, keys := range s.files {
+		if path != dir && !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		for _, key := range keys {
+			s.removeFromFile(path, key)
+		}
+		delete(s.files, path)
+		removed++
+	}
+	for path := range s.schemas {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			delete(s.schemas, path)
+		}
+	}
+	return removed
+}
+
+// Clear removes every resource, e.g. before the workspace is rescanned from
+// scratch.
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.resources) > 0 {
+		s.keys++
+	}
+	s.resources = make(map[string]*K8sResource)
+	s.files = make(map[string][]string)
+	s.uids = make(map[string]string)
+	s.names = make(map[string][]string)
+	s.duplicates = make(map[string][]*K8sResource)
+	s.shadowed = make(map[string][]*K8sResource)
+	s.schemas = make(map[string]map[string]*Schema)
+}
+
+// SetSchemas records the CRD schemas indexed from path, replacing the ones
+// it had. With complete false, as for a file that couldn't be read to the
+// end, the previous ones are kept unless replaced.
+func (s *Store) SetSchemas(path string, schemas map[string]*Schema, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !complete {
+		if len(schemas) == 0 {
+			return
+		}
+		merged := maps.Clone(s.schemas[path])
+		if merged == nil {
+			merged = make(map[string]*Schema)
+		}
+		maps.Copy(merged, schemas)
+		schemas = merged
+	}
+	if len(schemas) == 0 {
+		delete(s.schemas, path)
+		return
+	}
+	s.schemas[path] = schemas
+}
+
+// Schema returns the CRD schema of kind at apiVersion, nil when no indexed
+// CRD declares one. When several files do, the first path wins.
+func (s *Store) Schema(apiVersion, kind string) *Schema {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key := SchemaKey(apiVersion, kind)
+	var found *Schema
+	foundPath := ""
+	for path, schemas := range s.schemas {
+		if schema, ok := schemas[key]; ok && (found == nil || path < foundPath) {
+			found, foundPath = schema, path
+		}
+	}
+	return found
+}
+
+// Snapshot returns a copy of every indexed resource, taken under one read
+// lock so it is consistent while indexing continues. Labels, References,
+// DataKeys and Fields are copied too; Declared and DeclaredBy still point
+// into the Store.
+func (s *Store) Snapshot() []K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := make([]K8sResource, 0, len(s.resources))
+	for _, res := range s.resources {
+		c := *res
+		c.Labels = maps.Clone(res.Labels)
+		c.References = slices.Clone(res.References)
+		c.DataKeys = slices.Clone(res.DataKeys)
+		c.Fields = maps.Clone(res.Fields)
+		snapshot = append(snapshot, c)
+	}
+	return snapshot
+}
+
+// Len returns the number of indexed resources.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.resources)
+}
+
+func (s *Store) forgetFileKey(path, key string) {
+	keys := s.files[path]
+	for i, k := range keys {
+		if k == key {
+			keys = append(keys[:i], keys[i+1:]...)
+			break
+		}
+	}
+	if len(keys) == 0 {
+		delete(s.files, path)
+	} else {
+		s.files[path] = keys
+	}
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+// KeysVersion changes whenever a resource key is added or removed, but not
+// when an existing resource is re-indexed in place. Callers compare it to
+// tell whether lookups by name may now resolve differently.
+func (s *Store) KeysVersion() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys
 }
 
 func (s *Store) Get(kind, namespace, name string) *K8sResource {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	key := makeKey(kind, namespace, name)
-	log.Debug().Str("key", key).Msg("Getting resource from store")
-	return s.resources[key]
+	log.Debug().Str("key", s.makeKey(kind, namespace, name)).Msg("Getting resource from store")
+	return s.lookup(kind, namespace, name)
+}
+
+// Duplicates returns the other definitions of res: resources stored under
+// the same key from files other than res's, by path. Resources declared
+// through aliasKind are never duplicates.
+func (s *Store) Duplicates(res *K8sResource) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key := s.resourceKey(res)
+	var dups []*K8sResource
+	for _, dup := range s.duplicates[key] {
+		if dup.FilePath != res.FilePath {
+			dups = append(dups, dup)
+		}
+	}
+	if active, ok := s.resources[key]; ok && active.FilePath != res.FilePath && active.DeclaredBy == nil {
+		dups = append([]*K8sResource{active}, dups...)
+	}
+	return dups
+}
+
+// GetByUID returns the resource whose metadata.uid is uid. Removed
+// resources are not pruned from the UID index, so the match is confirmed
+// against the resource currently under the key.
+func (s *Store) GetByUID(uid string) *K8sResource {
+	if uid == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if res, ok := s.resources[s.uids[uid]]; ok && res.UID == uid {
+		return res
+	}
+	return nil
+}
+
+// Lookup is Get with the fallback navigation uses: a miss in namespace is
+// retried in "default". It also returns the namespace that satisfied the
+// lookup, so callers can tell a fallback-only resolution apart.
+func (s *Store) Lookup(kind, namespace, name string) (*K8sResource, string) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	if res := s.Get(kind, namespace, name); res != nil {
+		return res, namespace
+	}
+	if namespace != "default" {
+		if res := s.Get(kind, "default", name); res != nil {
+			return res, "default"
+		}
+	}
+	return nil, ""
+}
+
+// FindByFile returns the resources indexed from the given file, including
+// those shadowed by a duplicate from another file.
+func (s *Store) FindByFile(path string) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*K8sResource
+	for _, key := range s.files[path] {
+		if res, ok := s.resources[key]; ok && res.FilePath == path {
+			results = append(results, res)
+			continue
+		}
+		for _, dup := range s.duplicates[key] {
+			if dup.FilePath == path {
+				results = append(results, dup)
+				break
+			}
+		}
+	}
+	return results
+}
+
+// FindByBuildImage returns the resources declaring image as their build
+// artifact. Tags and digests are ignored on both sides.
+func (s *Store) FindByBuildImage(image string) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	repo := ImageRepository(image)
+	var results []*K8sResource
+	for _, res := range s.resources {
+		if res.BuildImage != "" && ImageRepository(res.BuildImage) == repo {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// FindServicesByAddress returns the Services whose clusterIP or
+// externalName is address. Hostnames compare case-insensitively.
+func (s *Store) FindServicesByAddress(address string) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*K8sResource
+	for _, res := range s.resources {
+		if !s.sameKind(res.Kind, "Service") || address == "" {
+			continue
+		}
+		if res.ClusterIP == address || strings.EqualFold(res.ExternalName, strings.TrimSuffix(address, ".")) {
+			results = append(results, res)
+		}
+	}
+	return results
 }
 
 func (s *Store) FindByLabel(key, value string) []*K8sResource {
@@ -77,13 +765,47 @@ func (s *Store) FindByLabel(key, value string) []*K8sResource {
 	return results
 }
 
+// ListNamespaces returns the names of the indexed Namespace resources
+// together with every namespace a resource declares, sorted.
+func (s *Store) ListNamespaces() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool)
+	for _, res := range s.resources {
+		if s.sameKind(res.Kind, "Namespace") && res.Name != "" {
+			seen[res.Name] = true
+		}
+		if res.Namespace != "" {
+			seen[res.Namespace] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// AllLabels returns every indexed label key with its values, each counted
+// by the number of resources carrying it.
+func (s *Store) AllLabels() map[string]map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	labels := make(map[string]map[string]int)
+	for _, res := range s.resources {
+		for key, value := range res.Labels {
+			if labels[key] == nil {
+				labels[key] = make(map[string]int)
+			}
+			labels[key][value]++
+		}
+	}
+	return labels
+}
+
 func (s *Store) FindReferences(kind, name string) []*K8sResource {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var results []*K8sResource
 	for _, res := range s.resources {
 		for _, ref := range res.References {
-			if ref.Kind == kind && ref.Name == name {
+			if s.sameKind(ref.Kind, kind) && ref.Name == name {
 				results = append(results, res)
 				// Break inner loop to avoid adding same resource multiple times if it references same target multiple times
 				break
@@ -93,6 +815,51 @@ func (s *Store) FindReferences(kind, name string) []*K8sResource {
 	return results
 }
 
+// FindGenerated returns the generateName resource of kind in namespace whose
+// prefix starts name, preferring the longest prefix. Exact names should be
+// looked up with Get first.
+func (s *Store) FindGenerated(kind, namespace, name string) *K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scope := s.makeKey(kind, namespace, "")
+	var best *K8sResource
+	for _, res := range s.resources {
+		if !res.GenerateName || !strings.HasPrefix(name, res.Name) || s.makeKey(res.Kind, res.Namespace, "") != scope {
+			continue
+		}
+		if best == nil || len(res.Name) > len(best.Name) {
+			best = res
+		}
+	}
+	return best
+}
+
+// OutgoingReferences returns the references made by the resource
+// kind/namespace/name, or nil when it isn't indexed.
+func (s *Store) OutgoingReferences(kind, namespace, name string) []Reference {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := s.lookup(kind, namespace, name)
+	if res == nil {
+		return nil
+	}
+	return append([]Reference(nil), res.References...)
+}
+
+// FindByField returns the resources of kind whose indexed field equals
+// value.
+func (s *Store) FindByField(kind, field, value string) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var results []*K8sResource
+	for _, res := range s.resources {
+		if v, ok := res.Fields[field]; ok && v == value && s.sameKind(res.Kind, kind) {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
 func (s *Store) FindLabelReferences(value string) []*K8sResource {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -108,12 +875,50 @@ func (s *Store) FindLabelReferences(value string) []*K8sResource {
 	return results
 }
 
+// ListByKind returns every resource of kind, across all namespaces, in no
+// particular order. Completion and the validator build on it.
 func (s *Store) ListByKind(kind string) []*K8sResource {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var results []*K8sResource
 	for _, res := range s.resources {
-		if res.Kind == kind {
+		if s.sameKind(res.Kind, kind) {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// SearchDataKeys returns the resources with a data key matching query, either
+// by key or as "name/key".
+func (s *Store) SearchDataKeys(query string) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q := strings.ToLower(query)
+	var results []*K8sResource
+	for _, res := range s.resources {
+		for _, dk := range res.DataKeys {
+			if strings.Contains(strings.ToLower(res.Name+"/"+dk.Key), q) {
+				results = append(results, res)
+				break
+			}
+		}
+	}
+	return results
+}
+
+// Search returns all resources whose name, "Kind/Name" or "Namespace/Name"
+// contains the query, ignoring case. An empty query matches every resource.
+func (s *Store) Search(query string) []*K8sResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q := strings.ToLower(query)
+	var results []*K8sResource
+	for _, res := range s.resources {
+		name := strings.ToLower(res.Name)
+		qualified := strings.ToLower(res.Kind + "/" + res.Name)
+		namespaced := strings.ToLower(res.Namespace + "/" + res.Name)
+		if strings.Contains(name, q) || strings.Contains(qualified, q) || strings.Contains(namespaced, q) {
 			results = append(results, res)
 		}
 	}
@@ -0,0 +1,536 @@
+package indexer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestStoreSearch(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "Service", Name: "redis-master", Namespace: "default"})
+	store.Add(&K8sResource{Kind: "Deployment", Name: "Redis-Worker", Namespace: "prod"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app-config", Namespace: "default"})
+
+	if got := store.Search("redis"); len(got) != 2 {
+		t.Fatalf("Expected 2 results for 'redis', got %d", len(got))
+	}
+	if got := store.Search("REDIS-M"); len(got) != 1 || got[0].Name != "redis-master" {
+		t.Fatalf("Expected case-insensitive match on redis-master, got %v", got)
+	}
+	if got := store.Search("configmap/app"); len(got) != 1 || got[0].Kind != "ConfigMap" {
+		t.Fatalf("Expected Kind/Name match on app-config, got %v", got)
+	}
+	if got := store.Search(""); len(got) != 3 {
+		t.Fatalf("Expected empty query to match all 3 resources, got %d", len(got))
+	}
+	if got := store.Search("missing"); len(got) != 0 {
+		t.Fatalf("Expected no results, got %d", len(got))
+	}
+}
+
+func TestStoreRemoveByFile(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "Service", Name: "web", FilePath: "/repo/app.yaml"})
+	store.Add(&K8sResource{Kind: "Deployment", Name: "web", FilePath: "/repo/app.yaml"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "shared", FilePath: "/repo/other.yaml"})
+
+	store.RemoveByFile("/repo/app.yaml")
+
+	if store.Get("Service", "default", "web") != nil || store.Get("Deployment", "default", "web") != nil {
+		t.Fatal("Expected resources from the deleted file to be removed")
+	}
+	if store.Get("ConfigMap", "default", "shared") == nil {
+		t.Fatal("Expected resources from other files to remain")
+	}
+}
+
+func TestStoreRemoveByFile_KeepsResourceMovedToAnotherFile(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/repo/old.yaml"})
+	// The same resource is now defined in a different file.
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/repo/new.yaml"})
+
+	store.RemoveByFile("/repo/old.yaml")
+
+	res := store.Get("ConfigMap", "default", "app")
+	if res == nil || res.FilePath != "/repo/new.yaml" {
+		t.Fatalf("Expected ConfigMap from new.yaml to survive, got %v", res)
+	}
+}
+
+func TestStoreLookupReportsNamespace(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "shared", FilePath: "/a.yaml"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "local", Namespace: "shop", FilePath: "/b.yaml"})
+
+	if res, ns := store.Lookup("ConfigMap", "shop", "local"); res == nil || ns != "shop" {
+		t.Errorf("Expected local in shop, got %v in %q", res, ns)
+	}
+	if res, ns := store.Lookup("ConfigMap", "shop", "shared"); res == nil || ns != "default" {
+		t.Errorf("Expected shared via the default fallback, got %v in %q", res, ns)
+	}
+	if res, ns := store.Lookup("ConfigMap", "", "shared"); res == nil || ns != "default" {
+		t.Errorf("Expected shared in default, got %v in %q", res, ns)
+	}
+	if res, _ := store.Lookup("ConfigMap", "shop", "missing"); res != nil {
+		t.Errorf("Expected no resource, got %v", res)
+	}
+}
+
+func TestStoreFindServicesByAddress(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "Service", Name: "db", ClusterIP: "10.96.0.42", FilePath: "/a.yaml"})
+	store.Add(&K8sResource{Kind: "Service", Name: "billing", ExternalName: "billing.example.com", FilePath: "/a.yaml"})
+
+	if found := store.FindServicesByAddress("10.96.0.42"); len(found) != 1 || found[0].Name != "db" {
+		t.Errorf("Expected db by clusterIP, got %v", found)
+	}
+	if found := store.FindServicesByAddress("Billing.Example.com."); len(found) != 1 || found[0].Name != "billing" {
+		t.Errorf("Expected billing by externalName, got %v", found)
+	}
+	if found := store.FindServicesByAddress(""); len(found) != 0 {
+		t.Errorf("Expected no match for an empty address, got %v", found)
+	}
+}
+
+func TestStoreKeysVersion(t *testing.T) {
+	store := NewStore()
+	v0 := store.KeysVersion()
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/a.yaml"})
+	v1 := store.KeysVersion()
+	if v1 == v0 {
+		t.Fatal("Expected adding a resource to change the version")
+	}
+
+	// Re-indexing the same resource in place keeps the version.
+	store.ReplaceFile("/a.yaml", []*K8sResource{{Kind: "ConfigMap", Name: "app", FilePath: "/a.yaml", Labels: map[string]string{"a": "b"}}})
+	if store.KeysVersion() != v1 {
+		t.Error("Expected an in-place update to keep the version")
+	}
+
+	// Renaming evicts one key and adds another.
+	store.ReplaceFile("/a.yaml", []*K8sResource{{Kind: "ConfigMap", Name: "web", FilePath: "/a.yaml"}})
+	v2 := store.KeysVersion()
+	if v2 == v1 {
+		t.Error("Expected a rename to change the version")
+	}
+	store.RemoveByFile("/a.yaml")
+	if store.KeysVersion() == v2 {
+		t.Error("Expected removing a file to change the version")
+	}
+
+	// A second definition of a key, and its promotion once the first one
+	// goes, change which one the other file sees as used.
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/b.yaml"})
+	v3 := store.KeysVersion()
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/c.yaml"})
+	v4 := store.KeysVersion()
+	if v4 == v3 {
+		t.Error("Expected a duplicate definition to change the version")
+	}
+	store.RemoveByFile("/c.yaml")
+	if store.KeysVersion() == v4 {
+		t.Error("Expected promoting a duplicate to change the version")
+	}
+}
+
+func TestStoreRemoveByPathPrefix(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/ws/app/cm.yaml"})
+	store.Add(&K8sResource{Kind: "Secret", Name: "app", FilePath: "/ws/app/nested/secret.yaml"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "infra", FilePath: "/ws/infra/cm.yaml"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "sibling", FilePath: "/ws/app-old/cm.yaml"})
+
+	if removed := store.RemoveByPathPrefix("/ws/app/"); removed != 2 {
+		t.Errorf("Expected 2 files removed, got %d", removed)
+	}
+	if store.Get("ConfigMap", "", "app") != nil || store.Get("Secret", "", "app") != nil {
+		t.Error("Expected resources under /ws/app to be removed")
+	}
+	if store.Get("ConfigMap", "", "infra") == nil || store.Get("ConfigMap", "", "sibling") == nil {
+		t.Error("Expected resources outside /ws/app to be kept")
+	}
+}
+
+func TestStoreClear(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/ws/app.yaml"})
+	before := store.KeysVersion()
+
+	store.Clear()
+	if store.Len() != 0 || len(store.FindByFile("/ws/app.yaml")) != 0 {
+		t.Error("Expected an empty store")
+	}
+	if store.KeysVersion() == before {
+		t.Error("Expected clearing to change the keys version")
+	}
+}
+
+func TestStoreFindByField(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "Cluster", Name: "main", Fields: map[string]string{"clusterName": "prod"}})
+	store.Add(&K8sResource{Kind: "Cluster", Name: "test", Fields: map[string]string{"clusterName": "staging"}})
+	store.Add(&K8sResource{Kind: "Backup", Name: "nightly", Fields: map[string]string{"clusterName": "prod"}})
+
+	found := store.FindByField("Cluster", "clusterName", "prod")
+	if len(found) != 1 || found[0].Name != "main" {
+		t.Errorf("Expected the prod Cluster only, got %v", found)
+	}
+	if found := store.FindByField("Cluster", "region", "prod"); len(found) != 0 {
+		t.Errorf("Expected no match on another field, got %v", found)
+	}
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{
+		Kind:       "Deployment",
+		Name:       "app",
+		Labels:     map[string]string{"app": "web"},
+		References: []Reference{{Kind: "ConfigMap", Name: "app-config"}},
+	})
+
+	snapshot := store.Snapshot()
+	if len(snapshot) != 1 || snapshot[0].Name != "app" {
+		t.Fatalf("Expected the Deployment, got %v", snapshot)
+	}
+	snapshot[0].Labels["app"] = "changed"
+	snapshot[0].References[0].Name = "changed"
+
+	res := store.Get("Deployment", "", "app")
+	if res.Labels["app"] != "web" || res.References[0].Name != "app-config" {
+		t.Errorf("Expected the snapshot to be a copy, got %+v", res)
+	}
+}
+
+func TestStoreAllLabels(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "Pod", Name: "a", Labels: map[string]string{"app": "foo"}})
+	store.Add(&K8sResource{Kind: "Pod", Name: "b", Labels: map[string]string{"app": "foo"}})
+	store.Add(&K8sResource{Kind: "Pod", Name: "c", Labels: map[string]string{"app": "bar"}})
+
+	labels := store.AllLabels()
+	if labels["app"]["foo"] != 2 || labels["app"]["bar"] != 1 || len(labels) != 1 {
+		t.Errorf("Expected app=foo twice and app=bar once, got %v", labels)
+	}
+}
+
+func TestStoreListNamespaces(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "Namespace", Name: "prod"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "prod"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "staging"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "unscoped"})
+
+	if got := store.ListNamespaces(); !reflect.DeepEqual(got, []string{"prod", "staging"}) {
+		t.Errorf("Expected prod and staging, got %v", got)
+	}
+}
+
+func TestStoreListByKind(t *testing.T) {
+	store := NewStore()
+	if got := store.ListByKind("ConfigMap"); len(got) != 0 {
+		t.Errorf("Expected no ConfigMaps in an empty store, got %v", got)
+	}
+
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "prod"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "staging"})
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "shared"})
+	store.Add(&K8sResource{Kind: "Secret", Name: "app", Namespace: "prod"})
+
+	var got []string
+	for _, res := range store.ListByKind("ConfigMap") {
+		got = append(got, res.Namespace+"/"+res.Name)
+	}
+	sort.Strings(got)
+	if want := []string{"/shared", "prod/app", "staging/app"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := store.ListByKind("Deployment"); len(got) != 0 {
+		t.Errorf("Expected no Deployments, got %v", got)
+	}
+
+	store.SetCaseInsensitiveKinds(true)
+	if got := store.ListByKind("configmap"); len(got) != 3 {
+		t.Errorf("Expected case-insensitive kinds to match, got %v", got)
+	}
+}
+
+func TestStoreGetByUID(t *testing.T) {
+	store := NewStore()
+	store.Add(&K8sResource{Kind: "ReplicaSet", Name: "web", Namespace: "prod", UID: "abc", FilePath: "/tmp/rs.yaml"})
+	store.Add(&K8sResource{Kind: "ReplicaSet", Name: "api", Namespace: "prod", FilePath: "/tmp/rs.yaml"})
+
+	if res := store.GetByUID("abc"); res == nil || res.Name != "web" {
+		t.Fatalf("Expected web by uid, got %v", res)
+	}
+	if res := store.GetByUID(""); res != nil {
+		t.Errorf("Expected no match for an empty uid, got %v", res)
+	}
+
+	// Re-indexed without the uid, and then removed, it no longer matches.
+	store.Add(&K8sResource{Kind: "ReplicaSet", Name: "web", Namespace: "prod", FilePath: "/tmp/rs.yaml"})
+	if res := store.GetByUID("abc"); res != nil {
+		t.Errorf("Expected the uid to go with the re-indexed resource, got %v", res)
+	}
+	store.Add(&K8sResource{Kind: "ReplicaSet", Name: "web", Namespace: "prod", UID: "abc", FilePath: "/tmp/rs.yaml"})
+	store.RemoveByFile("/tmp/rs.yaml")
+	if res := store.GetByUID("abc"); res != nil {
+		t.Errorf("Expected no match after removal, got %v", res)
+	}
+
+	// A duplicate taking over is found by its own uid.
+	store.Add(&K8sResource{Kind: "ReplicaSet", Name: "web", Namespace: "prod", UID: "abc", FilePath: "/tmp/a.yaml"})
+	store.Add(&K8sResource{Kind: "ReplicaSet", Name: "web", Namespace: "prod", UID: "def", FilePath: "/tmp/b.yaml"})
+	store.RemoveByFile("/tmp/a.yaml")
+	if res := store.GetByUID("def"); res == nil || res.FilePath != "/tmp/b.yaml" {
+		t.Errorf("Expected the promoted duplicate by its uid, got %v", res)
+	}
+}
+
+func TestStoreKeyTemplate(t *testing.T) {
+	store := NewStore()
+	if err := store.SetKeyTemplate("{{ .kind }}/{{ .namespace }}/{{ .name }}{{ with .fields.type }}#{{ . }}{{ end }}"); err != nil {
+		t.Fatal(err)
+	}
+	tls := &K8sResource{Kind: "Secret", Name: "web", FilePath: "/repo/a-tls.yaml", Fields: map[string]string{"type": "kubernetes.io/tls"}}
+	opaque := &K8sResource{Kind: "Secret", Name: "web", FilePath: "/repo/opaque.yaml", Fields: map[string]string{"type": "Opaque"}}
+	store.Add(opaque)
+	store.Add(tls)
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "web", FilePath: "/repo/a-tls.yaml"})
+
+	if got := store.ListByKind("Secret"); len(got) != 2 {
+		t.Fatalf("Expected Secrets of different types to be kept apart, got %d", len(got))
+	}
+	if got := store.Get("Secret", "", "web"); got != tls {
+		t.Errorf("Expected the Secret named web from the first path, got %+v", got)
+	}
+	if got := store.Get("ConfigMap", "default", "web"); got == nil {
+		t.Error("Expected a resource without the field to be keyed without it")
+	}
+
+	store.RemoveByFile("/repo/a-tls.yaml")
+	if got := store.Get("Secret", "default", "web"); got != opaque {
+		t.Errorf("Expected the remaining Secret after removing a-tls.yaml, got %+v", got)
+	}
+
+	// Changing the type re-keys the Secret, evicting the old key.
+	store.ReplaceFile("/repo/opaque.yaml", []*K8sResource{{Kind: "Secret", Name: "web", FilePath: "/repo/opaque.yaml", Fields: map[string]string{"type": "kubernetes.io/dockerconfigjson"}}})
+	if store.Len() != 1 {
+		t.Fatalf("Expected the stale key to be evicted, got %d resources", store.Len())
+	}
+	if got := store.Get("Secret", "default", "web"); got == nil || got.Fields["type"] != "kubernetes.io/dockerconfigjson" {
+		t.Errorf("Expected the re-keyed Secret, got %+v", got)
+	}
+}
+
+func TestStoreKeyTemplateDefault(t *testing.T) {
+	store := NewStore()
+	store.SetCaseInsensitiveKinds(true)
+	store.SetNamespaceAliases(map[string]string{"prod-eu": "prod"})
+	if err := store.SetKeyTemplate("{{ .kind }}{{ with .group }}.{{ . }}{{ end }}/{{ .namespace }}/{{ .name }}"); err != nil {
+		t.Fatal(err)
+	}
+	store.Add(&K8sResource{ApiVersion: "v1", Kind: "Service", Name: "api", Namespace: "prod-eu", FilePath: "/repo/a.yaml"})
+	store.Add(&K8sResource{ApiVersion: "v1", Kind: "service", Name: "api", Namespace: "prod", FilePath: "/repo/b.yaml"})
+
+	if store.Len() != 1 {
+		t.Fatalf("Expected the shipped template to key like the default, got %d resources", store.Len())
+	}
+	if got := store.Get("SERVICE", "prod-eu", "api"); got == nil || got.FilePath != "/repo/a.yaml" {
+		t.Errorf("Expected the definition from the first path, got %+v", got)
+	}
+	if got := store.KeyVars(&K8sResource{Kind: "Service", Namespace: "prod-eu"}); got["kind"] != "service" || got["namespace"] != "prod" {
+		t.Errorf("Expected normalized key variables, got %v", got)
+	}
+}
+
+func TestStoreRekeysOnSettingChange(t *testing.T) {
+	store := NewStore()
+	first := &K8sResource{Kind: "Service", Name: "api", Namespace: "prod-eu", FilePath: "/repo/a.yaml", Labels: map[string]string{"tier": "web"}}
+	second := &K8sResource{Kind: "Service", Name: "api", Namespace: "prod", FilePath: "/repo/b.yaml", Labels: map[string]string{"tier": "edge"}}
+	store.Add(first)
+	store.Add(second)
+	version := store.KeysVersion()
+
+	store.SetCaseInsensitiveKinds(true)
+	if got := store.Get("SERVICE", "prod", "api"); got != second {
+		t.Errorf("Expected case-insensitive kinds to apply to indexed resources, got %+v", got)
+	}
+	store.SetNamespaceAliases(map[string]string{"prod-eu": "prod"})
+	if store.Len() != 1 || store.Get("Service", "prod-eu", "api") != first {
+		t.Fatalf("Expected the aliased namespaces to share a key, got %d resources", store.Len())
+	}
+	if dups := store.Duplicates(first); len(dups) != 1 || dups[0] != second {
+		t.Errorf("Expected the other definition kept as a duplicate, got %v", dups)
+	}
+
+	if err := store.SetKeyTemplate("{{ .kind }}/{{ .namespace }}/{{ .name }}#{{ .labels.tier }}"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 2 || len(store.Duplicates(second)) != 0 {
+		t.Errorf("Expected the template to key the tiers apart, got %d resources", store.Len())
+	}
+	if store.KeysVersion() == version {
+		t.Error("Expected re-keying to bump the keys version")
+	}
+	store.RemoveByFile("/repo/a.yaml")
+	if store.Len() != 1 || store.Get("service", "prod", "api") != second {
+		t.Errorf("Expected the re-keyed files to be tracked, got %d resources", store.Len())
+	}
+}
+
+func TestStoreKeepsAPIGroupsApart(t *testing.T) {
+	for _, template := range []string{"", "{{ .kind }}{{ with .group }}.{{ . }}{{ end }}/{{ .namespace }}/{{ .name }}"} {
+		store := NewStore()
+		if err := store.SetKeyTemplate(template); err != nil {
+			t.Fatal(err)
+		}
+		a := &K8sResource{ApiVersion: "a.example.com/v1", Kind: "Database", Name: "orders", FilePath: "/repo/a.yaml"}
+		b := &K8sResource{ApiVersion: "b.example.com/v1alpha1", Kind: "Database", Name: "orders", FilePath: "/repo/b.yaml"}
+		store.Add(b)
+		store.Add(a)
+
+		if store.Len() != 2 || len(store.Duplicates(a)) != 0 || len(store.Duplicates(b)) != 0 {
+			t.Errorf("%q: expected two resources that don't duplicate each other, got %d", template, store.Len())
+		}
+		if got := store.Get("Database", "default", "orders"); got != a {
+			t.Errorf("%q: expected a lookup by name to find the first path, got %+v", template, got)
+		}
+		// Another version of the same group is the same resource.
+		store.Add(&K8sResource{ApiVersion: "a.example.com/v2", Kind: "Database", Name: "orders", FilePath: "/repo/c.yaml"})
+		if store.Len() != 2 || len(store.Duplicates(a)) != 1 {
+			t.Errorf("%q: expected a.example.com/v2 to duplicate a.example.com/v1, got %d", template, store.Len())
+		}
+		store.RemoveByFile("/repo/a.yaml")
+		store.RemoveByFile("/repo/c.yaml")
+		if got := store.Get("Database", "default", "orders"); got != b {
+			t.Errorf("%q: expected the other group's Database to remain, got %+v", template, got)
+		}
+	}
+}
+
+func TestStoreKeyTemplateInvalid(t *testing.T) {
+	store := NewStore()
+	if err := store.SetKeyTemplate("{{ .kind "); err == nil {
+		t.Fatal("Expected an unterminated template to be rejected")
+	}
+	store.Add(&K8sResource{Kind: "Service", Name: "web"})
+	if store.Get("Service", "default", "web") == nil {
+		t.Error("Expected the default keys after a rejected template")
+	}
+}
+
+func TestStoreDuplicates(t *testing.T) {
+	store := NewStore()
+	first := &K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/repo/a.yaml"}
+	second := &K8sResource{Kind: "ConfigMap", Name: "app", FilePath: "/repo/b.yaml"}
+	store.Add(first)
+	store.Add(second)
+	store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "prod", FilePath: "/repo/c.yaml"})
+
+	if got := store.Duplicates(first); len(got) != 1 || got[0] != second {
+		t.Errorf("Expected b.yaml as the duplicate of a.yaml, got %v", got)
+	}
+	if got := store.Duplicates(second); len(got) != 1 || got[0] != first {
+		t.Errorf("Expected a.yaml as the duplicate of b.yaml, got %v", got)
+	}
+	if got := store.FindByFile("/repo/a.yaml"); len(got) != 1 || got[0] != first {
+		t.Errorf("Expected the shadowed definition from its own file, got %v", got)
+	}
+
+	// The shadowed definition takes over once the other file goes.
+	version := store.KeysVersion()
+	store.RemoveByFile("/repo/b.yaml")
+	if got := store.Get("ConfigMap", "default", "app"); got != first {
+		t.Errorf("Expected a.yaml's ConfigMap to take over, got %v", got)
+	}
+	if store.KeysVersion() == version {
+		t.Error("Expected the promotion to change the version, so a.yaml's diagnostics update")
+	}
+	if got := store.Duplicates(first); len(got) != 0 {
+		t.Errorf("Expected no duplicates left, got %v", got)
+	}
+
+	// Renaming in one file ends the duplication too.
+	store.Add(second)
+	store.ReplaceFile("/repo/a.yaml", []*K8sResource{{Kind: "ConfigMap", Name: "renamed", FilePath: "/repo/a.yaml"}})
+	if got := store.Duplicates(second); len(got) != 0 {
+		t.Errorf("Expected the rename to end the duplication, got %v", got)
+	}
+	if got := store.Get("ConfigMap", "default", "app"); got != second {
+		t.Errorf("Expected b.yaml's ConfigMap, got %v", got)
+	}
+}
+
+func TestStoreDuplicatesIndependentOfOrder(t *testing.T) {
+	for _, order := range [][]string{{"/repo/a.yaml", "/repo/b.yaml", "/repo/c.yaml"}, {"/repo/c.yaml", "/repo/a.yaml", "/repo/b.yaml"}, {"/repo/b.yaml", "/repo/c.yaml", "/repo/a.yaml"}} {
+		store := NewStore()
+		if err := store.SetKeyTemplate("{{ .kind }}/{{ .namespace }}/{{ .name }}#{{ .labels.tier }}"); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range order {
+			store.Add(&K8sResource{Kind: "ConfigMap", Name: "app", FilePath: path})
+			store.Add(&K8sResource{Kind: "Secret", Name: "app", FilePath: path, Labels: map[string]string{"tier": path}})
+		}
+		if got := store.Get("ConfigMap", "default", "app"); got == nil || got.FilePath != "/repo/a.yaml" {
+			t.Errorf("%v: expected the ConfigMap from a.yaml, got %+v", order, got)
+		}
+		if got := store.Get("Secret", "default", "app"); got == nil || got.FilePath != "/repo/a.yaml" {
+			t.Errorf("%v: expected the Secret from a.yaml, got %+v", order, got)
+		}
+		store.RemoveByFile("/repo/a.yaml")
+		if got := store.Get("ConfigMap", "default", "app"); got == nil || got.FilePath != "/repo/b.yaml" {
+			t.Errorf("%v: expected b.yaml's ConfigMap to take over, got %+v", order, got)
+		}
+	}
+}
+
+func TestStoreDuplicatesIgnoreDeclaredAliases(t *testing.T) {
+	store := NewStore()
+	cert := &K8sResource{Kind: "Certificate", Name: "web", FilePath: "/repo/cert.yaml"}
+	alias := &K8sResource{Kind: "Secret", Name: "web-tls", FilePath: "/repo/cert.yaml", DeclaredBy: cert}
+	secret := &K8sResource{Kind: "Secret", Name: "web-tls", FilePath: "/repo/secret.yaml"}
+	store.Add(alias)
+	store.Add(secret)
+	if got := store.Duplicates(secret); len(got) != 0 {
+		t.Errorf("Expected a declared alias not to count as a duplicate, got %v", got)
+	}
+}
+
+func TestStoreRestoresShadowedAlias(t *testing.T) {
+	for _, aliasFirst := range []bool{true, false} {
+		store := NewStore()
+		cert := &K8sResource{Kind: "Certificate", Name: "web", FilePath: "/repo/cert.yaml"}
+		alias := &K8sResource{Kind: "Secret", Name: "web-tls", FilePath: "/repo/cert.yaml", DeclaredBy: cert}
+		secret := &K8sResource{Kind: "Secret", Name: "web-tls", FilePath: "/repo/secret.yaml"}
+		if aliasFirst {
+			store.ReplaceFile(cert.FilePath, []*K8sResource{cert, alias})
+			store.ReplaceFile(secret.FilePath, []*K8sResource{secret})
+		} else {
+			store.ReplaceFile(secret.FilePath, []*K8sResource{secret})
+			store.ReplaceFile(cert.FilePath, []*K8sResource{cert, alias})
+		}
+		if got := store.Get("Secret", "default", "web-tls"); got != secret {
+			t.Fatalf("aliasFirst=%v: expected the definition over the alias, got %+v", aliasFirst, got)
+		}
+		if got := store.Duplicates(secret); len(got) != 0 {
+			t.Errorf("aliasFirst=%v: expected a shadowed alias not to count as a duplicate, got %v", aliasFirst, got)
+		}
+
+		version := store.KeysVersion()
+		store.RemoveByFile(secret.FilePath)
+		if got := store.Get("Secret", "default", "web-tls"); got != alias {
+			t.Errorf("aliasFirst=%v: expected the alias back once the definition is gone, got %+v", aliasFirst, got)
+		}
+		if store.KeysVersion() == version {
+			t.Errorf("aliasFirst=%v: expected the keys version to change", aliasFirst)
+		}
+
+		store.RemoveByFile(cert.FilePath)
+		if got := store.Get("Secret", "default", "web-tls"); got != nil {
+			t.Errorf("aliasFirst=%v: expected nothing once the Certificate is gone too, got %+v", aliasFirst, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package indexer
+
+import "gopkg.in/yaml.v3"
+
+// extractTLSSecretReferences indexes the TLS Secrets served by an Ingress
+// (spec.tls[].secretName) and by Gateway API listeners
+// (spec.listeners[].tls.certificateRefs[]), so both resolve to the Secret
+// and show up among its references. A certificateRef may name a Secret in
+// another namespace; refs to other kinds (group/kind set to something else)
+// are skipped.
+func extractTLSSecretReferences(root *yaml.Node, kind string, resourceNamespace string) []Reference {
+	spec := getMapValue(root, "spec")
+	var refs []Reference
+	secretRef := func(nameNode *yaml.Node, namespace string) {
+		if nameNode == nil || nameNode.Kind != yaml.ScalarNode || nameNode.Value == "" {
+			return
+		}
+		refs = append(refs, Reference{
+			Kind:      "Secret",
+			Name:      nameNode.Value,
+			Namespace: namespace,
+			Line:      nameNode.Line - 1,
+			Col:       scalarCol(nameNode),
+		})
+	}
+
+	switch kind {
+	case "Ingress":
+		for _, tls := range asSequence(getMapValue(spec, "tls")) {
+			secretRef(getMapValue(tls, "secretName"), resourceNamespace)
+		}
+	case "Gateway":
+		for _, listener := range asSequence(getMapValue(spec, "listeners")) {
+			tls := getMapValue(listener, "tls")
+			for _, certRef := range asSequence(getMapValue(tls, "certificateRefs")) {
+				if v := scalarValue(getMapValue(certRef, "kind")); v != "" && v != "Secret" {
+					continue
+				}
+				if v := scalarValue(getMapValue(certRef, "group")); v != "" && v != "core" {
+					continue
+				}
+				namespace := resourceNamespace
+				if ns := scalarValue(getMapValue(certRef, "namespace")); ns != "" {
+					namespace = ns
+				}
+				secretRef(getMapValue(certRef, "name"), namespace)
+			}
+		}
+	}
+	return refs
+}
+
+func scalarValue(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
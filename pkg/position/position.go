@@ -0,0 +1,174 @@
+// Package position maps columns between the byte offsets the server works
+// in and the character offsets of the position encoding negotiated with the
+// client.
+//
+// yaml.v3 counts columns in code points, so documents are decoded through
+// Decoder, which rewrites node columns to byte offsets. Everything past
+// that (node columns, indexed columns, lengths of values) is in bytes, and
+// only the LSP boundary converts with FromByte and ToByte.
+package position
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Encoding is an LSP 3.17 PositionEncodingKind.
+type Encoding string
+
+const (
+	UTF8  Encoding = "utf-8"
+	UTF16 Encoding = "utf-16"
+	UTF32 Encoding = "utf-32"
+)
+
+// Negotiate picks the encoding for a client offering encodings: utf-8 when
+// offered, since it needs no conversion, and otherwise utf-16, which every
+// client supports.
+func Negotiate(offered []string) Encoding {
+	for _, e := range offered {
+		if Encoding(e) == UTF8 {
+			return UTF8
+		}
+	}
+	return UTF16
+}
+
+// FromByte converts a byte offset in line to a character offset in enc.
+// Offsets past the end of the line are kept past its end by the same number
+// of bytes; an offset inside a character counts from its start.
+func FromByte(line string, offset int, enc Encoding) uint32 {
+	if enc == UTF8 || offset <= 0 {
+		return uint32(max(offset, 0))
+	}
+	units := 0
+	i := 0
+	for i < len(line) && i < offset {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if i+size > offset {
+			break
+		}
+		units += width(r, enc)
+		i += size
+	}
+	if offset > len(line) {
+		units += offset - len(line)
+	}
+	return uint32(units)
+}
+
+// ToByte converts a character offset in enc on line to a byte offset. An
+// offset inside a character (half a surrogate pair, say) lands on its
+// start; offsets past the end of the line are clamped to it.
+func ToByte(line string, char uint32, enc Encoding) int {
+	if enc == UTF8 {
+		return min(int(char), len(line))
+	}
+	units := 0
+	i := 0
+	for i < len(line) {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		w := width(r, enc)
+		if units+w > int(char) {
+			break
+		}
+		units += w
+		i += size
+	}
+	return i
+}
+
+// width is the number of enc code units r takes.
+func width(r rune, enc Encoding) int {
+	switch enc {
+	case UTF16:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	case UTF32:
+		return 1
+	}
+	return utf8.RuneLen(r)
+}
+
+// Lines splits content into lines the way LSP counts them, without their
+// line breaks.
+func Lines(content string) []string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// IsASCII reports whether s needs no conversion in any encoding.
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Decoder reads YAML documents like yaml.Decoder, with node columns in
+// bytes instead of code points.
+type Decoder struct {
+	decoder *yaml.Decoder
+	lines   []string
+}
+
+func NewDecoder(content string) *Decoder {
+	d := &Decoder{decoder: yaml.NewDecoder(strings.NewReader(content))}
+	if !IsASCII(content) {
+		d.lines = Lines(content)
+	}
+	return d
+}
+
+// Decode decodes the next document into node.
+func (d *Decoder) Decode(node *yaml.Node) error {
+	if err := d.decoder.Decode(node); err != nil {
+		return err
+	}
+	if d.lines != nil {
+		byteColumns(node, d.lines)
+	}
+	return nil
+}
+
+// Unmarshal decodes the first document of content into node, with byte
+// columns.
+func Unmarshal(content string, node *yaml.Node) error {
+	err := NewDecoder(content).Decode(node)
+	if err == io.EOF {
+		// Like yaml.Unmarshal, an empty document is not an error.
+		return nil
+	}
+	return err
+}
+
+// byteColumns rewrites the code point columns of node and everything under
+// it to byte columns.
+func byteColumns(node *yaml.Node, lines []string) {
+	if node.Line >= 1 && node.Line <= len(lines) && node.Column > 1 {
+		node.Column = runeToByte(lines[node.Line-1], node.Column-1) + 1
+	}
+	for _, child := range node.Content {
+		byteColumns(child, lines)
+	}
+}
+
+// runeToByte returns the byte offset of the col-th code point of line.
+func runeToByte(line string, col int) int {
+	i := 0
+	for ; col > 0 && i < len(line); col-- {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+	}
+	return i + col
+}
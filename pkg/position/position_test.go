@@ -0,0 +1,87 @@
+package position
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		offered []string
+		want    Encoding
+	}{
+		{nil, UTF16},
+		{[]string{"utf-16"}, UTF16},
+		{[]string{"utf-32", "utf-16"}, UTF16},
+		{[]string{"utf-16", "utf-8"}, UTF8},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.offered); got != tt.want {
+			t.Errorf("Negotiate(%v) = %s, want %s", tt.offered, got, tt.want)
+		}
+	}
+}
+
+func TestConvertColumns(t *testing.T) {
+	// "한" is 3 bytes and one UTF-16 unit, "😀" 4 bytes and two units.
+	line := `a: "한😀b"`
+	tests := []struct {
+		offset int
+		enc    Encoding
+		char   uint32
+	}{
+		{4, UTF16, 4},
+		{7, UTF16, 5},
+		{11, UTF16, 7},
+		{12, UTF16, 8},
+		{11, UTF32, 6},
+		{11, UTF8, 11},
+		{14, UTF16, 10},
+	}
+	for _, tt := range tests {
+		if got := FromByte(line, tt.offset, tt.enc); got != tt.char {
+			t.Errorf("FromByte(%d, %s) = %d, want %d", tt.offset, tt.enc, got, tt.char)
+		}
+		if tt.offset > len(line) {
+			continue
+		}
+		if got := ToByte(line, tt.char, tt.enc); got != tt.offset {
+			t.Errorf("ToByte(%d, %s) = %d, want %d", tt.char, tt.enc, got, tt.offset)
+		}
+	}
+
+	// Half a surrogate pair lands on the start of its character, and
+	// offsets past the end are clamped.
+	if got := ToByte(line, 6, UTF16); got != 7 {
+		t.Errorf("Expected the middle of the emoji to map to its start, got %d", got)
+	}
+	if got := ToByte(line, 40, UTF16); got != len(line) {
+		t.Errorf("Expected an offset past the end to be clamped, got %d", got)
+	}
+}
+
+func TestDecoderByteColumns(t *testing.T) {
+	content := "설명: \"😀\"\nmeta: {이름: web, name: api}\n---\nkind: Pod\n"
+	decoder := NewDecoder(content)
+
+	var node yaml.Node
+	if err := decoder.Decode(&node); err != nil {
+		t.Fatal(err)
+	}
+	root := node.Content[0]
+	if value := root.Content[1]; value.Column != len("설명: ")+1 {
+		t.Errorf("Expected the value after a Korean key at byte column %d, got %d", len("설명: ")+1, value.Column)
+	}
+	flow := root.Content[3]
+	if name := flow.Content[2]; name.Value != "name" || name.Column != len("meta: {이름: web, ")+1 {
+		t.Errorf("Expected name at byte column %d, got %q at %d", len("meta: {이름: web, ")+1, name.Value, name.Column)
+	}
+
+	if err := decoder.Decode(&node); err != nil {
+		t.Fatal(err)
+	}
+	if kind := node.Content[0].Content[1]; kind.Line != 4 || kind.Column != 7 {
+		t.Errorf("Expected later documents to keep ASCII columns, got %d:%d", kind.Line, kind.Column)
+	}
+}
@@ -0,0 +1,137 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func aliasKindConfig() *config.Config {
+	return &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment", "Secret", "Certificate", "ExternalSecret"}, Path: "metadata.name"},
+					{Kinds: []string{"Certificate"}, Path: "spec.secretName", AliasKind: "Secret"},
+					{Kinds: []string{"ExternalSecret"}, Path: "spec.target.name", AliasKind: "Secret"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "workload.volume.secret",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Secret",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Deployment"},
+					Path:  "spec.template.spec.volumes[].secret.secretName",
+				},
+			},
+		},
+	}
+}
+
+func TestAliasKindDefinitions(t *testing.T) {
+	cfg := aliasKindConfig()
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+
+	idx.IndexContent("/tmp/cert.yaml", `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web-tls
+spec:
+  secretName: web-tls-secret
+  dnsNames: [web.example.com]
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: db
+spec:
+  target:
+    name: db-creds
+`)
+
+	deployYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - secretRef:
+                name: db-creds
+      volumes:
+        - name: tls
+          secret:
+            secretName: web-tls-secret
+`
+	idx.IndexContent("/tmp/deploy.yaml", deployYaml)
+	r := NewResolver(store, cfg)
+
+	// "            secretName: web-tls-secret" on line 15.
+	links, err := r.ResolveDefinition(context.Background(), deployYaml, "file:///tmp/deploy.yaml", 15, 26)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/cert.yaml" || links[0].TargetRange.Start.Line != 5 || links[0].TargetRange.Start.Character != 14 {
+		t.Fatalf("Expected the Certificate's spec.secretName, got %v", links)
+	}
+
+	// "                name: db-creds" on line 11.
+	links, err = r.ResolveDefinition(context.Background(), deployYaml, "file:///tmp/deploy.yaml", 11, 24)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/cert.yaml" || links[0].TargetRange.Start.Line != 14 {
+		t.Fatalf("Expected the ExternalSecret's spec.target.name, got %v", links)
+	}
+
+	hover, err := r.ResolveHover(context.Background(), deployYaml, "file:///tmp/deploy.yaml", 15, 26)
+	if err != nil {
+		t.Fatalf("ResolveHover failed: %v", err)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.(protocol.MarkupContent).Value, "declared by Certificate web-tls") {
+		t.Fatalf("Expected hover naming the declaring Certificate, got %v", hover)
+	}
+
+	// Renaming from the consumer edits the declaring field too.
+	edits, err := r.Rename(deployYaml, "file:///tmp/deploy.yaml", 15, 26, "web-tls-v2")
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	var uris []string
+	for _, e := range edits {
+		uris = append(uris, e.URI)
+	}
+	if len(edits) != 2 || !strings.Contains(strings.Join(uris, " "), "file:///tmp/cert.yaml") {
+		t.Fatalf("Expected edits in the Certificate and the Deployment, got %v", edits)
+	}
+
+	// A real Secret takes precedence over the declared one.
+	idx.IndexContent("/tmp/secret.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls-secret
+`)
+	idx.IndexContent("/tmp/cert.yaml", `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web-tls
+spec:
+  secretName: web-tls-secret
+`)
+	if res := store.Get("Secret", "default", "web-tls-secret"); res == nil || res.FilePath != "/tmp/secret.yaml" || res.DeclaredBy != nil {
+		t.Fatalf("Expected the real Secret to win, got %+v", res)
+	}
+}
@@ -0,0 +1,78 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestReferenceRulesMatchAPIGroup(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{{
+			Name: "k8s.resource.name",
+			Definitions: []config.SymbolDefinition{
+				{Kinds: []string{"Secret", "ConfigMap", "Database"}, Path: "metadata.name"},
+			},
+		}},
+		// Two operators ship a Database kind whose spec.credentials names
+		// different things.
+		References: []config.Reference{
+			{
+				Name:       "a.database.credentials",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Secret",
+				Match:      config.ReferenceMatch{Kinds: []string{"Database"}, ApiGroups: []string{"a.example.com"}, Path: "spec.credentials"},
+			},
+			{
+				Name:       "b.database.credentials",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match:      config.ReferenceMatch{Kinds: []string{"Database"}, ApiGroups: []string{"b.example.com"}, Path: "spec.credentials"},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/secret.yaml", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n")
+	idx.IndexContent("/tmp/configmap.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: creds\n")
+
+	database := func(group, name string) string {
+		return "apiVersion: " + group + "/v1\nkind: Database\nmetadata:\n  name: " + name + "\nspec:\n  credentials: creds\n"
+	}
+	for _, tt := range []struct {
+		group, name, target string
+	}{
+		{"a.example.com", "orders", "Secret"},
+		{"b.example.com", "users", "ConfigMap"},
+	} {
+		content := database(tt.group, tt.name)
+		idx.IndexContent("/tmp/"+tt.name+".yaml", content)
+
+		res := store.Get("Database", "default", tt.name)
+		if res == nil || len(res.References) != 1 || res.References[0].Kind != tt.target {
+			t.Errorf("Expected the %s Database to reference a %s, got %+v", tt.group, tt.target, res)
+		}
+
+		locs, err := r.ResolveDefinition(context.Background(), content, "file:///tmp/"+tt.name+".yaml", 5, 16)
+		if err != nil {
+			t.Fatalf("ResolveDefinition failed: %v", err)
+		}
+		want := "file:///tmp/secret.yaml"
+		if tt.target == "ConfigMap" {
+			want = "file:///tmp/configmap.yaml"
+		}
+		if len(locs) != 1 || locs[0].TargetURI != want {
+			t.Errorf("Expected the %s Database to resolve to %s, got %v", tt.group, want, locs)
+		}
+	}
+
+	// Neither rule applies to a third group.
+	idx.IndexContent("/tmp/other.yaml", database("c.example.com", "other"))
+	if res := store.Get("Database", "default", "other"); res == nil || len(res.References) != 0 {
+		t.Errorf("Expected no references from another group, got %+v", res)
+	}
+}
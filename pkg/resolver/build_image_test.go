@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_BuildImage(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{ImageBuildAnnotation: "skaffold.dev/build-image"},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment", "Job"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/api.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  annotations:
+    skaffold.dev/build-image: registry.local:5000/api
+`)
+	r := NewResolver(store, cfg)
+
+	jobYaml := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: registry.local:5000/api:v1.2
+`
+	idx.IndexContent("/tmp/job.yaml", jobYaml)
+
+	// "          image: registry.local:5000/api:v1.2" on line 9.
+	links, err := r.ResolveDefinition(context.Background(), jobYaml, "file:///tmp/job.yaml", 9, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("Expected 1 link, got %v", links)
+	}
+	target := links[0].TargetRange
+	if links[0].TargetURI != "file:///tmp/api.yaml" || target.Start.Line != 5 || target.Start.Character != 30 || target.End.Character != 53 {
+		t.Errorf("Expected the build annotation value, got %s %v", links[0].TargetURI, target)
+	}
+
+	// Off unless the annotation is configured.
+	cfg.Settings.ImageBuildAnnotation = ""
+	if links, _ := r.ResolveDefinition(context.Background(), jobYaml, "file:///tmp/job.yaml", 9, 20); len(links) != 0 {
+		t.Errorf("Expected no links with the feature off, got %v", links)
+	}
+}
@@ -0,0 +1,107 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestBuiltinFeature_VolumeMountNavigationDisabled(t *testing.T) {
+	uri := "file:///tmp/deployment.yaml"
+	yamlContent := strings.TrimLeft(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+spec:
+  template:
+    spec:
+      volumes:
+      - name: vector-config
+        configMap:
+          name: vector-config
+      containers:
+      - name: app
+        image: nginx
+        volumeMounts:
+        - name: vector-config
+          mountPath: /etc/vector/vector.yaml
+`, "\n")
+	// "        - name: vector-config" under volumeMounts is line 15 (0-based), value at col 16
+	line, col := 15, 16
+
+	cfg := &config.Config{}
+	r := NewResolver(indexer.NewStore(), cfg)
+
+	locs, err := r.ResolveDefinition(context.Background(), yamlContent, uri, line, col)
+	if err != nil || len(locs) != 1 {
+		t.Fatalf("Expected the built-in navigation by default, got %v (err %v)", locs, err)
+	}
+
+	cfg.Settings.BuiltinFeatures = map[string]bool{config.FeatureVolumeMountNavigation: false}
+	locs, err = r.ResolveDefinition(context.Background(), yamlContent, uri, line, col)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 0 {
+		t.Fatalf("Expected no definition with volumeMountNavigation disabled, got %v", locs)
+	}
+
+	explanation, err := r.ExplainPosition(yamlContent, line, col)
+	if err != nil || explanation == nil {
+		t.Fatalf("ExplainPosition failed: %v", err)
+	}
+	if len(explanation.SkippedFeatures) != 1 || explanation.SkippedFeatures[0] != config.FeatureVolumeMountNavigation {
+		t.Errorf("Expected volumeMountNavigation to be reported as skipped, got %+v", explanation)
+	}
+}
+
+func TestBuiltinFeature_EmbeddedFilesDisabled(t *testing.T) {
+	cfg := &config.Config{}
+	r := NewResolver(indexer.NewStore(), cfg)
+
+	yamlContent := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-cm
+data:
+  app.conf: |-
+    hello
+`
+	// "  app.conf: |-" is line 5 (0-based), key at col 2
+	hover, err := r.ResolveHover(context.Background(), yamlContent, "file:///tmp/cm.yaml", 5, 2)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected embedded file hover by default, got %v (err %v)", hover, err)
+	}
+
+	cfg.Settings.BuiltinFeatures = map[string]bool{config.FeatureEmbeddedFiles: false}
+	hover, err = r.ResolveHover(context.Background(), yamlContent, "file:///tmp/cm.yaml", 5, 2)
+	if err != nil {
+		t.Fatalf("ResolveHover failed: %v", err)
+	}
+	if hover != nil {
+		t.Fatalf("Expected no embedded file hover when disabled, got %v", hover)
+	}
+
+	explanation, err := r.ExplainPosition(yamlContent, 5, 2)
+	if err != nil || explanation == nil {
+		t.Fatalf("ExplainPosition failed: %v", err)
+	}
+	if len(explanation.BuiltinFeatures) != 0 || len(explanation.SkippedFeatures) != 1 || explanation.SkippedFeatures[0] != config.FeatureEmbeddedFiles {
+		t.Errorf("Expected embeddedFiles to be reported as skipped, got %+v", explanation)
+	}
+}
+
+func TestSettingsFeatureEnabledDefaultsToTrue(t *testing.T) {
+	var s config.Settings
+	if !s.FeatureEnabled(config.FeatureSubPathTargets) {
+		t.Fatal("Expected features to be enabled by default")
+	}
+	s.BuiltinFeatures = map[string]bool{config.FeatureSubPathTargets: false}
+	if s.FeatureEnabled(config.FeatureSubPathTargets) || !s.FeatureEnabled(config.FeaturePVCClaimUsages) {
+		t.Fatal("Expected only the listed feature to be disabled")
+	}
+}
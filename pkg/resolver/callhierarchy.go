@@ -0,0 +1,163 @@
+package resolver
+
+import (
+	"encoding/json"
+	"sort"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// callHierarchyData identifies the resource of a call hierarchy item. The
+// client echoes it back in incoming/outgoing call requests.
+type callHierarchyData struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// PrepareCallHierarchy returns the resource under the cursor, either at its
+// metadata.name or at a reference to it, as a call hierarchy item. Incoming
+// calls are the resources referencing it, outgoing calls the resources it
+// references.
+func (r *Resolver) PrepareCallHierarchy(docContent, uri string, line, col int) ([]protocol.CallHierarchyItem, error) {
+	target, err := r.renameTargetAt(docContent, uri, line, col)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	res := r.lookupResource(target.kind, target.namespace, target.name)
+	if res == nil {
+		return nil, nil
+	}
+	return []protocol.CallHierarchyItem{callHierarchyItem(res)}, nil
+}
+
+// IncomingCalls returns the resources referencing the item's resource, with
+// the ranges of their references.
+func (r *Resolver) IncomingCalls(item protocol.CallHierarchyItem) []protocol.CallHierarchyIncomingCall {
+	data, ok := decodeCallHierarchyData(item)
+	if !ok {
+		return nil
+	}
+
+	referrers := r.Store.FindReferences(data.Kind, data.Name)
+	sortResources(referrers)
+
+	var calls []protocol.CallHierarchyIncomingCall
+	for _, res := range referrers {
+		var ranges []protocol.Range
+		for _, ref := range res.References {
+			if !isCallReference(ref) || !r.sameKind(ref.Kind, data.Kind) || ref.Name != data.Name {
+				continue
+			}
+			if !indexer.IsClusterScoped(data.Kind) && r.canonicalNamespace(referenceNamespace(res, ref)) != r.canonicalNamespace(data.Namespace) {
+				continue
+			}
+			ranges = append(ranges, referenceRange(ref))
+		}
+		if len(ranges) > 0 {
+			calls = append(calls, protocol.CallHierarchyIncomingCall{From: callHierarchyItem(res), FromRanges: ranges})
+		}
+	}
+	return calls
+}
+
+// OutgoingCalls returns the indexed resources the item's resource references,
+// with the ranges of the references. Unresolved references are left out.
+func (r *Resolver) OutgoingCalls(item protocol.CallHierarchyItem) []protocol.CallHierarchyOutgoingCall {
+	data, ok := decodeCallHierarchyData(item)
+	if !ok {
+		return nil
+	}
+	source := r.lookupResource(data.Kind, data.Namespace, data.Name)
+	if source == nil {
+		return nil
+	}
+
+	var calls []protocol.CallHierarchyOutgoingCall
+	index := make(map[*indexer.K8sResource]int)
+	for _, ref := range r.Store.OutgoingReferences(data.Kind, data.Namespace, data.Name) {
+		if !isCallReference(ref) {
+			continue
+		}
+		target := r.lookupResource(ref.Kind, referenceNamespace(source, ref), ref.Name)
+		if target == nil {
+			continue
+		}
+		if i, ok := index[target]; ok {
+			calls[i].FromRanges = append(calls[i].FromRanges, referenceRange(ref))
+			continue
+		}
+		index[target] = len(calls)
+		calls = append(calls, protocol.CallHierarchyOutgoingCall{
+			To:         callHierarchyItem(target),
+			FromRanges: []protocol.Range{referenceRange(ref)},
+		})
+	}
+	return calls
+}
+
+// isCallReference reports whether ref names a resource, including through
+// one of its data keys. Label and field selectors are not followed.
+func isCallReference(ref indexer.Reference) bool {
+	return ref.Kind != "" && ref.Symbol != "k8s.label" && ref.Symbol != "k8s.field"
+}
+
+func callHierarchyItem(res *indexer.K8sResource) protocol.CallHierarchyItem {
+	detail := resourceDetail(res.ApiVersion, res.Kind)
+	if res.Namespace != "" {
+		detail += " in " + res.Namespace
+	}
+	nameRange := protocol.Range{
+		Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+		End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+	}
+	return protocol.CallHierarchyItem{
+		Name:           res.Kind + "/" + res.Name,
+		Kind:           symbolKindForResource(res.Kind),
+		Detail:         &detail,
+		URI:            fileURI(res.FilePath),
+		Range:          nameRange,
+		SelectionRange: nameRange,
+		Data:           callHierarchyData{Kind: res.Kind, Namespace: res.Namespace, Name: res.Name},
+	}
+}
+
+// decodeCallHierarchyData reads the data of an item sent back by the client,
+// where it arrives as generic JSON.
+func decodeCallHierarchyData(item protocol.CallHierarchyItem) (callHierarchyData, bool) {
+	var data callHierarchyData
+	if item.Data == nil {
+		return data, false
+	}
+	raw, err := json.Marshal(item.Data)
+	if err != nil {
+		return data, false
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, false
+	}
+	return data, data.Kind != "" && data.Name != ""
+}
+
+func referenceRange(ref indexer.Reference) protocol.Range {
+	display := ref.Name
+	if ref.Key != "" {
+		display = ref.Key
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col)},
+		End:   protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col + len(display))},
+	}
+}
+
+// sortResources orders resources by file and position for stable results.
+func sortResources(resources []*indexer.K8sResource) {
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].FilePath != resources[j].FilePath {
+			return resources[i].FilePath < resources[j].FilePath
+		}
+		return resources[i].Line < resources[j].Line
+	})
+}
@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func workloadYaml(name, namespace string) string {
+	return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ` + name + `
+  namespace: ` + namespace + `
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          envFrom:
+            - configMapRef:
+                name: settings
+            - secretRef:
+                name: creds
+          env:
+            - name: MODE
+              valueFrom:
+                configMapKeyRef:
+                  name: settings
+                  key: mode
+`
+}
+
+// roundTrip sends item through JSON like a client echoing it back.
+func roundTrip(t *testing.T, item protocol.CallHierarchyItem) protocol.CallHierarchyItem {
+	t.Helper()
+	raw, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out protocol.CallHierarchyItem
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestCallHierarchy(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment", "ConfigMap", "Secret"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	cmYaml := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: prod
+`
+	idx.IndexContent("/tmp/cm.yaml", cmYaml)
+	idx.IndexContent("/tmp/secret.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: prod
+`)
+	idx.IndexContent("/tmp/api.yaml", workloadYaml("api", "prod"))
+	idx.IndexContent("/tmp/worker.yaml", workloadYaml("worker", "prod"))
+	idx.IndexContent("/tmp/staging.yaml", workloadYaml("api", "staging"))
+
+	items, err := r.PrepareCallHierarchy(cmYaml, "file:///tmp/cm.yaml", 3, 9)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected the ConfigMap item, got %v, %v", items, err)
+	}
+	if items[0].Name != "ConfigMap/settings" || items[0].Kind != protocol.SymbolKindFile {
+		t.Errorf("Unexpected item %+v", items[0])
+	}
+
+	incoming := r.IncomingCalls(roundTrip(t, items[0]))
+	if len(incoming) != 2 {
+		t.Fatalf("Expected the two prod workloads, got %+v", incoming)
+	}
+	if incoming[0].From.URI != "file:///tmp/api.yaml" || incoming[1].From.URI != "file:///tmp/worker.yaml" {
+		t.Errorf("Unexpected callers %s, %s", incoming[0].From.URI, incoming[1].From.URI)
+	}
+	// envFrom name, configMapKeyRef name and key.
+	if len(incoming[0].FromRanges) != 3 {
+		t.Errorf("Expected 3 ranges, got %v", incoming[0].FromRanges)
+	}
+
+	// From the Deployment: its ConfigMap and Secret.
+	deployYaml := workloadYaml("api", "prod")
+	items, err = r.PrepareCallHierarchy(deployYaml, "file:///tmp/api.yaml", 3, 9)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected the Deployment item, got %v, %v", items, err)
+	}
+	outgoing := r.OutgoingCalls(roundTrip(t, items[0]))
+	if len(outgoing) != 2 {
+		t.Fatalf("Expected ConfigMap and Secret, got %+v", outgoing)
+	}
+	if outgoing[0].To.Name != "ConfigMap/settings" || len(outgoing[0].FromRanges) != 3 {
+		t.Errorf("Unexpected first callee %+v", outgoing[0])
+	}
+	if outgoing[1].To.Name != "Secret/creds" || outgoing[1].FromRanges[0].Start.Line != 14 {
+		t.Errorf("Unexpected second callee %+v", outgoing[1])
+	}
+}
@@ -0,0 +1,109 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+// cancelAfter is a context that reports itself cancelled once Err has been
+// called checks times, cancelling deterministically mid-resolution.
+type cancelAfter struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.checks <= 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func TestResolveHonorsCancellation(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "pod.configmap-ref",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Pod"},
+					Path:  "spec.volumes.configMap.name",
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared
+`)
+
+	// 500 Pods mounting the same ConfigMap; the cursor is on the last one.
+	var docs []string
+	for i := 0; i < 500; i++ {
+		docs = append(docs, fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-%d
+spec:
+  volumes:
+    - name: config
+      configMap:
+        name: shared
+`, i))
+	}
+	content := strings.Join(docs, "---\n")
+	idx.IndexContent("/tmp/pods.yaml", content)
+	line := 499*10 + 8
+	r := NewResolver(store, cfg)
+
+	links, err := r.ResolveDefinition(context.Background(), content, "file:///tmp/pods.yaml", line, 16)
+	if err != nil || len(links) != 1 {
+		t.Fatalf("Expected the ConfigMap without cancellation, got %v (%v)", links, err)
+	}
+	locs, err := r.ResolveReferences(context.Background(), content, "file:///tmp/pods.yaml", line, 16)
+	if err != nil || len(locs) != 500 {
+		t.Fatalf("Expected 500 references without cancellation, got %d (%v)", len(locs), err)
+	}
+
+	start := time.Now()
+	links, err = r.ResolveDefinition(&cancelAfter{context.Background(), 10}, content, "file:///tmp/pods.yaml", line, 16)
+	if err != nil || links != nil {
+		t.Errorf("Expected no definition once cancelled, got %v (%v)", links, err)
+	}
+	hover, err := r.ResolveHover(&cancelAfter{context.Background(), 10}, content, "file:///tmp/pods.yaml", line, 16)
+	if err != nil || hover != nil {
+		t.Errorf("Expected no hover once cancelled, got %v (%v)", hover, err)
+	}
+	items, err := r.Completion(&cancelAfter{context.Background(), 10}, content, line, 16)
+	if err != nil || items != nil {
+		t.Errorf("Expected no completion once cancelled, got %v (%v)", items, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cancelled requests to return promptly, took %v", elapsed)
+	}
+
+	// Cancelled during the store scan rather than the decode loop.
+	locs, err = r.ResolveReferences(&cancelAfter{context.Background(), 501}, content, "file:///tmp/pods.yaml", line, 16)
+	if err != nil || locs != nil {
+		t.Errorf("Expected no references once cancelled, got %d (%v)", len(locs), err)
+	}
+}
@@ -0,0 +1,84 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestCaseInsensitiveKindResolution(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{CaseInsensitiveKinds: true},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "gateway.backend",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "service", // lower-cased in a custom rule
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Gateway"},
+					Path:  "spec.backend.name",
+				},
+			},
+		},
+	}
+
+	store := indexer.NewStore()
+	store.SetCaseInsensitiveKinds(cfg.Settings.CaseInsensitiveKinds)
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/svc.yaml", `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`)
+
+	// The document's own kind is also spelled differently from the rule.
+	gatewayYaml := `
+apiVersion: example.com/v1
+kind: gateway
+metadata:
+  name: gw
+spec:
+  backend:
+    name: web
+`
+	locs, err := r.ResolveDefinition(context.Background(), gatewayYaml, "file:///tmp/gw.yaml", 7, 11)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 1 {
+		t.Fatalf("Expected 1 location, got %d", len(locs))
+	}
+	if locs[0].TargetURI != "file:///tmp/svc.yaml" {
+		t.Errorf("Expected file:///tmp/svc.yaml, got %s", locs[0].TargetURI)
+	}
+
+	// Names are still matched exactly.
+	if res := store.Get("SERVICE", "default", "WEB"); res != nil {
+		t.Errorf("Expected name lookup to stay case-sensitive, found %v", res)
+	}
+	if res := store.Get("SERVICE", "default", "web"); res == nil {
+		t.Error("Expected kind lookup to ignore case")
+	}
+}
+
+func TestCaseSensitiveKindsByDefault(t *testing.T) {
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "Service", Name: "web"})
+
+	if res := store.Get("service", "default", "web"); res != nil {
+		t.Errorf("Expected exact kind matching by default, found %v", res)
+	}
+}
@@ -0,0 +1,102 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// ShowReferencesCommand is run by the reference count lenses. The client
+// shows the references at the given position, as find-references would.
+const ShowReferencesCommand = "k8s.showReferences"
+
+// ShowReferencesArgs is the single argument of ShowReferencesCommand.
+type ShowReferencesArgs struct {
+	URI      string            `json:"uri"`
+	Position protocol.Position `json:"position"`
+}
+
+// CodeLenses returns a reference count lens above every resource's
+// metadata.name, and above each data key of ConfigMaps. Resources without
+// references get a "no references" lens so orphans stand out. Documents after
+// a parse error are omitted.
+func (r *Resolver) CodeLenses(docContent string, uri string) []protocol.CodeLens {
+	var lenses []protocol.CodeLens
+	decoder := position.NewDecoder(docContent)
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msg("Stopping code lenses at parse error")
+			}
+			break
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		for _, item := range indexer.ListItems(&node) {
+			if len(item.Content) == 0 || item.Content[0].Kind != yaml.MappingNode {
+				continue
+			}
+			root := item.Content[0]
+
+			kind := findKind(root)
+			nameNode := getMappingScalarValue(getMappingValue(root, "metadata"), "name")
+			if kind == "" || nameNode == nil || nameNode.Value == "" {
+				continue
+			}
+			namespace := findNamespace(root)
+			if namespace == "" {
+				namespace = "default"
+			}
+
+			count := 0
+			r.findReferences(context.Background(), kind, nameNode.Value, namespace, excludeNode(func(locs []protocol.Location) {
+				count += len(locs)
+			}, uri, nameNode))
+			lenses = append(lenses, referenceCountLens(uri, nameNode, count))
+
+			if kind != "ConfigMap" {
+				continue
+			}
+			for _, field := range []string{"data", "binaryData"} {
+				data := getMappingValue(root, field)
+				if data == nil || data.Kind != yaml.MappingNode {
+					continue
+				}
+				for i := 0; i < len(data.Content); i += 2 {
+					keyNode := data.Content[i]
+					usages := r.findConfigMapEmbeddedFileUsages(namespace, nameNode.Value, keyNode.Value)
+					lenses = append(lenses, referenceCountLens(uri, keyNode, len(usages)))
+				}
+			}
+		}
+	}
+	return lenses
+}
+
+func referenceCountLens(uri string, node *yaml.Node, count int) protocol.CodeLens {
+	rng := calculateOriginRange(node)
+	title := "no references"
+	switch {
+	case count == 1:
+		title = "1 reference"
+	case count > 1:
+		title = fmt.Sprintf("%d references", count)
+	}
+	return protocol.CodeLens{
+		Range: rng,
+		Command: &protocol.Command{
+			Title:     title,
+			Command:   ShowReferencesCommand,
+			Arguments: []any{ShowReferencesArgs{URI: uri, Position: rng.Start}},
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestCodeLenses(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Service", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	content := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  app.yaml: |
+    port: 8080
+  unused: "x"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: orphan
+`
+	idx.IndexContent("/tmp/cm.yaml", content)
+	for _, pod := range []string{"a", "b"} {
+		idx.IndexContent("/tmp/pod-"+pod+".yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: `+pod+`
+spec:
+  containers:
+    - name: app
+      env:
+        - name: CONFIG
+          valueFrom:
+            configMapKeyRef:
+              name: app-config
+              key: app.yaml
+`)
+	}
+
+	lenses := r.CodeLenses(content, "file:///tmp/cm.yaml")
+	want := []struct {
+		line  uint32
+		title string
+	}{
+		// Both Pods reference the name and the key. References to the whole
+		// ConfigMap count for every key, as in find-references on a key.
+		{3, "4 references"},
+		{5, "4 references"},
+		{7, "2 references"},
+		{12, "no references"},
+	}
+	if len(lenses) != len(want) {
+		t.Fatalf("Expected %d lenses, got %+v", len(want), lenses)
+	}
+	for i, w := range want {
+		lens := lenses[i]
+		if lens.Range.Start.Line != w.line || lens.Command == nil || lens.Command.Title != w.title {
+			t.Errorf("Lens %d: expected %q on line %d, got %+v", i, w.title, w.line, lens)
+			continue
+		}
+		args, ok := lens.Command.Arguments[0].(ShowReferencesArgs)
+		if lens.Command.Command != ShowReferencesCommand || !ok || args.URI != "file:///tmp/cm.yaml" || args.Position != lens.Range.Start {
+			t.Errorf("Lens %d: unexpected command %+v", i, lens.Command)
+		}
+	}
+}
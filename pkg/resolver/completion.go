@@ -1,18 +1,28 @@
 package resolver
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"strings"
+	"maps"
+	"slices"
+	"sort"
+
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
 
 	"github.com/rs/zerolog/log"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 	"gopkg.in/yaml.v3"
 )
 
-func (r *Resolver) Completion(docContent string, line, col int) ([]protocol.CompletionItem, error) {
-	decoder := yaml.NewDecoder(strings.NewReader(docContent))
+func (r *Resolver) Completion(ctx context.Context, docContent string, line, col int) ([]protocol.CompletionItem, error) {
+	decoder := position.NewDecoder(docContent)
 
 	for {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
 		var node yaml.Node
 		if err := decoder.Decode(&node); err != nil {
 			if err == io.EOF {
@@ -21,39 +31,216 @@ func (r *Resolver) Completion(docContent string, line, col int) ([]protocol.Comp
 			log.Error().Err(err).Msg("Failed to parse YAML for completion")
 			return nil, err
 		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		node = *listItemAt(&node, line+1)
 
 		// Find node at cursor
-		targetNode, _, path := findNodeAt(&node, line+1, col+1)
+		targetNode, parentNode, path, indices := findNodeAt(&node, line+1, col+1)
 		if targetNode != nil {
 			log.Debug().Str("value", targetNode.Value).Strs("path", path).Msg("Found node at cursor (Completion)")
 
 			kind := findKind(&node)
 
+			if items, ok := r.labelSelectorCompletion(kind, findAPIVersion(&node), path, indices, parentNode, targetNode); ok {
+				return items, nil
+			}
+			if matchPath(path, nil, "metadata.namespace") && !isMappingKey(parentNode, targetNode) {
+				return r.namespaceCompletion(), nil
+			}
+
 			// Check configured references
 			for _, refRule := range r.Config.References {
-				if matchesKind(refRule.Match.Kinds, kind) && matchPath(path, refRule.Match.Path) {
+				if matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && refRule.Match.MatchesAPIVersion(findAPIVersion(&node)) && matchPath(path, indices, refRule.Match.Path) {
 					if refRule.Symbol == "k8s.resource.name" {
 						targetKind := refRule.TargetKind
 						log.Debug().Str("targetKind", targetKind).Msg("Found completion rule")
 
-						resources := r.Store.ListByKind(targetKind)
-						var items []protocol.CompletionItem
-						for _, res := range resources {
-							label := res.Name
-							kind := protocol.CompletionItemKindReference
-							detail := "Namespace: " + res.Namespace
-
-							items = append(items, protocol.CompletionItem{
-								Label:  label,
-								Kind:   &kind,
-								Detail: &detail,
-							})
-						}
-						return items, nil
+						namespace := siblingNamespace(parentNode, findNamespace(&node))
+						return r.resourceNameCompletion(targetKind, namespace), nil
 					}
 				}
 			}
+			if items, ok := r.schemaCompletion(findAPIVersion(&node), kind, path, parentNode, targetNode); ok {
+				return items, nil
+			}
 		}
 	}
 	return nil, nil
 }
+
+// resourceNameCompletion offers the indexed resources of kind, those in
+// namespace (the document's, or the reference's own namespace field) ranked
+// first since a namespaced reference can only resolve to them. Resources
+// elsewhere stay available below, sorted by name within each group.
+func (r *Resolver) resourceNameCompletion(kind, namespace string) []protocol.CompletionItem {
+	resources := r.Store.ListByKind(kind)
+	namespace = r.canonicalNamespace(namespace)
+	inNamespace := func(res *indexer.K8sResource) bool {
+		return indexer.IsClusterScoped(res.Kind) || r.canonicalNamespace(res.Namespace) == namespace
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if inNamespace(a) != inNamespace(b) {
+			return inNamespace(a)
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Namespace < b.Namespace
+	})
+
+	var items []protocol.CompletionItem
+	for i, res := range resources {
+		kind := completionKindForResource(res.Kind)
+		detail := resourceDetail(res.ApiVersion, res.Kind) + ", Namespace: " + res.Namespace
+		sortText := fmt.Sprintf("%04d", i)
+		items = append(items, protocol.CompletionItem{
+			Label:    res.Name,
+			Kind:     &kind,
+			Detail:   &detail,
+			SortText: &sortText,
+			Data:     CompletionData{Kind: res.Kind, Namespace: res.Namespace, Name: res.Name},
+		})
+	}
+	return items
+}
+
+// namespaceCompletion offers every namespace the workspace knows of, noting
+// whether a Namespace manifest defines it or it is only used.
+func (r *Resolver) namespaceCompletion() []protocol.CompletionItem {
+	manifests := make(map[string]*indexer.K8sResource)
+	for _, res := range r.Store.ListByKind("Namespace") {
+		manifests[res.Name] = res
+	}
+	itemKind := completionKindForResource("Namespace")
+	var items []protocol.CompletionItem
+	for _, ns := range r.Store.ListNamespaces() {
+		detail := "Namespace (inferred from usage)"
+		if res, ok := manifests[ns]; ok {
+			detail = "Namespace defined in " + res.FilePath
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:  ns,
+			Kind:   &itemKind,
+			Detail: &detail,
+		})
+	}
+	return items
+}
+
+// schemaCompletion offers what the schema of a custom resource's CRD allows
+// at the cursor: the properties not yet set where a key goes, or the enum
+// values of a field. A plain word on its own line under a key parses as
+// that key's value, so it gets the key's properties too.
+func (r *Resolver) schemaCompletion(apiVersion, kind string, path []string, parent, target *yaml.Node) ([]protocol.CompletionItem, bool) {
+	schema := r.Store.Schema(apiVersion, kind)
+	if schema == nil || len(path) == 0 {
+		return nil, false
+	}
+
+	present := make(map[string]bool)
+	var object *indexer.Schema
+	if isMappingKey(parent, target) {
+		object = schema.Lookup(path[:len(path)-1]).Elem()
+		for k := 0; k < len(parent.Content); k += 2 {
+			if parent.Content[k] != target {
+				present[parent.Content[k].Value] = true
+			}
+		}
+	} else {
+		field := schema.Lookup(path).Elem()
+		if field == nil {
+			return nil, false
+		}
+		if len(field.Enum) > 0 {
+			itemKind := protocol.CompletionItemKindEnumMember
+			var items []protocol.CompletionItem
+			for _, value := range field.Enum {
+				items = append(items, protocol.CompletionItem{Label: value, Kind: &itemKind})
+			}
+			return items, true
+		}
+		if field.Type == "object" {
+			object = field
+		}
+	}
+	if object == nil || len(object.Properties) == 0 {
+		return nil, false
+	}
+
+	itemKind := protocol.CompletionItemKindProperty
+	var items []protocol.CompletionItem
+	for _, name := range slices.Sorted(maps.Keys(object.Properties)) {
+		if present[name] {
+			continue
+		}
+		prop := object.Properties[name]
+		item := protocol.CompletionItem{Label: name, Kind: &itemKind}
+		if prop.Type != "" {
+			detail := prop.Type
+			if slices.Contains(object.Required, name) {
+				detail += " (required)"
+			}
+			item.Detail = &detail
+		}
+		if prop.Description != "" {
+			item.Documentation = prop.Description
+		}
+		items = append(items, item)
+	}
+	return items, len(items) > 0
+}
+
+// workloadSelectorKinds select their pods through spec.selector, which no
+// reference rule covers since the pods are their own template.
+var workloadSelectorKinds = []string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job"}
+
+// labelSelectorCompletion offers the indexed labels inside a label
+// selector, the way findWorkloadsByLabel resolves them: the values of the
+// label under the cursor, or key/value pairs where a key goes.
+func (r *Resolver) labelSelectorCompletion(kind, apiVersion string, path []string, indices []int, parent, target *yaml.Node) ([]protocol.CompletionItem, bool) {
+	pattern := ""
+	for _, refRule := range r.Config.References {
+		if refRule.Symbol == "k8s.label" && matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && refRule.Match.MatchesAPIVersion(apiVersion) && matchPathPrefix(path, indices, refRule.Match.Path) {
+			pattern = refRule.Match.Path
+			break
+		}
+	}
+	if pattern == "" && containsKind(workloadSelectorKinds, kind, r.Config.Settings.CaseInsensitiveKinds) && matchPathPrefix(path, nil, "spec.selector") {
+		pattern = "spec.selector"
+	}
+	if pattern == "" {
+		return nil, false
+	}
+	labelKey, ok := selectorLabelKey(path, pattern)
+	if !ok {
+		return nil, false
+	}
+
+	labels := r.Store.AllLabels()
+	itemKind := protocol.CompletionItemKindValue
+	var items []protocol.CompletionItem
+	add := func(label, filter string, count int) {
+		detail := fmt.Sprintf("Label of %d resource(s)", count)
+		items = append(items, protocol.CompletionItem{
+			Label:      label,
+			Kind:       &itemKind,
+			Detail:     &detail,
+			FilterText: &filter,
+		})
+	}
+	if isMappingKey(parent, target) {
+		for _, key := range slices.Sorted(maps.Keys(labels)) {
+			for _, value := range slices.Sorted(maps.Keys(labels[key])) {
+				add(key+": "+value, key, labels[key][value])
+			}
+		}
+	} else {
+		for _, value := range slices.Sorted(maps.Keys(labels[labelKey])) {
+			add(value, value, labels[labelKey][value])
+		}
+	}
+	return items, true
+}
@@ -0,0 +1,96 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CompletionData is the CompletionItem.Data of a resource completion: the
+// Store key ResolveCompletionItem looks the resource up by.
+type CompletionData struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// ResolveCompletionItem fills in the documentation of a resource
+// completion: its file, namespace and labels, plus the data keys of a
+// ConfigMap or Secret, read from its file only now. Items without
+// CompletionData, or whose resource left the Store since, come back as
+// they are.
+func (r *Resolver) ResolveCompletionItem(item protocol.CompletionItem) protocol.CompletionItem {
+	if item.Data == nil {
+		return item
+	}
+	raw, err := json.Marshal(item.Data)
+	if err != nil {
+		return item
+	}
+	var data CompletionData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Kind == "" || data.Name == "" {
+		return item
+	}
+	res := r.Store.Get(data.Kind, data.Namespace, data.Name)
+	if res == nil {
+		log.Debug().Str("kind", data.Kind).Str("name", data.Name).Msg("Completion item no longer indexed")
+		return item
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s** (%s)\n\n", res.Name, resourceDetail(res.ApiVersion, res.Kind))
+	fmt.Fprintf(&sb, "File: %s\n\n", res.FilePath)
+	if res.Namespace != "" {
+		fmt.Fprintf(&sb, "Namespace: %s\n\n", res.Namespace)
+	}
+	if len(res.Labels) > 0 {
+		sb.WriteString("Labels:\n")
+		for _, key := range slices.Sorted(maps.Keys(res.Labels)) {
+			fmt.Fprintf(&sb, "- `%s: %s`\n", key, res.Labels[key])
+		}
+		sb.WriteString("\n")
+	}
+	if res.Kind == "ConfigMap" || res.Kind == "Secret" {
+		if keys := r.dataKeysInFile(res.FilePath, res.Kind, res.Namespace, res.Name); len(keys) > 0 {
+			sb.WriteString("Data keys:\n")
+			for _, key := range keys {
+				fmt.Fprintf(&sb, "- `%s`\n", key)
+			}
+		}
+	}
+
+	item.Documentation = protocol.MarkupContent{
+		Kind:  protocol.MarkupKindMarkdown,
+		Value: strings.TrimSpace(sb.String()),
+	}
+	return item
+}
+
+// dataKeysInFile lists the data, binaryData and stringData keys of a
+// ConfigMap or Secret as its file has them now.
+func (r *Resolver) dataKeysInFile(filePath, kind, namespace, name string) []string {
+	root, err := r.findResourceInFile(filePath, kind, namespace, name)
+	if err != nil {
+		log.Debug().Err(err).Str("path", filePath).Msg("Failed to read data keys")
+		return nil
+	}
+	var keys []string
+	for _, section := range []string{"data", "binaryData", "stringData"} {
+		m := getMappingValue(root, section)
+		if m == nil || m.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			if !slices.Contains(keys, m.Content[i].Value) {
+				keys = append(keys, m.Content[i].Value)
+			}
+		}
+	}
+	return keys
+}
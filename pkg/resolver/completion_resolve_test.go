@@ -0,0 +1,91 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestResolveCompletionItem(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cmPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cmPath, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: prod
+data:
+  LOG_LEVEL: info
+binaryData:
+  logo.png: aGVsbG8=
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+	idx.IndexFile(cmPath)
+
+	items, err := r.Completion(context.Background(), `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: a
+`, 11, 19)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected one completion, got %v (err %v)", items, err)
+	}
+	if items[0].Documentation != nil {
+		t.Error("Expected completion to leave the documentation to resolve")
+	}
+
+	// The item makes a round trip through the client as JSON.
+	raw, err := json.Marshal(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item protocol.CompletionItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := r.ResolveCompletionItem(item)
+	doc, ok := resolved.Documentation.(protocol.MarkupContent)
+	if !ok {
+		t.Fatalf("Expected markdown documentation, got %#v", resolved.Documentation)
+	}
+	for _, want := range []string{"File: " + cmPath, "Namespace: prod", "`LOG_LEVEL`", "`logo.png`"} {
+		if !strings.Contains(doc.Value, want) {
+			t.Errorf("Expected the documentation to contain %q, got %q", want, doc.Value)
+		}
+	}
+
+	store.Add(&indexer.K8sResource{Kind: "Service", Name: "web", Namespace: "prod", FilePath: "/tmp/svc.yaml", Labels: map[string]string{"tier": "front", "app": "web"}})
+	resolved = r.ResolveCompletionItem(protocol.CompletionItem{Label: "web", Data: CompletionData{Kind: "Service", Namespace: "prod", Name: "web"}})
+	if doc, ok := resolved.Documentation.(protocol.MarkupContent); !ok || !strings.Contains(doc.Value, "Labels:\n- `app: web`\n- `tier: front`") || strings.Contains(doc.Value, "Data keys") {
+		t.Errorf("Expected the Service's sorted labels, got %#v", resolved.Documentation)
+	}
+
+	store.RemoveByFile(cmPath)
+	if resolved := r.ResolveCompletionItem(item); resolved.Documentation != nil {
+		t.Errorf("Expected a removed resource to leave the item unchanged, got %v", resolved.Documentation)
+	}
+}
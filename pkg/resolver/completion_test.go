@@ -1,10 +1,15 @@
 package resolver
 
 import (
+	"context"
+	"reflect"
+	"strings"
 	"testing"
 
 	"k8s-lsp/pkg/config"
 	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
 func TestCompletion(t *testing.T) {
@@ -69,7 +74,7 @@ spec:
 	col := 20
 
 	// 5. Call Completion
-	items, err := r.Completion(yamlContent, line, col)
+	items, err := r.Completion(context.Background(), yamlContent, line, col)
 
 	// 6. Assertions
 	if err != nil {
@@ -99,3 +104,233 @@ spec:
 		t.Error("Did not find other-service in completion items")
 	}
 }
+
+func TestLabelSelectorCompletion(t *testing.T) {
+	cfg := &config.Config{
+		References: []config.Reference{
+			{
+				Name:       "service.selector.label",
+				Symbol:     "k8s.label",
+				TargetKind: "Pod",
+				Match:      config.ReferenceMatch{Kinds: []string{"Service"}, Path: "spec.selector"},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "Pod", Name: "foo", Labels: map[string]string{"app": "foo"}, FilePath: "/tmp/foo.yaml"})
+	store.Add(&indexer.K8sResource{Kind: "Pod", Name: "bar", Labels: map[string]string{"app": "bar", "tier": "web"}, FilePath: "/tmp/bar.yaml"})
+	r := NewResolver(store, cfg)
+
+	labels := func(items []protocol.CompletionItem) []string {
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Label)
+		}
+		return got
+	}
+
+	// "    app: " on line 6: the values of app.
+	serviceYaml := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: 
+`
+	items, err := r.Completion(context.Background(), serviceYaml, 6, 9)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if got := labels(items); !reflect.DeepEqual(got, []string{"bar", "foo"}) {
+		t.Errorf("Expected both app values, got %v", got)
+	}
+
+	// Deployments select through spec.selector.matchLabels; on a key,
+	// whole pairs are offered.
+	deploymentYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: foo
+`
+	items, err = r.Completion(context.Background(), deploymentYaml, 7, 7)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if got := labels(items); !reflect.DeepEqual(got, []string{"app: bar", "app: foo", "tier: web"}) {
+		t.Errorf("Expected every label pair, got %v", got)
+	}
+}
+
+func TestNamespaceCompletion(t *testing.T) {
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "Namespace", Name: "prod", FilePath: "/tmp/namespaces.yaml"})
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "prod", FilePath: "/tmp/app.yaml"})
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "staging", FilePath: "/tmp/staging.yaml"})
+	r := NewResolver(store, &config.Config{})
+
+	items, err := r.Completion(context.Background(), `apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: 
+`, 4, 13)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	details := make(map[string]string)
+	for _, item := range items {
+		details[item.Label] = *item.Detail
+	}
+	if len(details) != 2 {
+		t.Fatalf("Expected prod and staging, got %v", details)
+	}
+	if details["prod"] != "Namespace defined in /tmp/namespaces.yaml" {
+		t.Errorf("Expected prod to be defined by its manifest, got %q", details["prod"])
+	}
+	if details["staging"] != "Namespace (inferred from usage)" {
+		t.Errorf("Expected staging to be inferred, got %q", details["staging"])
+	}
+}
+
+func TestCompletionRanksSameNamespace(t *testing.T) {
+	cfg := &config.Config{
+		References: []config.Reference{
+			{
+				Name:       "configmap-ref",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Deployment"},
+					Path:  "spec.template.spec.volumes.configMap.name",
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	for _, res := range []*indexer.K8sResource{
+		{Kind: "ConfigMap", Name: "a-dev-only", Namespace: "dev", FilePath: "/tmp/dev.yaml"},
+		{Kind: "ConfigMap", Name: "settings", Namespace: "dev", FilePath: "/tmp/dev.yaml"},
+		{Kind: "ConfigMap", Name: "settings", Namespace: "prod", FilePath: "/tmp/prod.yaml"},
+		{Kind: "ConfigMap", Name: "z-prod-only", Namespace: "prod", FilePath: "/tmp/prod.yaml"},
+	} {
+		store.Add(res)
+	}
+	r := NewResolver(store, cfg)
+
+	yamlContent := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: s
+`
+	items, err := r.Completion(context.Background(), yamlContent, 11, 19)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	var got []string
+	for i, item := range items {
+		_, namespace, _ := strings.Cut(*item.Detail, "Namespace: ")
+		got = append(got, namespace+"/"+item.Label)
+		if i > 0 && *item.SortText <= *items[i-1].SortText {
+			t.Errorf("Expected increasing sort text, got %q after %q", *item.SortText, *items[i-1].SortText)
+		}
+	}
+	want := []string{"prod/settings", "prod/z-prod-only", "dev/a-dev-only", "dev/settings"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected prod ConfigMaps first, got %v", got)
+	}
+}
+
+func TestSchemaCompletion(t *testing.T) {
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, &config.Config{}).IndexContent("/repo/crd.yaml", `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: databases.example.com
+spec:
+  group: example.com
+  names:
+    kind: Database
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              required: [size]
+              properties:
+                size:
+                  type: integer
+                  description: Number of replicas.
+                engine:
+                  type: string
+                  enum: [postgres, mysql]
+                storage:
+                  type: object
+`)
+	r := NewResolver(store, &config.Config{})
+
+	labels := func(items []protocol.CompletionItem) []string {
+		var labels []string
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+
+	// A key being typed under spec, next to one already set.
+	items, err := r.Completion(context.Background(), `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: orders
+spec:
+  engine: mysql
+  s:
+`, 6, 3)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if got := labels(items); !reflect.DeepEqual(got, []string{"size", "storage"}) {
+		t.Fatalf("Expected spec's unset properties, got %v", got)
+	}
+	if size := items[0]; size.Detail == nil || *size.Detail != "integer (required)" || size.Documentation != "Number of replicas." {
+		t.Errorf("Expected size's type and description, got %+v", size)
+	}
+
+	// A word alone under spec parses as its value.
+	items, _ = r.Completion(context.Background(), `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: orders
+spec:
+  s
+`, 5, 3)
+	if got := labels(items); !reflect.DeepEqual(got, []string{"engine", "size", "storage"}) {
+		t.Fatalf("Expected every spec property, got %v", got)
+	}
+
+	items, _ = r.Completion(context.Background(), `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: orders
+spec:
+  engine: 
+`, 5, 10)
+	if got := labels(items); !reflect.DeepEqual(got, []string{"postgres", "mysql"}) {
+		t.Fatalf("Expected engine's enum values, got %v", got)
+	}
+}
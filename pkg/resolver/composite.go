@@ -0,0 +1,34 @@
+package resolver
+
+import (
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// compositeReference splits a reference value packed as the rule's
+// compositePath (e.g. "foo-ns/my-secret") into the namespace and name to
+// look up. The namespace falls back to ns when the value omits it, and
+// origin is narrowed to the name part so only the name is clickable.
+func compositeReference(format string, node *yaml.Node, value, ns string, origin protocol.Range) (string, string, protocol.Range, bool) {
+	namespace, name, offset, ok := indexer.SplitComposite(format, value)
+	if !ok {
+		return "", "", origin, false
+	}
+	if namespace == "" {
+		namespace = ns
+	}
+	// Interpolated values no longer line up with the document text.
+	if value == node.Value {
+		start := node.Column - 1 + offset
+		if node.Style == yaml.DoubleQuotedStyle || node.Style == yaml.SingleQuotedStyle {
+			start++
+		}
+		origin = protocol.Range{
+			Start: protocol.Position{Line: uint32(node.Line - 1), Character: uint32(start)},
+			End:   protocol.Position{Line: uint32(node.Line - 1), Character: uint32(start + len(name))},
+		}
+	}
+	return namespace, name, origin, true
+}
@@ -0,0 +1,92 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestCompositeReferenceResolvesNamespaceAndName(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Secret", "SecretMirror"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:          "secretmirror.source",
+				Symbol:        "k8s.resource.name",
+				TargetKind:    "Secret",
+				CompositePath: "namespace/name",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"SecretMirror"},
+					Path:  "spec.source",
+				},
+			},
+		},
+	}
+
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	secrets := `apiVersion: v1
+kind: Secret
+metadata:
+  name: my-secret
+  namespace: app
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-secret
+  namespace: foo-ns
+`
+	idx.IndexContent("/tmp/secrets.yaml", secrets)
+
+	yml := `apiVersion: example.com/v1
+kind: SecretMirror
+metadata:
+  name: mirror
+  namespace: app
+spec:
+  source: foo-ns/my-secret
+`
+	idx.IndexContent("/tmp/mirror.yaml", yml)
+
+	// "  source: foo-ns/my-secret" on line 6; the name starts at column 17.
+	links, err := r.ResolveDefinition(context.Background(), yml, "file:///tmp/mirror.yaml", 6, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetRange.Start.Line != 9 {
+		t.Fatalf("Expected Secret my-secret in foo-ns, got %v", links)
+	}
+	origin := links[0].OriginSelectionRange
+	if origin == nil || origin.Start.Character != 17 || origin.End.Character != 26 {
+		t.Errorf("Expected origin range to cover only the name (17-26), got %v", origin)
+	}
+
+	mirror := store.Get("SecretMirror", "app", "mirror")
+	if mirror == nil || len(mirror.References) != 1 {
+		t.Fatalf("Expected one indexed reference, got %v", mirror)
+	}
+	ref := mirror.References[0]
+	if ref.Namespace != "foo-ns" || ref.Name != "my-secret" || ref.Col != 17 {
+		t.Errorf("Expected foo-ns/my-secret at column 17, got %+v", ref)
+	}
+
+	locs, err := r.ResolveReferences(context.Background(), secrets, "file:///tmp/secrets.yaml", 9, 10)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].URI != "file:///tmp/mirror.yaml" || locs[0].Range.Start.Character != 17 {
+		t.Errorf("Expected the mirror's source at column 17, got %v", locs)
+	}
+}
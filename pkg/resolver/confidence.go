@@ -0,0 +1,170 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Confidence tells how closely a resolved reference matched its target, so
+// clients can show exact matches first and fallbacks as secondary.
+type Confidence string
+
+const (
+	// ConfidenceExact matches kind, namespace and name, or the uid.
+	ConfidenceExact Confidence = "exact"
+	// ConfidenceNamespaceFallback matched in "default" after the
+	// reference's own namespace had no such resource.
+	ConfidenceNamespaceFallback Confidence = "namespaceFallback"
+	// ConfidenceKindFallback matched the kind only ignoring case.
+	ConfidenceKindFallback Confidence = "kindFallback"
+	// ConfidenceNamePrefix matched a resource's generateName prefix.
+	ConfidenceNamePrefix Confidence = "namePrefix"
+)
+
+// resolvedTarget is the resource a reference resolved to and how.
+type resolvedTarget struct {
+	res        *indexer.K8sResource
+	confidence Confidence
+}
+
+// lookupTarget is lookupReference, reporting whether Store.Lookup had to
+// fall back to "default".
+func (r *Resolver) lookupTarget(kind, namespace, name string) (resolvedTarget, bool) {
+	if kind == "Namespace" || indexer.IsClusterScoped(kind) {
+		res := r.lookupResource(kind, namespace, name)
+		return resolvedTarget{res, ConfidenceExact}, res != nil
+	}
+	res, found := r.Store.Lookup(kind, namespace, name)
+	if res == nil {
+		return resolvedTarget{}, false
+	}
+	confidence := ConfidenceExact
+	if r.canonicalNamespace(found) != r.canonicalNamespace(namespace) {
+		confidence = ConfidenceNamespaceFallback
+	}
+	return resolvedTarget{res, r.kindConfidence(kind, res, confidence)}, true
+}
+
+// kindConfidence downgrades confidence when res only matches kind ignoring
+// case.
+func (r *Resolver) kindConfidence(kind string, res *indexer.K8sResource, confidence Confidence) Confidence {
+	if confidence == ConfidenceExact && res.Kind != kind {
+		return ConfidenceKindFallback
+	}
+	return confidence
+}
+
+// DetailedLocation is a location with the confidence of its match.
+type DetailedLocation struct {
+	protocol.Location
+	Confidence Confidence `json:"confidence"`
+}
+
+// DetailedReferences is the result of k8s.detailedReferences: the resource
+// at or referenced from a position, and the references to it.
+type DetailedReferences struct {
+	Target     *DetailedLocation  `json:"target"`
+	References []DetailedLocation `json:"references"`
+}
+
+// DetailedReferences resolves the indexed reference or resource name at a
+// position like definition and references do, labeling each result with
+// its Confidence. References that only resolve through a fallback are
+// included unless exactOnly is set; plain references leave them out.
+func (r *Resolver) DetailedReferences(ctx context.Context, uri string, line, col int, exactOnly bool) *DetailedReferences {
+	target, ok := r.targetAt(uri, line, col)
+	if !ok {
+		return nil
+	}
+	result := &DetailedReferences{
+		Target:     &DetailedLocation{Location: resourceLocation(target.res), Confidence: target.confidence},
+		References: []DetailedLocation{},
+	}
+	for _, ref := range r.detailedReferences(ctx, target.res) {
+		if exactOnly && ref.Confidence != ConfidenceExact {
+			continue
+		}
+		result.References = append(result.References, ref)
+	}
+	return result
+}
+
+// targetAt returns the target of the indexed reference at a position,
+// falling back to "default" like definition does for reference rules, or
+// the resource whose name is there.
+func (r *Resolver) targetAt(uri string, line, col int) (resolvedTarget, bool) {
+	if target, ok := r.indexedReferenceResolution(uri, line, col); ok {
+		return target, true
+	}
+	for _, res := range r.Store.FindByFile(uriPath(uri)) {
+		for _, ref := range res.References {
+			if !isResourceNameReference(ref) || ref.Line != line || col < ref.Col || col > ref.Col+len(ref.Name) {
+				continue
+			}
+			if target, ok := r.lookupTarget(ref.Kind, referenceNamespace(res, ref), ref.Name); ok {
+				return target, true
+			}
+		}
+		if res.Line == line && col >= res.Col && col <= res.Col+len(res.Name) {
+			return resolvedTarget{res, ConfidenceExact}, true
+		}
+	}
+	return resolvedTarget{}, false
+}
+
+// detailedReferences lists the name references to target. Those in its own
+// namespace are exact; those from another namespace lacking the name
+// resolve to a target in "default" as a fallback, as definition does.
+func (r *Resolver) detailedReferences(ctx context.Context, target *indexer.K8sResource) []DetailedLocation {
+	targetNamespace := r.canonicalNamespace(target.Namespace)
+	var locations []DetailedLocation
+	for i, res := range r.Store.FindReferences(target.Kind, target.Name) {
+		if i%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil
+		}
+		for _, ref := range res.References {
+			if !isResourceNameReference(ref) || ref.Name != target.Name || !strings.EqualFold(ref.Kind, target.Kind) {
+				continue
+			}
+			if ref.UID != "" && ref.UID != target.UID && target.UID != "" {
+				continue
+			}
+			confidence := ConfidenceExact
+			if !indexer.IsClusterScoped(target.Kind) {
+				ns := r.canonicalNamespace(referenceNamespace(res, ref))
+				switch {
+				case ns == targetNamespace:
+				case targetNamespace == "default" && r.Store.Get(target.Kind, ns, target.Name) == nil:
+					confidence = ConfidenceNamespaceFallback
+				default:
+					continue
+				}
+			}
+			locations = append(locations, DetailedLocation{
+				Location: protocol.Location{
+					URI: fileURI(res.FilePath),
+					Range: protocol.Range{
+						Start: protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col)},
+						End:   protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col + len(ref.Name))},
+					},
+				},
+				Confidence: r.kindConfidence(ref.Kind, target, confidence),
+			})
+		}
+	}
+	return locations
+}
+
+func resourceLocation(res *indexer.K8sResource) protocol.Location {
+	return protocol.Location{
+		URI: fileURI(res.FilePath),
+		Range: protocol.Range{
+			Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+			End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+		},
+	}
+}
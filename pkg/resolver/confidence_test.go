@@ -0,0 +1,133 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestDetailedReferencesConfidence(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`)
+	deployment := func(namespace string) string {
+		return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: ` + namespace + `
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: settings
+`
+	}
+	idx.IndexContent("/tmp/default.yaml", deployment("default"))
+	idx.IndexContent("/tmp/prod.yaml", deployment("prod"))
+
+	// From the references, the one in default matches exactly and the one
+	// in prod only through the fallback to default.
+	for _, tt := range []struct {
+		uri  string
+		want Confidence
+	}{
+		{"file:///tmp/default.yaml", ConfidenceExact},
+		{"file:///tmp/prod.yaml", ConfidenceNamespaceFallback},
+	} {
+		result := r.DetailedReferences(context.Background(), tt.uri, 11, 20, false)
+		if result == nil || result.Target.URI != "file:///tmp/config.yaml" || result.Target.Confidence != tt.want {
+			t.Errorf("Expected %s to resolve the ConfigMap with %s confidence, got %+v", tt.uri, tt.want, result)
+		}
+	}
+
+	result := r.DetailedReferences(context.Background(), "file:///tmp/config.yaml", 3, 8, false)
+	if result == nil || result.Target.Confidence != ConfidenceExact {
+		t.Fatalf("Expected the ConfigMap itself as an exact target, got %+v", result)
+	}
+	got := make(map[string]Confidence)
+	for _, ref := range result.References {
+		got[ref.URI] = ref.Confidence
+	}
+	if len(got) != 2 || got["file:///tmp/default.yaml"] != ConfidenceExact || got["file:///tmp/prod.yaml"] != ConfidenceNamespaceFallback {
+		t.Errorf("Expected an exact and a fallback reference, got %v", got)
+	}
+
+	result = r.DetailedReferences(context.Background(), "file:///tmp/config.yaml", 3, 8, true)
+	if len(result.References) != 1 || result.References[0].URI != "file:///tmp/default.yaml" {
+		t.Errorf("Expected only the exact reference, got %+v", result.References)
+	}
+
+}
+
+func TestDetailedReferencesNamePrefix(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{{
+			Name: "k8s.resource.name",
+			Definitions: []config.SymbolDefinition{
+				{Kinds: []string{"Pod", "ReplicaSet", "ConfigMap"}, Path: "metadata.name"},
+			},
+		}},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+	idx.IndexContent("/tmp/rs.yaml", `apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  generateName: api-
+`)
+	idx.IndexContent("/tmp/pod.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: api-5c6b4-x1
+  ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: api-5c6b4
+`)
+	result := r.DetailedReferences(context.Background(), "file:///tmp/pod.yaml", 7, 14, false)
+	if result == nil || result.Target.URI != "file:///tmp/rs.yaml" || result.Target.Confidence != ConfidenceNamePrefix {
+		t.Errorf("Expected the generateName ReplicaSet with namePrefix confidence, got %+v", result)
+	}
+
+	// Only owner references fall back to a generateName prefix.
+	cfg.References = []config.Reference{{
+		Name:       "pod.volume.configMap",
+		Symbol:     "k8s.resource.name",
+		TargetKind: "ConfigMap",
+		Match:      config.ReferenceMatch{Kinds: []string{"Pod"}, Path: "spec.volumes[].configMap.name"},
+	}}
+	idx.IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  generateName: api-
+`)
+	idx.IndexContent("/tmp/pod.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: api-5c6b4-x1
+spec:
+  volumes:
+    - name: config
+      configMap:
+        name: api-settings
+`)
+	if result := r.DetailedReferences(context.Background(), "file:///tmp/pod.yaml", 8, 15, false); result != nil {
+		t.Errorf("Expected no target for a ConfigMap that merely shares the prefix, got %+v", result)
+	}
+}
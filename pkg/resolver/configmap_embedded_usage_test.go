@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -38,7 +39,7 @@ data:
 `
 
 	// Cursor on the key "app.conf" (line 7 in YAML, 0-based line=6; column starts after two spaces)
-	locs, err := r.ResolveReferences(yamlContent, "file:///tmp/cm.yaml", 6, 2)
+	locs, err := r.ResolveReferences(context.Background(), yamlContent, "file:///tmp/cm.yaml", 6, 2)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -86,7 +87,7 @@ data:
     hello
 `
 
-	hover, err := r.ResolveHover(yamlContent, "file:///tmp/cm.yaml", 6, 2)
+	hover, err := r.ResolveHover(context.Background(), yamlContent, "file:///tmp/cm.yaml", 6, 2)
 	if err != nil {
 		t.Fatalf("ResolveHover failed: %v", err)
 	}
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"testing"
 
 	"k8s-lsp/pkg/config"
@@ -63,7 +64,7 @@ spec:
 	//     name: my-secret
 	// 01234567890
 
-	locs, err := res.ResolveDefinition(esYaml, "es.yaml", 8, 11)
+	locs, err := res.ResolveDefinition(context.Background(), esYaml, "es.yaml", 8, 11)
 	if err != nil {
 		t.Fatalf("ResolveDefinition failed: %v", err)
 	}
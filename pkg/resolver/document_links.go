@@ -0,0 +1,82 @@
+package resolver
+
+import (
+	"io"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultRegistryURLs are the built-in image links; see
+// config.Settings.ImageRegistryURLs.
+var defaultRegistryURLs = map[string]string{
+	"docker.io": "https://hub.docker.com/r/{repository}",
+	"ghcr.io":   "https://ghcr.io/{repository}",
+	"quay.io":   "https://quay.io/repository/{repository}",
+	"gcr.io":    "https://gcr.io/{repository}",
+}
+
+// DocumentLinks links every container image in the document to its page in
+// the registry's web UI. The link covers the image string only.
+func (r *Resolver) DocumentLinks(docContent string) []protocol.DocumentLink {
+	var links []protocol.DocumentLink
+	decoder := position.NewDecoder(docContent)
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msg("Stopping document links at parse error")
+			}
+			break
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+
+		for _, item := range indexer.ListItems(&node) {
+			podSpec := findPodSpecNode(item)
+			for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+				containers := getMappingValue(podSpec, field)
+				if containers == nil || containers.Kind != yaml.SequenceNode {
+					continue
+				}
+				for _, container := range containers.Content {
+					imageNode := getMappingScalarValue(container, "image")
+					if imageNode == nil || imageNode.Value == "" {
+						continue
+					}
+					target, tooltip := r.imageURL(imageNode.Value)
+					links = append(links, protocol.DocumentLink{
+						Range:   scalarValueRange(imageNode),
+						Target:  &target,
+						Tooltip: &tooltip,
+					})
+				}
+			}
+		}
+	}
+	return links
+}
+
+// imageURL returns the registry page of image and a tooltip describing it.
+func (r *Resolver) imageURL(image string) (string, string) {
+	registry, repository := indexer.ParseImage(image)
+	tooltip := "Open " + repository + " on " + registry
+
+	if template, ok := r.Config.Settings.ImageRegistryURLs[registry]; ok {
+		return strings.ReplaceAll(template, "{repository}", repository), tooltip
+	}
+	// Official Docker Hub images have their own pages.
+	if name, ok := strings.CutPrefix(repository, "library/"); ok && registry == "docker.io" {
+		return "https://hub.docker.com/_/" + name, tooltip
+	}
+	if template, ok := defaultRegistryURLs[registry]; ok {
+		return strings.ReplaceAll(template, "{repository}", repository), tooltip
+	}
+	return "https://" + registry, tooltip
+}
@@ -0,0 +1,72 @@
+package resolver
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDocumentLinks_Images(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{
+			ImageRegistryURLs: map[string]string{"registry.corp": "https://registry.corp/ui/{repository}"},
+		},
+	}
+	r := NewResolver(indexer.NewStore(), cfg)
+
+	content := `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: nightly
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+            - name: init
+              image: "nginx:1.25"
+          containers:
+            - name: app
+              image: ghcr.io/org/app:v1.2.3
+            - name: pinned
+              image: quay.io/org/tool@sha256:0123abcd
+            - name: hub
+              image: bitnami/redis
+            - name: corp
+              image: registry.corp/team/svc:latest
+            - name: other
+              image: registry.example.com:5000/team/svc
+`
+	links := r.DocumentLinks(content)
+	want := []struct {
+		line, start, end uint32
+		target           string
+	}{
+		{11, 22, 32, "https://hub.docker.com/_/nginx"},
+		{14, 21, 43, "https://ghcr.io/org/app"},
+		{16, 21, 53, "https://quay.io/repository/org/tool"},
+		{18, 21, 34, "https://hub.docker.com/r/bitnami/redis"},
+		{20, 21, 50, "https://registry.corp/ui/team/svc"},
+		{22, 21, 55, "https://registry.example.com:5000"},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("Expected %d links, got %d", len(want), len(links))
+	}
+	for i, w := range want {
+		link := links[i]
+		wantRange := protocol.Range{
+			Start: protocol.Position{Line: w.line, Character: w.start},
+			End:   protocol.Position{Line: w.line, Character: w.end},
+		}
+		if link.Range != wantRange {
+			t.Errorf("Link %d: expected range %v, got %v", i, wantRange, link.Range)
+		}
+		if link.Target == nil || *link.Target != w.target {
+			t.Errorf("Link %d: expected target %s, got %v", i, w.target, link.Target)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package resolver
+
+import (
+	"io"
+	"strings"
+
+	"k8s-lsp/pkg/position"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentSymbols returns an outline with one symbol per YAML document
+// ("Kind/name") and children for containers, volumes and ConfigMap/Secret
+// data keys. Documents after a parse error are omitted.
+func (r *Resolver) DocumentSymbols(docContent string) []protocol.DocumentSymbol {
+	lines := contentLines(docContent)
+	separators := documentSeparators(lines)
+
+	var symbols []protocol.DocumentSymbol
+	decoder := position.NewDecoder(docContent)
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msg("Stopping document outline at parse error")
+			}
+			break
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		root := node.Content[0]
+
+		kindNode := getMappingScalarValue(root, "kind")
+		if kindNode == nil || kindNode.Value == "" {
+			continue
+		}
+		kind := kindNode.Value
+
+		name := kind
+		selection := nodeRange(kindNode)
+		if nameNode := getMappingScalarValue(getMappingValue(root, "metadata"), "name"); nameNode != nil && nameNode.Value != "" {
+			name = kind + "/" + nameNode.Value
+			selection = nodeRange(nameNode)
+		}
+
+		apiVersion := ""
+		if apiVersionNode := getMappingScalarValue(root, "apiVersion"); apiVersionNode != nil {
+			apiVersion = apiVersionNode.Value
+		}
+		detail := resourceDetail(apiVersion, kind)
+
+		symbols = append(symbols, protocol.DocumentSymbol{
+			Name:           name,
+			Detail:         &detail,
+			Kind:           symbolKindForResource(kind),
+			Range:          documentRange(lines, separators, root.Line-1),
+			SelectionRange: selection,
+			Children:       documentSectionSymbols(lines, root, kind),
+		})
+	}
+	return symbols
+}
+
+func documentSectionSymbols(lines []string, root *yaml.Node, kind string) []protocol.DocumentSymbol {
+	var children []protocol.DocumentSymbol
+
+	if podSpec := findPodSpecNode(root); podSpec != nil {
+		for _, field := range []string{"initContainers", "containers"} {
+			children = append(children, namedItemSymbols(lines, getMappingValue(podSpec, field), protocol.SymbolKindObject)...)
+		}
+		children = append(children, namedItemSymbols(lines, getMappingValue(podSpec, "volumes"), protocol.SymbolKindField)...)
+	}
+
+	if kind == "ConfigMap" || kind == "Secret" {
+		for _, field := range []string{"data", "stringData", "binaryData"} {
+			data := getMappingValue(root, field)
+			if data == nil || data.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(data.Content); i += 2 {
+				keyNode := data.Content[i]
+				children = append(children, protocol.DocumentSymbol{
+					Name:           keyNode.Value,
+					Kind:           protocol.SymbolKindKey,
+					Range:          spanRange(lines, keyNode, data.Content[i+1]),
+					SelectionRange: nodeRange(keyNode),
+				})
+			}
+		}
+	}
+
+	return children
+}
+
+// namedItemSymbols returns a symbol per sequence item, named by its "name" field.
+func namedItemSymbols(lines []string, seq *yaml.Node, kind protocol.SymbolKind) []protocol.DocumentSymbol {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var symbols []protocol.DocumentSymbol
+	for _, item := range seq.Content {
+		nameNode := getMappingScalarValue(item, "name")
+		if nameNode == nil || nameNode.Value == "" {
+			continue
+		}
+		symbols = append(symbols, protocol.DocumentSymbol{
+			Name:           nameNode.Value,
+			Kind:           kind,
+			Range:          spanRange(lines, item, item),
+			SelectionRange: nodeRange(nameNode),
+		})
+	}
+	return symbols
+}
+
+// contentLines splits content into lines; a trailing newline doesn't start
+// another line.
+func contentLines(content string) []string {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// documentSeparators returns the indexes of the "---" lines.
+func documentSeparators(lines []string) []int {
+	var separators []int
+	for i, line := range lines {
+		if isDocumentSeparator(line) {
+			separators = append(separators, i)
+		}
+	}
+	return separators
+}
+
+func isDocumentSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+	rest := line[3:]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r'
+}
+
+// documentRange spans the lines between the separators around startLine; the
+// last document runs to the end of the content.
+func documentRange(lines []string, separators []int, startLine int) protocol.Range {
+	first, last := 0, len(lines)-1
+	for _, sep := range separators {
+		if sep < startLine {
+			first = sep + 1
+		} else {
+			last = sep - 1
+			break
+		}
+	}
+	if last < first {
+		last = first
+	}
+	end := 0
+	if last < len(lines) {
+		end = len(strings.TrimSuffix(lines[last], "\r"))
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(first), Character: 0},
+		End:   protocol.Position{Line: uint32(last), Character: uint32(end)},
+	}
+}
+
+// spanRange covers start through the end of the last scalar below end.
+func spanRange(lines []string, start, end *yaml.Node) protocol.Range {
+	endLine, endCol := nodeEnd(lines, end)
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(start.Line - 1), Character: uint32(start.Column - 1)},
+		End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(endCol - 1)},
+	}
+}
+
+// nodeEnd returns the 1-based line and column just past the last scalar in n.
+// Block scalars end with the last line of their content.
+func nodeEnd(lines []string, n *yaml.Node) (int, int) {
+	line, col := n.Line, n.Column+len(n.Value)
+	if n.Kind == yaml.ScalarNode && (n.Style == yaml.LiteralStyle || n.Style == yaml.FoldedStyle) {
+		valueLines := strings.Split(strings.TrimSuffix(n.Value, "\n"), "\n")
+		line = n.Line + len(valueLines)
+		if line > len(lines) {
+			line = len(lines)
+		}
+		return line, len(strings.TrimSuffix(lines[line-1], "\r")) + 1
+	}
+	for _, child := range n.Content {
+		l, c := nodeEnd(lines, child)
+		if l > line || (l == line && c > col) {
+			line, col = l, c
+		}
+	}
+	return line, col
+}
+
+func nodeRange(n *yaml.Node) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(n.Line - 1), Character: uint32(n.Column - 1)},
+		End:   protocol.Position{Line: uint32(n.Line - 1), Character: uint32(n.Column - 1 + len(n.Value))},
+	}
+}
@@ -0,0 +1,80 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDocumentSymbols_MultiDocument(t *testing.T) {
+	content := strings.TrimLeft(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+      - name: sidecar
+        image: busybox
+      volumes:
+      - name: config
+        configMap:
+          name: web-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  app.conf: |
+    listen 80;
+    root /srv;
+  mode: prod`, "\n")
+
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+	symbols := r.DocumentSymbols(content)
+	if len(symbols) != 2 {
+		t.Fatalf("Expected 2 document symbols, got %d", len(symbols))
+	}
+
+	deploy := symbols[0]
+	if deploy.Name != "Deployment/web" || deploy.Kind != protocol.SymbolKindClass {
+		t.Errorf("Unexpected first symbol %q (%v)", deploy.Name, deploy.Kind)
+	}
+	if deploy.Range.Start.Line != 0 || deploy.Range.End.Line != 15 {
+		t.Errorf("Expected first document to span lines 0-15, got %v", deploy.Range)
+	}
+	if deploy.SelectionRange.Start.Line != 3 || deploy.SelectionRange.Start.Character != 8 {
+		t.Errorf("Expected selection range at metadata.name, got %v", deploy.SelectionRange)
+	}
+	var children []string
+	for _, c := range deploy.Children {
+		children = append(children, c.Name)
+	}
+	if strings.Join(children, ",") != "app,sidecar,config" {
+		t.Errorf("Expected container and volume children, got %v", children)
+	}
+
+	cm := symbols[1]
+	if cm.Name != "ConfigMap/web-config" {
+		t.Errorf("Unexpected second symbol %q", cm.Name)
+	}
+	// The last document has no trailing separator or newline.
+	if cm.Range.Start.Line != 17 || cm.Range.End.Line != 25 || cm.Range.End.Character != 12 {
+		t.Errorf("Expected last document to span to the end of content, got %v", cm.Range)
+	}
+	if len(cm.Children) != 2 || cm.Children[0].Name != "app.conf" || cm.Children[1].Name != "mode" {
+		t.Fatalf("Expected data key children, got %v", cm.Children)
+	}
+	if cm.Children[0].Range.End.Line != 24 || cm.Children[0].Range.End.Character != 14 {
+		t.Errorf("Expected block scalar child to end on its last content line, got %v", cm.Children[0].Range)
+	}
+}
@@ -0,0 +1,173 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s-lsp/pkg/config"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// dotenvAssignment matches a KEY=value line of a dotenv file, optionally
+// exported.
+var dotenvAssignment = regexp.MustCompile(`^(export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// dotenvExpansion matches a $NAME or ${NAME} expansion in a dotenv value.
+var dotenvExpansion = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+
+type dotenvEntry struct {
+	name      string
+	line, col int // 0-based, within the embedded file
+}
+
+// parseDotenv returns the variables content assigns when it is in dotenv
+// format: every line blank, a # comment or KEY=value, with at least one
+// assignment. A variable assigned twice keeps its last line, as a shell
+// sourcing the file would.
+func parseDotenv(content string) ([]dotenvEntry, bool) {
+	var entries []dotenvEntry
+	index := make(map[string]int)
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		m := dotenvAssignment.FindStringSubmatchIndex(trimmed)
+		if m == nil {
+			return nil, false
+		}
+		entry := dotenvEntry{
+			name: trimmed[m[4]:m[5]],
+			line: i,
+			col:  len(line) - len(trimmed) + m[4],
+		}
+		if j, ok := index[entry.name]; ok {
+			entries[j] = entry
+			continue
+		}
+		index[entry.name] = len(entries)
+		entries = append(entries, entry)
+	}
+	return entries, len(entries) > 0
+}
+
+// isContainerEnvNamePath matches containers[].env[].name and
+// initContainers[].env[].name.
+func isContainerEnvNamePath(path []string) bool {
+	n := len(path)
+	return n >= 3 && (path[n-3] == "containers" || path[n-3] == "initContainers") && path[n-2] == "env" && path[n-1] == "name"
+}
+
+// dotenvDefinitions links a container env var to the line assigning it in
+// the embedded file of a dotenv-format data entry, for each ConfigMap the
+// workload in root references. Only exact names match.
+func (r *Resolver) dotenvDefinitions(root *yaml.Node, name string, originRange protocol.Range) []protocol.LocationLink {
+	if !r.featureEnabled(config.FeatureEmbeddedFiles) {
+		return nil
+	}
+	ns := findNamespace(root)
+	workload := r.Store.Get(findKind(root), ns, findName(root))
+	if workload == nil {
+		return nil
+	}
+
+	var links []protocol.LocationLink
+	seen := make(map[string]bool)
+	for _, ref := range workload.References {
+		if ref.Kind != "ConfigMap" || seen[ref.Name] {
+			continue
+		}
+		seen[ref.Name] = true
+		res := r.lookupResource("ConfigMap", ns, ref.Name)
+		if res == nil {
+			continue
+		}
+		cmRoot, err := r.findResourceInFile(res.FilePath, "ConfigMap", res.Namespace, res.Name)
+		if err != nil {
+			continue
+		}
+		data := getMappingValue(cmRoot, "data")
+		if data == nil || data.Kind != yaml.MappingNode {
+			continue
+		}
+		cmNamespace := res.Namespace
+		if cmNamespace == "" {
+			cmNamespace = "default"
+		}
+		for i := 0; i+1 < len(data.Content); i += 2 {
+			key := data.Content[i].Value
+			entries, ok := parseDotenv(data.Content[i+1].Value)
+			if !ok {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.name != name {
+					continue
+				}
+				sourceEncoded := base64.URLEncoding.EncodeToString([]byte(fileURI(res.FilePath)))
+				keyEncoded := base64.URLEncoding.EncodeToString([]byte(key))
+				targetRange := protocol.Range{
+					Start: protocol.Position{Line: uint32(entry.line), Character: uint32(entry.col)},
+					End:   protocol.Position{Line: uint32(entry.line), Character: uint32(entry.col + len(entry.name))},
+				}
+				links = append(links, protocol.LocationLink{
+					OriginSelectionRange: &originRange,
+					TargetURI:            fmt.Sprintf("k8s-embedded://%s/%s/%s?source=%s&key=%s", cmNamespace, res.Name, key, sourceEncoded, keyEncoded),
+					TargetRange:          targetRange,
+					TargetSelectionRange: targetRange,
+				})
+			}
+		}
+	}
+	return links
+}
+
+// DotenvDefinition links the $NAME or ${NAME} expansion at line/col of the
+// embedded file uri, whose text is content, to the line assigning NAME in
+// the same file. Files not in dotenv format and names they don't assign
+// give nothing.
+func (r *Resolver) DotenvDefinition(content, uri string, line, col int) []protocol.LocationLink {
+	if !r.featureEnabled(config.FeatureEmbeddedFiles) {
+		return nil
+	}
+	entries, ok := parseDotenv(content)
+	if !ok {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return nil
+	}
+	text := strings.TrimSuffix(lines[line], "\r")
+	for _, m := range dotenvExpansion.FindAllStringSubmatchIndex(text, -1) {
+		if col < m[0] || col > m[1] {
+			continue
+		}
+		name := text[m[2]:m[3]]
+		for _, entry := range entries {
+			if entry.name != name {
+				continue
+			}
+			originRange := protocol.Range{
+				Start: protocol.Position{Line: uint32(line), Character: uint32(m[0])},
+				End:   protocol.Position{Line: uint32(line), Character: uint32(m[1])},
+			}
+			targetRange := protocol.Range{
+				Start: protocol.Position{Line: uint32(entry.line), Character: uint32(entry.col)},
+				End:   protocol.Position{Line: uint32(entry.line), Character: uint32(entry.col + len(entry.name))},
+			}
+			return []protocol.LocationLink{{
+				OriginSelectionRange: &originRange,
+				TargetURI:            uri,
+				TargetRange:          targetRange,
+				TargetSelectionRange: targetRange,
+			}}
+		}
+	}
+	return nil
+}
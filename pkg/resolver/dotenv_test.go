@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestParseDotenv(t *testing.T) {
+	entries, ok := parseDotenv("# settings\nLOG_LEVEL=info\n\nexport  PORT = 8080\nLOG_LEVEL=debug\n")
+	if !ok || len(entries) != 2 {
+		t.Fatalf("Expected two variables, got %v (ok %v)", entries, ok)
+	}
+	if entries[0] != (dotenvEntry{name: "LOG_LEVEL", line: 4, col: 0}) || entries[1] != (dotenvEntry{name: "PORT", line: 3, col: 8}) {
+		t.Errorf("Unexpected entries %v", entries)
+	}
+
+	for _, content := range []string{"", "# only a comment\n", "server {\n  listen 80;\n}\n", "a.b=c\n"} {
+		if _, ok := parseDotenv(content); ok {
+			t.Errorf("Expected %q not to be detected as dotenv", content)
+		}
+	}
+}
+
+func TestContainerEnvToDotenvLine(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	dir := t.TempDir()
+	cmPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cmPath, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-env
+data:
+  app.env: |
+    # runtime settings
+    LOG_LEVEL=info
+    DATABASE_URL=postgres://db
+  nginx.conf: |
+    LOG_LEVEL=ignored, not dotenv
+    server {}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+	idx.IndexFile(cmPath)
+
+	deployYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: DATABASE_URL
+            - name: UNKNOWN
+          volumeMounts:
+            - name: env
+              mountPath: /app/.env
+              subPath: app.env
+      volumes:
+        - name: env
+          configMap:
+            name: app-env
+`
+	idx.IndexContent("/tmp/deploy.yaml", deployYaml)
+
+	links, err := r.ResolveDefinition(context.Background(), deployYaml, "file:///tmp/deploy.yaml", 10, 22)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("Expected one link to the dotenv line, got %v", links)
+	}
+	link := links[0]
+	if !strings.HasPrefix(link.TargetURI, "k8s-embedded://default/app-env/app.env?") {
+		t.Errorf("Expected the embedded app.env file, got %s", link.TargetURI)
+	}
+	if link.TargetRange.Start.Line != 2 || link.TargetRange.Start.Character != 0 || link.TargetRange.End.Character != uint32(len("DATABASE_URL")) {
+		t.Errorf("Expected DATABASE_URL on line 2 of the embedded file, got %v", link.TargetRange)
+	}
+
+	links, err = r.ResolveDefinition(context.Background(), deployYaml, "file:///tmp/deploy.yaml", 11, 22)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("Expected no link for a variable the file doesn't set, got %v", links)
+	}
+}
+
+func TestDotenvExpansionToAssignment(t *testing.T) {
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+	uri := "k8s-embedded://default/app-env/app.env?source=c291cmNl&key=YXBwLmVudg=="
+	content := "HOST=db\nPORT=5432\nURL=postgres://${HOST}:$PORT/app\n"
+
+	// Cursor on "${HOST}" and on "$PORT" of line 2.
+	for _, tc := range []struct {
+		col, line, from, to int
+	}{{17, 0, 15, 22}, {24, 1, 23, 28}} {
+		links := r.DotenvDefinition(content, uri, 2, tc.col)
+		if len(links) != 1 {
+			t.Fatalf("Expected one link at column %d, got %v", tc.col, links)
+		}
+		link := links[0]
+		if link.TargetURI != uri || link.TargetRange.Start.Line != uint32(tc.line) || link.TargetRange.Start.Character != 0 {
+			t.Errorf("Expected the assignment on line %d, got %+v", tc.line, link)
+		}
+		if origin := link.OriginSelectionRange; origin == nil || origin.Start.Character != uint32(tc.from) || origin.End.Character != uint32(tc.to) {
+			t.Errorf("Expected the expansion at %d-%d as origin, got %v", tc.from, tc.to, origin)
+		}
+	}
+
+	if links := r.DotenvDefinition(content, uri, 2, 2); links != nil {
+		t.Errorf("Expected nothing outside an expansion, got %v", links)
+	}
+	if links := r.DotenvDefinition("URL=$UNSET\n", uri, 0, 6); links != nil {
+		t.Errorf("Expected nothing for a name the file doesn't assign, got %v", links)
+	}
+	if links := r.DotenvDefinition("server {\n  root $document_root;\n}\n", uri, 1, 9); links != nil {
+		t.Errorf("Expected nothing in a file that isn't dotenv, got %v", links)
+	}
+}
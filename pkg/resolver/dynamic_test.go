@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"testing"
 
 	"k8s-lsp/pkg/config"
@@ -74,7 +75,7 @@ data:
 	//   ref: target-resource
 	// 01234567
 
-	locs, err := res.ResolveDefinition(sourceYaml, "source.yaml", 5, 8)
+	locs, err := res.ResolveDefinition(context.Background(), sourceYaml, "source.yaml", 5, 8)
 	if err != nil {
 		t.Fatalf("ResolveDefinition failed: %v", err)
 	}
@@ -0,0 +1,118 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// PendingEdit is a text replacement computed from indexed positions.
+// OldText is the text the producer expects to find at Range; it is checked
+// against the current document before the edit is emitted.
+type PendingEdit struct {
+	URI     string
+	Range   protocol.Range
+	OldText string
+	NewText string
+}
+
+// DocumentSource returns the current text of a document. version is non-nil
+// only when the document is open in the client; for unopened files the text
+// is read from disk and version is nil.
+type DocumentSource func(uri string) (content string, version *protocol.Integer, ok bool)
+
+// VerifiedWorkspaceEdit is a WorkspaceEdit whose edits have been re-checked
+// against the current document text. Edits that no longer match are dropped
+// and reported in Warnings instead of being misapplied.
+type VerifiedWorkspaceEdit struct {
+	protocol.WorkspaceEdit
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BuildVerifiedEdit groups edits per document into versioned TextDocumentEdits.
+// Each edit is kept only if the current text at its range equals OldText.
+func BuildVerifiedEdit(edits []PendingEdit, source DocumentSource) VerifiedWorkspaceEdit {
+	var result VerifiedWorkspaceEdit
+
+	byURI := make(map[string][]PendingEdit)
+	var uris []string
+	for _, e := range edits {
+		if _, ok := byURI[e.URI]; !ok {
+			uris = append(uris, e.URI)
+		}
+		byURI[e.URI] = append(byURI[e.URI], e)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		content, version, ok := source(uri)
+		if !ok {
+			for _, e := range byURI[uri] {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s: document not available, skipped edit of %q", uri, e.OldText))
+			}
+			continue
+		}
+
+		var textEdits []any
+		for _, e := range byURI[uri] {
+			current, ok := textInRange(content, e.Range)
+			if !ok || current != e.OldText {
+				warning := fmt.Sprintf("%s:%d:%d: expected %q but found %q, skipped stale edit",
+					uri, e.Range.Start.Line+1, e.Range.Start.Character+1, e.OldText, current)
+				log.Warn().Str("uri", uri).Str("expected", e.OldText).Str("found", current).Msg("Dropping stale workspace edit")
+				result.Warnings = append(result.Warnings, warning)
+				continue
+			}
+			textEdits = append(textEdits, protocol.TextEdit{Range: e.Range, NewText: e.NewText})
+		}
+		if len(textEdits) == 0 {
+			continue
+		}
+
+		result.DocumentChanges = append(result.DocumentChanges, protocol.TextDocumentEdit{
+			TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+				Version:                version,
+			},
+			Edits: textEdits,
+		})
+	}
+
+	return result
+}
+
+// textInRange returns the text covered by r. Positions past the end of a line
+// or of the document are clamped, as clients do when applying edits.
+func textInRange(content string, r protocol.Range) (string, bool) {
+	start := offsetAt(content, r.Start)
+	end := offsetAt(content, r.End)
+	if end < start {
+		return "", false
+	}
+	return content[start:end], true
+}
+
+func offsetAt(content string, pos protocol.Position) int {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		idx := strings.IndexByte(content[offset:], '\n')
+		if idx < 0 {
+			// Past the last line: clamp to the end of the document.
+			return len(content)
+		}
+		offset += idx + 1
+	}
+
+	lineEnd := strings.IndexByte(content[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(content) - offset
+	}
+	col := int(pos.Character)
+	if col > lineEnd {
+		col = lineEnd
+	}
+	return offset + col
+}
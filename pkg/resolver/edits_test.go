@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestBuildVerifiedEdit_WithholdsStaleStoreEntry(t *testing.T) {
+	// The store still believes the Service is called "web", but the file was
+	// edited since indexing and now names it "api".
+	stale := &indexer.K8sResource{Kind: "Service", Name: "web", FilePath: "/tmp/svc.yaml", Line: 3, Col: 8}
+	fresh := &indexer.K8sResource{Kind: "ConfigMap", Name: "web-config", FilePath: "/tmp/cm.yaml", Line: 3, Col: 8}
+
+	docs := map[string]string{
+		"file:///tmp/svc.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n",
+		"file:///tmp/cm.yaml":  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web-config\n",
+	}
+	version := protocol.Integer(7)
+	source := func(uri string) (string, *protocol.Integer, bool) {
+		content, ok := docs[uri]
+		if uri == "file:///tmp/cm.yaml" {
+			return content, &version, ok
+		}
+		return content, nil, ok
+	}
+
+	var edits []PendingEdit
+	for _, res := range []*indexer.K8sResource{stale, fresh} {
+		edits = append(edits, PendingEdit{
+			URI: "file://" + res.FilePath,
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+				End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+			},
+			OldText: res.Name,
+			NewText: "renamed",
+		})
+	}
+
+	result := BuildVerifiedEdit(edits, source)
+
+	if len(result.Warnings) != 1 {
+		t.Fatalf("Expected 1 warning for the stale edit, got %v", result.Warnings)
+	}
+	if len(result.DocumentChanges) != 1 {
+		t.Fatalf("Expected only the verified document edit, got %d", len(result.DocumentChanges))
+	}
+
+	docEdit, ok := result.DocumentChanges[0].(protocol.TextDocumentEdit)
+	if !ok {
+		t.Fatalf("Expected TextDocumentEdit, got %T", result.DocumentChanges[0])
+	}
+	if docEdit.TextDocument.URI != "file:///tmp/cm.yaml" {
+		t.Errorf("Unexpected document %s", docEdit.TextDocument.URI)
+	}
+	if docEdit.TextDocument.Version == nil || *docEdit.TextDocument.Version != 7 {
+		t.Errorf("Expected version 7 for open document, got %v", docEdit.TextDocument.Version)
+	}
+	if len(docEdit.Edits) != 1 {
+		t.Fatalf("Expected 1 text edit, got %d", len(docEdit.Edits))
+	}
+}
+
+func TestBuildVerifiedEdit_UnopenedDocumentHasNullVersion(t *testing.T) {
+	content := "kind: ConfigMap\nmetadata:\n  name: app\n"
+	source := func(uri string) (string, *protocol.Integer, bool) {
+		return content, nil, true
+	}
+
+	result := BuildVerifiedEdit([]PendingEdit{{
+		URI: "file:///tmp/app.yaml",
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 3, Character: 0},
+		},
+		OldText: content,
+		NewText: "kind: ConfigMap\n",
+	}}, source)
+
+	if len(result.Warnings) != 0 {
+		t.Fatalf("Unexpected warnings: %v", result.Warnings)
+	}
+	docEdit := result.DocumentChanges[0].(protocol.TextDocumentEdit)
+	if docEdit.TextDocument.Version != nil {
+		t.Errorf("Expected null version for unopened document, got %d", *docEdit.TextDocument.Version)
+	}
+}
@@ -0,0 +1,76 @@
+package resolver
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestEmbeddedDiff(t *testing.T) {
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+	doc := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  app.properties: |
+    host=localhost
+    port=8080
+    debug=false
+`
+	diff, err := r.EmbeddedDiff(doc, "app.properties", "host=localhost\nport=9090\ndebug=false\n")
+	if err != nil {
+		t.Fatalf("EmbeddedDiff failed: %v", err)
+	}
+	want := `--- a/app.properties
++++ b/app.properties
+@@ -1,3 +1,3 @@
+ host=localhost
+-port=8080
++port=9090
+ debug=false
+`
+	if diff != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", diff, want)
+	}
+
+	if diff, err := r.EmbeddedDiff(doc, "app.properties", "host=localhost\nport=8080\ndebug=false\n"); err != nil || diff != "" {
+		t.Errorf("Expected no diff for unchanged content, got %q (err %v)", diff, err)
+	}
+	if _, err := r.EmbeddedDiff(doc, "missing", "x"); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "x\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+x\n",
+		},
+		{
+			name: "missing final newline",
+			a:    "x\n",
+			b:    "x",
+			want: "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-x\n+x\n\\ No newline at end of file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", tt.a, tt.b); got != tt.want {
+				t.Errorf("unifiedDiff:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestEmbeddedContentSizeLimit(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Settings.DocumentLimits.MaxEmbeddedBytes = 16
+	r := NewResolver(indexer.NewStore(), cfg)
+
+	docContent := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: example
+data:
+  small: ok
+  large: this value is longer than sixteen bytes
+`
+	if got, err := r.ResolveEmbeddedContent(docContent, "small"); err != nil || got != "ok" {
+		t.Fatalf("Expected the small value, got %q (err %v)", got, err)
+	}
+	if _, err := r.ResolveEmbeddedContent(docContent, "large"); !errors.Is(err, ErrEmbeddedContentTooLarge) {
+		t.Errorf("Expected ErrEmbeddedContentTooLarge reading large, got %v", err)
+	}
+	_, err := r.UpdateEmbeddedContent(docContent, "small", strings.Repeat("x", 17))
+	if !errors.Is(err, ErrEmbeddedContentTooLarge) {
+		t.Fatalf("Expected ErrEmbeddedContentTooLarge saving 17 bytes, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "maxEmbeddedBytes") {
+		t.Errorf("Expected the error to name the setting, got %q", err)
+	}
+}
+
+func TestUpdateEmbeddedContentKeepsRestOfDocument(t *testing.T) {
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+
+	docContent := `# app settings
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: example
+data:
+  # the main config
+  app.conf: |
+    old line 1
+    old line 2
+
+  other: 'kept as is'   # trailing comment
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
+`
+	updated, err := r.UpdateEmbeddedContent(docContent, "app.conf", "new line 1\nnew line 2\n")
+	if err != nil {
+		t.Fatalf("UpdateEmbeddedContent failed: %v", err)
+	}
+	want := strings.Replace(docContent, "|\n    old line 1\n    old line 2", "|-\n    new line 1\n    new line 2", 1)
+	if updated != want {
+		t.Errorf("Expected only the value to change, got:\n%s", updated)
+	}
+}
+
+func TestUpdateEmbeddedContentNonASCIIKey(t *testing.T) {
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+
+	for _, key := range []string{"설정", "🚀.conf"} {
+		docContent := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: example\ndata:\n  " + key + ": old-value\n  other: kept\n"
+		updated, err := r.UpdateEmbeddedContent(docContent, key, "new")
+		if err != nil {
+			t.Fatalf("UpdateEmbeddedContent failed: %v", err)
+		}
+		want := strings.Replace(docContent, key+": old-value", key+": |-\n    new", 1)
+		if updated != want {
+			t.Errorf("Expected only the value of %s to change, got:\n%s", key, updated)
+		}
+	}
+}
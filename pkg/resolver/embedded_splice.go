@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// spliceMappingValue replaces the text of value, the block mapping value of
+// key, with its re-encoded form. Everything else in doc (other values,
+// comments, formatting, further documents) is kept byte for byte, which
+// re-serializing the whole document would not. ok is false when the value's
+// extent can't be told from the text.
+func spliceMappingValue(doc string, key, value *yaml.Node) (string, bool) {
+	if key == nil || value == nil || value.Line < key.Line || value.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	// Comments after the value would be swallowed by the splice.
+	if value.LineComment != "" || value.FootComment != "" || key.FootComment != "" {
+		return "", false
+	}
+
+	lines := strings.SplitAfter(doc, "\n")
+	if value.Line > len(lines) {
+		return "", false
+	}
+	lineOffset := make([]int, len(lines)+1)
+	for i, line := range lines {
+		lineOffset[i+1] = lineOffset[i] + len(line)
+	}
+	first := value.Line - 1
+	// position.Decoder reports byte columns.
+	col := value.Column - 1
+	if col < 0 || col > len(lines[first]) {
+		return "", false
+	}
+	start := lineOffset[first] + col
+
+	// The value runs on while lines are blank or indented past the key.
+	keyIndent := key.Column - 1
+	last := first
+	for i := first + 1; i < len(lines); i++ {
+		text := strings.TrimRight(lines[i], "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if len(text)-len(strings.TrimLeft(text, " ")) <= keyIndent {
+			break
+		}
+		last = i
+	}
+	end := lineOffset[last] + len(strings.TrimRight(lines[last], "\r\n"))
+
+	newline := "\n"
+	if strings.HasSuffix(lines[first], "\r\n") {
+		newline = "\r\n"
+	}
+	rendered, ok := renderMappingValue(value, strings.Repeat(" ", keyIndent), newline)
+	if !ok {
+		return "", false
+	}
+	updated := doc[:start] + rendered + doc[end:]
+
+	// Never hand back a document the edit broke.
+	decoder := yaml.NewDecoder(strings.NewReader(updated))
+	for {
+		var n yaml.Node
+		if err := decoder.Decode(&n); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", false
+		}
+	}
+	return updated, true
+}
+
+// renderMappingValue encodes value as it would follow "key: " in a block
+// mapping whose keys are indented by indent.
+func renderMappingValue(value *yaml.Node, indent, newline string) (string, bool) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	m := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: "k"}, value}}
+	if err := encoder.Encode(m); err != nil {
+		return "", false
+	}
+	if err := encoder.Close(); err != nil {
+		return "", false
+	}
+	out, ok := strings.CutPrefix(buf.String(), "k: ")
+	if !ok {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, newline), true
+}
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isEnvFromSourcePath matches envFrom[].configMapRef.name,
+// envFrom[].secretRef.name and envFrom[].prefix.
+func isEnvFromSourcePath(path []string) bool {
+	n := len(path)
+	if n >= 2 && path[n-2] == "envFrom" && path[n-1] == "prefix" {
+		return true
+	}
+	return n >= 3 && path[n-3] == "envFrom" && (path[n-2] == "configMapRef" || path[n-2] == "secretRef") && path[n-1] == "name"
+}
+
+// envFromHover describes the envFrom entry whose name or prefix is target:
+// the ConfigMap or Secret, then every variable it produces, noting those a
+// later source of the same container overrides.
+func (r *Resolver) envFromHover(doc *yaml.Node, kind string, target *yaml.Node) string {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return ""
+	}
+	namespace := findNamespace(doc)
+	for _, sources := range indexer.ContainerEnvFrom(doc.Content[0], kind) {
+		for _, source := range sources {
+			if source.NameNode != target && source.PrefixNode != target {
+				continue
+			}
+			res := r.lookupResource(source.Kind, namespace, source.Name)
+			if res == nil {
+				return ""
+			}
+
+			vars := r.Store.EnvFromVars(sources, namespace)
+			winner := make(map[string]indexer.EnvFromSource)
+			for _, v := range vars {
+				winner[v.Name] = v.Source
+			}
+
+			var sb strings.Builder
+			sb.WriteString(resourceHoverContents(res))
+			if source.Prefix != "" {
+				fmt.Fprintf(&sb, "\n\nPrefix: `%s`", source.Prefix)
+			}
+			sb.WriteString("\n\nProduces:\n")
+			for _, v := range vars {
+				if v.Source != source {
+					continue
+				}
+				fmt.Fprintf(&sb, "\n- `%s` from key `%s`", v.Name, v.Key)
+				if w := winner[v.Name]; w != source {
+					fmt.Fprintf(&sb, " (overridden by %s %s)", w.Kind, w.Name)
+				}
+			}
+			return sb.String()
+		}
+	}
+	return ""
+}
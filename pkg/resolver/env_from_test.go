@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestEnvFromHoverAttributesVariables(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: info
+  PORT: "8080"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: db-config
+data:
+  LOG_LEVEL: warn
+  HOST: db
+`)
+	deployYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - prefix: APP_
+              configMapRef:
+                name: app-config
+            - prefix: DB_
+              configMapRef:
+                name: db-config
+            - configMapRef:
+                name: app-config
+            - configMapRef:
+                name: db-config
+`
+	idx.IndexContent("/tmp/deploy.yaml", deployYaml)
+
+	tests := []struct {
+		name       string
+		line, char int
+		want       []string
+		notWant    []string
+	}{
+		{"prefixed name", 12, 24, []string{"/tmp/config.yaml", "Prefix: `APP_`", "`APP_LOG_LEVEL` from key `LOG_LEVEL`", "`APP_PORT` from key `PORT`"}, []string{"DB_", "overridden"}},
+		{"prefix", 13, 23, []string{"Prefix: `DB_`", "`DB_LOG_LEVEL` from key `LOG_LEVEL`", "`DB_HOST` from key `HOST`"}, []string{"APP_", "overridden"}},
+		{"overridden", 17, 24, []string{"`LOG_LEVEL` from key `LOG_LEVEL` (overridden by ConfigMap db-config)", "- `PORT` from key `PORT`"}, []string{"Prefix"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hover, err := r.ResolveHover(context.Background(), deployYaml, "file:///tmp/deploy.yaml", tt.line, tt.char)
+			if err != nil || hover == nil {
+				t.Fatalf("Expected a hover, got %v (err %v)", hover, err)
+			}
+			value := hover.Contents.(protocol.MarkupContent).Value
+			for _, want := range tt.want {
+				if !strings.Contains(value, want) {
+					t.Errorf("Expected the hover to contain %q, got %q", want, value)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(value, notWant) {
+					t.Errorf("Expected the hover not to contain %q, got %q", notWant, value)
+				}
+			}
+		})
+	}
+
+	// References from a ConfigMap still finds both of its envFrom entries.
+	locs, err := r.ResolveReferences(context.Background(), `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: db-config
+`, "file:///tmp/config.yaml", 3, 8)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	var entries []uint32
+	for _, loc := range locs {
+		if loc.URI == "file:///tmp/deploy.yaml" {
+			entries = append(entries, loc.Range.Start.Line)
+		}
+	}
+	if len(entries) != 2 || entries[0] != 15 || entries[1] != 19 {
+		t.Errorf("Expected both db-config envFrom entries, got %v", locs)
+	}
+}
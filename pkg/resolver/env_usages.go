@@ -0,0 +1,217 @@
+package resolver
+
+import (
+	"context"
+	"path"
+	"slices"
+	"sort"
+
+	"k8s-lsp/pkg/indexer"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvVarUsage is a container consuming a ConfigMap key, as an environment
+// variable (Via "env" or "envFrom") or a mounted file (Via "volume").
+type EnvVarUsage struct {
+	protocol.Location
+	Kind      string `json:"kind"`
+	Workload  string `json:"workload"`
+	Namespace string `json:"namespace"`
+	Container string `json:"container"`
+	Via       string `json:"via"`
+	EnvVar    string `json:"envVar,omitempty"`
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// EnvVarUsages reports every container that consumes key of the ConfigMap
+// namespace/name: env entries reading it through configMapKeyRef, envFrom
+// sources producing it (behind their prefix) and volume mounts exposing it
+// as a file. Workloads are found through their indexed references and then
+// read from disk for the container details. envFrom sources and volumes
+// projecting the whole ConfigMap only count when the indexed ConfigMap has
+// the key, or when the ConfigMap isn't indexed at all.
+func (r *Resolver) EnvVarUsages(ctx context.Context, namespace, name, key string) []EnvVarUsage {
+	if namespace == "" {
+		namespace = "default"
+	}
+	hasKey := true
+	if cm, _ := r.Store.Lookup("ConfigMap", namespace, name); cm != nil {
+		hasKey = slices.ContainsFunc(cm.DataKeys, func(k indexer.DataKey) bool { return k.Key == key })
+	}
+
+	var usages []EnvVarUsage
+	for _, res := range r.Store.FindReferences("ConfigMap", name) {
+		if ctx.Err() != nil {
+			break
+		}
+		if r.canonicalNamespace(res.Namespace) != r.canonicalNamespace(namespace) {
+			continue
+		}
+		root, err := r.findResourceInFile(res.FilePath, res.Kind, res.Namespace, res.Name)
+		if err != nil {
+			log.Debug().Err(err).Str("path", res.FilePath).Msg("Skipping unreadable workload")
+			continue
+		}
+		podSpec := findPodSpecNode(root)
+		if podSpec == nil {
+			continue
+		}
+		workload := EnvVarUsage{
+			Location:  protocol.Location{URI: fileURI(res.FilePath)},
+			Kind:      res.Kind,
+			Workload:  res.Name,
+			Namespace: namespace,
+		}
+		files := configMapVolumeFiles(podSpec, name, key, hasKey)
+		for _, field := range []string{"initContainers", "containers"} {
+			containers := getMappingValue(podSpec, field)
+			if containers == nil || containers.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, container := range containers.Content {
+				usage := workload
+				if nameNode := getMappingScalarValue(container, "name"); nameNode != nil {
+					usage.Container = nameNode.Value
+				}
+				usages = append(usages, containerEnvUsages(usage, container, name, key, hasKey)...)
+				usages = append(usages, containerMountUsages(usage, container, files)...)
+			}
+		}
+	}
+	sort.SliceStable(usages, func(i, j int) bool {
+		if usages[i].URI != usages[j].URI {
+			return usages[i].URI < usages[j].URI
+		}
+		return comparePosition(usages[i].Range.Start, usages[j].Range.Start) < 0
+	})
+	return usages
+}
+
+// containerEnvUsages returns the env and envFrom entries of container that
+// read key of the ConfigMap name, filled in from usage.
+func containerEnvUsages(usage EnvVarUsage, container *yaml.Node, name, key string, hasKey bool) []EnvVarUsage {
+	var usages []EnvVarUsage
+	if env := getMappingValue(container, "env"); env != nil && env.Kind == yaml.SequenceNode {
+		for _, item := range env.Content {
+			ref := getMappingValue(getMappingValue(item, "valueFrom"), "configMapKeyRef")
+			refName := getMappingScalarValue(ref, "name")
+			refKey := getMappingScalarValue(ref, "key")
+			varName := getMappingScalarValue(item, "name")
+			if refName == nil || refName.Value != name || refKey == nil || refKey.Value != key || varName == nil {
+				continue
+			}
+			u := usage
+			u.Range = scalarValueRange(refKey)
+			u.Via = "env"
+			u.EnvVar = varName.Value
+			usages = append(usages, u)
+		}
+	}
+	if !hasKey {
+		return usages
+	}
+	if envFrom := getMappingValue(container, "envFrom"); envFrom != nil && envFrom.Kind == yaml.SequenceNode {
+		for _, item := range envFrom.Content {
+			refName := getMappingScalarValue(getMappingValue(item, "configMapRef"), "name")
+			if refName == nil || refName.Value != name {
+				continue
+			}
+			u := usage
+			u.Range = scalarValueRange(refName)
+			u.Via = "envFrom"
+			u.EnvVar = key
+			if prefix := getMappingScalarValue(item, "prefix"); prefix != nil {
+				u.EnvVar = prefix.Value + key
+			}
+			usages = append(usages, u)
+		}
+	}
+	return usages
+}
+
+// configMapVolumeFiles maps the names of the volumes projecting key of the
+// ConfigMap name to the path of its file inside the volume. Without hasKey
+// only volumes listing the key in their items count.
+func configMapVolumeFiles(podSpec *yaml.Node, name, key string, hasKey bool) map[string]string {
+	files := make(map[string]string)
+	add := func(volumeName string, source *yaml.Node) {
+		sourceName := getMappingScalarValue(source, "name")
+		if sourceName == nil || sourceName.Value != name {
+			return
+		}
+		items := getMappingValue(source, "items")
+		if items == nil || items.Kind != yaml.SequenceNode || len(items.Content) == 0 {
+			if hasKey {
+				files[volumeName] = key
+			}
+			return
+		}
+		for _, item := range items.Content {
+			itemKey := getMappingScalarValue(item, "key")
+			itemPath := getMappingScalarValue(item, "path")
+			if itemKey != nil && itemKey.Value == key && itemPath != nil {
+				files[volumeName] = itemPath.Value
+			}
+		}
+	}
+	volumes := getMappingValue(podSpec, "volumes")
+	if volumes == nil || volumes.Kind != yaml.SequenceNode {
+		return files
+	}
+	for _, volume := range volumes.Content {
+		volumeName := getMappingScalarValue(volume, "name")
+		if volumeName == nil {
+			continue
+		}
+		if cm := getMappingValue(volume, "configMap"); cm != nil {
+			add(volumeName.Value, cm)
+		}
+		sources := getMappingValue(getMappingValue(volume, "projected"), "sources")
+		if sources != nil && sources.Kind == yaml.SequenceNode {
+			for _, source := range sources.Content {
+				if cm := getMappingValue(source, "configMap"); cm != nil {
+					add(volumeName.Value, cm)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// containerMountUsages returns the volumeMounts of container that expose
+// one of files, with the path the key ends up at. A subPath mount only
+// counts when it selects that file.
+func containerMountUsages(usage EnvVarUsage, container *yaml.Node, files map[string]string) []EnvVarUsage {
+	mounts := getMappingValue(container, "volumeMounts")
+	if len(files) == 0 || mounts == nil || mounts.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var usages []EnvVarUsage
+	for _, mount := range mounts.Content {
+		mountName := getMappingScalarValue(mount, "name")
+		mountPath := getMappingScalarValue(mount, "mountPath")
+		if mountName == nil || mountPath == nil {
+			continue
+		}
+		file, ok := files[mountName.Value]
+		if !ok {
+			continue
+		}
+		u := usage
+		u.Range = scalarValueRange(mountPath)
+		u.Via = "volume"
+		if subPath := getMappingScalarValue(mount, "subPath"); subPath != nil && subPath.Value != "" {
+			if subPath.Value != file {
+				continue
+			}
+			u.MountPath = mountPath.Value
+		} else {
+			u.MountPath = path.Join(mountPath.Value, file)
+		}
+		usages = append(usages, u)
+	}
+	return usages
+}
@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestEnvVarUsages(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared
+data:
+  LOG_LEVEL: info
+  other: x
+`,
+		"deploy.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: APP_LOG_LEVEL
+              valueFrom:
+                configMapKeyRef:
+                  name: shared
+                  key: LOG_LEVEL
+          volumeMounts:
+            - name: config
+              mountPath: /etc/app
+      volumes:
+        - name: config
+          configMap:
+            name: shared
+`,
+		"job.yaml": `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          envFrom:
+            - prefix: DB_
+              configMapRef:
+                name: shared
+          volumeMounts:
+            - name: settings
+              mountPath: /etc/level
+              subPath: level.txt
+      volumes:
+        - name: settings
+          configMap:
+            name: shared
+            items:
+              - key: LOG_LEVEL
+                path: level.txt
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		idx.IndexFile(path)
+	}
+
+	usages := r.EnvVarUsages(context.Background(), "", "shared", "LOG_LEVEL")
+	type summary struct{ workload, container, via, envVar, mountPath string }
+	var got []summary
+	for _, u := range usages {
+		got = append(got, summary{u.Workload, u.Container, u.Via, u.EnvVar, u.MountPath})
+	}
+	want := []summary{
+		{"web", "app", "env", "APP_LOG_LEVEL", ""},
+		{"web", "app", "volume", "", "/etc/app/LOG_LEVEL"},
+		{"migrate", "migrate", "envFrom", "DB_LOG_LEVEL", ""},
+		{"migrate", "migrate", "volume", "", "/etc/level"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d usages, got %+v", len(want), got)
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == w
+		}
+		if !found {
+			t.Errorf("Expected usage %+v, got %+v", w, got)
+		}
+	}
+	if usages[0].URI != "file://"+filepath.Join(dir, "deploy.yaml") || usages[0].Range.Start.Line != 14 {
+		t.Errorf("Expected the first usage at the configMapKeyRef key, got %+v", usages[0].Location)
+	}
+
+	// Only the whole-ConfigMap mount and envFrom consume the other key.
+	if usages := r.EnvVarUsages(context.Background(), "", "shared", "other"); len(usages) != 2 {
+		t.Errorf("Expected 2 usages of the other key, got %+v", usages)
+	}
+	// A key the ConfigMap doesn't have isn't produced by envFrom or mounted.
+	if usages := r.EnvVarUsages(context.Background(), "", "shared", "missing"); len(usages) != 0 {
+		t.Errorf("Expected no usages of a missing key, got %+v", usages)
+	}
+}
@@ -0,0 +1,132 @@
+package resolver
+
+import (
+	"io"
+	"strings"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/position"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PositionExplanation describes how the server interprets a document position:
+// the YAML path under the cursor and which rules and built-in special cases
+// apply to it.
+type PositionExplanation struct {
+	Kind  string   `json:"kind"`
+	Path  []string `json:"path"`
+	Value string   `json:"value"`
+	// Definitions lists the symbols defined at this position.
+	Definitions []string `json:"definitions,omitempty"`
+	// References lists the names of the reference rules matching this position.
+	References []string `json:"references,omitempty"`
+	// BuiltinFeatures lists the enabled special cases handling this position.
+	BuiltinFeatures []string `json:"builtinFeatures,omitempty"`
+	// SkippedFeatures lists special cases that would apply but are disabled
+	// in settings.builtinFeatures.
+	SkippedFeatures []string `json:"skippedFeatures,omitempty"`
+}
+
+// ExplainPosition reports how the node at the given position is interpreted.
+// It returns nil when there is no node at the position.
+func (r *Resolver) ExplainPosition(docContent string, line, col int) (*PositionExplanation, error) {
+	decoder := position.NewDecoder(docContent)
+
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		node = *listItemAt(&node, line+1)
+
+		targetNode, parentNode, path, indices := findNodeAt(&node, line+1, col+1)
+		if targetNode == nil {
+			continue
+		}
+
+		kind := findKind(&node)
+		explanation := &PositionExplanation{
+			Kind:  kind,
+			Path:  path,
+			Value: targetNode.Value,
+		}
+
+		for _, feature := range builtinFeaturesAt(kind, path, targetNode, parentNode) {
+			if r.featureEnabled(feature) {
+				explanation.BuiltinFeatures = append(explanation.BuiltinFeatures, feature)
+			} else {
+				explanation.SkippedFeatures = append(explanation.SkippedFeatures, feature)
+			}
+		}
+
+		for _, sym := range r.Config.Symbols {
+			for _, def := range sym.Definitions {
+				match := matchPath(path, indices, def.Path)
+				if !match && sym.Name == "k8s.label" {
+					match = matchPathPrefix(path, indices, def.Path)
+				}
+				if match && containsKind(def.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && def.MatchesAPIVersion(findAPIVersion(&node)) {
+					explanation.Definitions = append(explanation.Definitions, sym.Name)
+					break
+				}
+			}
+		}
+
+		for _, refRule := range r.Config.References {
+			match := matchPath(path, indices, refRule.Match.Path)
+			if !match && refRule.Symbol == "k8s.label" {
+				match = matchPathPrefix(path, indices, refRule.Match.Path)
+			}
+			if match && matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && refRule.Match.MatchesAPIVersion(findAPIVersion(&node)) {
+				explanation.References = append(explanation.References, refRule.Name)
+			}
+		}
+
+		return explanation, nil
+	}
+	return nil, nil
+}
+
+// builtinFeaturesAt returns the built-in special cases whose entry condition
+// matches the node, regardless of whether they are enabled.
+func builtinFeaturesAt(kind string, path []string, targetNode, parentNode *yaml.Node) []string {
+	var features []string
+	if isVolumeMountNamePath(path) {
+		features = append(features, config.FeatureVolumeMountNavigation)
+	}
+	if isVolumeMountSubPathPath(path) {
+		features = append(features, config.FeatureSubPathTargets)
+	}
+	if isEmbeddedFileKey(kind, path, targetNode, parentNode) {
+		features = append(features, config.FeatureEmbeddedFiles)
+	}
+	if isWorkloadPVCClaimNamePath(path) {
+		features = append(features, config.FeaturePVCClaimUsages)
+	}
+	return features
+}
+
+// isEmbeddedFileKey reports whether targetNode is a file-like ConfigMap
+// data/binaryData key whose value is a block scalar.
+func isEmbeddedFileKey(kind string, path []string, targetNode, parentNode *yaml.Node) bool {
+	if kind != "ConfigMap" || len(path) < 2 || (path[len(path)-2] != "data" && path[len(path)-2] != "binaryData") {
+		return false
+	}
+	if !strings.Contains(targetNode.Value, ".") || parentNode == nil || parentNode.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(parentNode.Content); i += 2 {
+		if parentNode.Content[i] == targetNode {
+			valNode := parentNode.Content[i+1]
+			return valNode.Style == yaml.LiteralStyle || valNode.Style == yaml.FoldedStyle
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package resolver
+
+import (
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// findResourcesByField returns the kind resources whose indexed field
+// equals value: the targets of a k8s.field reference. Namespaced targets
+// must be in namespace.
+func (r *Resolver) findResourcesByField(kind, namespace, field, value string) []*indexer.K8sResource {
+	var matches []*indexer.K8sResource
+	for _, res := range r.Store.FindByField(kind, field, value) {
+		if !indexer.IsClusterScoped(res.Kind) && r.canonicalNamespace(res.Namespace) != r.canonicalNamespace(namespace) {
+			continue
+		}
+		matches = append(matches, res)
+	}
+	return matches
+}
+
+func fieldSelectorLinks(resources []*indexer.K8sResource, originRange protocol.Range) []protocol.LocationLink {
+	var links []protocol.LocationLink
+	for _, res := range resources {
+		targetRange := protocol.Range{
+			Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+			End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+		}
+		links = append(links, protocol.LocationLink{
+			OriginSelectionRange: &originRange,
+			TargetURI:            fileURI(res.FilePath),
+			TargetRange:          targetRange,
+			TargetSelectionRange: targetRange,
+		})
+	}
+	return links
+}
+
+func fieldSelectorHoverContents(resources []*indexer.K8sResource) string {
+	contents := make([]string, len(resources))
+	for i, res := range resources {
+		contents[i] = resourceHoverContents(res)
+	}
+	return strings.Join(contents, "\n\n---\n\n")
+}
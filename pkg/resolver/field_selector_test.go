@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestFieldSelectorResolution(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Cluster", "Backup"}, Path: "metadata.name"},
+				},
+			},
+			{
+				Name: "k8s.field",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Cluster"}, Path: "spec.clusterName", Field: "clusterName"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "backup.clusterSelector",
+				Symbol:     "k8s.field",
+				TargetKind: "Cluster",
+				Field:      "clusterName",
+				Match:      config.ReferenceMatch{Kinds: []string{"Backup"}, Path: "spec.selector.clusterName"},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/clusters.yaml", `apiVersion: db.example.com/v1
+kind: Cluster
+metadata:
+  name: pg-main
+  namespace: db
+spec:
+  clusterName: prod
+---
+apiVersion: db.example.com/v1
+kind: Cluster
+metadata:
+  name: pg-test
+  namespace: db
+spec:
+  clusterName: staging
+---
+apiVersion: db.example.com/v1
+kind: Cluster
+metadata:
+  name: pg-elsewhere
+  namespace: other
+spec:
+  clusterName: prod
+`)
+
+	backupYaml := `apiVersion: db.example.com/v1
+kind: Backup
+metadata:
+  name: nightly
+  namespace: db
+spec:
+  selector:
+    clusterName: prod
+`
+	idx.IndexContent("/tmp/backup.yaml", backupYaml)
+
+	// "    clusterName: prod" on line 7 selects pg-main only: pg-test has
+	// another value and pg-elsewhere is in another namespace.
+	links, err := r.ResolveDefinition(context.Background(), backupYaml, "file:///tmp/backup.yaml", 7, 19)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/clusters.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected pg-main, got %v", links)
+	}
+
+	hover, err := r.ResolveHover(context.Background(), backupYaml, "file:///tmp/backup.yaml", 7, 19)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected a hover, got %v (err %v)", hover, err)
+	}
+	if value := hover.Contents.(protocol.MarkupContent).Value; !strings.Contains(value, "pg-main") || strings.Contains(value, "pg-elsewhere") {
+		t.Errorf("Expected the hover to show pg-main only, got %q", value)
+	}
+
+	// The selector value is not a resource name: a Cluster named "prod"
+	// doesn't count it among its references.
+	idx.IndexContent("/tmp/prod.yaml", `apiVersion: db.example.com/v1
+kind: Cluster
+metadata:
+  name: prod
+  namespace: db
+`)
+	locs, err := r.ResolveReferences(context.Background(), `apiVersion: db.example.com/v1
+kind: Cluster
+metadata:
+  name: prod
+  namespace: db
+`, "file:///tmp/prod.yaml", 3, 8)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	for _, loc := range locs {
+		if loc.URI == "file:///tmp/backup.yaml" {
+			t.Errorf("Expected no reference from the field selector, got %v", locs)
+		}
+	}
+}
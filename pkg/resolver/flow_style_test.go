@@ -0,0 +1,181 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func flowStyleConfig() *config.Config {
+	return &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment", "Service", "ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+			{
+				Name: "k8s.label",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment"}, Path: "metadata.labels"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "service.selector.label",
+				Symbol:     "k8s.label",
+				TargetKind: "Pod",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Service"},
+					Path:  "spec.selector",
+				},
+			},
+		},
+	}
+}
+
+const flowDeploymentYaml = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {app: web, role: web}
+`
+
+// Line 5 is "  selector: {app: web, role: web}": the values start at col 18
+// and col 29.
+const flowServiceYaml = `apiVersion: v1
+kind: Service
+metadata:
+  name: web-svc
+spec:
+  selector: {app: web, role: web}
+`
+
+func TestFlowStyleSelectorDefinition(t *testing.T) {
+	cfg := flowStyleConfig()
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/deploy.yaml", flowDeploymentYaml)
+	r := NewResolver(store, cfg)
+
+	// Cursor on the second "web" (role), col 29.
+	links, err := r.ResolveDefinition(context.Background(), flowServiceYaml, "file:///tmp/svc.yaml", 5, 29)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("Expected 1 link, got %d", len(links))
+	}
+	wantOrigin := protocol.Range{
+		Start: protocol.Position{Line: 5, Character: 29},
+		End:   protocol.Position{Line: 5, Character: 32},
+	}
+	if *links[0].OriginSelectionRange != wantOrigin {
+		t.Errorf("Expected origin range %v, got %v", wantOrigin, *links[0].OriginSelectionRange)
+	}
+	if links[0].TargetURI != "file:///tmp/deploy.yaml" {
+		t.Errorf("Expected target file:///tmp/deploy.yaml, got %s", links[0].TargetURI)
+	}
+}
+
+func TestFlowStyleSelectorReferences(t *testing.T) {
+	cfg := flowStyleConfig()
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/deploy.yaml", flowDeploymentYaml)
+	idx.IndexContent("/tmp/svc.yaml", flowServiceYaml)
+	r := NewResolver(store, cfg)
+
+	// The cursor's own entry is dropped and the sibling "role: web" entry
+	// isn't a usage of app=web, whether the cursor is on or just past "web".
+	for _, col := range []int{18, 21} {
+		locs, err := r.ResolveReferences(context.Background(), flowServiceYaml, "file:///tmp/svc.yaml", 5, col)
+		if err != nil {
+			t.Fatalf("ResolveReferences failed: %v", err)
+		}
+		if len(locs) != 1 {
+			t.Fatalf("col %d: expected 1 location, got %v", col, locs)
+		}
+		if locs[0].URI != "file:///tmp/deploy.yaml" {
+			t.Errorf("col %d: expected file:///tmp/deploy.yaml, got %s", col, locs[0].URI)
+		}
+	}
+}
+
+func TestFlowStyleLabelDefinitionReferences(t *testing.T) {
+	cfg := flowStyleConfig()
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/deploy.yaml", flowDeploymentYaml)
+	idx.IndexContent("/tmp/svc.yaml", flowServiceYaml)
+	r := NewResolver(store, cfg)
+
+	// "  labels: {app: web, role: web}" - cursor on "web" of role (col 27).
+	locs, err := r.ResolveReferences(context.Background(), flowDeploymentYaml, "file:///tmp/deploy.yaml", 4, 27)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+
+	wantUsage := protocol.Range{
+		Start: protocol.Position{Line: 5, Character: 29},
+		End:   protocol.Position{Line: 5, Character: 32},
+	}
+	var usages []protocol.Range
+	for _, loc := range locs {
+		if loc.URI == "file:///tmp/svc.yaml" {
+			usages = append(usages, loc.Range)
+		}
+	}
+	if len(usages) != 1 || usages[0] != wantUsage {
+		t.Errorf("Expected only the role selector usage %v, got %v", wantUsage, usages)
+	}
+}
+
+func TestFlowStyleQuotedNameReferences(t *testing.T) {
+	cfg := flowStyleConfig()
+	cfg.References = append(cfg.References, config.Reference{
+		Name:       "service.configmap",
+		Symbol:     "k8s.resource.name",
+		TargetKind: "ConfigMap",
+		Match: config.ReferenceMatch{
+			Kinds: []string{"Service"},
+			Path:  "metadata.annotations.config",
+		},
+	})
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	cmYaml := `apiVersion: v1
+kind: ConfigMap
+metadata: {name: "app-config"}
+`
+	idx.IndexContent("/tmp/cm.yaml", cmYaml)
+	idx.IndexContent("/tmp/svc.yaml", `apiVersion: v1
+kind: Service
+metadata: {name: svc, annotations: {config: 'app-config'}}
+`)
+	r := NewResolver(store, cfg)
+
+	// Cursor on the opening quote of "app-config".
+	locs, err := r.ResolveReferences(context.Background(), cmYaml, "file:///tmp/cm.yaml", 2, 17)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 1 {
+		t.Fatalf("Expected 1 location, got %v", locs)
+	}
+	// "metadata: {name: svc, annotations: {config: 'app-config'}}"
+	//  0123456789012345678901234567890123456789012345678901234567
+	want := protocol.Range{
+		Start: protocol.Position{Line: 2, Character: 45},
+		End:   protocol.Position{Line: 2, Character: 55},
+	}
+	if locs[0].URI != "file:///tmp/svc.yaml" || locs[0].Range != want {
+		t.Errorf("Expected %v in svc.yaml, got %v", want, locs[0])
+	}
+}
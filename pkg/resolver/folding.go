@@ -0,0 +1,123 @@
+package resolver
+
+import (
+	"io"
+	"sort"
+
+	"k8s-lsp/pkg/position"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// maxFoldingDepth caps how many levels of nested mapping keys get their own
+// fold below a document; deeper sections fold with their ancestors.
+const maxFoldingDepth = 3
+
+// FoldingRanges returns a fold per YAML document, per multi-line mapping key
+// up to maxFoldingDepth levels, per containers and volumes item, and per
+// block scalar in ConfigMap data. yaml.v3 only records start positions, so
+// end lines come from the last scalar below each node. Documents after a
+// parse error are omitted.
+func (r *Resolver) FoldingRanges(docContent string) []protocol.FoldingRange {
+	lines := contentLines(docContent)
+	separators := documentSeparators(lines)
+
+	folds := &foldingRanges{seen: make(map[[2]int]bool)}
+	decoder := position.NewDecoder(docContent)
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msg("Stopping folding ranges at parse error")
+			}
+			break
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		root := node.Content[0]
+
+		doc := documentRange(lines, separators, root.Line-1)
+		folds.add(int(doc.Start.Line), int(doc.End.Line))
+		folds.addMapping(lines, root, 1)
+
+		if podSpec := findPodSpecNode(root); podSpec != nil {
+			for _, field := range []string{"initContainers", "containers", "ephemeralContainers", "volumes"} {
+				seq := getMappingValue(podSpec, field)
+				if seq == nil || seq.Kind != yaml.SequenceNode {
+					continue
+				}
+				for _, item := range seq.Content {
+					endLine, _ := nodeEnd(lines, item)
+					folds.add(item.Line-1, endLine-1)
+				}
+			}
+		}
+
+		if findKind(root) == "ConfigMap" {
+			for _, field := range []string{"data", "binaryData"} {
+				data := getMappingValue(root, field)
+				if data == nil || data.Kind != yaml.MappingNode {
+					continue
+				}
+				for i := 0; i+1 < len(data.Content); i += 2 {
+					keyNode, valNode := data.Content[i], data.Content[i+1]
+					if valNode.Style != yaml.LiteralStyle && valNode.Style != yaml.FoldedStyle {
+						continue
+					}
+					endLine, _ := nodeEnd(lines, valNode)
+					folds.add(keyNode.Line-1, endLine-1)
+				}
+			}
+		}
+	}
+
+	sort.Slice(folds.ranges, func(i, j int) bool {
+		if folds.ranges[i].StartLine != folds.ranges[j].StartLine {
+			return folds.ranges[i].StartLine < folds.ranges[j].StartLine
+		}
+		return folds.ranges[i].EndLine > folds.ranges[j].EndLine
+	})
+	return folds.ranges
+}
+
+type foldingRanges struct {
+	ranges []protocol.FoldingRange
+	seen   map[[2]int]bool
+}
+
+// add records a region fold over 0-based lines start through end. Single
+// lines and repeats are skipped.
+func (f *foldingRanges) add(start, end int) {
+	if end <= start || f.seen[[2]int{start, end}] {
+		return
+	}
+	f.seen[[2]int{start, end}] = true
+	kind := string(protocol.FoldingRangeKindRegion)
+	f.ranges = append(f.ranges, protocol.FoldingRange{
+		StartLine: uint32(start),
+		EndLine:   uint32(end),
+		Kind:      &kind,
+	})
+}
+
+// addMapping folds each key of m whose value spans several lines, recursing
+// into nested mappings up to maxFoldingDepth.
+func (f *foldingRanges) addMapping(lines []string, m *yaml.Node, depth int) {
+	if depth > maxFoldingDepth {
+		return
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		keyNode, valNode := m.Content[i], m.Content[i+1]
+		endLine, _ := nodeEnd(lines, valNode)
+		f.add(keyNode.Line-1, endLine-1)
+		if valNode.Kind == yaml.MappingNode {
+			f.addMapping(lines, valNode, depth+1)
+		}
+	}
+}
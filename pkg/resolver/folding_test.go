@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestFoldingRanges(t *testing.T) {
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+
+	content := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx-config
+data:
+  nginx.conf: |
+    server {
+      listen 80;
+    }
+  mode: fast
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx
+          volumeMounts:
+            - name: config
+              mountPath: /etc/nginx
+      volumes:
+        - name: config
+          configMap:
+            name: nginx-config
+`
+	folds := r.FoldingRanges(content)
+
+	var got []string
+	for _, f := range folds {
+		if f.Kind == nil || *f.Kind != string(protocol.FoldingRangeKindRegion) {
+			t.Errorf("Expected region kind, got %v", f.Kind)
+		}
+		got = append(got, fmt.Sprintf("%d-%d", f.StartLine, f.EndLine))
+	}
+	want := []string{
+		"0-9",   // ConfigMap document
+		"2-3",   // metadata
+		"4-9",   // data
+		"5-8",   // nginx.conf block scalar
+		"11-27", // Deployment document
+		"13-14", // metadata
+		"15-27", // spec
+		"16-27", // template
+		"17-27", // template.spec; deeper keys like containers exceed maxFoldingDepth
+		"19-23", // containers[0]
+		"25-27", // volumes[0]
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected folds %v, got %v", want, got)
+	}
+}
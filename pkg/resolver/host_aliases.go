@@ -0,0 +1,168 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// isHostAliasPath matches hostAliases[].ip and hostAliases[].hostnames[] of
+// a pod spec.
+func isHostAliasPath(path []string) bool {
+	n := len(path)
+	return n >= 2 && path[n-2] == "hostAliases" && (path[n-1] == "ip" || path[n-1] == "hostnames")
+}
+
+// isDNSConfigPath matches the dnsPolicy and anything under dnsConfig of a
+// pod spec.
+func isDNSConfigPath(path []string) bool {
+	if len(path) > 0 && path[len(path)-1] == "dnsPolicy" {
+		return true
+	}
+	return contains(path, "dnsConfig")
+}
+
+// findHostAliasEntry returns the hostAliases entry of podSpec holding node,
+// as its ip or one of its hostnames.
+func findHostAliasEntry(podSpec, node *yaml.Node) *yaml.Node {
+	aliases := getMappingValue(podSpec, "hostAliases")
+	if aliases == nil || aliases.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range aliases.Content {
+		if getMappingScalarValue(entry, "ip") == node {
+			return entry
+		}
+		hostnames := getMappingValue(entry, "hostnames")
+		if hostnames == nil {
+			continue
+		}
+		for _, hostname := range hostnames.Content {
+			if hostname == node {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// hostAliasServices returns the Services a hostAliases value names, sorted
+// by file and position.
+func (r *Resolver) hostAliasServices(value string) []*indexer.K8sResource {
+	services := r.Store.FindServicesByAddress(value)
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].FilePath != services[j].FilePath {
+			return services[i].FilePath < services[j].FilePath
+		}
+		return services[i].Line < services[j].Line
+	})
+	return services
+}
+
+// hostAliasHover summarizes the hostAliases entry holding node and the
+// Services its ip and hostnames match.
+func (r *Resolver) hostAliasHover(root, node *yaml.Node) string {
+	entry := findHostAliasEntry(findPodSpecNode(root), node)
+	if entry == nil {
+		return ""
+	}
+	ip := ""
+	if ipNode := getMappingScalarValue(entry, "ip"); ipNode != nil {
+		ip = ipNode.Value
+	}
+	var hostnames []string
+	if seq := getMappingValue(entry, "hostnames"); seq != nil {
+		for _, hostname := range seq.Content {
+			hostnames = append(hostnames, "`"+hostname.Value+"`")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**hostAlias** `%s` → %s\n\nAdded to /etc/hosts of every container in the pod.", ip, strings.Join(hostnames, ", "))
+	var matches []string
+	for _, res := range r.hostAliasServices(node.Value) {
+		matches = append(matches, fmt.Sprintf("- Service %s (%s)", res.Name, res.FilePath))
+	}
+	if len(matches) > 0 {
+		fmt.Fprintf(&b, "\n\n`%s` matches:\n%s", node.Value, strings.Join(matches, "\n"))
+	}
+	return b.String()
+}
+
+// dnsConfigHover summarizes the dnsPolicy and dnsConfig of the pod spec.
+func dnsConfigHover(root *yaml.Node) string {
+	podSpec := findPodSpecNode(root)
+	if podSpec == nil {
+		return ""
+	}
+	policy := "ClusterFirst"
+	if policyNode := getMappingScalarValue(podSpec, "dnsPolicy"); policyNode != nil {
+		policy = policyNode.Value
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**DNS** policy `%s`", policy)
+	dnsConfig := getMappingValue(podSpec, "dnsConfig")
+	list := func(key string) []string {
+		var values []string
+		if seq := getMappingValue(dnsConfig, key); seq != nil {
+			for _, item := range seq.Content {
+				if item.Kind == yaml.ScalarNode {
+					values = append(values, "`"+item.Value+"`")
+				}
+			}
+		}
+		return values
+	}
+	if nameservers := list("nameservers"); len(nameservers) > 0 {
+		fmt.Fprintf(&b, "\n\nNameservers: %s", strings.Join(nameservers, ", "))
+	}
+	if searches := list("searches"); len(searches) > 0 {
+		fmt.Fprintf(&b, "\n\nSearch domains: %s", strings.Join(searches, ", "))
+	}
+	var options []string
+	if seq := getMappingValue(dnsConfig, "options"); seq != nil {
+		for _, option := range seq.Content {
+			nameNode := getMappingScalarValue(option, "name")
+			if nameNode == nil {
+				continue
+			}
+			if valueNode := getMappingScalarValue(option, "value"); valueNode != nil {
+				options = append(options, fmt.Sprintf("`%s=%s`", nameNode.Value, valueNode.Value))
+			} else {
+				options = append(options, "`"+nameNode.Value+"`")
+			}
+		}
+	}
+	if len(options) > 0 {
+		fmt.Fprintf(&b, "\n\nOptions: %s", strings.Join(options, ", "))
+	}
+	if policy == "None" && dnsConfig == nil {
+		b.WriteString("\n\ndnsPolicy None requires a dnsConfig.")
+	}
+	return b.String()
+}
+
+// hostAliasDefinitions links a hostAliases ip or hostname to the Services
+// whose clusterIP or externalName it names.
+func (r *Resolver) hostAliasDefinitions(value string, originRange protocol.Range) []protocol.LocationLink {
+	var links []protocol.LocationLink
+	for _, res := range r.hostAliasServices(value) {
+		targetRange := protocol.Range{
+			Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+			End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+		}
+		links = append(links, protocol.LocationLink{
+			OriginSelectionRange: &originRange,
+			TargetURI:            fileURI(res.FilePath),
+			TargetRange:          targetRange,
+			TargetSelectionRange: targetRange,
+		})
+	}
+	return links
+}
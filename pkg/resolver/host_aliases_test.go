@@ -0,0 +1,109 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestHostAliasHoverAndNavigation(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{HostAliasLinks: true},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service", "Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/services.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: legacy-db
+spec:
+  clusterIP: 10.96.0.42
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: billing
+spec:
+  type: ExternalName
+  externalName: billing.example.com.
+`)
+	r := NewResolver(store, cfg)
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      hostAliases:
+        - ip: 10.96.0.42
+          hostnames:
+            - db.internal
+            - billing.example.com
+      dnsPolicy: None
+      dnsConfig:
+        nameservers:
+          - 1.1.1.1
+        options:
+          - name: ndots
+            value: "2"
+`
+	uri := "file:///tmp/deploy.yaml"
+
+	// "        - ip: 10.96.0.42" on line 8.
+	hover, err := r.ResolveHover(context.Background(), deployment, uri, 8, 16)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected a hostAlias hover, got %v (%v)", hover, err)
+	}
+	value := hover.Contents.(protocol.MarkupContent).Value
+	for _, want := range []string{"`10.96.0.42` → `db.internal`, `billing.example.com`", "Service legacy-db"} {
+		if !strings.Contains(value, want) {
+			t.Errorf("Expected hover to contain %q, got %q", want, value)
+		}
+	}
+
+	// "            - billing.example.com" on line 11 matches the externalName.
+	links, err := r.ResolveDefinition(context.Background(), deployment, uri, 11, 16)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/services.yaml" || links[0].TargetRange.Start.Line != 10 {
+		t.Fatalf("Expected the billing Service, got %v", links)
+	}
+	links, err = r.ResolveDefinition(context.Background(), deployment, uri, 8, 16)
+	if err != nil || len(links) != 1 || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the legacy-db Service, got %v (%v)", links, err)
+	}
+
+	// "          - 1.1.1.1" on line 15 summarizes the DNS config.
+	hover, err = r.ResolveHover(context.Background(), deployment, uri, 15, 13)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected a DNS hover, got %v (%v)", hover, err)
+	}
+	value = hover.Contents.(protocol.MarkupContent).Value
+	for _, want := range []string{"policy `None`", "Nameservers: `1.1.1.1`", "Options: `ndots=2`"} {
+		if !strings.Contains(value, want) {
+			t.Errorf("Expected DNS hover to contain %q, got %q", want, value)
+		}
+	}
+
+	// Off by default.
+	r = NewResolver(store, &config.Config{Symbols: cfg.Symbols})
+	if hover, _ := r.ResolveHover(context.Background(), deployment, uri, 8, 16); hover != nil {
+		t.Errorf("Expected no hover without hostAliasLinks, got %v", hover)
+	}
+}
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -66,7 +67,7 @@ spec:
 	col := 20
 
 	// 5. Call ResolveHover
-	hover, err := r.ResolveHover(yamlContent, "file:///tmp/deployment.yaml", line, col)
+	hover, err := r.ResolveHover(context.Background(), yamlContent, "file:///tmp/deployment.yaml", line, col)
 
 	// 6. Assertions
 	if err != nil {
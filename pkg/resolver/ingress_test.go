@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestIngressBackendNavigation(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service", "Ingress"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+
+	servicePath := filepath.Join(t.TempDir(), "service.yaml")
+	serviceYaml := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  ports:
+    - name: http
+      port: 80
+    - name: metrics
+      port: 9090
+`
+	if err := os.WriteFile(servicePath, []byte(serviceYaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx.IndexContent(servicePath, serviceYaml)
+	r := NewResolver(store, cfg)
+
+	ingressYaml := `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: shop
+spec:
+  defaultBackend:
+    service:
+      name: web
+      port:
+        number: 9090
+  rules:
+    - http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: web
+                port:
+                  name: http
+`
+	tests := []struct {
+		name       string
+		line, col  int
+		targetLine uint32
+	}{
+		// "                name: web" -> the Service name.
+		{"service name", 18, 23, 3},
+		// "      name: web" of the default backend.
+		{"default backend service name", 8, 13, 3},
+		// "                  name: http" -> ports[0].name.
+		{"port name", 20, 25, 7},
+		// "        number: 9090" -> ports[1].port.
+		{"port number", 10, 17, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := r.ResolveDefinition(context.Background(), ingressYaml, "file:///tmp/ingress.yaml", tt.line, tt.col)
+			if err != nil {
+				t.Fatalf("ResolveDefinition failed: %v", err)
+			}
+			if len(links) != 1 || links[0].TargetURI != "file://"+servicePath || links[0].TargetRange.Start.Line != tt.targetLine {
+				t.Fatalf("Expected %s line %d, got %v", servicePath, tt.targetLine, links)
+			}
+		})
+	}
+
+	// An unknown port name still lands on the Service.
+	unknown := ingressYaml[:len(ingressYaml)-len("http\n")] + "grpc\n"
+	links, err := r.ResolveDefinition(context.Background(), unknown, "file:///tmp/ingress.yaml", 20, 25)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the Service for an unknown port, got %v", links)
+	}
+}
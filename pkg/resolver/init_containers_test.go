@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_InitContainerConfigMapKeyRef(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+`)
+	r := NewResolver(store, cfg)
+
+	podYaml := `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  initContainers:
+    - name: init
+      env:
+        - name: MODE
+          valueFrom:
+            configMapKeyRef:
+              name: settings
+              key: mode
+`
+	idx.IndexContent("/tmp/pod.yaml", podYaml)
+
+	// "              name: settings" on line 11.
+	links, err := r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 11, 22)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/cm.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the ConfigMap name, got %v", links)
+	}
+}
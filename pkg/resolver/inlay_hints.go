@@ -0,0 +1,150 @@
+package resolver
+
+import (
+	"io"
+
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
+
+	"github.com/rs/zerolog/log"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// InlayHintKind is the LSP 3.17 InlayHintKind, which the 3.16 protocol
+// package predates.
+type InlayHintKind int
+
+const (
+	InlayHintKindType      InlayHintKind = 1
+	InlayHintKindParameter InlayHintKind = 2
+)
+
+// InlayHint is the LSP 3.17 InlayHint with a plain string label.
+type InlayHint struct {
+	Position    protocol.Position `json:"position"`
+	Label       string            `json:"label"`
+	Kind        InlayHintKind     `json:"kind,omitempty"`
+	PaddingLeft bool              `json:"paddingLeft,omitempty"`
+}
+
+// InlayHints returns a hint after every resource name reference in rng,
+// naming the kind and namespace it resolves to, e.g.
+// "→ PersistentVolumeClaim (ns: prod)", or "→ not found" as a parameter hint
+// when the target isn't indexed. Documents and nodes outside rng are skipped
+// without being resolved.
+func (r *Resolver) InlayHints(docContent string, uri string, rng protocol.Range) []InlayHint {
+	var hints []InlayHint
+	decoder := position.NewDecoder(docContent)
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msg("Stopping inlay hints at parse error")
+			}
+			break
+		}
+		if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		root := node.Content[0]
+		if uint32(root.Line-1) > rng.End.Line {
+			break
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		kind := findKind(root)
+		if kind == "" {
+			continue
+		}
+		apiVersion := findAPIVersion(root)
+		namespace := findNamespace(root)
+		walkInRange(root, nil, nil, nil, rng, func(n, parent *yaml.Node, path []string, indices []int) {
+			hints = append(hints, r.referenceHints(uri, kind, apiVersion, namespace, n, parent, path, indices)...)
+		})
+	}
+	return hints
+}
+
+// walkInRange visits the scalar values of mappings under node whose line
+// falls in rng, with their parent mapping, the path of keys leading to them
+// and the list indices along it, as findNodeAt returns them.
+func walkInRange(node, parent *yaml.Node, path []string, indices []int, rng protocol.Range, visit func(n, parent *yaml.Node, path []string, indices []int)) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valNode := node.Content[i], node.Content[i+1]
+			if uint32(keyNode.Line-1) > rng.End.Line {
+				return
+			}
+			walkInRange(valNode, node, append(path[:len(path):len(path)], keyNode.Value), append(indices[:len(indices):len(indices)], -1), rng, visit)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if uint32(item.Line-1) > rng.End.Line {
+				return
+			}
+			itemIndices := indices
+			if n := len(indices); n > 0 && indices[n-1] == -1 {
+				itemIndices = append(indices[:n-1:n-1], i)
+			}
+			walkInRange(item, node, path, itemIndices, rng, visit)
+		}
+	case yaml.ScalarNode:
+		line := uint32(node.Line - 1)
+		if parent != nil && parent.Kind == yaml.MappingNode && line >= rng.Start.Line && line <= rng.End.Line {
+			visit(node, parent, path, indices)
+		}
+	}
+}
+
+// referenceHints returns the hint for n if a k8s.resource.name reference
+// rule matches it.
+func (r *Resolver) referenceHints(uri, kind, apiVersion, namespace string, n, parent *yaml.Node, path []string, indices []int) []InlayHint {
+	if n.Value == "" {
+		return nil
+	}
+	for _, refRule := range r.Config.References {
+		if refRule.Symbol != "k8s.resource.name" || refRule.TargetKind == "" {
+			continue
+		}
+		if !matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) || !refRule.Match.MatchesAPIVersion(apiVersion) || !matchPath(path, indices, refRule.Match.Path) {
+			continue
+		}
+
+		ns := siblingNamespace(parent, namespace)
+		if indexer.IsClusterScoped(refRule.TargetKind) {
+			ns = ""
+		}
+		name, ok := r.referenceName(n.Value, uri)
+		if !ok {
+			return nil
+		}
+		origin := scalarValueRange(n)
+		if refRule.CompositePath != "" {
+			ns, name, origin, ok = compositeReference(refRule.CompositePath, n, name, ns, origin)
+			if !ok {
+				continue
+			}
+		}
+
+		hint := InlayHint{Position: origin.End, PaddingLeft: true}
+		if target, found := r.lookupTarget(refRule.TargetKind, ns, name); found {
+			hint.Label = "→ " + target.res.Kind
+			if !indexer.IsClusterScoped(target.res.Kind) && target.res.Kind != "Namespace" {
+				ns := target.res.Namespace
+				if ns == "" {
+					ns = "default"
+				}
+				hint.Label += " (ns: " + ns + ")"
+			}
+			hint.Kind = InlayHintKindType
+		} else {
+			hint.Label = "→ not found"
+			hint.Kind = InlayHintKindParameter
+		}
+		return []InlayHint{hint}
+	}
+	return nil
+}
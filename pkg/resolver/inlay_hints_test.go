@@ -0,0 +1,75 @@
+package resolver
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestInlayHints(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/namespace.yaml", `apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`)
+	idx.IndexContent("/tmp/pvc.yaml", `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data-pvc
+  namespace: prod
+`)
+	content := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  template:
+    spec:
+      volumes:
+        - name: data
+          persistentVolumeClaim:
+            claimName: data-pvc
+        - name: config
+          configMap:
+            name: app-config
+`
+	all := protocol.Range{End: protocol.Position{Line: 100}}
+	hints := r.InlayHints(content, "file:///tmp/deploy.yaml", all)
+	if len(hints) != 3 {
+		t.Fatalf("Expected 3 hints, got %+v", hints)
+	}
+	if hints[0].Label != "→ Namespace" {
+		t.Errorf("Unexpected hint for the namespace: %+v", hints[0])
+	}
+	hints = hints[1:]
+	if hints[0].Label != "→ PersistentVolumeClaim (ns: prod)" || hints[0].Kind != InlayHintKindType {
+		t.Errorf("Unexpected hint for the claim: %+v", hints[0])
+	}
+	if hints[0].Position != (protocol.Position{Line: 11, Character: 31}) {
+		t.Errorf("Expected the hint after data-pvc, got %+v", hints[0].Position)
+	}
+	if hints[1].Label != "→ not found" || hints[1].Kind != InlayHintKindParameter {
+		t.Errorf("Unexpected hint for the missing ConfigMap: %+v", hints[1])
+	}
+
+	// Only references in the requested range are hinted.
+	hints = r.InlayHints(content, "file:///tmp/deploy.yaml", protocol.Range{
+		Start: protocol.Position{Line: 12},
+		End:   protocol.Position{Line: 14},
+	})
+	if len(hints) != 1 || hints[0].Label != "→ not found" {
+		t.Errorf("Expected only the ConfigMap hint in range, got %+v", hints)
+	}
+}
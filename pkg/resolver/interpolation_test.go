@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestInterpolatedReferenceNames(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Settings: config.Settings{
+			Interpolation: config.Interpolation{
+				Values:     map[string]string{"APP": "web"},
+				ValuesFile: "values.yaml",
+			},
+		},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "deployment.configmap-ref",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Deployment"},
+					Path:  "spec.template.spec.volumes.configMap.name",
+				},
+			},
+		},
+	}
+
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-prod
+`)
+
+	deployment := func(name string) string {
+		return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: ` + name + `
+`
+	}
+
+	// "            name: $(APP)-config" on line 10.
+	yml := deployment("$(APP)-config")
+	links, err := r.ResolveDefinition(context.Background(), yml, "file://"+dir+"/deploy.yaml", 10, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/cm.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected ConfigMap web-config, got %v", links)
+	}
+
+	hover, err := r.ResolveHover(context.Background(), yml, "file://"+dir+"/deploy.yaml", 10, 20)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected hover for the interpolated name, got %v (%v)", hover, err)
+	}
+
+	// An unknown variable resolves to nothing rather than a wrong resource.
+	yml = deployment("${STAGE}-config")
+	links, err = r.ResolveDefinition(context.Background(), yml, "file://"+dir+"/deploy.yaml", 10, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 0 {
+		t.Fatalf("Expected no definition for an unresolved variable, got %v", links)
+	}
+
+	// The values file next to the manifest overrides the configured values.
+	if err := os.WriteFile(filepath.Join(dir, "values.yaml"), []byte("APP: web\nSTAGE: prod\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	yml = deployment("${APP}-${STAGE}")
+	links, err = r.ResolveDefinition(context.Background(), yml, "file://"+dir+"/deploy.yaml", 10, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetRange.Start.Line != 8 {
+		t.Fatalf("Expected ConfigMap web-prod, got %v", links)
+	}
+}
@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// kindPresentation is how resources of a kind are shown by completion,
+// document symbols and workspace symbols.
+type kindPresentation struct {
+	symbol     protocol.SymbolKind
+	completion protocol.CompletionItemKind
+}
+
+var (
+	workloadPresentation = kindPresentation{protocol.SymbolKindClass, protocol.CompletionItemKindClass}
+	configPresentation   = kindPresentation{protocol.SymbolKindFile, protocol.CompletionItemKindFile}
+	networkPresentation  = kindPresentation{protocol.SymbolKindInterface, protocol.CompletionItemKindInterface}
+	storagePresentation  = kindPresentation{protocol.SymbolKindObject, protocol.CompletionItemKindValue}
+	rbacPresentation     = kindPresentation{protocol.SymbolKindProperty, protocol.CompletionItemKindProperty}
+	policyPresentation   = kindPresentation{protocol.SymbolKindEvent, protocol.CompletionItemKindEvent}
+
+	// defaultPresentation covers CRDs and any kind not listed below.
+	defaultPresentation = kindPresentation{protocol.SymbolKindStruct, protocol.CompletionItemKindStruct}
+)
+
+var kindPresentations = map[string]kindPresentation{
+	"Pod":         workloadPresentation,
+	"Deployment":  workloadPresentation,
+	"ReplicaSet":  workloadPresentation,
+	"StatefulSet": workloadPresentation,
+	"DaemonSet":   workloadPresentation,
+	"Job":         workloadPresentation,
+	"CronJob":     workloadPresentation,
+
+	"ConfigMap": configPresentation,
+	"Secret":    configPresentation,
+
+	"Service":       networkPresentation,
+	"Ingress":       networkPresentation,
+	"IngressClass":  networkPresentation,
+	"NetworkPolicy": networkPresentation,
+
+	"Namespace": {protocol.SymbolKindModule, protocol.CompletionItemKindModule},
+
+	"PersistentVolumeClaim": storagePresentation,
+	"PersistentVolume":      storagePresentation,
+	"StorageClass":          storagePresentation,
+
+	"ServiceAccount":     rbacPresentation,
+	"Role":               rbacPresentation,
+	"ClusterRole":        rbacPresentation,
+	"RoleBinding":        rbacPresentation,
+	"ClusterRoleBinding": rbacPresentation,
+
+	"HorizontalPodAutoscaler": policyPresentation,
+	"PodDisruptionBudget":     policyPresentation,
+	"LimitRange":              policyPresentation,
+	"ResourceQuota":           policyPresentation,
+}
+
+func presentationForKind(kind string) kindPresentation {
+	if p, ok := kindPresentations[kind]; ok {
+		return p
+	}
+	return defaultPresentation
+}
+
+func symbolKindForResource(kind string) protocol.SymbolKind {
+	return presentationForKind(kind).symbol
+}
+
+func completionKindForResource(kind string) protocol.CompletionItemKind {
+	return presentationForKind(kind).completion
+}
+
+// resourceDetail describes a kind with its apiVersion, e.g. "apps/v1
+// Deployment", so same-named kinds from different groups can be told apart.
+func resourceDetail(apiVersion, kind string) string {
+	if apiVersion == "" {
+		return kind
+	}
+	return apiVersion + " " + kind
+}
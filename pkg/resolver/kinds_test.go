@@ -0,0 +1,43 @@
+package resolver
+
+import (
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestKindPresentation(t *testing.T) {
+	tests := []struct {
+		kind       string
+		symbol     protocol.SymbolKind
+		completion protocol.CompletionItemKind
+	}{
+		{"Deployment", protocol.SymbolKindClass, protocol.CompletionItemKindClass},
+		{"CronJob", protocol.SymbolKindClass, protocol.CompletionItemKindClass},
+		{"ConfigMap", protocol.SymbolKindFile, protocol.CompletionItemKindFile},
+		{"Secret", protocol.SymbolKindFile, protocol.CompletionItemKindFile},
+		{"Service", protocol.SymbolKindInterface, protocol.CompletionItemKindInterface},
+		{"Ingress", protocol.SymbolKindInterface, protocol.CompletionItemKindInterface},
+		{"Namespace", protocol.SymbolKindModule, protocol.CompletionItemKindModule},
+		// CRDs and kinds not in the table fall back to Struct.
+		{"Certificate", protocol.SymbolKindStruct, protocol.CompletionItemKindStruct},
+		{"", protocol.SymbolKindStruct, protocol.CompletionItemKindStruct},
+	}
+	for _, tt := range tests {
+		if got := symbolKindForResource(tt.kind); got != tt.symbol {
+			t.Errorf("symbolKindForResource(%q) = %v, want %v", tt.kind, got, tt.symbol)
+		}
+		if got := completionKindForResource(tt.kind); got != tt.completion {
+			t.Errorf("completionKindForResource(%q) = %v, want %v", tt.kind, got, tt.completion)
+		}
+	}
+}
+
+func TestResourceDetail(t *testing.T) {
+	if got := resourceDetail("apps/v1", "Deployment"); got != "apps/v1 Deployment" {
+		t.Errorf("Unexpected detail %q", got)
+	}
+	if got := resourceDetail("", "Deployment"); got != "Deployment" {
+		t.Errorf("Unexpected detail without apiVersion %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestLabelSelectorNavigation(t *testing.T) {
+	// The shipped rules declare the PodDisruptionBudget and NetworkPolicy
+	// selectors.
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	deploymentYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+`
+	idx.IndexContent("/tmp/deployment.yaml", deploymentYaml)
+
+	pdbYaml := `apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: web
+    matchExpressions:
+      - key: app
+        operator: In
+        values: [web]
+`
+	idx.IndexContent("/tmp/pdb.yaml", pdbYaml)
+
+	policyYaml := `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web
+spec:
+  podSelector:
+    matchLabels:
+      app: web
+  policyTypes: [Ingress]
+`
+	idx.IndexContent("/tmp/policy.yaml", policyYaml)
+
+	tests := []struct {
+		name      string
+		yaml, uri string
+		line, col int
+	}{
+		// "      app: web" under the PDB's matchLabels, line 8.
+		{"pdb", pdbYaml, "file:///tmp/pdb.yaml", 8, 12},
+		// "      app: web" under the NetworkPolicy's podSelector, line 7.
+		{"networkpolicy", policyYaml, "file:///tmp/policy.yaml", 7, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := r.ResolveDefinition(context.Background(), tt.yaml, tt.uri, tt.line, tt.col)
+			if err != nil {
+				t.Fatalf("ResolveDefinition failed: %v", err)
+			}
+			if len(links) != 1 || links[0].TargetURI != "file:///tmp/deployment.yaml" {
+				t.Fatalf("Expected the labeled Deployment, got %v", links)
+			}
+
+			locs, err := r.ResolveReferences(context.Background(), tt.yaml, tt.uri, tt.line, tt.col)
+			if err != nil {
+				t.Fatalf("ResolveReferences failed: %v", err)
+			}
+			if !containsLocation(locs, "file:///tmp/deployment.yaml") {
+				t.Errorf("Expected the Deployment among the references, got %v", locs)
+			}
+		})
+	}
+
+	// From the Deployment's label, both selectors are references.
+	locs, err := r.ResolveReferences(context.Background(), deploymentYaml, "file:///tmp/deployment.yaml", 5, 10)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if !containsLocation(locs, "file:///tmp/pdb.yaml") || !containsLocation(locs, "file:///tmp/policy.yaml") {
+		t.Errorf("Expected the PDB and NetworkPolicy selectors, got %v", locs)
+	}
+
+	// matchExpressions entries are not labels.
+	links, err := r.ResolveDefinition(context.Background(), pdbYaml, "file:///tmp/pdb.yaml", 10, 14)
+	if err != nil || len(links) != 0 {
+		t.Errorf("Expected no definition for a matchExpressions key, got %v (%v)", links, err)
+	}
+}
+
+func containsLocation(locs []protocol.Location, uri string) bool {
+	for _, loc := range locs {
+		if loc.URI == uri {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_IntoLibraryRoot(t *testing.T) {
+	libDir := t.TempDir()
+	libFile := filepath.Join(libDir, "shared", "secret.yaml")
+	if err := os.MkdirAll(filepath.Dir(libFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(libFile, []byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: registry-creds
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Settings: config.Settings{LibraryRoots: []string{libDir}},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Secret", "Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "workload.imagePullSecrets",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Secret",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Deployment"},
+					Path:  "spec.template.spec.imagePullSecrets[].name",
+				},
+			},
+		},
+	}
+
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.SetLibraryRoots(cfg.Settings.LibraryRoots)
+	if err := idx.ScanLibraries(); err != nil {
+		t.Fatalf("ScanLibraries failed: %v", err)
+	}
+
+	secret := store.Get("Secret", "default", "registry-creds")
+	if secret == nil {
+		t.Fatal("Expected library Secret to be indexed")
+	}
+	if !secret.Library {
+		t.Error("Expected library resource to be marked read-only")
+	}
+
+	deployYaml := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+        - name: registry-creds
+`
+	idx.IndexContent("/workspace/deploy.yaml", deployYaml)
+	if res := store.Get("Deployment", "default", "web"); res == nil || res.Library {
+		t.Fatalf("Expected workspace resource not to be marked as library, got %+v", res)
+	}
+
+	// "        - name: registry-creds" is line 9 (0-based), value at col 16
+	locs, err := NewResolver(store, cfg).ResolveDefinition(context.Background(), deployYaml, "file:///workspace/deploy.yaml", 9, 18)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].TargetURI != "file://"+libFile {
+		t.Fatalf("Expected definition in library file %s, got %v", libFile, locs)
+	}
+}
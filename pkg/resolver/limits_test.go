@@ -0,0 +1,53 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveSkipsTooComplexDocuments(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: deep\nspec:\n")
+	for d := 1; d <= 2000; d++ {
+		b.WriteString(strings.Repeat(" ", d) + "a:\n")
+	}
+	content := b.String()
+
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+	// Cursor on the innermost key.
+	links, err := r.ResolveDefinition(context.Background(), content, "file:///tmp/deep.yaml", 2004, 2001)
+	if err != nil || links != nil {
+		t.Errorf("Expected no definition, got %v, %v", links, err)
+	}
+	if symbols := r.DocumentSymbols(content); len(symbols) != 0 {
+		t.Errorf("Expected no symbols, got %d", len(symbols))
+	}
+}
+
+func TestFindResourceInFileUsesConfiguredLimits(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: deep\ndata:\n  key: value\nspec:\n")
+	for d := 1; d <= 150; d++ {
+		b.WriteString(strings.Repeat(" ", d) + "a:\n")
+	}
+	path := filepath.Join(t.TempDir(), "deep.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	r := NewResolver(indexer.NewStore(), cfg)
+	if _, err := r.findResourceInFile(path, "ConfigMap", "", "deep"); err == nil {
+		t.Fatal("Expected a document above the default depth limit to be skipped")
+	}
+	cfg.Settings.DocumentLimits.MaxDepth = 200
+	if _, _, err := r.findResourceDataEntryInFile(path, "ConfigMap", "", "deep", "key"); err != nil {
+		t.Errorf("Expected the raised depth limit to apply, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_InsideList(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secrets.yaml")
+	if err := os.WriteFile(secretPath, []byte(`apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: other
+    data:
+      password: b3RoZXI=
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: db-creds
+    data:
+      username: YWRtaW4=
+      password: c2VjcmV0
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Secret", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexFile(secretPath)
+	r := NewResolver(store, cfg)
+
+	podYaml := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Pod
+    metadata:
+      name: app
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: DB_PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: db-creds
+                  key: password
+`
+	idx.IndexContent("/tmp/pod.yaml", podYaml)
+
+	// Cursor on the Secret name, "                  name: db-creds" on line 14.
+	links, err := r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 14, 26)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file://"+secretPath || links[0].TargetRange.Start.Line != 12 {
+		t.Fatalf("Expected the Secret name at line 12, got %v", links)
+	}
+
+	// Cursor on the key: the data entry of the second item, not the first.
+	links, err = r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 15, 25)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) == 0 || links[0].TargetURI != "file://"+secretPath || links[0].TargetRange.Start.Line != 15 {
+		t.Fatalf("Expected the password entry at line 15, got %v", links)
+	}
+
+	explanation, err := r.ExplainPosition(podYaml, 14, 26)
+	if err != nil {
+		t.Fatalf("ExplainPosition failed: %v", err)
+	}
+	if explanation == nil || explanation.Kind != "Pod" {
+		t.Errorf("Expected the position to be read as part of the Pod, got %+v", explanation)
+	}
+}
@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestNamespaceAliasResolution(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{
+			NamespaceAliases: map[string]string{"a-alias": "a"},
+		},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Secret", "ExternalSecret"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "secret.ref",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Secret",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"ExternalSecret"},
+					Path:  "spec.secretRef.name",
+				},
+			},
+		},
+	}
+
+	store := indexer.NewStore()
+	store.SetNamespaceAliases(cfg.Settings.NamespaceAliases)
+	idx := indexer.NewIndexer(store, cfg)
+	res := NewResolver(store, cfg)
+
+	secretYaml := `apiVersion: v1
+kind: Secret
+metadata:
+  name: my-secret
+  namespace: a
+`
+	idx.IndexContent("/tmp/secret.yaml", secretYaml)
+
+	esYaml := `apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: my-es
+  namespace: default
+spec:
+  secretRef:
+    name: my-secret
+    namespace: a-alias
+`
+	idx.IndexContent("/tmp/es.yaml", esYaml)
+
+	// "    name: my-secret" on line 7.
+	links, err := res.ResolveDefinition(context.Background(), esYaml, "file:///tmp/es.yaml", 7, 11)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/secret.yaml" {
+		t.Fatalf("Expected the Secret in namespace a, got %v", links)
+	}
+
+	// The explicit a-alias namespace counts as a reference to a/my-secret.
+	locs, err := res.ResolveReferences(context.Background(), secretYaml, "file:///tmp/secret.yaml", 3, 9)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].URI != "file:///tmp/es.yaml" || locs[0].Range.Start.Line != 7 {
+		t.Errorf("Expected the ExternalSecret reference, got %v", locs)
+	}
+}
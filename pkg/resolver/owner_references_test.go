@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_OwnerReferenceOfGeneratedPod(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Pod", "ReplicaSet"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/rs.yaml", `apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web-7d9f8
+  generateName: web-
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  generateName: api-
+`)
+	r := NewResolver(store, cfg)
+
+	podYaml := `apiVersion: v1
+kind: Pod
+metadata:
+  generateName: web-7d9f8-
+  ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: web-7d9f8
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  generateName: api-5c6b4-
+  ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: api-5c6b4
+`
+	idx.IndexContent("/tmp/pods.yaml", podYaml)
+
+	// "      name: web-7d9f8" on line 7 resolves by exact name, even though
+	// the Pod itself is only indexed under its prefix.
+	links, err := r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pods.yaml", 7, 14)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/rs.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the ReplicaSet by exact name, got %v", links)
+	}
+
+	// "      name: api-5c6b4" on line 16 falls back to the ReplicaSet whose
+	// generateName prefix matches.
+	links, err = r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pods.yaml", 16, 14)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetRange.Start.Line != 9 {
+		t.Fatalf("Expected the generateName ReplicaSet, got %v", links)
+	}
+
+	if refs := store.FindReferences("ReplicaSet", "web-7d9f8"); len(refs) != 1 || refs[0].Name != "web-7d9f8-" {
+		t.Fatalf("Expected the generated Pod to reference its owner, got %v", refs)
+	}
+}
+
+func TestResolveDefinition_OwnerReferenceByUID(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Pod", "ReplicaSet"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/default-rs.yaml", `apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web
+  uid: 0b5c1b7e-default
+`)
+	idx.IndexContent("/tmp/prod-rs.yaml", `apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web
+  namespace: prod
+  uid: 7f3e2a10-prod
+`)
+	r := NewResolver(store, cfg)
+
+	// Exported from a namespace the workspace has no ReplicaSet "web" in,
+	// so only the uid tells which of the two it is.
+	podYaml := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web-abc12
+  namespace: exported
+  ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: web
+      uid: 7f3e2a10-prod
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-def34
+  namespace: exported
+  ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: web
+`
+	idx.IndexContent("/tmp/pods.yaml", podYaml)
+
+	tests := []struct {
+		name   string
+		line   int
+		target string
+	}{
+		{"by uid", 8, "file:///tmp/prod-rs.yaml"},
+		{"name only", 18, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pods.yaml", tt.line, 14)
+			if err != nil {
+				t.Fatalf("ResolveDefinition failed: %v", err)
+			}
+			if tt.target == "" {
+				if len(links) != 0 {
+					t.Fatalf("Expected no link without a uid, got %v", links)
+				}
+				return
+			}
+			if len(links) != 1 || links[0].TargetURI != tt.target {
+				t.Fatalf("Expected %s, got %v", tt.target, links)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestReferenceRulesMatchArrayIndex(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{{
+			Name: "k8s.resource.name",
+			Definitions: []config.SymbolDefinition{
+				{Kinds: []string{"Service", "Router"}, Path: "metadata.name"},
+			},
+		}},
+		// Only the first backend is a Service; later ones are hostnames.
+		References: []config.Reference{{
+			Name:       "router.primary",
+			Symbol:     "k8s.resource.name",
+			TargetKind: "Service",
+			Match:      config.ReferenceMatch{Kinds: []string{"Router"}, Path: "spec.backends[0].target"},
+		}},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/svc.yaml", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")
+	content := `apiVersion: example.com/v1
+kind: Router
+metadata:
+  name: edge
+spec:
+  backends:
+    - target: web
+    - target: web
+`
+	idx.IndexContent("/tmp/router.yaml", content)
+
+	locs, err := r.ResolveDefinition(context.Background(), content, "file:///tmp/router.yaml", 6, 15)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].TargetURI != "file:///tmp/svc.yaml" {
+		t.Errorf("Expected the first backend to resolve to the Service, got %v", locs)
+	}
+
+	locs, err = r.ResolveDefinition(context.Background(), content, "file:///tmp/router.yaml", 7, 15)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 0 {
+		t.Errorf("Expected the second backend not to match the rule, got %v", locs)
+	}
+}
+
+func TestFindNodeAtIndices(t *testing.T) {
+	content := `spec:
+  containers:
+    - name: app
+      ports:
+        - containerPort: 80
+        - containerPort: 443
+  args:
+    - - nested
+      - inner
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line, col int
+		path      string
+		indices   []int
+	}{
+		{3, 13, "spec.containers.name", []int{-1, 0, -1}},
+		{6, 26, "spec.containers.ports.containerPort", []int{-1, 0, 1, -1}},
+		{9, 9, "spec.args", []int{-1, 0}},
+	}
+	for _, tt := range tests {
+		found, _, path, indices := findNodeAt(&node, tt.line, tt.col)
+		if found == nil {
+			t.Fatalf("Expected a node at %d:%d", tt.line, tt.col)
+		}
+		if strings.Join(path, ".") != tt.path || !slices.Equal(indices, tt.indices) {
+			t.Errorf("At %d:%d expected %s %v, got %s %v", tt.line, tt.col, tt.path, tt.indices, strings.Join(path, "."), indices)
+		}
+	}
+
+	path, indices := []string{"spec", "containers", "ports", "containerPort"}, []int{-1, 0, 1, -1}
+	for pattern, want := range map[string]bool{
+		"spec.containers.ports.containerPort":       true,
+		"spec.containers[].ports[].containerPort":   true,
+		"spec.containers[0].ports[1].containerPort": true,
+		"spec.containers[0].ports[0].containerPort": false,
+		"spec.containers[1].ports.containerPort":    false,
+		"spec.*.ports.containerPort":                true,
+		"spec.*[0].ports.containerPort":             true,
+		"spec.containers.ports.*":                   true,
+		"*.containers.*":                            false,
+		"spec.*.containerPort":                      false,
+	} {
+		if got := matchPath(path, indices, pattern); got != want {
+			t.Errorf("matchPath(%s) = %v, want %v", pattern, got, want)
+		}
+	}
+	if matchPath(path, nil, "spec.containers[0].ports.containerPort") {
+		t.Error("Expected an index not to match a path without indices")
+	}
+	if !matchPathPrefix(path, indices, "spec.containers[0]") || matchPathPrefix(path, indices, "spec.containers[2]") {
+		t.Error("Expected matchPathPrefix to honor indices")
+	}
+	if !matchPathPrefix(path, indices, "spec.*") || matchPathPrefix(path, indices, "status.*") {
+		t.Error("Expected matchPathPrefix to honor wildcards")
+	}
+}
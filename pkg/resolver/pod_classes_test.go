@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestPodClassNameNavigation(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/classes.yaml", `apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: high-priority
+value: 1000000
+---
+apiVersion: node.k8s.io/v1
+kind: RuntimeClass
+metadata:
+  name: gvisor
+handler: runsc
+`)
+
+	cronJobYaml := `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+  namespace: batch
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          priorityClassName: high-priority
+          runtimeClassName: "gvisor"
+          containers:
+            - name: report
+              image: report:latest
+`
+	idx.IndexContent("/tmp/cronjob.yaml", cronJobYaml)
+
+	tests := []struct {
+		name       string
+		line, char int
+		targetLine uint32
+	}{
+		{"priorityClassName", 11, 32, 3},
+		{"runtimeClassName", 12, 31, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := r.ResolveDefinition(context.Background(), cronJobYaml, "file:///tmp/cronjob.yaml", tt.line, tt.char)
+			if err != nil {
+				t.Fatalf("ResolveDefinition failed: %v", err)
+			}
+			if len(links) != 1 || links[0].TargetURI != "file:///tmp/classes.yaml" || links[0].TargetRange.Start.Line != tt.targetLine {
+				t.Fatalf("Expected the class on line %d, got %v", tt.targetLine, links)
+			}
+
+			hover, err := r.ResolveHover(context.Background(), cronJobYaml, "file:///tmp/cronjob.yaml", tt.line, tt.char)
+			if err != nil || hover == nil {
+				t.Fatalf("Expected a hover, got %v (err %v)", hover, err)
+			}
+			if value := hover.Contents.(protocol.MarkupContent).Value; !strings.Contains(value, "/tmp/classes.yaml") {
+				t.Errorf("Expected the hover to show the class file, got %q", value)
+			}
+		})
+	}
+
+	// From the PriorityClass, references find the CronJob's pod spec.
+	classYaml := `apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: high-priority
+value: 1000000
+`
+	locs, err := r.ResolveReferences(context.Background(), classYaml, "file:///tmp/classes.yaml", 3, 8)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].URI != "file:///tmp/cronjob.yaml" || locs[0].Range.Start.Line != 11 {
+		t.Errorf("Expected the CronJob's priorityClassName, got %v", locs)
+	}
+}
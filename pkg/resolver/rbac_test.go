@@ -0,0 +1,215 @@
+package resolver
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestRoleRefNavigation(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+
+	rolesYaml := `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+  namespace: apps
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`
+	idx.IndexContent("/tmp/roles.yaml", rolesYaml)
+
+	bindingsYaml := `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: read-apps
+  namespace: apps
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: read-cluster
+  namespace: apps
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: read-all
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+`
+	idx.IndexContent("/tmp/bindings.yaml", bindingsYaml)
+	r := NewResolver(store, cfg)
+
+	// roleRef.kind picks the target: "  name: reader" on lines 8, 18 and 27.
+	for _, tc := range []struct {
+		line     int
+		wantLine uint32
+	}{
+		{8, 3},  // Role in the binding's namespace
+		{18, 9}, // ClusterRole from a RoleBinding
+		{27, 9}, // ClusterRole from a ClusterRoleBinding
+	} {
+		links, err := r.ResolveDefinition(context.Background(), bindingsYaml, "file:///tmp/bindings.yaml", tc.line, 9)
+		if err != nil {
+			t.Fatalf("ResolveDefinition failed: %v", err)
+		}
+		if len(links) != 1 || links[0].TargetURI != "file:///tmp/roles.yaml" || links[0].TargetRange.Start.Line != tc.wantLine {
+			t.Fatalf("line %d: expected roles.yaml line %d, got %v", tc.line, tc.wantLine, links)
+		}
+	}
+
+	hover, err := r.ResolveHover(context.Background(), bindingsYaml, "file:///tmp/bindings.yaml", 18, 9)
+	if err != nil {
+		t.Fatalf("ResolveHover failed: %v", err)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.(protocol.MarkupContent).Value, "Kind: ClusterRole") {
+		t.Fatalf("Expected hover for the ClusterRole, got %v", hover)
+	}
+
+	referenceLines := func(line int) []uint32 {
+		locs, err := r.ResolveReferences(context.Background(), rolesYaml, "file:///tmp/roles.yaml", line, 9)
+		if err != nil {
+			t.Fatalf("ResolveReferences failed: %v", err)
+		}
+		var lines []uint32
+		for _, loc := range locs {
+			if loc.URI == "file:///tmp/bindings.yaml" {
+				lines = append(lines, loc.Range.Start.Line)
+			}
+		}
+		sort.Slice(lines, func(i, j int) bool { return lines[i] < lines[j] })
+		return lines
+	}
+	if got := referenceLines(3); len(got) != 1 || got[0] != 8 {
+		t.Fatalf("Expected the Role to be referenced by its RoleBinding only, got %v", got)
+	}
+	if got := referenceLines(9); len(got) != 2 || got[0] != 18 || got[1] != 27 {
+		t.Fatalf("Expected the ClusterRole to be referenced by both bindings, got %v", got)
+	}
+}
+
+func TestBindingSubjectServiceAccounts(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ServiceAccount", "RoleBinding", "ClusterRoleBinding"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/sa.yaml", `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: deployer
+  namespace: apps
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: deployer
+  namespace: ci
+`)
+	r := NewResolver(store, cfg)
+
+	bindingsYaml := `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: deploy
+  namespace: apps
+subjects:
+  - kind: ServiceAccount
+    name: deployer
+  - kind: ServiceAccount
+    name: deployer
+    namespace: ci
+  - kind: User
+    name: deployer
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: deploy-all
+subjects:
+  - kind: ServiceAccount
+    name: deployer
+    namespace: ci
+`
+	idx.IndexContent("/tmp/bindings.yaml", bindingsYaml)
+
+	for _, tc := range []struct {
+		line     int
+		wantLine uint32
+	}{
+		{7, 3},  // the binding's namespace
+		{9, 9},  // the subject's namespace overrides it
+		{20, 9}, // ClusterRoleBindings rely on the subject's namespace
+	} {
+		links, err := r.ResolveDefinition(context.Background(), bindingsYaml, "file:///tmp/bindings.yaml", tc.line, 12)
+		if err != nil {
+			t.Fatalf("ResolveDefinition failed: %v", err)
+		}
+		if len(links) != 1 || links[0].TargetURI != "file:///tmp/sa.yaml" || links[0].TargetRange.Start.Line != tc.wantLine {
+			t.Fatalf("line %d: expected sa.yaml line %d, got %v", tc.line, tc.wantLine, links)
+		}
+	}
+
+	// User subjects aren't ServiceAccounts.
+	if links, _ := r.ResolveDefinition(context.Background(), bindingsYaml, "file:///tmp/bindings.yaml", 12, 12); len(links) != 0 {
+		t.Fatalf("Expected no definition for a User subject, got %v", links)
+	}
+
+	locs, err := r.ResolveReferences(context.Background(), `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: deployer
+  namespace: ci
+`, "file:///tmp/sa-ci.yaml", 3, 9)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	var lines []uint32
+	for _, loc := range locs {
+		if loc.URI == "file:///tmp/bindings.yaml" {
+			lines = append(lines, loc.Range.Start.Line)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i] < lines[j] })
+	if len(lines) != 2 || lines[0] != 9 || lines[1] != 20 {
+		t.Fatalf("Expected the ci ServiceAccount to be referenced by both ci subjects, got %v", lines)
+	}
+}
@@ -0,0 +1,200 @@
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// validResourceName matches RFC 1123 subdomains, which is what most kinds
+// accept in metadata.name.
+var validResourceName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// renameTarget is the resource a rename applies to and the range of its name
+// under the cursor.
+type renameTarget struct {
+	kind      string
+	namespace string
+	name      string
+	rng       protocol.Range
+}
+
+// PrepareRename returns the range of the resource name under the cursor,
+// without surrounding quotes, or nil when the position can't be renamed.
+func (r *Resolver) PrepareRename(docContent, uri string, line, col int) (*protocol.Range, error) {
+	target, err := r.renameTargetAt(docContent, uri, line, col)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	if def := r.lookupResource(target.kind, target.namespace, target.name); def != nil && def.Library {
+		return nil, fmt.Errorf("%s/%s belongs to a read-only library root", target.kind, target.name)
+	}
+	return &target.rng, nil
+}
+
+// Rename returns the edits renaming the resource under the cursor, which is
+// either its metadata.name or a reference to it. The definition and every
+// indexed reference to it from the same namespace are renamed; resources
+// under library roots are never edited.
+func (r *Resolver) Rename(docContent, uri string, line, col int, newName string) ([]PendingEdit, error) {
+	if !validResourceName.MatchString(newName) {
+		return nil, fmt.Errorf("%q is not a valid resource name", newName)
+	}
+
+	target, err := r.renameTargetAt(docContent, uri, line, col)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("no resource name at %d:%d", line+1, col+1)
+	}
+
+	var edits []PendingEdit
+	seen := make(map[string]bool)
+	addEdit := func(path string, line, col int) {
+		// Built-in extraction and rules may index the same position twice.
+		key := fmt.Sprintf("%s:%d:%d", path, line, col)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		edits = append(edits, PendingEdit{
+			URI: fileURI(path),
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(line), Character: uint32(col)},
+				End:   protocol.Position{Line: uint32(line), Character: uint32(col + len(target.name))},
+			},
+			OldText: target.name,
+			NewText: newName,
+		})
+	}
+
+	if def := r.lookupResource(target.kind, target.namespace, target.name); def != nil {
+		if def.Library {
+			return nil, fmt.Errorf("%s/%s belongs to a read-only library root", target.kind, target.name)
+		}
+		addEdit(def.FilePath, def.Line, def.Col)
+	}
+
+	for _, res := range r.Store.FindReferences(target.kind, target.name) {
+		if res.Library {
+			continue
+		}
+		for _, ref := range res.References {
+			if !isResourceNameReference(ref) || !r.sameKind(ref.Kind, target.kind) || ref.Name != target.name {
+				continue
+			}
+			if !indexer.IsClusterScoped(target.kind) && r.canonicalNamespace(referenceNamespace(res, ref)) != r.canonicalNamespace(target.namespace) {
+				continue
+			}
+			addEdit(res.FilePath, ref.Line, ref.Col)
+		}
+	}
+
+	return edits, nil
+}
+
+// renameTargetAt finds the resource name under the cursor: a metadata.name
+// defining a k8s.resource.name symbol, or a reference indexed for this file.
+func (r *Resolver) renameTargetAt(docContent, uri string, line, col int) (*renameTarget, error) {
+	decoder := position.NewDecoder(docContent)
+
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		node = *listItemAt(&node, line+1)
+
+		targetNode, parentNode, path, indices := findNodeAt(&node, line+1, col+1)
+		if targetNode == nil || targetNode.Kind != yaml.ScalarNode || targetNode.Value == "" || isMappingKey(parentNode, targetNode) {
+			continue
+		}
+
+		kind := findKind(&node)
+		for _, sym := range r.Config.Symbols {
+			if sym.Name != "k8s.resource.name" {
+				continue
+			}
+			for _, def := range sym.Definitions {
+				if matchPath(path, indices, def.Path) && containsKind(def.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && def.MatchesAPIVersion(findAPIVersion(&node)) {
+					definedKind := kind
+					if def.AliasKind != "" {
+						definedKind = def.AliasKind
+					}
+					return &renameTarget{
+						kind:      definedKind,
+						namespace: findNamespace(&node),
+						name:      targetNode.Value,
+						rng:       scalarValueRange(targetNode),
+					}, nil
+				}
+			}
+		}
+
+		for _, res := range r.Store.FindByFile(uriPath(uri)) {
+			for _, ref := range res.References {
+				if !isResourceNameReference(ref) || ref.Line != line || col < ref.Col || col > ref.Col+len(ref.Name) {
+					continue
+				}
+				return &renameTarget{
+					kind:      ref.Kind,
+					namespace: referenceNamespace(res, ref),
+					name:      ref.Name,
+					rng: protocol.Range{
+						Start: protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col)},
+						End:   protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col + len(ref.Name))},
+					},
+				}, nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+func (r *Resolver) lookupResource(kind, namespace, name string) *indexer.K8sResource {
+	if indexer.IsClusterScoped(kind) {
+		namespace = ""
+	}
+	return r.Store.Get(kind, namespace, name)
+}
+
+// isResourceNameReference reports whether ref points at a resource by name,
+// as opposed to a label selector entry or a ConfigMap data key.
+func isResourceNameReference(ref indexer.Reference) bool {
+	return ref.Kind != "" && ref.Symbol != "k8s.label" && ref.Key == ""
+}
+
+// referenceNamespace is the namespace a reference resolves in: the one it
+// names explicitly, or that of the referencing resource.
+func referenceNamespace(res *indexer.K8sResource, ref indexer.Reference) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return res.Namespace
+}
+
+// scalarValueRange covers a scalar's value, excluding surrounding quotes.
+func scalarValueRange(n *yaml.Node) protocol.Range {
+	start := n.Column - 1
+	if n.Style == yaml.DoubleQuotedStyle || n.Style == yaml.SingleQuotedStyle {
+		start++
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(n.Line - 1), Character: uint32(start)},
+		End:   protocol.Position{Line: uint32(n.Line - 1), Character: uint32(start + len(n.Value))},
+	}
+}
@@ -0,0 +1,176 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func renameConfig(libDir string) *config.Config {
+	return &config.Config{
+		Settings: config.Settings{LibraryRoots: []string{libDir}},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service", "Ingress"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "ingress.backend.service",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Service",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Ingress"},
+					Path:  "spec.rules[].http.paths[].backend.service.name",
+				},
+			},
+		},
+	}
+}
+
+func ingressYaml(name, namespace, service string) string {
+	return `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: ` + name + `
+  namespace: ` + namespace + `
+spec:
+  rules:
+    - http:
+        paths:
+          - backend:
+              service:
+                name: ` + service + `
+`
+}
+
+func TestRenameService(t *testing.T) {
+	dir := t.TempDir()
+	libDir := t.TempDir()
+
+	files := map[string]string{
+		filepath.Join(dir, "svc.yaml"): `apiVersion: v1
+kind: Service
+metadata:
+  name: "web"
+  namespace: prod
+`,
+		filepath.Join(dir, "ingress.yaml"):       ingressYaml("public", "prod", "web"),
+		filepath.Join(dir, "other-ingress.yaml"): ingressYaml("public", "staging", "web"),
+		filepath.Join(libDir, "ingress.yaml"):    ingressYaml("shared", "prod", "web"),
+	}
+	cfg := renameConfig(libDir)
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.SetLibraryRoots(cfg.Settings.LibraryRoots)
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		idx.IndexFile(path)
+	}
+	r := NewResolver(store, cfg)
+
+	ingressPath := filepath.Join(dir, "ingress.yaml")
+	ingressURI := "file://" + ingressPath
+	// "                name: web" - the reference starts at col 22.
+	rng, err := r.PrepareRename(files[ingressPath], ingressURI, 11, 23)
+	if err != nil {
+		t.Fatalf("PrepareRename failed: %v", err)
+	}
+	want := protocol.Range{
+		Start: protocol.Position{Line: 11, Character: 22},
+		End:   protocol.Position{Line: 11, Character: 25},
+	}
+	if rng == nil || *rng != want {
+		t.Fatalf("Expected prepare range %v, got %v", want, rng)
+	}
+
+	edits, err := r.Rename(files[ingressPath], ingressURI, 11, 23, "web-v2")
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	result := BuildVerifiedEdit(edits, func(uri string) (string, *protocol.Integer, bool) {
+		content, ok := files[strings.TrimPrefix(uri, "file://")]
+		return content, nil, ok
+	})
+	if len(result.Warnings) != 0 {
+		t.Fatalf("Expected no warnings, got %v", result.Warnings)
+	}
+
+	changed := make(map[string]bool)
+	for _, c := range result.DocumentChanges {
+		change := c.(protocol.TextDocumentEdit)
+		changed[strings.TrimPrefix(change.TextDocument.URI, "file://")] = true
+		if len(change.Edits) != 1 {
+			t.Errorf("Expected one edit in %s, got %d", change.TextDocument.URI, len(change.Edits))
+		}
+	}
+	if len(changed) != 2 || !changed[filepath.Join(dir, "svc.yaml")] || !changed[ingressPath] {
+		t.Fatalf("Expected edits in svc.yaml and ingress.yaml only, got %v", changed)
+	}
+
+	// The Service name is quoted; only the text between the quotes changes.
+	for _, c := range result.DocumentChanges {
+		change := c.(protocol.TextDocumentEdit)
+		if change.TextDocument.URI != "file://"+filepath.Join(dir, "svc.yaml") {
+			continue
+		}
+		edit := change.Edits[0].(protocol.TextEdit)
+		got, _ := textInRange(files[filepath.Join(dir, "svc.yaml")], edit.Range)
+		if got != "web" || edit.Range.Start.Character != 9 {
+			t.Errorf("Expected edit inside the quotes at col 9, got %q at %v", got, edit.Range)
+		}
+	}
+}
+
+func TestPrepareRenameRejectsInvalidPositions(t *testing.T) {
+	libDir := t.TempDir()
+	libFile := filepath.Join(libDir, "svc.yaml")
+	libContent := `apiVersion: v1
+kind: Service
+metadata:
+  name: shared
+`
+	if err := os.WriteFile(libFile, []byte(libContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := renameConfig(libDir)
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.SetLibraryRoots(cfg.Settings.LibraryRoots)
+	if err := idx.ScanLibraries(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewResolver(store, cfg)
+
+	content := ingressYaml("public", "default", "shared")
+	idx.IndexContent("/tmp/ingress.yaml", content)
+
+	// On the "name" key rather than its value.
+	if rng, err := r.PrepareRename(content, "file:///tmp/ingress.yaml", 11, 17); err != nil || rng != nil {
+		t.Errorf("Expected no rename on a key, got %v, %v", rng, err)
+	}
+	// On a value that isn't a resource name.
+	if rng, err := r.PrepareRename(content, "file:///tmp/ingress.yaml", 0, 14); err != nil || rng != nil {
+		t.Errorf("Expected no rename on apiVersion, got %v, %v", rng, err)
+	}
+	// On a reference to a read-only library resource.
+	if _, err := r.PrepareRename(content, "file:///tmp/ingress.yaml", 11, 23); err == nil {
+		t.Error("Expected an error renaming a library resource")
+	}
+	if _, err := r.Rename(content, "file:///tmp/ingress.yaml", 3, 9, "Not Valid"); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+}
@@ -2,15 +2,21 @@ package resolver
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"k8s-lsp/pkg/config"
 	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
+	"k8s-lsp/pkg/uri"
 
 	"github.com/rs/zerolog/log"
 	protocol "github.com/tliron/glsp/protocol_3_16"
@@ -26,10 +32,13 @@ func NewResolver(store *indexer.Store, cfg *config.Config) *Resolver {
 	return &Resolver{Store: store, Config: cfg}
 }
 
-func (r *Resolver) ResolveHover(docContent string, uri string, line, col int) (*protocol.Hover, error) {
-	decoder := yaml.NewDecoder(strings.NewReader(docContent))
+func (r *Resolver) ResolveHover(ctx context.Context, docContent string, uri string, line, col int) (*protocol.Hover, error) {
+	decoder := position.NewDecoder(docContent)
 
 	for {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
 		var node yaml.Node
 		if err := decoder.Decode(&node); err != nil {
 			if err == io.EOF {
@@ -37,13 +46,47 @@ func (r *Resolver) ResolveHover(docContent string, uri string, line, col int) (*
 			}
 			return nil, err
 		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		node = *listItemAt(&node, line+1)
 
-		targetNode, parentNode, path := findNodeAt(&node, line+1, col+1)
+		targetNode, parentNode, path, indices := findNodeAt(&node, line+1, col+1)
 		if targetNode != nil {
 			kind := findKind(&node)
 
+			// Opt-in summaries of pod hostAliases and DNS settings.
+			if r.Config.Settings.HostAliasLinks {
+				contents := ""
+				if isHostAliasPath(path) {
+					contents = r.hostAliasHover(&node, targetNode)
+				} else if isDNSConfigPath(path) {
+					contents = dnsConfigHover(&node)
+				}
+				if contents != "" {
+					return &protocol.Hover{
+						Contents: protocol.MarkupContent{
+							Kind:  protocol.MarkupKindMarkdown,
+							Value: contents,
+						},
+					}, nil
+				}
+			}
+
+			// envFrom sources list the variables they produce.
+			if isEnvFromSourcePath(path) && !isMappingKey(parentNode, targetNode) {
+				if contents := r.envFromHover(&node, kind, targetNode); contents != "" {
+					return &protocol.Hover{
+						Contents: protocol.MarkupContent{
+							Kind:  protocol.MarkupKindMarkdown,
+							Value: contents,
+						},
+					}, nil
+				}
+			}
+
 			// Check for ConfigMap embedded file
-			if kind == "ConfigMap" && len(path) >= 2 && (path[len(path)-2] == "data" || path[len(path)-2] == "binaryData") {
+			if r.featureEnabled(config.FeatureEmbeddedFiles) && kind == "ConfigMap" && len(path) >= 2 && (path[len(path)-2] == "data" || path[len(path)-2] == "binaryData") {
 				var valNode *yaml.Node
 				if parentNode != nil && parentNode.Kind == yaml.MappingNode {
 					for i := 0; i < len(parentNode.Content); i += 2 {
@@ -99,32 +142,42 @@ func (r *Resolver) ResolveHover(docContent string, uri string, line, col int) (*
 
 			currentNamespace := findNamespace(&node)
 
+			if ns, name, _, ok := injectCAFromTarget(targetNode, parentNode, path, currentNamespace); ok {
+				if res := r.Store.Get("Certificate", ns, name); res != nil {
+					contents := resourceHoverContents(res)
+
+					return &protocol.Hover{
+						Contents: protocol.MarkupContent{
+							Kind:  protocol.MarkupKindMarkdown,
+							Value: contents,
+						},
+					}, nil
+				}
+				return nil, nil
+			}
+
 			for _, refRule := range r.Config.References {
-				if matchesKind(refRule.Match.Kinds, kind) && matchPath(path, refRule.Match.Path) {
+				if matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && refRule.Match.MatchesAPIVersion(findAPIVersion(&node)) && matchPath(path, indices, refRule.Match.Path) {
 					if refRule.Symbol == "k8s.resource.name" {
 						targetKind := refRule.TargetKind
-						ns := currentNamespace
-						// Check for sibling namespace
-						if parentNode != nil && parentNode.Kind == yaml.MappingNode {
-							for k := 0; k < len(parentNode.Content); k += 2 {
-								if parentNode.Content[k].Value == "namespace" {
-									ns = parentNode.Content[k+1].Value
-									break
-								}
-							}
-						}
-						if targetKind == "Namespace" {
+						ns := siblingNamespace(parentNode, currentNamespace)
+						if indexer.IsClusterScoped(targetKind) {
 							ns = ""
 						}
 
-						res := r.Store.Get(targetKind, ns, targetNode.Value)
-						if res == nil && targetKind != "Namespace" && ns != "default" {
-							// Store treats empty/cluster-scoped namespaces as "default".
-							res = r.Store.Get(targetKind, "default", targetNode.Value)
+						name, ok := r.referenceName(targetNode.Value, uri)
+						if !ok {
+							return nil, nil
+						}
+						if refRule.CompositePath != "" {
+							ns, name, _, ok = compositeReference(refRule.CompositePath, targetNode, name, ns, protocol.Range{})
+							if !ok {
+								continue
+							}
 						}
+						res := r.lookupReference(targetKind, ns, name)
 						if res != nil {
-							contents := fmt.Sprintf("**%s**\n\nKind: %s\nNamespace: %s\nFile: %s",
-								res.Name, res.Kind, res.Namespace, res.FilePath)
+							contents := resourceHoverContents(res)
 
 							return &protocol.Hover{
 								Contents: protocol.MarkupContent{
@@ -133,18 +186,46 @@ func (r *Resolver) ResolveHover(docContent string, uri string, line, col int) (*
 								},
 							}, nil
 						}
+					} else if refRule.Symbol == "k8s.field" {
+						matches := r.findResourcesByField(refRule.TargetKind, siblingNamespace(parentNode, currentNamespace), refRule.Field, targetNode.Value)
+						if len(matches) > 0 {
+							return &protocol.Hover{
+								Contents: protocol.MarkupContent{
+									Kind:  protocol.MarkupKindMarkdown,
+									Value: fieldSelectorHoverContents(matches),
+								},
+							}, nil
+						}
 					}
 				}
 			}
+
+			// Fall back to references the indexer extracts without rules,
+			// e.g. serviceAccountName in Pods and CronJobs.
+			if !isMappingKey(parentNode, targetNode) {
+				if res := r.indexedReferenceTarget(uri, line, col); res != nil {
+					contents := resourceHoverContents(res)
+
+					return &protocol.Hover{
+						Contents: protocol.MarkupContent{
+							Kind:  protocol.MarkupKindMarkdown,
+							Value: contents,
+						},
+					}, nil
+				}
+			}
 		}
 	}
 	return nil, nil
 }
 
-func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col int) ([]protocol.LocationLink, error) {
-	decoder := yaml.NewDecoder(strings.NewReader(docContent))
+func (r *Resolver) ResolveDefinition(ctx context.Context, docContent string, uri string, line, col int) ([]protocol.LocationLink, error) {
+	decoder := position.NewDecoder(docContent)
 
 	for {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
 		var node yaml.Node
 		if err := decoder.Decode(&node); err != nil {
 			if err == io.EOF {
@@ -153,9 +234,13 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 			log.Error().Err(err).Msg("Failed to parse YAML for definition")
 			return nil, err
 		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		node = *listItemAt(&node, line+1)
 
 		// LSP is 0-based, yaml.v3 is 1-based
-		targetNode, parentNode, path := findNodeAt(&node, line+1, col+1)
+		targetNode, parentNode, path, indices := findNodeAt(&node, line+1, col+1)
 		if targetNode != nil {
 			log.Debug().Str("value", targetNode.Value).Strs("path", path).Msg("Found node at cursor")
 
@@ -163,11 +248,16 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 
 			// Special case: within a workload, go-to-definition for
 			// containers[].volumeMounts[].name -> spec.template.spec.volumes[].name
-			// (and initContainers[].volumeMounts[].name).
-			if isVolumeMountNamePath(path) {
+			// (and initContainers[].volumeMounts[].name). A StatefulSet can
+			// also mount spec.volumeClaimTemplates[].metadata.name.
+			if r.featureEnabled(config.FeatureVolumeMountNavigation) && isVolumeMountNamePath(path) {
 				podSpec := findPodSpecNode(&node)
 				if podSpec != nil {
-					if volNameNode := findVolumeNameNodeByName(podSpec, targetNode.Value); volNameNode != nil {
+					volNameNode := findVolumeNameNodeByName(podSpec, targetNode.Value)
+					if volNameNode == nil {
+						volNameNode = findVolumeClaimTemplateNameNode(&node, targetNode.Value)
+					}
+					if volNameNode != nil {
 						targetRange := protocol.Range{
 							Start: protocol.Position{Line: uint32(volNameNode.Line - 1), Character: uint32(volNameNode.Column - 1)},
 							End:   protocol.Position{Line: uint32(volNameNode.Line - 1), Character: uint32(volNameNode.Column - 1 + len(volNameNode.Value))},
@@ -183,9 +273,76 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 				}
 			}
 
+			// Special case: env[].valueFrom.secretKeyRef.{name,key} -> the
+			// Secret, or its data entry (and embedded file) for the key.
+			// envFrom[].secretRef.name links to the Secret, including in
+			// Pods and initContainers which the reference rules don't cover.
+			if (isSecretKeyRefPath(path) || isEnvFromSecretRefPath(path)) && !isMappingKey(parentNode, targetNode) {
+				if links := r.secretKeyRefDefinition(&node, parentNode, path, originRange); len(links) > 0 {
+					return links, nil
+				}
+			}
+
+			// subjects[].name of a RoleBinding/ClusterRoleBinding -> the
+			// ServiceAccount, in the subject's own namespace when it names one.
+			if isServiceAccountSubjectPath(path, parentNode) && !isMappingKey(parentNode, targetNode) {
+				ns := siblingNamespace(parentNode, findNamespace(&node))
+				if res := r.lookupResource("ServiceAccount", ns, targetNode.Value); res != nil {
+					targetRange := protocol.Range{
+						Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+						End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+					}
+					return []protocol.LocationLink{{
+						OriginSelectionRange: &originRange,
+						TargetURI:            fileURI(res.FilePath),
+						TargetRange:          targetRange,
+						TargetSelectionRange: targetRange,
+					}}, nil
+				}
+			}
+
+			// Ingress backend.service.name -> the Service, and
+			// backend.service.port.{name,number} -> its ports[] entry.
+			if findKind(&node) == "Ingress" && !isMappingKey(parentNode, targetNode) {
+				if isIngressServiceRef(path) {
+					if links := r.findServiceByName(findNamespace(&node), targetNode.Value, originRange); len(links) > 0 {
+						return links, nil
+					}
+				}
+				if isIngressServicePortPath(path) {
+					if links := r.ingressServicePortDefinition(&node, parentNode, targetNode, path[len(path)-1], originRange); len(links) > 0 {
+						return links, nil
+					}
+				}
+			}
+
+			// Opt-in: hostAliases[].ip and hostnames[] -> the Services whose
+			// clusterIP or externalName they name.
+			if r.Config.Settings.HostAliasLinks && isHostAliasPath(path) && !isMappingKey(parentNode, targetNode) {
+				if links := r.hostAliasDefinitions(targetNode.Value, originRange); len(links) > 0 {
+					return links, nil
+				}
+			}
+
+			// Opt-in: containers[].image -> the resources declaring the image
+			// as their build artifact via the configured annotation.
+			if r.Config.Settings.ImageBuildAnnotation != "" && isContainerImagePath(path) && !isMappingKey(parentNode, targetNode) {
+				if links := r.buildImageDefinitions(targetNode.Value, originRange); len(links) > 0 {
+					return links, nil
+				}
+			}
+
+			// containers[].env[].name -> the line setting the variable in a
+			// dotenv-format embedded file of a ConfigMap the workload uses.
+			if isContainerEnvNamePath(path) && !isMappingKey(parentNode, targetNode) {
+				if links := r.dotenvDefinitions(&node, targetNode.Value, originRange); len(links) > 0 {
+					return links, nil
+				}
+			}
+
 			// Check for ConfigMap embedded file
 			kind := findKind(&node)
-			if kind == "ConfigMap" && len(path) >= 2 && (path[len(path)-2] == "data" || path[len(path)-2] == "binaryData") {
+			if r.featureEnabled(config.FeatureEmbeddedFiles) && kind == "ConfigMap" && len(path) >= 2 && (path[len(path)-2] == "data" || path[len(path)-2] == "binaryData") {
 				// Check if targetNode is a key
 				var valNode *yaml.Node
 				if parentNode != nil && parentNode.Kind == yaml.MappingNode {
@@ -235,10 +392,29 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 
 			currentNamespace := findNamespace(&node)
 
+			// cert-manager.io/inject-ca-from: <namespace>/<certificate>
+			if ns, name, nameRange, ok := injectCAFromTarget(targetNode, parentNode, path, currentNamespace); ok {
+				res := r.Store.Get("Certificate", ns, name)
+				if res == nil {
+					log.Debug().Str("ns", ns).Str("name", name).Msg("Certificate not found in store")
+					return nil, nil
+				}
+				targetRange := protocol.Range{
+					Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+					End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+				}
+				return []protocol.LocationLink{{
+					OriginSelectionRange: &nameRange,
+					TargetURI:            fileURI(res.FilePath),
+					TargetRange:          targetRange,
+					TargetSelectionRange: targetRange,
+				}}, nil
+			}
+
 			// Check if we are at a definition site (Symbol)
 			for _, sym := range r.Config.Symbols {
 				for _, def := range sym.Definitions {
-					if contains(def.Kinds, kind) && matchPath(path, def.Path) {
+					if containsKind(def.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && def.MatchesAPIVersion(findAPIVersion(&node)) && matchPath(path, indices, def.Path) {
 						log.Debug().Str("symbol", sym.Name).Msg("Found definition site at cursor")
 						// We are at the definition. Return self.
 						// We need to construct a LocationLink where TargetURI is the current file.
@@ -263,42 +439,44 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 			for _, refRule := range r.Config.References {
 				isMatch := false
 				if refRule.Symbol == "k8s.label" {
-					isMatch = matchPathPrefix(path, refRule.Match.Path)
+					isMatch = matchPathPrefix(path, indices, refRule.Match.Path)
 				} else {
-					isMatch = matchPath(path, refRule.Match.Path)
+					isMatch = matchPath(path, indices, refRule.Match.Path)
 				}
 
-				if matchesKind(refRule.Match.Kinds, kind) && isMatch {
+				if matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && refRule.Match.MatchesAPIVersion(findAPIVersion(&node)) && isMatch {
 					if refRule.Symbol == "k8s.label" {
-						labelKey := path[len(path)-1]
+						labelKey, ok := selectorLabelKey(path, refRule.Match.Path)
+						if !ok {
+							continue
+						}
 						labelValue := targetNode.Value
 						return r.findWorkloadsByLabel(labelKey, labelValue, originRange), nil
 					} else if refRule.Symbol == "k8s.resource.name" {
 						targetKind := refRule.TargetKind
 
 						if targetKind != "" {
-							// Namespace resource has no namespace
-							ns := currentNamespace
-							// Check for sibling namespace
-							if parentNode != nil && parentNode.Kind == yaml.MappingNode {
-								for k := 0; k < len(parentNode.Content); k += 2 {
-									if parentNode.Content[k].Value == "namespace" {
-										ns = parentNode.Content[k+1].Value
-										break
-									}
-								}
-							}
+							// Cluster-scoped resources (Namespace, StorageClass)
+							// have no namespace
+							ns := siblingNamespace(parentNode, currentNamespace)
 
-							if targetKind == "Namespace" {
+							if indexer.IsClusterScoped(targetKind) {
 								ns = "" // or "default" depending on store
 							}
 
-							log.Debug().Str("kind", targetKind).Str("ns", ns).Str("name", targetNode.Value).Msg("Looking up definition")
-							res := r.Store.Get(targetKind, ns, targetNode.Value)
-							if res == nil && targetKind != "Namespace" && ns != "default" {
-								// Store treats empty/cluster-scoped namespaces as "default".
-								res = r.Store.Get(targetKind, "default", targetNode.Value)
+							name, ok := r.referenceName(targetNode.Value, uri)
+							if !ok {
+								log.Debug().Str("value", targetNode.Value).Msg("Unresolved interpolation in reference")
+								return nil, nil
+							}
+							if refRule.CompositePath != "" {
+								ns, name, originRange, ok = compositeReference(refRule.CompositePath, targetNode, name, ns, originRange)
+								if !ok {
+									continue
+								}
 							}
+							log.Debug().Str("kind", targetKind).Str("ns", ns).Str("name", name).Msg("Looking up definition")
+							res := r.lookupReference(targetKind, ns, name)
 							if res != nil {
 								targetRange := protocol.Range{
 									Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
@@ -306,7 +484,7 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 								}
 								return []protocol.LocationLink{{
 									OriginSelectionRange: &originRange,
-									TargetURI:            "file://" + res.FilePath,
+									TargetURI:            fileURI(res.FilePath),
 									TargetRange:          targetRange,
 									TargetSelectionRange: targetRange,
 								}}, nil
@@ -314,10 +492,23 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 								log.Debug().Msg("Definition not found in store")
 							}
 						}
+					} else if refRule.Symbol == "k8s.field" {
+						matches := r.findResourcesByField(refRule.TargetKind, siblingNamespace(parentNode, currentNamespace), refRule.Field, targetNode.Value)
+						if len(matches) > 0 {
+							return fieldSelectorLinks(matches, originRange), nil
+						}
 					}
 				}
 			}
 
+			// Fall back to references the indexer extracts without rules,
+			// e.g. configMapKeyRef in Pods or initContainers.
+			if !isMappingKey(parentNode, targetNode) {
+				if link, ok := r.indexedReferenceDefinition(uri, line, col, originRange); ok {
+					return []protocol.LocationLink{link}, nil
+				}
+			}
+
 			return nil, nil
 		}
 	}
@@ -325,20 +516,129 @@ func (r *Resolver) ResolveDefinition(docContent string, uri string, line, col in
 	return nil, nil
 }
 
-func (r *Resolver) ResolveReferences(docContent string, uri string, line, col int) ([]protocol.Location, error) {
-	decoder := yaml.NewDecoder(strings.NewReader(docContent))
+// lookupReference finds the target of a reference rule, falling back to the
+// "default" namespace where Store keeps empty and cluster-scoped namespaces.
+func (r *Resolver) lookupReference(kind, namespace, name string) *indexer.K8sResource {
+	target, _ := r.lookupTarget(kind, namespace, name)
+	return target.res
+}
+
+// referenceName substitutes the configured interpolation variables into a
+// reference value. ok is false when a $(var) or ${var} stays unresolved.
+func (r *Resolver) referenceName(value, uri string) (string, bool) {
+	if !r.Config.Settings.Interpolation.Enabled() || !indexer.HasInterpolation(value) {
+		return value, true
+	}
+	vars := indexer.InterpolationVars(r.Config.Settings.Interpolation, uriPath(uri))
+	return indexer.Interpolate(value, vars)
+}
+
+// resourceHoverContents describes a resource for hovers on references to it.
+func resourceHoverContents(res *indexer.K8sResource) string {
+	contents := fmt.Sprintf("**%s**\n\nKind: %s\nNamespace: %s\nFile: %s",
+		res.Name, res.Kind, res.Namespace, res.FilePath)
+	if res.DeclaredBy != nil {
+		contents += fmt.Sprintf("\n\ndeclared by %s %s", res.DeclaredBy.Kind, res.DeclaredBy.Name)
+	}
+	return contents
+}
+
+// indexedReferenceTarget returns the resource named by the indexed resource
+// name reference of uri at line/col.
+func (r *Resolver) indexedReferenceTarget(uri string, line, col int) *indexer.K8sResource {
+	target, _ := r.indexedReferenceResolution(uri, line, col)
+	return target.res
+}
+
+// indexedReferenceResolution is indexedReferenceTarget, reporting how the
+// target was found.
+func (r *Resolver) indexedReferenceResolution(uri string, line, col int) (resolvedTarget, bool) {
+	for _, res := range r.Store.FindByFile(uriPath(uri)) {
+		for _, ref := range res.References {
+			if !isResourceNameReference(ref) || ref.Line != line || col < ref.Col || col > ref.Col+len(ref.Name) {
+				continue
+			}
+			// A uid names exactly one resource, however ambiguous the name.
+			if target := r.Store.GetByUID(ref.UID); target != nil && matchesKind([]string{ref.Kind}, target.Kind, r.Config.Settings.CaseInsensitiveKinds) {
+				return resolvedTarget{target, ConfidenceExact}, true
+			}
+			if target := r.lookupResource(ref.Kind, referenceNamespace(res, ref), ref.Name); target != nil {
+				return resolvedTarget{target, r.kindConfidence(ref.Kind, target, ConfidenceExact)}, true
+			}
+			// Exported state names owners like ReplicaSets exactly, while
+			// the manifest may only carry their generateName prefix. Other
+			// references name what they mean, so a prefix proves nothing.
+			if ref.Symbol != "k8s.owner" {
+				continue
+			}
+			ns := referenceNamespace(res, ref)
+			if indexer.IsClusterScoped(ref.Kind) {
+				ns = ""
+			}
+			if target := r.Store.FindGenerated(ref.Kind, ns, ref.Name); target != nil {
+				return resolvedTarget{target, ConfidenceNamePrefix}, true
+			}
+		}
+	}
+	return resolvedTarget{}, false
+}
+
+// indexedReferenceDefinition links the indexed resource name reference of
+// uri at line/col to the resource it names.
+func (r *Resolver) indexedReferenceDefinition(uri string, line, col int, originRange protocol.Range) (protocol.LocationLink, bool) {
+	target := r.indexedReferenceTarget(uri, line, col)
+	if target == nil {
+		return protocol.LocationLink{}, false
+	}
+	targetRange := protocol.Range{
+		Start: protocol.Position{Line: uint32(target.Line), Character: uint32(target.Col)},
+		End:   protocol.Position{Line: uint32(target.Line), Character: uint32(target.Col + len(target.Name))},
+	}
+	return protocol.LocationLink{
+		OriginSelectionRange: &originRange,
+		TargetURI:            fileURI(target.FilePath),
+		TargetRange:          targetRange,
+		TargetSelectionRange: targetRange,
+	}, true
+}
+
+// ResolveReferences returns every reference to the symbol at line/col.
+func (r *Resolver) ResolveReferences(ctx context.Context, docContent string, uri string, line, col int) ([]protocol.Location, error) {
+	var locs []protocol.Location
+	err := r.StreamReferences(ctx, docContent, uri, line, col, func(batch []protocol.Location) {
+		locs = append(locs, batch...)
+	})
+	if err != nil || ctx.Err() != nil {
+		return nil, err
+	}
+	return locs, nil
+}
+
+// StreamReferences finds the same references as ResolveReferences but
+// hands them to emit in batches as the Store scan discovers them, so a
+// client can show the first results of a large workspace early. Batches
+// are never empty and never repeat a location.
+func (r *Resolver) StreamReferences(ctx context.Context, docContent string, uri string, line, col int, emit func([]protocol.Location)) error {
+	decoder := position.NewDecoder(docContent)
 
 	for {
+		if ctx.Err() != nil {
+			return nil
+		}
 		var node yaml.Node
 		if err := decoder.Decode(&node); err != nil {
 			if err == io.EOF {
 				break
 			}
 			log.Error().Err(err).Msg("Failed to parse YAML for references")
-			return nil, err
+			return err
 		}
+		if r.tooComplex(&node) {
+			continue
+		}
+		node = *listItemAt(&node, line+1)
 
-		targetNode, parentNode, path := findNodeAt(&node, line+1, col+1)
+		targetNode, parentNode, path, indices := findNodeAt(&node, line+1, col+1)
 		if targetNode != nil {
 			log.Debug().Str("value", targetNode.Value).Strs("path", path).Msg("Found node at cursor (References)")
 
@@ -346,17 +646,18 @@ func (r *Resolver) ResolveReferences(docContent string, uri string, line, col in
 			// with multiple targets so the user can choose:
 			// - the ConfigMap key definition (in the ConfigMap YAML)
 			// - the virtual embedded file (k8s-embedded://)
-			if isVolumeMountSubPathPath(path) {
+			if r.featureEnabled(config.FeatureSubPathTargets) && isVolumeMountSubPathPath(path) {
 				locs := r.findVolumeMountSubPathTargets(&node, parentNode, targetNode.Value)
 				if len(locs) > 0 {
-					return locs, nil
+					emit(locs)
+					return nil
 				}
 			}
 
 			// Special case: ConfigMap embedded file (data/binaryData key)
 			// Shift+F12 should return all usages (mounts/refs), not the virtual file.
 			kind := findKind(&node)
-			if kind == "ConfigMap" && len(path) >= 2 && (path[len(path)-2] == "data" || path[len(path)-2] == "binaryData") {
+			if r.featureEnabled(config.FeatureEmbeddedFiles) && kind == "ConfigMap" && len(path) >= 2 && (path[len(path)-2] == "data" || path[len(path)-2] == "binaryData") {
 				var valNode *yaml.Node
 				if parentNode != nil && parentNode.Kind == yaml.MappingNode {
 					for i := 0; i < len(parentNode.Content); i += 2 {
@@ -380,7 +681,8 @@ func (r *Resolver) ResolveReferences(docContent string, uri string, line, col in
 					}
 
 					locs := r.findConfigMapEmbeddedFileUsages(ns, cmName, targetNode.Value)
-					return filterOutLocationAtPosition(locs, uri, line, col), nil
+					excludeNode(emit, uri, targetNode)(locs)
+					return nil
 				}
 			}
 
@@ -388,10 +690,11 @@ func (r *Resolver) ResolveReferences(docContent string, uri string, line, col in
 			// spec.template.spec.volumes[].persistentVolumeClaim.claimName ->
 			// containers[].volumeMounts[].name locations for the matching volume.
 			// This helps "find references" show where a PVC claim is mounted.
-			if isWorkloadPVCClaimNamePath(path) {
+			if r.featureEnabled(config.FeaturePVCClaimUsages) && isWorkloadPVCClaimNamePath(path) {
 				locs := findPVCClaimMountUsagesInDocument(&node, uri, targetNode.Value)
 				if len(locs) > 0 {
-					return filterOutLocationAtPosition(locs, uri, line, col), nil
+					excludeNode(emit, uri, targetNode)(locs)
+					return nil
 				}
 			}
 
@@ -412,8 +715,8 @@ func (r *Resolver) ResolveReferences(docContent string, uri string, line, col in
 
 				if kind != "" && name != "" {
 					log.Debug().Str("kind", kind).Str("name", name).Str("namespace", namespace).Msg("Finding references for resource")
-					locs := r.findReferences(kind, name, namespace)
-					return filterOutLocationAtPosition(locs, uri, line, col), nil
+					r.findReferences(ctx, kind, name, namespace, excludeNode(emit, uri, targetNode))
+					return nil
 				}
 			}
 
@@ -423,8 +726,13 @@ func (r *Resolver) ResolveReferences(docContent string, uri string, line, col in
 				namespaceName := targetNode.Value
 				log.Debug().Str("namespace", namespaceName).Msg("Finding references for namespace")
 				// Namespace resources are cluster-scoped, so namespace arg is empty
-				locs := r.findReferences("Namespace", namespaceName, "")
-				return filterOutLocationAtPosition(locs, uri, line, col), nil
+				r.findReferences(ctx, "Namespace", namespaceName, "", excludeNode(emit, uri, targetNode))
+				return nil
+			}
+
+			if ns, name, _, ok := injectCAFromTarget(targetNode, parentNode, path, findNamespace(&node)); ok {
+				r.findReferences(ctx, "Certificate", name, ns, excludeNode(emit, uri, targetNode))
+				return nil
 			}
 
 			// Check configured references
@@ -433,66 +741,102 @@ func (r *Resolver) ResolveReferences(docContent string, uri string, line, col in
 			// Check if we are at a definition site (Symbol)
 			for _, sym := range r.Config.Symbols {
 				for _, def := range sym.Definitions {
-					match := matchPath(path, def.Path)
+					match := matchPath(path, indices, def.Path)
 					if !match && sym.Name == "k8s.label" {
-						match = matchPathPrefix(path, def.Path)
+						match = matchPathPrefix(path, indices, def.Path)
 					}
 
-					if contains(def.Kinds, kind) && match {
+					if containsKind(def.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && def.MatchesAPIVersion(findAPIVersion(&node)) && match {
+						if sym.Name == "k8s.resource.name" && def.AliasKind != "" {
+							r.findReferences(ctx, def.AliasKind, targetNode.Value, findNamespace(&node), excludeNode(emit, uri, targetNode))
+							return nil
+						}
 						if sym.Name == "k8s.label" {
 							// Assuming we are on the value
 							labelKey := path[len(path)-1]
 							labelValue := targetNode.Value
 							log.Debug().Str("key", labelKey).Str("value", labelValue).Msg("Finding references for label definition")
-							locs := r.findLabelReferences(labelKey, labelValue)
-							return filterOutLocationAtPosition(locs, uri, line, col), nil
+							r.findLabelReferences(ctx, labelKey, labelValue, excludeNode(emit, uri, targetNode))
+							return nil
 						}
 					}
 				}
 			}
 
 			for _, refRule := range r.Config.References {
-				match := matchPath(path, refRule.Match.Path)
+				match := matchPath(path, indices, refRule.Match.Path)
 				if !match && refRule.Symbol == "k8s.label" {
-					match = matchPathPrefix(path, refRule.Match.Path)
+					match = matchPathPrefix(path, indices, refRule.Match.Path)
 				}
 
-				if matchesKind(refRule.Match.Kinds, kind) && match {
+				if matchesKind(refRule.Match.Kinds, kind, r.Config.Settings.CaseInsensitiveKinds) && refRule.Match.MatchesAPIVersion(findAPIVersion(&node)) && match {
 					if refRule.Symbol == "k8s.resource.name" {
 						targetKind := refRule.TargetKind
 						targetName := targetNode.Value
-						// For namespace reference, target namespace is empty
+						// Cluster-scoped targets like Namespace have no namespace
 						targetNamespace := ""
-						if targetKind != "Namespace" {
-							targetNamespace = findNamespace(&node)
+						if !indexer.IsClusterScoped(targetKind) {
+							targetNamespace = siblingNamespace(parentNode, findNamespace(&node))
+						}
+						if refRule.CompositePath != "" {
+							var ok bool
+							targetNamespace, targetName, _, ok = compositeReference(refRule.CompositePath, targetNode, targetName, targetNamespace, protocol.Range{})
+							if !ok {
+								continue
+							}
 						}
 
 						log.Debug().Str("targetKind", targetKind).Str("targetName", targetName).Msg("Finding references for configured rule")
-						locs := r.findReferences(targetKind, targetName, targetNamespace)
-						return filterOutLocationAtPosition(locs, uri, line, col), nil
+						r.findReferences(ctx, targetKind, targetName, targetNamespace, excludeNode(emit, uri, targetNode))
+						return nil
 					} else if refRule.Symbol == "k8s.label" {
-						labelKey := path[len(path)-1]
+						labelKey, ok := selectorLabelKey(path, refRule.Match.Path)
+						if !ok {
+							continue
+						}
 						labelValue := targetNode.Value
 						log.Debug().Str("key", labelKey).Str("value", labelValue).Msg("Finding references for label usage")
-						locs := r.findLabelReferences(labelKey, labelValue)
-						return filterOutLocationAtPosition(locs, uri, line, col), nil
+						r.findLabelReferences(ctx, labelKey, labelValue, excludeNode(emit, uri, targetNode))
+						return nil
 					}
 				}
 			}
 		}
 	}
-	return nil, nil
+	return nil
+}
+
+// excludeNode wraps emit to drop the locations covering node, see
+// filterOutNodeLocation, and the batches left empty by that.
+func excludeNode(emit func([]protocol.Location), uri string, node *yaml.Node) func([]protocol.Location) {
+	return func(locs []protocol.Location) {
+		if locs = filterOutNodeLocation(locs, uri, node); len(locs) > 0 {
+			emit(locs)
+		}
+	}
+}
+
+// flushLocations hands a non-empty batch to emit and starts a new one.
+func flushLocations(batch []protocol.Location, emit func([]protocol.Location)) []protocol.Location {
+	if len(batch) > 0 {
+		emit(batch)
+	}
+	return nil
 }
 
-func filterOutLocationAtPosition(locs []protocol.Location, uri string, line, col int) []protocol.Location {
-	if len(locs) == 0 {
+// filterOutNodeLocation drops the locations that cover node itself, so
+// "find references" doesn't list the occurrence under the cursor. Only the
+// node's own line and columns are compared, which keeps neighbouring entries
+// of a flow collection ({app: web, tier: web}) in the result.
+func filterOutNodeLocation(locs []protocol.Location, uri string, node *yaml.Node) []protocol.Location {
+	if len(locs) == 0 || node == nil {
 		return locs
 	}
 
-	pos := protocol.Position{Line: uint32(line), Character: uint32(col)}
+	self := calculateOriginRange(node)
 	out := locs[:0]
 	for _, loc := range locs {
-		if loc.URI == uri && rangeContainsPosition(loc.Range, pos) {
+		if loc.URI == uri && rangesOverlap(loc.Range, self) {
 			continue
 		}
 		out = append(out, loc)
@@ -500,9 +844,9 @@ func filterOutLocationAtPosition(locs []protocol.Location, uri string, line, col
 	return out
 }
 
-func rangeContainsPosition(r protocol.Range, p protocol.Position) bool {
+func rangesOverlap(a, b protocol.Range) bool {
 	// LSP ranges are half-open: [start, end)
-	return comparePosition(r.Start, p) <= 0 && comparePosition(p, r.End) < 0
+	return comparePosition(a.Start, b.End) < 0 && comparePosition(b.Start, a.End) < 0
 }
 
 func comparePosition(a, b protocol.Position) int {
@@ -529,11 +873,7 @@ func (r *Resolver) findConfigMapEmbeddedFileUsages(namespace, configMapName, key
 
 	resources := r.Store.FindReferences("ConfigMap", configMapName)
 	for _, res := range resources {
-		resNS := res.Namespace
-		if resNS == "" {
-			resNS = "default"
-		}
-		if resNS != namespace {
+		if r.canonicalNamespace(res.Namespace) != r.canonicalNamespace(namespace) {
 			continue
 		}
 
@@ -550,7 +890,7 @@ func (r *Resolver) findConfigMapEmbeddedFileUsages(namespace, configMapName, key
 				display = ref.Key
 			}
 			locations = append(locations, protocol.Location{
-				URI: "file://" + res.FilePath,
+				URI: fileURI(res.FilePath),
 				Range: protocol.Range{
 					Start: protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col)},
 					End:   protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col + len(display))},
@@ -625,6 +965,131 @@ func isVolumeMountNamePath(path []string) bool {
 	return path[len(path)-2] == "volumeMounts" && path[len(path)-1] == "name"
 }
 
+func isSecretKeyRefPath(path []string) bool {
+	// ...env[].valueFrom.secretKeyRef.name OR ...env[].valueFrom.secretKeyRef.key
+	if len(path) < 3 {
+		return false
+	}
+	return path[len(path)-3] == "valueFrom" && path[len(path)-2] == "secretKeyRef" &&
+		(path[len(path)-1] == "name" || path[len(path)-1] == "key")
+}
+
+func isContainerImagePath(path []string) bool {
+	// ...containers[].image, initContainers[].image or ephemeralContainers[].image
+	if len(path) < 2 || path[len(path)-1] != "image" {
+		return false
+	}
+	switch path[len(path)-2] {
+	case "containers", "initContainers", "ephemeralContainers":
+		return true
+	}
+	return false
+}
+
+// buildImageDefinitions links an image to the build annotations declaring it.
+func (r *Resolver) buildImageDefinitions(image string, originRange protocol.Range) []protocol.LocationLink {
+	resources := r.Store.FindByBuildImage(image)
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].FilePath != resources[j].FilePath {
+			return resources[i].FilePath < resources[j].FilePath
+		}
+		return resources[i].BuildImageLine < resources[j].BuildImageLine
+	})
+
+	var links []protocol.LocationLink
+	for _, res := range resources {
+		targetRange := protocol.Range{
+			Start: protocol.Position{Line: uint32(res.BuildImageLine), Character: uint32(res.BuildImageCol)},
+			End:   protocol.Position{Line: uint32(res.BuildImageLine), Character: uint32(res.BuildImageCol + len(res.BuildImage))},
+		}
+		links = append(links, protocol.LocationLink{
+			OriginSelectionRange: &originRange,
+			TargetURI:            fileURI(res.FilePath),
+			TargetRange:          targetRange,
+			TargetSelectionRange: targetRange,
+		})
+	}
+	return links
+}
+
+// isServiceAccountSubjectPath matches subjects[].name where the subject's
+// kind is ServiceAccount.
+func isServiceAccountSubjectPath(path []string, subject *yaml.Node) bool {
+	if len(path) != 2 || path[0] != "subjects" || path[1] != "name" {
+		return false
+	}
+	kindNode := getMappingScalarValue(subject, "kind")
+	return kindNode != nil && kindNode.Value == "ServiceAccount"
+}
+
+func isEnvFromSecretRefPath(path []string) bool {
+	// ...containers[].envFrom[].secretRef.name OR ...initContainers[].envFrom[].secretRef.name
+	if len(path) < 3 {
+		return false
+	}
+	return path[len(path)-3] == "envFrom" && path[len(path)-2] == "secretRef" && path[len(path)-1] == "name"
+}
+
+// secretKeyRefDefinition resolves a secretKeyRef name to the Secret and a key
+// to its data/stringData entry, plus the virtual embedded file for the key.
+func (r *Resolver) secretKeyRefDefinition(root, secretKeyRef *yaml.Node, path []string, originRange protocol.Range) []protocol.LocationLink {
+	nameNode := getMappingScalarValue(secretKeyRef, "name")
+	if nameNode == nil || nameNode.Value == "" {
+		return nil
+	}
+
+	ns := findNamespace(root)
+	if ns == "" {
+		ns = "default"
+	}
+	res := r.Store.Get("Secret", ns, nameNode.Value)
+	if res == nil {
+		return nil
+	}
+
+	if path[len(path)-1] == "name" {
+		targetRange := protocol.Range{
+			Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+			End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+		}
+		return []protocol.LocationLink{{
+			OriginSelectionRange: &originRange,
+			TargetURI:            fileURI(res.FilePath),
+			TargetRange:          targetRange,
+			TargetSelectionRange: targetRange,
+		}}
+	}
+
+	keyNode := getMappingScalarValue(secretKeyRef, "key")
+	if keyNode == nil || keyNode.Value == "" {
+		return nil
+	}
+	entryNode, _, err := r.findResourceDataEntryInFile(res.FilePath, "Secret", res.Namespace, res.Name, keyNode.Value)
+	if err != nil || entryNode == nil {
+		log.Debug().Err(err).Str("secret", res.Name).Str("key", keyNode.Value).Msg("Secret key not found")
+		return nil
+	}
+
+	entryRange := calculateOriginRange(entryNode)
+	links := []protocol.LocationLink{{
+		OriginSelectionRange: &originRange,
+		TargetURI:            fileURI(res.FilePath),
+		TargetRange:          entryRange,
+		TargetSelectionRange: entryRange,
+	}}
+
+	if r.featureEnabled(config.FeatureEmbeddedFiles) {
+		sourceEncoded := base64.URLEncoding.EncodeToString([]byte(fileURI(res.FilePath)))
+		keyEncoded := base64.URLEncoding.EncodeToString([]byte(keyNode.Value))
+		embeddedURI := fmt.Sprintf("k8s-embedded://%s/%s/%s?source=%s&key=%s", ns, res.Name, keyNode.Value, sourceEncoded, keyEncoded)
+		links = append(links, protocol.LocationLink{
+			OriginSelectionRange: &originRange,
+			TargetURI:            embeddedURI,
+		})
+	}
+	return links
+}
+
 func isVolumeMountSubPathPath(path []string) bool {
 	// ...containers[].volumeMounts[].subPath OR ...initContainers[].volumeMounts[].subPath
 	if len(path) < 2 {
@@ -650,6 +1115,28 @@ func findVolumeNameNodeByName(podSpec *yaml.Node, volumeName string) *yaml.Node
 	return nil
 }
 
+// findVolumeClaimTemplateNameNode returns the metadata.name node of the
+// StatefulSet volumeClaimTemplates entry called name.
+func findVolumeClaimTemplateNameNode(root *yaml.Node, name string) *yaml.Node {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if findKind(root) != "StatefulSet" {
+		return nil
+	}
+	templates := getMappingValue(getMappingValue(root, "spec"), "volumeClaimTemplates")
+	if templates == nil || templates.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, tmpl := range templates.Content {
+		n := getMappingValue(getMappingValue(tmpl, "metadata"), "name")
+		if n != nil && n.Kind == yaml.ScalarNode && n.Value == name {
+			return n
+		}
+	}
+	return nil
+}
+
 func findVolumeNodeByName(podSpec *yaml.Node, volumeName string) *yaml.Node {
 	if podSpec == nil || podSpec.Kind != yaml.MappingNode {
 		return nil
@@ -742,27 +1229,24 @@ func (r *Resolver) findVolumeMountSubPathTargets(root *yaml.Node, volumeMountNod
 		if resName == "" || key == "" {
 			return
 		}
-		res := r.Store.Get(kind, ns, resName)
-		if res == nil && ns != "default" {
-			res = r.Store.Get(kind, "default", resName)
-		}
+		res, _ := r.Store.Lookup(kind, ns, resName)
 		if res == nil {
 			return
 		}
 
-		keyNode, _, err := findResourceDataEntryInFile(res.FilePath, kind, ns, resName, key)
+		keyNode, _, err := r.findResourceDataEntryInFile(res.FilePath, kind, res.Namespace, resName, key)
 		if err != nil || keyNode == nil {
 			return
 		}
 
 		keyRange := calculateOriginRange(keyNode)
 		targets = append(targets, protocol.Location{
-			URI: "file://" + res.FilePath,
+			URI: fileURI(res.FilePath),
 			Range: protocol.Range{Start: keyRange.Start, End: keyRange.End},
 		})
 
 		// Offer the virtual embedded file as an alternative target.
-		sourceURI := "file://" + res.FilePath
+		sourceURI := fileURI(res.FilePath)
 		sourceEncoded := base64.URLEncoding.EncodeToString([]byte(sourceURI))
 		keyEncoded := base64.URLEncoding.EncodeToString([]byte(key))
 		embeddedURI := fmt.Sprintf("k8s-embedded://%s/%s/%s?source=%s&key=%s", ns, resName, key, sourceEncoded, keyEncoded)
@@ -876,85 +1360,97 @@ func resolveKeyFromItems(items *yaml.Node, subPath string) (string, bool) {
 	return "", false
 }
 
-func findResourceDataEntryInFile(filePath, expectedKind, namespace, resName, key string) (*yaml.Node, *yaml.Node, error) {
+// findResourceInFile returns the root mapping of the named resource in
+// filePath. Documents above the configured limits are skipped, as indexing
+// skips them.
+func (r *Resolver) findResourceInFile(filePath, expectedKind, namespace, resName string) (*yaml.Node, error) {
 	bytes, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = "default"
 	}
 
-	decoder := yaml.NewDecoder(strings.NewReader(string(bytes)))
+	decoder := position.NewDecoder(string(bytes))
 	for {
 		var doc yaml.Node
 		if err := decoder.Decode(&doc); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return nil, nil, err
-		}
-
-		root := &doc
-		if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
-			root = root.Content[0]
+			return nil, err
 		}
-		if root == nil || root.Kind != yaml.MappingNode {
+		if indexer.CheckDocumentLimits(&doc, r.Config.Settings.DocumentLimits) != nil {
 			continue
 		}
 
-		if findKind(root) != expectedKind {
-			continue
-		}
-		if findName(root) != resName {
-			continue
-		}
-		resNS := findNamespace(root)
-		if resNS == "" {
-			resNS = "default"
-		}
-		if namespace == "" {
-			namespace = "default"
-		}
-		if resNS != namespace {
-			continue
+		for _, item := range indexer.ListItems(&doc) {
+			root := item
+			if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+				root = root.Content[0]
+			}
+			if root == nil || root.Kind != yaml.MappingNode {
+				continue
+			}
+			if findKind(root) != expectedKind || findName(root) != resName {
+				continue
+			}
+			resNS := findNamespace(root)
+			if resNS == "" {
+				resNS = "default"
+			}
+			if resNS == namespace {
+				return root, nil
+			}
 		}
+	}
+	return nil, fmt.Errorf("%s %s/%s not found in %s", expectedKind, namespace, resName, filePath)
+}
 
-		searchSections := func(sectionKeys ...string) (*yaml.Node, *yaml.Node) {
-			for i := 0; i < len(root.Content); i += 2 {
-				for _, secKey := range sectionKeys {
-					if root.Content[i].Value != secKey {
-						continue
-					}
-					dataNode := root.Content[i+1]
-					if dataNode == nil || dataNode.Kind != yaml.MappingNode {
-						continue
-					}
-					for j := 0; j < len(dataNode.Content); j += 2 {
-						k := dataNode.Content[j]
-						v := dataNode.Content[j+1]
-						if k != nil && k.Kind == yaml.ScalarNode && k.Value == key {
-							return k, v
-						}
+func (r *Resolver) findResourceDataEntryInFile(filePath, expectedKind, namespace, resName, key string) (*yaml.Node, *yaml.Node, error) {
+	root, err := r.findResourceInFile(filePath, expectedKind, namespace, resName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	searchSections := func(sectionKeys ...string) (*yaml.Node, *yaml.Node) {
+		for i := 0; i < len(root.Content); i += 2 {
+			for _, secKey := range sectionKeys {
+				if root.Content[i].Value != secKey {
+					continue
+				}
+				dataNode := root.Content[i+1]
+				if dataNode == nil || dataNode.Kind != yaml.MappingNode {
+					continue
+				}
+				for j := 0; j < len(dataNode.Content); j += 2 {
+					k := dataNode.Content[j]
+					v := dataNode.Content[j+1]
+					if k != nil && k.Kind == yaml.ScalarNode && k.Value == key {
+						return k, v
 					}
 				}
 			}
-			return nil, nil
 		}
+		return nil, nil
+	}
 
-		if expectedKind == "ConfigMap" {
-			k, v := searchSections("data", "binaryData")
-			if k != nil {
-				return k, v, nil
-			}
+	if expectedKind == "ConfigMap" {
+		k, v := searchSections("data", "binaryData")
+		if k != nil {
+			return k, v, nil
 		}
-		if expectedKind == "Secret" {
-			// Prefer stringData if present.
-			k, v := searchSections("stringData")
-			if k != nil {
-				return k, v, nil
-			}
-			k, v = searchSections("data")
-			if k != nil {
-				return k, v, nil
-			}
+	}
+	if expectedKind == "Secret" {
+		// Prefer stringData if present.
+		k, v := searchSections("stringData")
+		if k != nil {
+			return k, v, nil
+		}
+		k, v = searchSections("data")
+		if k != nil {
+			return k, v, nil
 		}
 	}
 
@@ -1154,6 +1650,17 @@ func findName(root *yaml.Node) string {
 	return ""
 }
 
+// findAPIVersion returns the apiVersion of the document root.
+func findAPIVersion(root *yaml.Node) string {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if node := getMappingScalarValue(root, "apiVersion"); node != nil {
+		return node.Value
+	}
+	return ""
+}
+
 func findKind(root *yaml.Node) string {
 	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
 		root = root.Content[0]
@@ -1168,31 +1675,47 @@ func findKind(root *yaml.Node) string {
 	return ""
 }
 
-func (r *Resolver) findReferences(kind, name, namespace string) []protocol.Location {
-	var locations []protocol.Location
+// cancelCheckInterval is how many resources a store scan visits between
+// checks for a cancelled request.
+const cancelCheckInterval = 64
 
+// findReferences emits the definition of kind/name first, then the
+// references to it, in batches of up to cancelCheckInterval resources.
+func (r *Resolver) findReferences(ctx context.Context, kind, name, namespace string, emit func([]protocol.Location)) {
 	// 1. Add the definition itself if found
 	def := r.Store.Get(kind, namespace, name)
 	if def != nil {
-		locations = append(locations, protocol.Location{
-			URI: "file://" + def.FilePath,
+		emit([]protocol.Location{{
+			URI: fileURI(def.FilePath),
 			Range: protocol.Range{
 				Start: protocol.Position{Line: uint32(def.Line), Character: uint32(def.Col)},
 				End:   protocol.Position{Line: uint32(def.Line), Character: uint32(def.Col + len(def.Name))},
 			},
-		})
+		}})
 	}
 
 	// 2. Find references in other files
 	resources := r.Store.FindReferences(kind, name)
 
-	for _, res := range resources {
+	var batch []protocol.Location
+	for i, res := range resources {
+		if i%cancelCheckInterval == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			batch = flushLocations(batch, emit)
+		}
 
 		// Find the exact location of the reference in the file
 		for _, ref := range res.References {
-			if ref.Kind == kind && ref.Name == name {
-				locations = append(locations, protocol.Location{
-					URI: "file://" + res.FilePath,
+			if ref.Kind == kind && ref.Name == name && ref.Symbol != "k8s.field" {
+				// References that name their target namespace explicitly
+				// only count for a resource in that namespace.
+				if ref.Namespace != "" && !indexer.IsClusterScoped(kind) && r.canonicalNamespace(ref.Namespace) != r.canonicalNamespace(namespace) {
+					continue
+				}
+				batch = append(batch, protocol.Location{
+					URI: fileURI(res.FilePath),
 					Range: protocol.Range{
 						Start: protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col)},
 						End:   protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col + len(ref.Name))},
@@ -1201,7 +1724,7 @@ func (r *Resolver) findReferences(kind, name, namespace string) []protocol.Locat
 			}
 		}
 	}
-	return locations
+	flushLocations(batch, emit)
 }
 
 func calculateOriginRange(node *yaml.Node) protocol.Range {
@@ -1229,7 +1752,7 @@ func (r *Resolver) findWorkloadsByLabel(key, value string, originRange protocol.
 		}
 		links = append(links, protocol.LocationLink{
 			OriginSelectionRange: &originRange,
-			TargetURI:            "file://" + res.FilePath,
+			TargetURI:            fileURI(res.FilePath),
 			TargetRange:          targetRange,
 			TargetSelectionRange: targetRange,
 		})
@@ -1237,15 +1760,8 @@ func (r *Resolver) findWorkloadsByLabel(key, value string, originRange protocol.
 	return links
 }
 
-func (r *Resolver) findServiceByName(name string, originRange protocol.Range) []protocol.LocationLink {
-	// Assuming current namespace (we need context of the current file's namespace, but let's search all for now or default)
-	// Ideally we pass the current document's namespace to ResolveDefinition.
-
-	// Simple lookup by name (ignoring namespace for a moment or checking all namespaces)
-	// Store.Get requires (kind, namespace, name).
-	// We'll implement a FindByName in Store to search across namespaces or just use "default" for now.
-
-	res := r.Store.Get("Service", "default", name) // TODO: Handle namespace correctly
+func (r *Resolver) findServiceByName(namespace, name string, originRange protocol.Range) []protocol.LocationLink {
+	res := r.lookupResource("Service", namespace, name)
 	if res != nil {
 		targetRange := protocol.Range{
 			Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
@@ -1253,7 +1769,7 @@ func (r *Resolver) findServiceByName(name string, originRange protocol.Range) []
 		}
 		return []protocol.LocationLink{{
 			OriginSelectionRange: &originRange,
-			TargetURI:            "file://" + res.FilePath,
+			TargetURI:            fileURI(res.FilePath),
 			TargetRange:          targetRange,
 			TargetSelectionRange: targetRange,
 		}}
@@ -1272,7 +1788,7 @@ func (r *Resolver) findNamespaceByName(name string, originRange protocol.Range)
 		}
 		return []protocol.LocationLink{{
 			OriginSelectionRange: &originRange,
-			TargetURI:            "file://" + res.FilePath,
+			TargetURI:            fileURI(res.FilePath),
 			TargetRange:          targetRange,
 			TargetSelectionRange: targetRange,
 		}}
@@ -1294,12 +1810,91 @@ func isServiceSelector(path []string) bool {
 	return false
 }
 
+// isIngressServicePortPath matches backend.service.port.{name,number} of an
+// Ingress rule path, or of its defaultBackend.
+func isIngressServicePortPath(path []string) bool {
+	n := len(path)
+	if n < 4 || (path[n-1] != "name" && path[n-1] != "number") {
+		return false
+	}
+	return path[n-2] == "port" && path[n-3] == "service" && isIngressBackendKey(path[n-4])
+}
+
+// isIngressBackendKey matches a rule path's backend and spec.defaultBackend.
+func isIngressBackendKey(key string) bool {
+	return key == "backend" || key == "defaultBackend"
+}
+
+// ingressServicePortDefinition links an Ingress backend port to the matching
+// ports[] entry of the Service named by its service.name sibling.
+func (r *Resolver) ingressServicePortDefinition(root, portNode, targetNode *yaml.Node, field string, originRange protocol.Range) []protocol.LocationLink {
+	serviceNode := findParentMapping(root, portNode)
+	nameNode := getMappingScalarValue(serviceNode, "name")
+	if nameNode == nil {
+		return nil
+	}
+	namespace := findNamespace(root)
+	res := r.lookupResource("Service", namespace, nameNode.Value)
+	if res == nil {
+		return nil
+	}
+	service, err := r.findResourceInFile(res.FilePath, "Service", res.Namespace, res.Name)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to read Service for Ingress backend port")
+		return nil
+	}
+	// port.name matches ports[].name; port.number matches ports[].port.
+	matchKey := "name"
+	if field == "number" {
+		matchKey = "port"
+	}
+	ports := getMappingValue(getMappingValue(service, "spec"), "ports")
+	if ports == nil || ports.Kind != yaml.SequenceNode {
+		return r.findServiceByName(namespace, nameNode.Value, originRange)
+	}
+	for _, entry := range ports.Content {
+		if match := getMappingScalarValue(entry, matchKey); match != nil && match.Value == targetNode.Value {
+			targetRange := protocol.Range{
+				Start: protocol.Position{Line: uint32(match.Line - 1), Character: uint32(match.Column - 1)},
+				End:   protocol.Position{Line: uint32(match.Line - 1), Character: uint32(match.Column - 1 + len(match.Value))},
+			}
+			return []protocol.LocationLink{{
+				OriginSelectionRange: &originRange,
+				TargetURI:            fileURI(res.FilePath),
+				TargetRange:          targetRange,
+				TargetSelectionRange: targetRange,
+			}}
+		}
+	}
+	return r.findServiceByName(namespace, nameNode.Value, originRange)
+}
+
+// findParentMapping returns the mapping under root holding child as a value.
+func findParentMapping(root, child *yaml.Node) *yaml.Node {
+	if root == nil {
+		return nil
+	}
+	if root.Kind == yaml.MappingNode {
+		for i := 1; i < len(root.Content); i += 2 {
+			if root.Content[i] == child {
+				return root
+			}
+		}
+	}
+	for _, c := range root.Content {
+		if found := findParentMapping(c, child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 func isIngressServiceRef(path []string) bool {
 	// spec.rules[].http.paths[].backend.service.name
 	if len(path) < 3 {
 		return false
 	}
-	if path[len(path)-1] == "name" && path[len(path)-2] == "service" && path[len(path)-3] == "backend" {
+	if path[len(path)-1] == "name" && path[len(path)-2] == "service" && isIngressBackendKey(path[len(path)-3]) {
 		return true
 	}
 	return false
@@ -1314,13 +1909,19 @@ func isNamespaceRef(path []string) bool {
 }
 
 // findNodeAt traverses the YAML AST to find the node at the given line/col.
-// It returns the node and the path of keys leading to it.
-func findNodeAt(node *yaml.Node, line, col int) (*yaml.Node, *yaml.Node, []string) {
+// It returns the node and the path of keys leading to it, with the list
+// index of each key's item along the way (-1 outside of lists, as in the
+// indexer's traverse).
+func findNodeAt(node *yaml.Node, line, col int) (*yaml.Node, *yaml.Node, []string, []int) {
 	if node.Kind == yaml.DocumentNode {
 		if len(node.Content) > 0 {
-			return findNodeAt(node.Content[0], line, col)
+			found, parent, path, indices := findNodeAt(node.Content[0], line, col)
+			if node.Content[0].Kind == yaml.SequenceNode && len(indices) > 0 {
+				indices = indices[1:]
+			}
+			return found, parent, path, indices
 		}
-		return nil, nil, nil
+		return nil, nil, nil, nil
 	}
 
 	if node.Kind == yaml.MappingNode {
@@ -1331,19 +1932,23 @@ func findNodeAt(node *yaml.Node, line, col int) (*yaml.Node, *yaml.Node, []strin
 			// Check if cursor is on the key
 			// Key is usually strict
 			if isKeyMatch(keyNode, line, col) {
-				return keyNode, node, []string{keyNode.Value}
+				return keyNode, node, []string{keyNode.Value}, []int{-1}
 			}
 
 			// Check if cursor is on the value
 			// Value can be loose (rest of the line) or inside complex structure
 			if isValueMatch(valNode, line, col) {
 				if valNode.Kind == yaml.ScalarNode {
-					return valNode, node, []string{keyNode.Value}
+					return valNode, node, []string{keyNode.Value}, []int{-1}
 				}
 				// Recurse
-				found, parent, subPath := findNodeAt(valNode, line, col)
+				found, parent, subPath, subIndices := findNodeAt(valNode, line, col)
 				if found != nil {
-					return found, parent, append([]string{keyNode.Value}, subPath...)
+					// A list leads with the index of the item found in it.
+					if valNode.Kind != yaml.SequenceNode {
+						subIndices = append([]int{-1}, subIndices...)
+					}
+					return found, parent, append([]string{keyNode.Value}, subPath...), subIndices
 				}
 			} else {
 				// Fallback: if key is on the same line, and cursor is after key, and valNode is null/empty scalar on same line
@@ -1352,27 +1957,31 @@ func findNodeAt(node *yaml.Node, line, col int) (*yaml.Node, *yaml.Node, []strin
 					// Check if cursor is after the key
 					keyEndCol := keyNode.Column + len(keyNode.Value)
 					if col > keyEndCol {
-						return valNode, node, []string{keyNode.Value}
+						return valNode, node, []string{keyNode.Value}, []int{-1}
 					}
 				}
 			}
 		}
 	} else if node.Kind == yaml.SequenceNode {
-		for _, item := range node.Content {
+		for i, item := range node.Content {
 			if isValueMatch(item, line, col) {
-				found, parent, subPath := findNodeAt(item, line, col)
+				found, parent, subPath, subIndices := findNodeAt(item, line, col)
 				if found != nil {
-					return found, parent, subPath
+					// Nested lists only keep the outermost index.
+					if item.Kind == yaml.SequenceNode {
+						subIndices = subIndices[1:]
+					}
+					return found, parent, subPath, append([]int{i}, subIndices...)
 				}
 			}
 		}
 	} else if node.Kind == yaml.ScalarNode {
 		if isValueMatch(node, line, col) {
-			return node, nil, nil
+			return node, nil, nil, nil
 		}
 	}
 
-	return nil, nil, nil
+	return nil, nil, nil, nil
 }
 
 func isKeyMatch(node *yaml.Node, line, col int) bool {
@@ -1446,20 +2055,108 @@ func findNamespace(root *yaml.Node) string {
 	return ""
 }
 
-func matchPath(current []string, pattern string) bool {
+// canonicalNamespace maps ns to the namespace it is compared as: "default"
+// when empty, otherwise its alias target from settings.namespaceAliases.
+func (r *Resolver) canonicalNamespace(ns string) string {
+	return r.Config.Settings.CanonicalNamespace(ns)
+}
+
+// siblingNamespace returns the value of a "namespace" key next to the node
+// under the cursor (e.g. clientConfig.service.namespace), or fallback.
+func siblingNamespace(parent *yaml.Node, fallback string) string {
+	if parent != nil && parent.Kind == yaml.MappingNode {
+		for k := 0; k < len(parent.Content); k += 2 {
+			if parent.Content[k].Value == "namespace" {
+				return parent.Content[k+1].Value
+			}
+		}
+	}
+	return fallback
+}
+
+func isMappingKey(parent, node *yaml.Node) bool {
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return false
+	}
+	for k := 0; k < len(parent.Content); k += 2 {
+		if parent.Content[k] == node {
+			return true
+		}
+	}
+	return false
+}
+
+// injectCAFromTarget reports the Certificate named by a cert-manager
+// inject-ca-from annotation value under the cursor, along with the range
+// of the certificate name within the value.
+func injectCAFromTarget(targetNode, parentNode *yaml.Node, path []string, currentNamespace string) (string, string, protocol.Range, bool) {
+	if len(path) != 3 || path[0] != "metadata" || path[1] != "annotations" || path[2] != indexer.InjectCAFromAnnotation {
+		return "", "", protocol.Range{}, false
+	}
+	if isMappingKey(parentNode, targetNode) {
+		return "", "", protocol.Range{}, false
+	}
+
+	ns, name, offset, ok := indexer.ParseInjectCAFrom(targetNode.Value, currentNamespace)
+	if !ok {
+		return "", "", protocol.Range{}, false
+	}
+
+	start := targetNode.Column - 1 + offset
+	if targetNode.Style == yaml.DoubleQuotedStyle || targetNode.Style == yaml.SingleQuotedStyle {
+		start++
+	}
+	nameRange := protocol.Range{
+		Start: protocol.Position{Line: uint32(targetNode.Line - 1), Character: uint32(start)},
+		End:   protocol.Position{Line: uint32(targetNode.Line - 1), Character: uint32(start + len(name))},
+	}
+	return ns, name, nameRange, true
+}
+
+// matchPath reports whether current matches the dotted pattern, where a *
+// segment matches any one key. A segment may end in [] to mark a list,
+// which matches any item like the bare key does, or in [N] to only match
+// the item at index N. indices are the
+// positions findNodeAt tracks; nil means unknown, which no [N] matches.
+func matchPath(current []string, indices []int, pattern string) bool {
 	parts := strings.Split(pattern, ".")
 	if len(parts) != len(current) {
 		return false
 	}
+	return matchSegments(current, indices, parts)
+}
+
+func matchSegments(current []string, indices []int, parts []string) bool {
 	for i, part := range parts {
-		cleanPart := strings.TrimSuffix(part, "[]")
-		if cleanPart != current[i] {
+		key, index := pathSegment(part)
+		if key != "*" && key != current[i] {
+			return false
+		}
+		if index >= 0 && (i >= len(indices) || indices[i] != index) {
 			return false
 		}
 	}
 	return true
 }
 
+// pathSegment splits a rule path segment into its key and the list index
+// it selects, -1 for "key" and "key[]".
+func pathSegment(part string) (string, int) {
+	open := strings.LastIndexByte(part, '[')
+	if open < 0 || !strings.HasSuffix(part, "]") {
+		return part, -1
+	}
+	inner := part[open+1 : len(part)-1]
+	if inner == "" {
+		return part[:open], -1
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return part, -1
+	}
+	return part[:open], index
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -1469,51 +2166,120 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func matchesKind(ruleKinds []string, currentKind string) bool {
+func (r *Resolver) featureEnabled(name string) bool {
+	return r.Config.Settings.FeatureEnabled(name)
+}
+
+// tooComplex reports whether node exceeds the configured document limits.
+// Such documents are skipped instead of traversed.
+func (r *Resolver) tooComplex(node *yaml.Node) bool {
+	if err := indexer.CheckDocumentLimits(node, r.Config.Settings.DocumentLimits); err != nil {
+		log.Warn().Err(err).Msg("Skipping document")
+		return true
+	}
+	return false
+}
+
+// listItemAt returns the item of a kind: List document that holds line, so
+// that a feature at a position sees the resource there. Any other document,
+// and the lines of a List before its first item, give node itself.
+func listItemAt(node *yaml.Node, line int) *yaml.Node {
+	items := indexer.ListItems(node)
+	for j := len(items) - 1; j >= 0; j-- {
+		if items[j] != node && items[j].Line <= line {
+			return items[j]
+		}
+	}
+	return node
+}
+
+// sameKind compares two kinds, honoring the case-insensitive kinds setting.
+func (r *Resolver) sameKind(a, b string) bool {
+	if r.Config.Settings.CaseInsensitiveKinds {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func matchesKind(ruleKinds []string, currentKind string, foldCase bool) bool {
 	for _, k := range ruleKinds {
-		if k == "*" || k == currentKind {
+		if k == "*" || k == currentKind || (foldCase && strings.EqualFold(k, currentKind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsKind reports whether kind is listed in kinds, optionally ignoring case.
+func containsKind(kinds []string, kind string, foldCase bool) bool {
+	for _, k := range kinds {
+		if k == kind || (foldCase && strings.EqualFold(k, kind)) {
 			return true
 		}
 	}
 	return false
 }
 
-func matchPathPrefix(current []string, pattern string) bool {
+// selectorLabelKey returns the label key under the cursor of a selector
+// matched by pattern: a direct entry (Service spec.selector) or one nested
+// under matchLabels (a LabelSelector). matchExpressions are not labels.
+func selectorLabelKey(path []string, pattern string) (string, bool) {
+	depth := len(strings.Split(pattern, "."))
+	switch {
+	case len(path) == depth+1 && path[depth] != "matchLabels":
+		return path[depth], true
+	case len(path) == depth+2 && path[depth] == "matchLabels":
+		return path[depth+1], true
+	}
+	return "", false
+}
+
+// matchPathPrefix is matchPath for paths under the one pattern matches.
+func matchPathPrefix(current []string, indices []int, pattern string) bool {
 	parts := strings.Split(pattern, ".")
 	if len(parts) > len(current) {
 		return false
 	}
-	for i, part := range parts {
-		cleanPart := strings.TrimSuffix(part, "[]")
-		if cleanPart != current[i] {
-			return false
-		}
-	}
-	return true
+	return matchSegments(current, indices, parts)
 }
 
-func (r *Resolver) findLabelReferences(key, value string) []protocol.Location {
-	var locations []protocol.Location
+// findLabelReferences emits the resources carrying key=value, then the
+// selectors matching it, batched like findReferences.
+func (r *Resolver) findLabelReferences(ctx context.Context, key, value string, emit func([]protocol.Location)) {
+	var batch []protocol.Location
 
 	// 1. Find definitions (resources having this label)
 	resources := r.Store.FindByLabel(key, value)
-	for _, res := range resources {
-		locations = append(locations, protocol.Location{
-			URI: "file://" + res.FilePath,
+	for i, res := range resources {
+		if i%cancelCheckInterval == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			batch = flushLocations(batch, emit)
+		}
+		batch = append(batch, protocol.Location{
+			URI: fileURI(res.FilePath),
 			Range: protocol.Range{
 				Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
 				End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
 			},
 		})
 	}
+	batch = flushLocations(batch, emit)
 
 	// 2. Find usages (resources referencing this label)
 	refs := r.Store.FindLabelReferences(value)
-	for _, res := range refs {
+	for i, res := range refs {
+		if i%cancelCheckInterval == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			batch = flushLocations(batch, emit)
+		}
 		for _, ref := range res.References {
-			if ref.Symbol == "k8s.label" && ref.Name == value {
-				locations = append(locations, protocol.Location{
-					URI: "file://" + res.FilePath,
+			if ref.Symbol == "k8s.label" && (ref.Key == "" || ref.Key == key) && ref.Name == value {
+				batch = append(batch, protocol.Location{
+					URI: fileURI(res.FilePath),
 					Range: protocol.Range{
 						Start: protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col)},
 						End:   protocol.Position{Line: uint32(ref.Line), Character: uint32(ref.Col + len(ref.Name))},
@@ -1522,12 +2288,22 @@ func (r *Resolver) findLabelReferences(key, value string) []protocol.Location {
 			}
 		}
 	}
+	flushLocations(batch, emit)
+}
 
-	return locations
+// ErrEmbeddedContentTooLarge reports a ConfigMap or Secret value above
+// DocumentLimits.MaxEmbeddedBytes.
+var ErrEmbeddedContentTooLarge = errors.New("embedded content too large")
+
+func (r *Resolver) checkEmbeddedSize(key string, size int) error {
+	if limit := r.Config.Settings.DocumentLimits.EmbeddedBytes(); size > limit {
+		return fmt.Errorf("%w: %s is %d bytes, above the %d byte limit (documentLimits.maxEmbeddedBytes)", ErrEmbeddedContentTooLarge, key, size, limit)
+	}
+	return nil
 }
 
 func (r *Resolver) ResolveEmbeddedContent(docContent string, key string) (string, error) {
-	decoder := yaml.NewDecoder(strings.NewReader(docContent))
+	decoder := position.NewDecoder(docContent)
 
 	for {
 		var node yaml.Node
@@ -1537,6 +2313,9 @@ func (r *Resolver) ResolveEmbeddedContent(docContent string, key string) (string
 			}
 			return "", err
 		}
+		if r.tooComplex(&node) {
+			continue
+		}
 
 		if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
 			continue
@@ -1571,18 +2350,21 @@ func (r *Resolver) ResolveEmbeddedContent(docContent string, key string) (string
 
 		if kind == "ConfigMap" {
 			if v, ok := searchMap("data"); ok {
-				return v, nil
+				return v, r.checkEmbeddedSize(key, len(v))
 			}
 			if v, ok := searchMap("binaryData"); ok {
-				return v, nil
+				return v, r.checkEmbeddedSize(key, len(v))
 			}
 		}
 		if kind == "Secret" {
 			// Prefer stringData (plain-text).
 			if v, ok := searchMap("stringData"); ok {
-				return v, nil
+				return v, r.checkEmbeddedSize(key, len(v))
 			}
 			if v, ok := searchMap("data"); ok {
+				if err := r.checkEmbeddedSize(key, base64.StdEncoding.DecodedLen(len(v))); err != nil {
+					return "", err
+				}
 				decoded, err := base64.StdEncoding.DecodeString(v)
 				if err != nil {
 					return "", fmt.Errorf("failed to decode Secret.data[%s]: %w", key, err)
@@ -1596,10 +2378,13 @@ func (r *Resolver) ResolveEmbeddedContent(docContent string, key string) (string
 
 func (r *Resolver) UpdateEmbeddedContent(docContent string, key string, newContent string) (string, error) {
 	var node yaml.Node
-	decoder := yaml.NewDecoder(strings.NewReader(docContent))
+	decoder := position.NewDecoder(docContent)
 	if err := decoder.Decode(&node); err != nil {
 		return "", err
 	}
+	if err := indexer.CheckDocumentLimits(&node, r.Config.Settings.DocumentLimits); err != nil {
+		return "", err
+	}
 
 	found := false
 	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
@@ -1621,7 +2406,13 @@ func (r *Resolver) UpdateEmbeddedContent(docContent string, key string, newConte
 	}
 	normalized = strings.Join(lines, "\n")
 	normalized = strings.TrimSuffix(normalized, "\n")
+	if err := r.checkEmbeddedSize(key, len(normalized)); err != nil {
+		return "", err
+	}
 
+	// The updated entry, spliced into docContent when its layout allows.
+	var keyNode, valueNode *yaml.Node
+	flow := false
 	updateInSection := func(section string, newVal string, style yaml.Style) bool {
 		for i := 0; i < len(root.Content); i += 2 {
 			if root.Content[i].Value != section {
@@ -1631,6 +2422,7 @@ func (r *Resolver) UpdateEmbeddedContent(docContent string, key string, newConte
 			if m == nil || m.Kind != yaml.MappingNode {
 				return false
 			}
+			flow = m.Style&yaml.FlowStyle != 0
 			m.Style = 0
 			for j := 0; j < len(m.Content); j += 2 {
 				if m.Content[j].Value == key {
@@ -1640,6 +2432,7 @@ func (r *Resolver) UpdateEmbeddedContent(docContent string, key string, newConte
 					}
 					valNode.Value = newVal
 					valNode.Style = style
+					keyNode, valueNode = m.Content[j], valNode
 					return true
 				}
 			}
@@ -1669,6 +2462,12 @@ func (r *Resolver) UpdateEmbeddedContent(docContent string, key string, newConte
 		return "", fmt.Errorf("key %s not found", key)
 	}
 
+	if !flow {
+		if updated, ok := spliceMappingValue(docContent, keyNode, valueNode); ok {
+			return updated, nil
+		}
+	}
+
 	log.Info().Str("key", key).Str("buf", fmt.Sprintf("%v", node)).Msg("Updated embedded content in ConfigMap")
 
 	var buf bytes.Buffer
@@ -1683,3 +2482,17 @@ func (r *Resolver) UpdateEmbeddedContent(docContent string, key string, newConte
 
 	return buf.String(), nil
 }
+
+// fileURI returns the file:// URI of an indexed resource's path.
+func fileURI(path string) string {
+	return uri.FromPath(path)
+}
+
+// uriPath returns the path of a file:// URI as the store keys it, or the URI
+// itself for documents that aren't files.
+func uriPath(documentURI string) string {
+	if path, err := uri.ToPath(documentURI); err == nil {
+		return path
+	}
+	return documentURI
+}
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -68,7 +69,7 @@ spec:
 	col := 20
 
 	// 5. Call ResolveDefinition
-	locs, err := r.ResolveDefinition(yamlContent, "file:///tmp/deployment.yaml", line, col)
+	locs, err := r.ResolveDefinition(context.Background(), yamlContent, "file:///tmp/deployment.yaml", line, col)
 
 	// 6. Assertions
 	if err != nil {
@@ -130,7 +131,7 @@ spec:
 	col := 8
 
 	// 5. Call ResolveDefinition
-	locs, err := r.ResolveDefinition(yamlContent, "file:///tmp/service.yaml", line, col)
+	locs, err := r.ResolveDefinition(context.Background(), yamlContent, "file:///tmp/service.yaml", line, col)
 
 	// 6. Assertions
 	if err != nil {
@@ -190,7 +191,7 @@ spec:
 	line := 7
 	col := 15
 
-	locs, err := r.ResolveDefinition(yamlContent, "file:///tmp/pvc.yaml", line, col)
+	locs, err := r.ResolveDefinition(context.Background(), yamlContent, "file:///tmp/pvc.yaml", line, col)
 	if err != nil {
 		t.Fatalf("ResolveDefinition failed: %v", err)
 	}
@@ -276,7 +277,7 @@ spec:
 	col := 8
 
 	// 5. Call ResolveReferences
-	locs, err := r.ResolveReferences(yamlContent, "file:///tmp/service.yaml", line, col)
+	locs, err := r.ResolveReferences(context.Background(), yamlContent, "file:///tmp/service.yaml", line, col)
 
 	// 6. Assertions
 	if err != nil {
@@ -375,7 +376,7 @@ spec:
 	col := 9
 
 	// 5. Call ResolveReferences
-	locs, err := r.ResolveReferences(yamlContent, "file:///tmp/pod.yaml", line, col)
+	locs, err := r.ResolveReferences(context.Background(), yamlContent, "file:///tmp/pod.yaml", line, col)
 
 	// 6. Assertions
 	if err != nil {
@@ -591,7 +592,7 @@ func assertWorkloadPVCClaimNameReferences(t *testing.T, uri string, yamlContent
 	line := claimNode.Line - 1
 	col := claimNode.Column - 1
 
-	locs, err := r.ResolveReferences(yamlContent, uri, line, col)
+	locs, err := r.ResolveReferences(context.Background(), yamlContent, uri, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -631,7 +632,7 @@ func assertWorkloadPVCClaimNameReferencesWithClaimPath(t *testing.T, uri string,
 	line := claimNode.Line - 1
 	col := claimNode.Column - 1
 
-	locs, err := r.ResolveReferences(yamlContent, uri, line, col)
+	locs, err := r.ResolveReferences(context.Background(), yamlContent, uri, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -750,7 +751,7 @@ spec:
 	col := 9
 
 	// 5. Call ResolveDefinition
-	locs, err := r.ResolveDefinition(yamlContent, "file:///tmp/service.yaml", line, col)
+	locs, err := r.ResolveDefinition(context.Background(), yamlContent, "file:///tmp/service.yaml", line, col)
 
 	// 6. Assertions
 	if err != nil {
@@ -800,7 +801,7 @@ spec:
 	line := mountNameNode.Line - 1
 	col := mountNameNode.Column - 1
 
-	locs, err := r.ResolveDefinition(yamlContent, uri, line, col)
+	locs, err := r.ResolveDefinition(context.Background(), yamlContent, uri, line, col)
 	if err != nil {
 		t.Fatalf("ResolveDefinition failed: %v", err)
 	}
@@ -887,7 +888,7 @@ spec:
 	line := subPathNode.Line - 1
 	col := subPathNode.Column - 1
 
-	locs, err := r.ResolveReferences(workloadYaml, workloadURI, line, col)
+	locs, err := r.ResolveReferences(context.Background(), workloadYaml, workloadURI, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -984,7 +985,7 @@ spec:
 	line := subPathNode.Line - 1
 	col := subPathNode.Column - 1
 
-	locs, err := r.ResolveReferences(workloadYaml, workloadURI, line, col)
+	locs, err := r.ResolveReferences(context.Background(), workloadYaml, workloadURI, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -1075,7 +1076,7 @@ spec:
 	line := subPathNode.Line - 1
 	col := subPathNode.Column - 1
 
-	locs, err := r.ResolveReferences(workloadYaml, workloadURI, line, col)
+	locs, err := r.ResolveReferences(context.Background(), workloadYaml, workloadURI, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -1163,7 +1164,7 @@ spec:
 	line := subPathNode.Line - 1
 	col := subPathNode.Column - 1
 
-	locs, err := r.ResolveReferences(workloadYaml, workloadURI, line, col)
+	locs, err := r.ResolveReferences(context.Background(), workloadYaml, workloadURI, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
@@ -1248,7 +1249,7 @@ spec:
 	line := subPathNode.Line - 1
 	col := subPathNode.Column - 1
 
-	locs, err := r.ResolveReferences(workloadYaml, workloadURI, line, col)
+	locs, err := r.ResolveReferences(context.Background(), workloadYaml, workloadURI, line, col)
 	if err != nil {
 		t.Fatalf("ResolveReferences failed: %v", err)
 	}
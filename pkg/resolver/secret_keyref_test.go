@@ -0,0 +1,159 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_SecretKeyRef(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(secretPath, []byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: db-creds
+  namespace: prod
+data:
+  username: YWRtaW4=
+  password: c2VjcmV0
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Secret", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexFile(secretPath)
+	r := NewResolver(store, cfg)
+
+	podYaml := `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  namespace: prod
+spec:
+  containers:
+    - name: app
+      env:
+        - name: DB_PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: db-creds
+              key: password
+`
+	idx.IndexContent("/tmp/pod.yaml", podYaml)
+
+	pod := store.Get("Pod", "prod", "app")
+	if pod == nil {
+		t.Fatal("Expected Pod to be indexed")
+	}
+	var nameRef, keyRef bool
+	for _, ref := range pod.References {
+		if ref.Kind != "Secret" || ref.Name != "db-creds" {
+			continue
+		}
+		// Line 12 is "              name: db-creds", line 13 "              key: password".
+		if ref.Key == "" && ref.Line == 12 && ref.Col == 20 {
+			nameRef = true
+		}
+		if ref.Key == "password" && ref.Line == 13 && ref.Col == 19 {
+			keyRef = true
+		}
+	}
+	if !nameRef || !keyRef {
+		t.Fatalf("Expected Secret name and key references, got %+v", pod.References)
+	}
+
+	// Cursor on the Secret name.
+	links, err := r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 12, 22)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("Expected 1 link, got %d", len(links))
+	}
+	if links[0].TargetURI != "file://"+secretPath || links[0].TargetRange.Start.Line != 3 {
+		t.Errorf("Expected Secret name in %s at line 3, got %s line %d", secretPath, links[0].TargetURI, links[0].TargetRange.Start.Line)
+	}
+
+	// Cursor on the key: the data entry, then the embedded file.
+	links, err = r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 13, 21)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %d", len(links))
+	}
+	if links[0].TargetURI != "file://"+secretPath || links[0].TargetRange.Start.Line != 7 {
+		t.Errorf("Expected password entry at line 7, got %s line %d", links[0].TargetURI, links[0].TargetRange.Start.Line)
+	}
+	if !strings.HasPrefix(links[1].TargetURI, "k8s-embedded://prod/db-creds/password?") {
+		t.Errorf("Expected embedded URI for the key, got %s", links[1].TargetURI)
+	}
+}
+
+func TestResolveDefinition_EnvFromSecretRef(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Secret", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/secret.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: db-creds
+`)
+	r := NewResolver(store, cfg)
+
+	podYaml := `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  initContainers:
+    - name: migrate
+      envFrom:
+        - secretRef:
+            name: db-creds
+`
+	idx.IndexContent("/tmp/pod.yaml", podYaml)
+
+	// "            name: db-creds" on line 9.
+	links, err := r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 9, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/secret.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the Secret name, got %v", links)
+	}
+
+	locs, err := r.ResolveReferences(context.Background(), "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db-creds\n", "file:///tmp/secret.yaml", 3, 9)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].URI != "file:///tmp/pod.yaml" || locs[0].Range.Start.Line != 9 {
+		t.Errorf("Expected the envFrom reference, got %v", locs)
+	}
+}
@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestServiceAccountReferences(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ServiceAccount", "Pod", "CronJob"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/sa.yaml", `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: runner
+  namespace: batch
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: runner
+`)
+	r := NewResolver(store, cfg)
+
+	cronJobYaml := `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: nightly
+  namespace: batch
+spec:
+  schedule: "0 0 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          serviceAccountName: runner
+`
+	idx.IndexContent("/tmp/cronjob.yaml", cronJobYaml)
+
+	// "          serviceAccountName: runner" on line 11 resolves in the
+	// CronJob's namespace.
+	links, err := r.ResolveDefinition(context.Background(), cronJobYaml, "file:///tmp/cronjob.yaml", 11, 32)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/sa.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the batch ServiceAccount, got %v", links)
+	}
+
+	hover, err := r.ResolveHover(context.Background(), cronJobYaml, "file:///tmp/cronjob.yaml", 11, 32)
+	if err != nil {
+		t.Fatalf("ResolveHover failed: %v", err)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.(protocol.MarkupContent).Value, "File: /tmp/sa.yaml") {
+		t.Fatalf("Expected hover showing the ServiceAccount file, got %v", hover)
+	}
+
+	// The deprecated spec.serviceAccount of a Pod in the default namespace.
+	podYaml := `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  serviceAccount: runner
+`
+	idx.IndexContent("/tmp/pod.yaml", podYaml)
+
+	links, err = r.ResolveDefinition(context.Background(), podYaml, "file:///tmp/pod.yaml", 5, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetRange.Start.Line != 9 {
+		t.Fatalf("Expected the default ServiceAccount, got %v", links)
+	}
+
+	if refs := store.FindReferences("ServiceAccount", "runner"); len(refs) != 2 {
+		t.Fatalf("Expected both workloads to reference the ServiceAccount, got %d", len(refs))
+	}
+}
@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestStorageClassNameNavigation(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	idx.IndexContent("/tmp/storageclass.yaml", `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast-ssd
+provisioner: ebs.csi.aws.com
+`)
+
+	pvcYaml := `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: app
+spec:
+  storageClassName: fast-ssd
+  accessModes: ["ReadWriteOnce"]
+`
+	idx.IndexContent("/tmp/pvc.yaml", pvcYaml)
+
+	// "  storageClassName: fast-ssd" on line 6; the cluster-scoped
+	// StorageClass resolves from a PVC in any namespace.
+	links, err := r.ResolveDefinition(context.Background(), pvcYaml, "file:///tmp/pvc.yaml", 6, 22)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/storageclass.yaml" || links[0].TargetRange.Start.Line != 3 {
+		t.Fatalf("Expected the fast-ssd StorageClass, got %v", links)
+	}
+
+	hover, err := r.ResolveHover(context.Background(), pvcYaml, "file:///tmp/pvc.yaml", 6, 22)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected a hover, got %v (err %v)", hover, err)
+	}
+	if value := hover.Contents.(protocol.MarkupContent).Value; !strings.Contains(value, "/tmp/storageclass.yaml") {
+		t.Errorf("Expected the hover to show the StorageClass file, got %q", value)
+	}
+
+	// From the StorageClass, references find the PVC.
+	scYaml := `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast-ssd
+provisioner: ebs.csi.aws.com
+`
+	locs, err := r.ResolveReferences(context.Background(), scYaml, "file:///tmp/storageclass.yaml", 3, 8)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].URI != "file:///tmp/pvc.yaml" || locs[0].Range.Start.Line != 6 {
+		t.Errorf("Expected the PVC's storageClassName, got %v", locs)
+	}
+}
@@ -0,0 +1,128 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestStreamReferencesBatches(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Pod"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "pod.configmap-ref",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "ConfigMap",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"Pod"},
+					Path:  "spec.volumes.configMap.name",
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	cm := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared
+`
+	idx.IndexContent("/tmp/cm.yaml", cm)
+	for i := 0; i < 150; i++ {
+		idx.IndexContent(fmt.Sprintf("/tmp/pod-%03d.yaml", i), fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-%d
+spec:
+  volumes:
+    - name: config
+      configMap:
+        name: shared
+`, i))
+	}
+	r := NewResolver(store, cfg)
+
+	var batches [][]protocol.Location
+	err := r.StreamReferences(context.Background(), cm, "file:///tmp/cm.yaml", 3, 8, func(locs []protocol.Location) {
+		batches = append(batches, locs)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The definition under the cursor is filtered out, leaving the 150
+	// Pods in batches of at most cancelCheckInterval.
+	if len(batches) != 3 {
+		t.Fatalf("Expected 3 batches, got %d", len(batches))
+	}
+	seen := map[string]bool{}
+	var streamed []protocol.Location
+	for _, batch := range batches {
+		if len(batch) == 0 || len(batch) > cancelCheckInterval {
+			t.Errorf("Expected batches of 1-%d locations, got %d", cancelCheckInterval, len(batch))
+		}
+		for _, loc := range batch {
+			if seen[loc.URI] {
+				t.Errorf("Expected %s to be streamed once", loc.URI)
+			}
+			seen[loc.URI] = true
+		}
+		streamed = append(streamed, batch...)
+	}
+
+	locs, err := r.ResolveReferences(context.Background(), cm, "file:///tmp/cm.yaml", 3, 8)
+	if err != nil || len(locs) != len(streamed) {
+		t.Fatalf("Expected the collected result to match the %d streamed locations, got %d (%v)", len(streamed), len(locs), err)
+	}
+	for _, loc := range locs {
+		if !seen[loc.URI] {
+			t.Errorf("Expected %s to have been streamed", loc.URI)
+		}
+	}
+
+	// From a reference the definition comes first, in a batch of its own.
+	batches = nil
+	podContent := `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-0
+spec:
+  volumes:
+    - name: config
+      configMap:
+        name: shared
+`
+	if err := r.StreamReferences(context.Background(), podContent, "file:///tmp/pod-000.yaml", 8, 16, func(locs []protocol.Location) {
+		batches = append(batches, locs)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) < 2 || len(batches[0]) != 1 || batches[0][0].URI != "file:///tmp/cm.yaml" {
+		t.Fatalf("Expected the ConfigMap definition as the first batch, got %v", batches)
+	}
+	total := 0
+	for _, batch := range batches[1:] {
+		for _, loc := range batch {
+			if !strings.HasPrefix(loc.URI, "file:///tmp/pod-") || loc.URI == "file:///tmp/pod-000.yaml" {
+				t.Errorf("Expected only the other Pods after the definition, got %s", loc.URI)
+			}
+		}
+		total += len(batch)
+	}
+	if total != 149 {
+		t.Errorf("Expected 149 other Pods, got %d", total)
+	}
+}
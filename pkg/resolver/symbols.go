@@ -0,0 +1,169 @@
+package resolver
+
+import (
+	"sort"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// maxWorkspaceSymbols caps the number of results returned for a query so
+// short queries on large workspaces stay responsive.
+const maxWorkspaceSymbols = 200
+
+// embeddedFileQueryPrefix restricts a workspace symbol query to the embedded
+// files of ConfigMaps and Secrets.
+const embeddedFileQueryPrefix = "embed:"
+
+// WorkspaceSymbols returns a symbol for every indexed resource matching the
+// query, best matches first, followed by the matching embedded files as
+// "name/key". Queries starting with "embed:" only return embedded files.
+func (r *Resolver) WorkspaceSymbols(query string) []protocol.SymbolInformation {
+	var symbols []protocol.SymbolInformation
+	if q, ok := strings.CutPrefix(query, embeddedFileQueryPrefix); ok {
+		symbols = r.embeddedFileSymbols(q)
+	} else {
+		symbols = append(r.resourceSymbols(query), r.embeddedFileSymbols(query)...)
+	}
+	if len(symbols) > maxWorkspaceSymbols {
+		symbols = symbols[:maxWorkspaceSymbols]
+	}
+	return symbols
+}
+
+func (r *Resolver) resourceSymbols(query string) []protocol.SymbolInformation {
+	resources := r.Store.Search(query)
+	q := strings.ToLower(query)
+	sort.Slice(resources, func(i, j int) bool {
+		ri, rj := symbolMatchRank(resources[i], q), symbolMatchRank(resources[j], q)
+		if ri != rj {
+			return ri < rj
+		}
+		if resources[i].Name != resources[j].Name {
+			return resources[i].Name < resources[j].Name
+		}
+		if resources[i].Namespace != resources[j].Namespace {
+			return resources[i].Namespace < resources[j].Namespace
+		}
+		return resources[i].Kind < resources[j].Kind
+	})
+	if len(resources) > maxWorkspaceSymbols {
+		resources = resources[:maxWorkspaceSymbols]
+	}
+
+	symbols := make([]protocol.SymbolInformation, 0, len(resources))
+	for _, res := range resources {
+		containerName := resourceDetail(res.ApiVersion, res.Kind)
+		name := res.Name
+		if res.Namespace != "" {
+			name = res.Namespace + "/" + res.Name
+		}
+		symbols = append(symbols, protocol.SymbolInformation{
+			Name:          name,
+			Kind:          symbolKindForResource(res.Kind),
+			ContainerName: &containerName,
+			Location: protocol.Location{
+				URI: fileURI(res.FilePath),
+				Range: protocol.Range{
+					Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+					End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+				},
+			},
+		})
+	}
+	return symbols
+}
+
+type embeddedFileMatch struct {
+	res  *indexer.K8sResource
+	key  indexer.DataKey
+	rank int
+}
+
+// embeddedFileSymbols returns a File symbol for every ConfigMap or Secret
+// data key matching the query by key or by "name/key", ranked on the key.
+func (r *Resolver) embeddedFileSymbols(query string) []protocol.SymbolInformation {
+	q := strings.ToLower(query)
+	var matches []embeddedFileMatch
+	for _, res := range r.Store.SearchDataKeys(query) {
+		for _, dk := range res.DataKeys {
+			if !strings.Contains(strings.ToLower(res.Name+"/"+dk.Key), q) {
+				continue
+			}
+			matches = append(matches, embeddedFileMatch{res: res, key: dk, rank: embeddedFileMatchRank(dk.Key, q)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		mi, mj := matches[i], matches[j]
+		if mi.rank != mj.rank {
+			return mi.rank < mj.rank
+		}
+		if mi.key.Key != mj.key.Key {
+			return mi.key.Key < mj.key.Key
+		}
+		if mi.res.Name != mj.res.Name {
+			return mi.res.Name < mj.res.Name
+		}
+		if mi.res.Namespace != mj.res.Namespace {
+			return mi.res.Namespace < mj.res.Namespace
+		}
+		return mi.res.Kind < mj.res.Kind
+	})
+	if len(matches) > maxWorkspaceSymbols {
+		matches = matches[:maxWorkspaceSymbols]
+	}
+
+	symbols := make([]protocol.SymbolInformation, 0, len(matches))
+	for _, m := range matches {
+		containerName := m.res.Kind + "/" + m.res.Name
+		if m.res.Namespace != "" {
+			containerName = m.res.Kind + "/" + m.res.Namespace + "/" + m.res.Name
+		}
+		symbols = append(symbols, protocol.SymbolInformation{
+			Name:          m.res.Name + "/" + m.key.Key,
+			Kind:          protocol.SymbolKindFile,
+			ContainerName: &containerName,
+			Location: protocol.Location{
+				URI: fileURI(m.res.FilePath),
+				Range: protocol.Range{
+					Start: protocol.Position{Line: uint32(m.key.Line), Character: uint32(m.key.Col)},
+					End:   protocol.Position{Line: uint32(m.key.Line), Character: uint32(m.key.Col + len(m.key.Key))},
+				},
+			},
+		})
+	}
+	return symbols
+}
+
+// embeddedFileMatchRank ranks like symbolMatchRank, on the data key.
+func embeddedFileMatchRank(key, query string) int {
+	key = strings.ToLower(key)
+	switch {
+	case key == query:
+		return 0
+	case strings.HasPrefix(key, query):
+		return 1
+	case strings.Contains(key, query):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// symbolMatchRank orders matches: exact name, name prefix, name substring,
+// then matches on the qualified "Kind/Name" or "Namespace/Name" only.
+func symbolMatchRank(res *indexer.K8sResource, query string) int {
+	name := strings.ToLower(res.Name)
+	switch {
+	case name == query:
+		return 0
+	case strings.HasPrefix(name, query):
+		return 1
+	case strings.Contains(name, query):
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,134 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestWorkspaceSymbols(t *testing.T) {
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "Service", ApiVersion: "v1", Name: "web", Namespace: "default", FilePath: "/tmp/svc.yaml", Line: 3, Col: 8})
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "web-config", Namespace: "default", FilePath: "/tmp/cm.yaml", Line: 4, Col: 8})
+	store.Add(&indexer.K8sResource{Kind: "Secret", Name: "db", Namespace: "default", FilePath: "/tmp/secret.yaml", Line: 2, Col: 8})
+
+	r := NewResolver(store, &config.Config{})
+
+	symbols := r.WorkspaceSymbols("WEB")
+	if len(symbols) != 2 {
+		t.Fatalf("Expected 2 symbols, got %d", len(symbols))
+	}
+
+	svc := symbols[0]
+	if svc.Name != "default/web" {
+		t.Fatalf("Expected first symbol 'default/web', got %q", svc.Name)
+	}
+	if svc.Kind != protocol.SymbolKindInterface {
+		t.Errorf("Expected Service to map to Interface, got %v", svc.Kind)
+	}
+	if svc.ContainerName == nil || *svc.ContainerName != "v1 Service" {
+		t.Errorf("Expected container name 'v1 Service', got %v", svc.ContainerName)
+	}
+	if svc.Location.URI != "file:///tmp/svc.yaml" {
+		t.Errorf("Unexpected URI %s", svc.Location.URI)
+	}
+	if svc.Location.Range.Start.Line != 3 || svc.Location.Range.Start.Character != 8 || svc.Location.Range.End.Character != 11 {
+		t.Errorf("Unexpected range %+v", svc.Location.Range)
+	}
+
+	if symbols[1].Kind != protocol.SymbolKindFile {
+		t.Errorf("Expected ConfigMap to map to File, got %v", symbols[1].Kind)
+	}
+}
+
+func TestWorkspaceSymbols_RankedAndCapped(t *testing.T) {
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "Deployment", Name: "app-redis", Namespace: "prod", FilePath: "/tmp/a.yaml"})
+	store.Add(&indexer.K8sResource{Kind: "Service", Name: "redis-master", Namespace: "prod", FilePath: "/tmp/b.yaml"})
+	store.Add(&indexer.K8sResource{Kind: "StatefulSet", Name: "redis", Namespace: "prod", FilePath: "/tmp/c.yaml"})
+	store.Add(&indexer.K8sResource{Kind: "PersistentVolume", Name: "data", FilePath: "/tmp/d.yaml"})
+
+	r := NewResolver(store, &config.Config{})
+
+	symbols := r.WorkspaceSymbols("redis")
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.Name)
+	}
+	want := []string{"prod/redis", "prod/redis-master", "prod/app-redis"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+
+	// Cluster-scoped resources without a namespace keep their bare name.
+	if symbols := r.WorkspaceSymbols("data"); len(symbols) != 1 || symbols[0].Name != "data" {
+		t.Fatalf("Expected bare name for cluster-scoped resource, got %v", symbols)
+	}
+
+	for i := 0; i < maxWorkspaceSymbols+10; i++ {
+		store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: fmt.Sprintf("cm-%03d", i), FilePath: "/tmp/many.yaml"})
+	}
+	if got := len(r.WorkspaceSymbols("cm-")); got != maxWorkspaceSymbols {
+		t.Fatalf("Expected results to be capped at %d, got %d", maxWorkspaceSymbols, got)
+	}
+}
+
+func TestWorkspaceSymbols_EmbeddedFiles(t *testing.T) {
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "nginx-config", Namespace: "default", FilePath: "/tmp/nginx.yaml", Line: 3, Col: 8,
+		DataKeys: []indexer.DataKey{{Key: "app.conf", Line: 5, Col: 2}, {Key: "mime.types", Line: 7, Col: 2}}})
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "api-config", Namespace: "prod", FilePath: "/tmp/api.yaml", Line: 3, Col: 8,
+		DataKeys: []indexer.DataKey{{Key: "app.conf", Line: 6, Col: 2}}})
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "app", Namespace: "default", FilePath: "/tmp/app.yaml", Line: 3, Col: 8,
+		DataKeys: []indexer.DataKey{{Key: "settings.json", Line: 5, Col: 2}}})
+
+	r := NewResolver(store, &config.Config{})
+
+	symbols := r.WorkspaceSymbols("embed:app.conf")
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.Name)
+	}
+	want := []string{"api-config/app.conf", "nginx-config/app.conf"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+	first := symbols[0]
+	if first.Kind != protocol.SymbolKindFile {
+		t.Errorf("Expected File kind, got %v", first.Kind)
+	}
+	if first.ContainerName == nil || *first.ContainerName != "ConfigMap/prod/api-config" {
+		t.Errorf("Expected owning resource as container name, got %v", first.ContainerName)
+	}
+	if first.Location.URI != "file:///tmp/api.yaml" ||
+		first.Location.Range.Start.Line != 6 || first.Location.Range.Start.Character != 2 || first.Location.Range.End.Character != 10 {
+		t.Errorf("Expected location at the key node, got %+v", first.Location)
+	}
+
+	// The prefix also matches on "name/key" and excludes resources.
+	if symbols := r.WorkspaceSymbols("embed:nginx-config/"); len(symbols) != 2 {
+		t.Fatalf("Expected both nginx-config keys, got %v", symbols)
+	}
+
+	// Without the prefix, resources come first, then embedded files.
+	names = nil
+	for _, s := range r.WorkspaceSymbols("app") {
+		names = append(names, s.Name)
+	}
+	want = []string{"default/app", "api-config/app.conf", "nginx-config/app.conf", "app/settings.json"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+
+	for i := 0; i < maxWorkspaceSymbols+10; i++ {
+		store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: fmt.Sprintf("cm-%03d", i), FilePath: "/tmp/many.yaml",
+			DataKeys: []indexer.DataKey{{Key: "app.conf"}}})
+	}
+	if got := len(r.WorkspaceSymbols("embed:app.conf")); got != maxWorkspaceSymbols {
+		t.Fatalf("Expected results to be capped at %d, got %d", maxWorkspaceSymbols, got)
+	}
+}
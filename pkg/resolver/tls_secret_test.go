@@ -0,0 +1,106 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestTLSSecretReferences(t *testing.T) {
+	cfg, err := config.Load("../..")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	r := NewResolver(store, cfg)
+
+	secretYaml := `apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+  namespace: certs
+type: kubernetes.io/tls
+`
+	idx.IndexContent("/tmp/secret.yaml", secretYaml)
+
+	ingressYaml := `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: certs
+spec:
+  tls:
+    - hosts: ["example.com"]
+      secretName: web-tls
+`
+	idx.IndexContent("/tmp/ingress.yaml", ingressYaml)
+
+	gatewayYaml := `apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: edge
+  namespace: gateways
+spec:
+  gatewayClassName: envoy
+  listeners:
+    - name: https
+      protocol: HTTPS
+      port: 443
+      tls:
+        certificateRefs:
+          - kind: Secret
+            name: web-tls
+            namespace: certs
+          - kind: ConfigMap
+            name: web-tls
+`
+	idx.IndexContent("/tmp/gateway.yaml", gatewayYaml)
+
+	// "      secretName: web-tls" on line 8.
+	links, err := r.ResolveDefinition(context.Background(), ingressYaml, "file:///tmp/ingress.yaml", 8, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/secret.yaml" {
+		t.Fatalf("Expected the Ingress TLS secret to resolve, got %v", links)
+	}
+
+	// "            name: web-tls" on line 14 names a Secret in another
+	// namespace.
+	links, err = r.ResolveDefinition(context.Background(), gatewayYaml, "file:///tmp/gateway.yaml", 14, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != "file:///tmp/secret.yaml" {
+		t.Fatalf("Expected the Gateway certificateRef to resolve, got %v", links)
+	}
+
+	// The ConfigMap certificateRef on line 17 is not a Secret.
+	links, err = r.ResolveDefinition(context.Background(), gatewayYaml, "file:///tmp/gateway.yaml", 17, 20)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("Expected no link for a non-Secret certificateRef, got %v", links)
+	}
+
+	locs, err := r.ResolveReferences(context.Background(), secretYaml, "file:///tmp/secret.yaml", 3, 8)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	if len(locs) != 2 {
+		t.Fatalf("Expected the Ingress and Gateway consumers, got %v", locs)
+	}
+	for _, want := range []string{"file:///tmp/ingress.yaml", "file:///tmp/gateway.yaml"} {
+		found := false
+		for _, loc := range locs {
+			found = found || loc.URI == want
+		}
+		if !found {
+			t.Errorf("Expected a reference from %s, got %v", want, locs)
+		}
+	}
+}
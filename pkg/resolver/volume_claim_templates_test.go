@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestResolveDefinition_StatefulSetVolumeClaimTemplate(t *testing.T) {
+	uri := "file:///tmp/statefulset.yaml"
+	yamlContent := strings.TrimLeft(`
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      volumes:
+      - name: config
+        configMap:
+          name: db-config
+      containers:
+      - name: db
+        image: postgres
+        volumeMounts:
+        - name: data
+          mountPath: /var/lib/postgresql
+        - name: config
+          mountPath: /etc/postgresql
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      accessModes: ["ReadWriteOnce"]
+`, "\n")
+
+	r := NewResolver(indexer.NewStore(), &config.Config{})
+
+	// "        - name: data" under volumeMounts is line 15, value at col 16.
+	links, err := r.ResolveDefinition(context.Background(), yamlContent, uri, 15, 16)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetURI != uri {
+		t.Fatalf("Expected one link to the claim template, got %v", links)
+	}
+	// "      name: data" under volumeClaimTemplates is line 21, value at col 12.
+	if got := links[0].TargetRange.Start; got.Line != 21 || got.Character != 12 {
+		t.Errorf("Expected target at 21:12, got %d:%d", got.Line, got.Character)
+	}
+
+	// Pod volumes still take precedence.
+	links, err = r.ResolveDefinition(context.Background(), yamlContent, uri, 17, 16)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(links) != 1 || links[0].TargetRange.Start.Line != 8 {
+		t.Errorf("Expected link to the config volume on line 8, got %v", links)
+	}
+}
@@ -0,0 +1,170 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func newWebhookTestResolver() (*indexer.Indexer, *Resolver) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service", "Certificate", "ValidatingWebhookConfiguration"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "webhook.clientConfig.service",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "Service",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"},
+					Path:  "webhooks[].clientConfig.service.name",
+				},
+			},
+		},
+	}
+
+	store := indexer.NewStore()
+	return indexer.NewIndexer(store, cfg), NewResolver(store, cfg)
+}
+
+const webhookYaml = `
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: policy
+  annotations:
+    cert-manager.io/inject-ca-from: webhooks/policy-cert
+webhooks:
+  - name: validate.example.com
+    clientConfig:
+      service:
+        namespace: webhooks
+        name: policy-svc
+        port: 8443
+`
+
+func TestWebhookServiceReference(t *testing.T) {
+	idx, res := newWebhookTestResolver()
+
+	idx.IndexContent("/tmp/svc-webhooks.yaml", `
+apiVersion: v1
+kind: Service
+metadata:
+  name: policy-svc
+  namespace: webhooks
+`)
+	// Same name in another namespace must not be picked up.
+	idx.IndexContent("/tmp/svc-default.yaml", `
+apiVersion: v1
+kind: Service
+metadata:
+  name: policy-svc
+`)
+	idx.IndexContent("/tmp/webhook.yaml", webhookYaml)
+
+	// "        name: policy-svc" is line 12 (0-based), value starts at col 14
+	locs, err := res.ResolveDefinition(context.Background(), webhookYaml, "file:///tmp/webhook.yaml", 12, 16)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].TargetURI != "file:///tmp/svc-webhooks.yaml" {
+		t.Fatalf("Expected definition in svc-webhooks.yaml, got %v", locs)
+	}
+
+	hover, err := res.ResolveHover(context.Background(), webhookYaml, "file:///tmp/webhook.yaml", 12, 16)
+	if err != nil || hover == nil {
+		t.Fatalf("Expected hover for webhook service, got %v (err %v)", hover, err)
+	}
+
+	// Find references from the Service side.
+	svcYaml := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: policy-svc
+  namespace: webhooks
+`
+	refs, err := res.ResolveReferences(context.Background(), svcYaml, "file:///tmp/svc-webhooks.yaml", 4, 9)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	found := false
+	for _, loc := range refs {
+		if loc.URI == "file:///tmp/webhook.yaml" && loc.Range.Start.Line == 12 && loc.Range.Start.Character == 14 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected webhook reference from Service, got %v", refs)
+	}
+
+	refs, err = res.ResolveReferences(context.Background(), `
+apiVersion: v1
+kind: Service
+metadata:
+  name: policy-svc
+`, "file:///tmp/svc-default.yaml", 4, 9)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	for _, loc := range refs {
+		if loc.URI == "file:///tmp/webhook.yaml" {
+			t.Fatalf("Service in default namespace should not be referenced by the webhook, got %v", refs)
+		}
+	}
+}
+
+func TestInjectCAFromAnnotation(t *testing.T) {
+	idx, res := newWebhookTestResolver()
+
+	idx.IndexContent("/tmp/cert.yaml", `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: policy-cert
+  namespace: webhooks
+`)
+	idx.IndexContent("/tmp/webhook.yaml", webhookYaml)
+
+	// "    cert-manager.io/inject-ca-from: webhooks/policy-cert" is line 6 (0-based);
+	// the value starts at col 36 and the certificate name at col 45.
+	locs, err := res.ResolveDefinition(context.Background(), webhookYaml, "file:///tmp/webhook.yaml", 6, 47)
+	if err != nil {
+		t.Fatalf("ResolveDefinition failed: %v", err)
+	}
+	if len(locs) != 1 || locs[0].TargetURI != "file:///tmp/cert.yaml" {
+		t.Fatalf("Expected definition in cert.yaml, got %v", locs)
+	}
+	if locs[0].OriginSelectionRange.Start.Character != 45 || locs[0].OriginSelectionRange.End.Character != 56 {
+		t.Errorf("Expected origin range to cover the certificate name, got %v", locs[0].OriginSelectionRange)
+	}
+
+	certYaml := `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: policy-cert
+  namespace: webhooks
+`
+	refs, err := res.ResolveReferences(context.Background(), certYaml, "file:///tmp/cert.yaml", 4, 9)
+	if err != nil {
+		t.Fatalf("ResolveReferences failed: %v", err)
+	}
+	found := false
+	for _, loc := range refs {
+		if loc.URI == "file:///tmp/webhook.yaml" && loc.Range.Start.Line == 6 && loc.Range.Start.Character == 45 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected annotation reference from Certificate, got %v", refs)
+	}
+}
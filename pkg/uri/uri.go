@@ -0,0 +1,67 @@
+// Package uri converts between file:// URIs and filesystem paths.
+//
+// Windows paths are recognised by their shape (a drive letter or a UNC
+// \\server\share prefix) rather than by the OS the server runs on, so the
+// same URIs round-trip everywhere. Drive letters are upper-cased both ways:
+// clients disagree on their case (VS Code sends file:///c%3A/...), and paths
+// are compared as plain strings in the store.
+package uri
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// ToPath returns the filesystem path of a file:// URI, decoding
+// percent-escapes. A URI with a host is a UNC path.
+func ToPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "file" {
+		return "", fmt.Errorf("not a file URI: %s", uri)
+	}
+	path := parsed.Path
+	switch {
+	case parsed.Host != "" && parsed.Host != "localhost":
+		path = "//" + parsed.Host + path
+	case len(path) >= 3 && path[0] == '/' && hasDrive(path[1:]):
+		path = upperDrive(path[1:])
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// FromPath returns the file:// URI of an absolute path, percent-encoding
+// anything that isn't allowed in a URI path (spaces, Hangul, '#', ...).
+func FromPath(path string) string {
+	if hasDrive(path) || strings.HasPrefix(path, `\\`) {
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+	path = filepath.ToSlash(path)
+
+	u := url.URL{Scheme: "file", Path: path}
+	switch {
+	case strings.HasPrefix(path, "//"):
+		host, rest, _ := strings.Cut(path[2:], "/")
+		u.Host, u.Path = host, "/"+rest
+	case hasDrive(path):
+		u.Path = "/" + upperDrive(path)
+	}
+	return u.String()
+}
+
+// hasDrive reports whether path starts with a drive letter like C:.
+func hasDrive(path string) bool {
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	c := path[0]
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func upperDrive(path string) string {
+	return strings.ToUpper(path[:1]) + path[1:]
+}
@@ -0,0 +1,65 @@
+package uri
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		path string
+		uri  string
+		// back is the path ToPath gives, in slash form, when it isn't path.
+		back string
+	}{
+		{"/tmp/app.yaml", "file:///tmp/app.yaml", ""},
+		{"/tmp/my manifests/app.yaml", "file:///tmp/my%20manifests/app.yaml", ""},
+		{"/home/user/배포/서비스.yaml", "file:///home/user/%EB%B0%B0%ED%8F%AC/%EC%84%9C%EB%B9%84%EC%8A%A4.yaml", ""},
+		{"/tmp/100%/a#b.yaml", "file:///tmp/100%25/a%23b.yaml", ""},
+		{`C:\work\app.yaml`, "file:///C:/work/app.yaml", "C:/work/app.yaml"},
+		{`c:\My Work\배포.yaml`, "file:///C:/My%20Work/%EB%B0%B0%ED%8F%AC.yaml", "C:/My Work/배포.yaml"},
+		{"D:/work/app.yaml", "file:///D:/work/app.yaml", ""},
+		{`\\server\share\k8s\app.yaml`, "file://server/share/k8s/app.yaml", "//server/share/k8s/app.yaml"},
+	}
+	for _, tt := range tests {
+		if got := FromPath(tt.path); got != tt.uri {
+			t.Errorf("FromPath(%q) = %q, want %q", tt.path, got, tt.uri)
+		}
+		back := tt.back
+		if back == "" {
+			back = tt.path
+		}
+		got, err := ToPath(tt.uri)
+		if err != nil {
+			t.Errorf("ToPath(%q) failed: %v", tt.uri, err)
+			continue
+		}
+		if got != filepath.FromSlash(back) {
+			t.Errorf("ToPath(%q) = %q, want %q", tt.uri, got, filepath.FromSlash(back))
+		}
+	}
+}
+
+func TestToPathDriveLetters(t *testing.T) {
+	// VS Code lower-cases the drive and escapes its colon.
+	for _, uri := range []string{"file:///c%3A/work/app.yaml", "file:///C:/work/app.yaml", "file:///c:/work/app.yaml"} {
+		got, err := ToPath(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != filepath.FromSlash("C:/work/app.yaml") {
+			t.Errorf("ToPath(%q) = %q, want the upper-case drive", uri, got)
+		}
+	}
+	if got, _ := ToPath("file://localhost/etc/app.yaml"); got != filepath.FromSlash("/etc/app.yaml") {
+		t.Errorf("Expected localhost to be the local machine, got %q", got)
+	}
+}
+
+func TestToPathRejectsOtherSchemes(t *testing.T) {
+	for _, uri := range []string{"k8s-embedded://default/cm/key", "untitled:Untitled-1", "%zz"} {
+		if _, err := ToPath(uri); err == nil {
+			t.Errorf("Expected ToPath(%q) to fail", uri)
+		}
+	}
+}
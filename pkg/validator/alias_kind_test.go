@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestDeclaredSecretSatisfiesReference(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Certificate"}, Path: "metadata.name"},
+					{Kinds: []string{"Certificate"}, Path: "spec.secretName", AliasKind: "Secret"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/cert.yaml", `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web-tls
+spec:
+  secretName: web-tls-secret
+`)
+
+	v := &Validator{
+		store: store,
+		rules: []Rule{{
+			Kind: "Deployment",
+			Checks: []Check{{
+				Type:       "reference",
+				Path:       "spec.template.spec.containers.envFrom.secretRef.name",
+				TargetKind: "Secret",
+				Message:    "Secret not found",
+			}},
+		}},
+	}
+
+	deployment := func(secret string) string {
+		return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - secretRef:
+                name: ` + secret + `
+`
+	}
+	if diags := v.Validate("file:///tmp/deploy.yaml", deployment("web-tls-secret")); len(diags) != 0 {
+		t.Fatalf("Expected the declared Secret to count as defined, got %v", diags)
+	}
+	if diags := v.Validate("file:///tmp/deploy.yaml", deployment("other")); len(diags) != 1 {
+		t.Fatalf("Expected a missing Secret diagnostic, got %v", diags)
+	}
+}
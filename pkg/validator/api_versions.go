@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"fmt"
+	"slices"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeDeprecatedAPIVersion marks an apiVersion that Kubernetes no longer
+// serves for the resource's kind.
+const CodeDeprecatedAPIVersion = "deprecated-api-version"
+
+// removedAPI is an apiVersion that was removed for some kinds, or for every
+// kind when kinds is empty. An empty replacement means the kind was removed
+// along with it.
+type removedAPI struct {
+	apiVersion  string
+	kinds       []string
+	replacement string
+	removedIn   string
+}
+
+// removedAPIs lists the apiVersions removed from Kubernetes, following the
+// upstream deprecated API migration guide.
+var removedAPIs = []removedAPI{
+	{"extensions/v1beta1", []string{"Deployment", "DaemonSet", "ReplicaSet"}, "apps/v1", "1.16"},
+	{"extensions/v1beta1", []string{"NetworkPolicy"}, "networking.k8s.io/v1", "1.16"},
+	{"extensions/v1beta1", []string{"Ingress"}, "networking.k8s.io/v1", "1.22"},
+	{"extensions/v1beta1", []string{"PodSecurityPolicy"}, "", "1.25"},
+	{"apps/v1beta1", nil, "apps/v1", "1.16"},
+	{"apps/v1beta2", nil, "apps/v1", "1.16"},
+	{"networking.k8s.io/v1beta1", []string{"Ingress", "IngressClass"}, "networking.k8s.io/v1", "1.22"},
+	{"rbac.authorization.k8s.io/v1beta1", nil, "rbac.authorization.k8s.io/v1", "1.22"},
+	{"admissionregistration.k8s.io/v1beta1", nil, "admissionregistration.k8s.io/v1", "1.22"},
+	{"apiextensions.k8s.io/v1beta1", nil, "apiextensions.k8s.io/v1", "1.22"},
+	{"apiregistration.k8s.io/v1beta1", nil, "apiregistration.k8s.io/v1", "1.22"},
+	{"certificates.k8s.io/v1beta1", nil, "certificates.k8s.io/v1", "1.22"},
+	{"coordination.k8s.io/v1beta1", nil, "coordination.k8s.io/v1", "1.22"},
+	{"scheduling.k8s.io/v1beta1", nil, "scheduling.k8s.io/v1", "1.22"},
+	{"storage.k8s.io/v1beta1", []string{"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"}, "storage.k8s.io/v1", "1.22"},
+	{"batch/v1beta1", []string{"CronJob"}, "batch/v1", "1.25"},
+	{"discovery.k8s.io/v1beta1", []string{"EndpointSlice"}, "discovery.k8s.io/v1", "1.25"},
+	{"events.k8s.io/v1beta1", []string{"Event"}, "events.k8s.io/v1", "1.25"},
+	{"node.k8s.io/v1beta1", []string{"RuntimeClass"}, "node.k8s.io/v1", "1.25"},
+	{"policy/v1beta1", []string{"PodDisruptionBudget"}, "policy/v1", "1.25"},
+	{"policy/v1beta1", []string{"PodSecurityPolicy"}, "", "1.25"},
+	{"autoscaling/v2beta1", []string{"HorizontalPodAutoscaler"}, "autoscaling/v2", "1.25"},
+	{"autoscaling/v2beta2", []string{"HorizontalPodAutoscaler"}, "autoscaling/v2", "1.26"},
+	{"storage.k8s.io/v1beta1", []string{"CSIStorageCapacity"}, "storage.k8s.io/v1", "1.27"},
+}
+
+// lookupRemovedAPI returns the removedAPIs entry for apiVersion and kind.
+func lookupRemovedAPI(apiVersion, kind string) (removedAPI, bool) {
+	for _, api := range removedAPIs {
+		if api.apiVersion != apiVersion {
+			continue
+		}
+		if len(api.kinds) == 0 || slices.Contains(api.kinds, kind) {
+			return api, true
+		}
+	}
+	return removedAPI{}, false
+}
+
+// checkDeprecatedAPIVersion warns on an apiVersion that Kubernetes removed for
+// the document's kind, naming the one to use instead.
+func checkDeprecatedAPIVersion(root *yaml.Node, kind string) []protocol.Diagnostic {
+	node := firstNode(root, "apiVersion")
+	if node == nil || node.Kind != yaml.ScalarNode || kind == "" {
+		return nil
+	}
+	api, ok := lookupRemovedAPI(node.Value, kind)
+	if !ok {
+		return nil
+	}
+	message := fmt.Sprintf("%s %s was removed in Kubernetes %s; use %s", api.apiVersion, kind, api.removedIn, api.replacement)
+	if api.replacement == "" {
+		message = fmt.Sprintf("%s %s was removed in Kubernetes %s and has no replacement", api.apiVersion, kind, api.removedIn)
+	}
+	diag := newDiagnostic(node, scalarLength(node), protocol.DiagnosticSeverityWarning, message)
+	return []protocol.Diagnostic{withCode(diag, CodeDeprecatedAPIVersion)}
+}
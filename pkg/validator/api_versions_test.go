@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestDeprecatedAPIVersion(t *testing.T) {
+	v := &Validator{store: indexer.NewStore()}
+
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{
+			name: "removed Deployment",
+			content: `apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: web
+`,
+			message: "apps/v1beta1 Deployment was removed in Kubernetes 1.16; use apps/v1",
+		},
+		{
+			name: "removed Ingress",
+			content: `apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: web
+`,
+			message: "extensions/v1beta1 Ingress was removed in Kubernetes 1.22; use networking.k8s.io/v1",
+		},
+		{
+			name: "removed without replacement",
+			content: `apiVersion: "policy/v1beta1"
+kind: PodSecurityPolicy
+metadata:
+  name: restricted
+`,
+			message: "policy/v1beta1 PodSecurityPolicy was removed in Kubernetes 1.25 and has no replacement",
+		},
+		{
+			name: "current Deployment",
+			content: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector: {matchLabels: {app: web}}
+`,
+		},
+		{
+			name: "kind still served by the group version",
+			content: `apiVersion: policy/v1beta1
+kind: Eviction
+metadata:
+  name: web
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := v.Validate("file:///app.yaml", tt.content)
+			if tt.message == "" {
+				if len(diags) != 0 {
+					t.Fatalf("Expected no diagnostics, got %v", diags)
+				}
+				return
+			}
+			if len(diags) != 1 || diags[0].Message != tt.message || diags[0].Code == nil || diags[0].Code.Value != CodeDeprecatedAPIVersion {
+				t.Fatalf("Expected %q, got %v", tt.message, diags)
+			}
+			// The range covers the apiVersion value, quotes included.
+			line := strings.Split(tt.content, "\n")[0]
+			if r := diags[0].Range; r.Start.Line != 0 || r.Start.Character != 12 || int(r.End.Character) != len(line) {
+				t.Errorf("Expected the apiVersion value's range, got %v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// checkHorizontalPodAutoscaler runs the autoscaling policy pack against an HPA:
+//   - minReplicas must not exceed maxReplicas
+//   - the target workload should not also pin spec.replicas
+//   - a PodDisruptionBudget selecting the target's pods must allow evictions
+//     at minReplicas, otherwise rollouts and node drains can deadlock
+func (v *Validator) checkHorizontalPodAutoscaler(root *yaml.Node, namespace string) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+
+	minNode := firstNode(root, "spec.minReplicas")
+	maxNode := firstNode(root, "spec.maxReplicas")
+	minReplicas := 1 // Kubernetes default
+	if minNode != nil {
+		if n, err := strconv.Atoi(minNode.Value); err == nil {
+			minReplicas = n
+		}
+	}
+
+	if minNode != nil && maxNode != nil {
+		if maxReplicas, err := strconv.Atoi(maxNode.Value); err == nil && minReplicas > maxReplicas {
+			diag := newDiagnostic(minNode, len(minNode.Value), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("minReplicas (%d) is greater than maxReplicas (%d)", minReplicas, maxReplicas))
+			diagnostics = append(diagnostics, diag)
+		}
+	}
+
+	targetKindNode := firstNode(root, "spec.scaleTargetRef.kind")
+	targetNameNode := firstNode(root, "spec.scaleTargetRef.name")
+	if targetKindNode == nil || targetNameNode == nil {
+		return diagnostics
+	}
+	target := v.store.Get(targetKindNode.Value, namespace, targetNameNode.Value)
+	if target == nil {
+		return diagnostics
+	}
+	targetRoot := v.findResourceNode(target)
+	if targetRoot == nil {
+		return diagnostics
+	}
+
+	if replicasNode := firstNode(targetRoot, "spec.replicas"); replicasNode != nil {
+		diag := newDiagnostic(targetNameNode, len(targetNameNode.Value), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("%s %s sets spec.replicas, which conflicts with this HorizontalPodAutoscaler", target.Kind, target.Name))
+		diag.RelatedInformation = []protocol.DiagnosticRelatedInformation{
+			relatedInfo(target, replicasNode, "spec.replicas is set here"),
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+
+	templateLabels := make(map[string]string)
+	if labels := firstNode(targetRoot, "spec.template.metadata.labels"); labels != nil && labels.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(labels.Content); i += 2 {
+			templateLabels[labels.Content[i].Value] = labels.Content[i+1].Value
+		}
+	}
+	if len(templateLabels) == 0 {
+		return diagnostics
+	}
+
+	anchor := minNode
+	if anchor == nil {
+		anchor = targetNameNode
+	}
+	for _, pdb := range v.store.ListByKind("PodDisruptionBudget") {
+		if v.settings.CanonicalNamespace(pdb.Namespace) != v.settings.CanonicalNamespace(namespace) {
+			continue
+		}
+		pdbRoot := v.findResourceNode(pdb)
+		if pdbRoot == nil || !selectorMatches(firstNode(pdbRoot, "spec.selector.matchLabels"), templateLabels) {
+			continue
+		}
+		minAvailableNode := firstNode(pdbRoot, "spec.minAvailable")
+		if minAvailableNode == nil {
+			continue
+		}
+		// Percentages depend on the live replica count; only absolute values are checked.
+		minAvailable, err := strconv.Atoi(minAvailableNode.Value)
+		if err != nil || minAvailable < minReplicas {
+			continue
+		}
+
+		diag := newDiagnostic(anchor, len(anchor.Value), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("PodDisruptionBudget %s requires minAvailable %d with minReplicas %d; evictions can deadlock", pdb.Name, minAvailable, minReplicas))
+		diag.RelatedInformation = []protocol.DiagnosticRelatedInformation{
+			relatedInfo(pdb, minAvailableNode, "minAvailable is set here"),
+			{
+				Location: protocol.Location{URI: fileURI(target.FilePath), Range: resourceNameRange(target)},
+				Message:  fmt.Sprintf("selects the pods of %s %s", target.Kind, target.Name),
+			},
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+
+	return diagnostics
+}
+
+func firstNode(root *yaml.Node, path string) *yaml.Node {
+	nodes := findNodes(root, path)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// selectorMatches reports whether a non-empty matchLabels mapping selects the labels.
+func selectorMatches(matchLabels *yaml.Node, labels map[string]string) bool {
+	if matchLabels == nil || matchLabels.Kind != yaml.MappingNode || len(matchLabels.Content) == 0 {
+		return false
+	}
+	for i := 0; i+1 < len(matchLabels.Content); i += 2 {
+		if labels[matchLabels.Content[i].Value] != matchLabels.Content[i+1].Value {
+			return false
+		}
+	}
+	return true
+}
+
+func relatedInfo(res *indexer.K8sResource, node *yaml.Node, message string) protocol.DiagnosticRelatedInformation {
+	return protocol.DiagnosticRelatedInformation{
+		Location: protocol.Location{
+			URI: fileURI(res.FilePath),
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(node.Line - 1), Character: uint32(node.Column - 1)},
+				End:   protocol.Position{Line: uint32(node.Line - 1), Character: uint32(node.Column - 1 + len(node.Value))},
+			},
+		},
+		Message: message,
+	}
+}
+
+func resourceNameRange(res *indexer.K8sResource) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col)},
+		End:   protocol.Position{Line: uint32(res.Line), Character: uint32(res.Col + len(res.Name))},
+	}
+}
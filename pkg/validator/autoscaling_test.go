@@ -0,0 +1,154 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func newAutoscalingValidator(t *testing.T, files map[string]string) *Validator {
+	t.Helper()
+	cfg := &config.Config{
+		Settings: config.Settings{PolicyPacks: []string{config.PolicyPackAutoscaling}},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Deployment", "PodDisruptionBudget"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		idx.IndexFile(path)
+	}
+	return &Validator{store: store, settings: cfg.Settings}
+}
+
+func deploymentFixture(replicas string) string {
+	return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+` + replicas + `  template:
+    metadata:
+      labels:
+        app: web
+`
+}
+
+const pdbFixture = `apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web-pdb
+spec:
+  minAvailable: 2
+  selector:
+    matchLabels:
+      app: web
+`
+
+func hpaFixture(minReplicas, maxReplicas string) string {
+	return `apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+  minReplicas: ` + minReplicas + `
+  maxReplicas: ` + maxReplicas + `
+`
+}
+
+func TestAutoscaling_MinGreaterThanMax(t *testing.T) {
+	v := newAutoscalingValidator(t, map[string]string{"deploy.yaml": deploymentFixture("")})
+
+	diags := v.Validate("file:///hpa.yaml", hpaFixture("5", "3"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "minReplicas (5) is greater than maxReplicas (3)") {
+		t.Fatalf("Expected min/max diagnostic, got %v", diags)
+	}
+	if diags[0].Range.Start.Line != 9 {
+		t.Errorf("Expected diagnostic at minReplicas, got %v", diags[0].Range)
+	}
+}
+
+func TestAutoscaling_TargetSetsReplicas(t *testing.T) {
+	v := newAutoscalingValidator(t, map[string]string{"deploy.yaml": deploymentFixture("  replicas: 3\n")})
+
+	diags := v.Validate("file:///hpa.yaml", hpaFixture("2", "5"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "sets spec.replicas") {
+		t.Fatalf("Expected replicas conflict diagnostic, got %v", diags)
+	}
+	if diags[0].Range.Start.Line != 8 {
+		t.Errorf("Expected diagnostic at scaleTargetRef.name, got %v", diags[0].Range)
+	}
+	related := diags[0].RelatedInformation
+	if len(related) != 1 || !strings.HasSuffix(related[0].Location.URI, "deploy.yaml") || related[0].Location.Range.Start.Line != 5 {
+		t.Errorf("Expected related information at the Deployment's spec.replicas, got %v", related)
+	}
+}
+
+func TestAutoscaling_PDBMinAvailableBlocksEvictions(t *testing.T) {
+	v := newAutoscalingValidator(t, map[string]string{
+		"deploy.yaml": deploymentFixture(""),
+		"pdb.yaml":    pdbFixture,
+	})
+
+	diags := v.Validate("file:///hpa.yaml", hpaFixture("2", "5"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "PodDisruptionBudget web-pdb") {
+		t.Fatalf("Expected PDB deadlock diagnostic, got %v", diags)
+	}
+	related := diags[0].RelatedInformation
+	if len(related) != 2 || !strings.HasSuffix(related[0].Location.URI, "pdb.yaml") || !strings.HasSuffix(related[1].Location.URI, "deploy.yaml") {
+		t.Errorf("Expected related information for the PDB and the Deployment, got %v", related)
+	}
+
+	if diags := v.Validate("file:///hpa.yaml", hpaFixture("3", "5")); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics when minReplicas exceeds minAvailable, got %v", diags)
+	}
+}
+
+func TestAutoscaling_RequiresPolicyPack(t *testing.T) {
+	v := newAutoscalingValidator(t, map[string]string{"deploy.yaml": deploymentFixture("  replicas: 3\n")})
+	v.settings = config.Settings{}
+
+	if diags := v.Validate("file:///hpa.yaml", hpaFixture("5", "3")); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics without the autoscaling policy pack, got %v", diags)
+	}
+}
+
+func TestAutoscaling_RelatedInformationInByteColumns(t *testing.T) {
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec: {note: "héllo wörld", replicas: 3}
+`
+	v := newAutoscalingValidator(t, map[string]string{"deploy.yaml": deployment})
+
+	diags := v.Validate("file:///hpa.yaml", hpaFixture("2", "5"))
+	if len(diags) != 1 || len(diags[0].RelatedInformation) != 1 {
+		t.Fatalf("Expected replicas conflict diagnostic with related information, got %v", diags)
+	}
+	line := strings.Split(deployment, "\n")[4]
+	want := uint32(strings.Index(line, "3}"))
+	if got := diags[0].RelatedInformation[0].Location.Range.Start; got.Line != 4 || got.Character != want {
+		t.Errorf("Expected related information at byte column %d of line 4, got %v", want, got)
+	}
+}
@@ -0,0 +1,45 @@
+package validator
+
+import (
+	"fmt"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// checkDuplicateDataKeys reports a key repeated within a ConfigMap or Secret
+// data map. Depending on the parser one value silently wins, so every later
+// occurrence is flagged and points back at the first. The raw mapping
+// content is scanned because yaml.v3 keeps duplicate keys in a yaml.Node.
+func checkDuplicateDataKeys(uri string, root *yaml.Node) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+	for _, field := range []string{"data", "binaryData", "stringData"} {
+		data := firstNode(root, field)
+		if data == nil || data.Kind != yaml.MappingNode {
+			continue
+		}
+		seen := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(data.Content); i += 2 {
+			key := data.Content[i]
+			first, ok := seen[key.Value]
+			if !ok {
+				seen[key.Value] = key
+				continue
+			}
+			diag := newDiagnostic(key, scalarLength(key), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("Duplicate key %q in %s; only one of the values will be used", key.Value, field))
+			diag.RelatedInformation = []protocol.DiagnosticRelatedInformation{{
+				Location: protocol.Location{
+					URI: uri,
+					Range: protocol.Range{
+						Start: protocol.Position{Line: uint32(first.Line - 1), Character: uint32(first.Column - 1)},
+						End:   protocol.Position{Line: uint32(first.Line - 1), Character: uint32(first.Column - 1 + scalarLength(first))},
+					},
+				},
+				Message: "first defined here",
+			}}
+			diagnostics = append(diagnostics, diag)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestDuplicateDataKeys(t *testing.T) {
+	v := &Validator{store: indexer.NewStore()}
+
+	diags := v.Validate("file:///tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  LOG_LEVEL: info
+  PORT: "8080"
+  LOG_LEVEL: debug
+binaryData:
+  cert: Zm9v
+`)
+	if len(diags) != 1 {
+		t.Fatalf("Expected one duplicate key diagnostic, got %v", diags)
+	}
+	d := diags[0]
+	if d.Range.Start.Line != 7 || d.Range.Start.Character != 2 || d.Range.End.Character != 11 {
+		t.Errorf("Expected the second LOG_LEVEL at 7:2-11, got %v", d.Range)
+	}
+	if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.Range.Start.Line != 5 {
+		t.Errorf("Expected related information at the first LOG_LEVEL, got %v", d.RelatedInformation)
+	}
+
+	diags = v.Validate("file:///tmp/secret.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: Zm9v
+stringData:
+  password: bar
+`)
+	if len(diags) != 0 {
+		t.Errorf("Expected keys in different maps not to be duplicates, got %v", diags)
+	}
+}
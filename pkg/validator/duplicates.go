@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeDuplicateResource marks a resource that another file defines too.
+const CodeDuplicateResource = "duplicate-resource"
+
+// checkDuplicateResource warns on metadata.name when another file defines
+// the same resource, since only one of them can be navigated to. Resources
+// named through generateName are never the same.
+func (v *Validator) checkDuplicateResource(uri string, root *yaml.Node, kind string) []protocol.Diagnostic {
+	nameNodes := findNodes(root, "metadata.name")
+	if len(nameNodes) == 0 || nameNodes[0].Value == "" {
+		return nil
+	}
+	nameNode := nameNodes[0]
+	namespace := ""
+	if nsNodes := findNodes(root, "metadata.namespace"); len(nsNodes) > 0 {
+		namespace = nsNodes[0].Value
+	}
+
+	for _, res := range v.store.FindByFile(uriPath(uri)) {
+		if res.Kind != kind || res.Name != nameNode.Value || res.GenerateName || res.DeclaredBy != nil {
+			continue
+		}
+		if v.settings.CanonicalNamespace(res.Namespace) != v.settings.CanonicalNamespace(namespace) {
+			continue
+		}
+		dups := v.store.Duplicates(res)
+		if len(dups) == 0 {
+			return nil
+		}
+		var files []string
+		var related []protocol.DiagnosticRelatedInformation
+		for _, dup := range dups {
+			files = append(files, filepath.Base(dup.FilePath))
+			related = append(related, protocol.DiagnosticRelatedInformation{
+				Location: protocol.Location{URI: fileURI(dup.FilePath), Range: resourceNameRange(dup)},
+				Message:  "also defined here",
+			})
+		}
+		diag := newDiagnostic(nameNode, scalarLength(nameNode), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("%s %s is also defined in %s", kind, res.Name, strings.Join(files, ", ")))
+		diag.RelatedInformation = related
+		return []protocol.Diagnostic{withCode(diag, CodeDuplicateResource)}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestDuplicateResource(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{{
+			Name:        "k8s.resource.name",
+			Definitions: []config.SymbolDefinition{{Kinds: []string{"ConfigMap"}, Path: "metadata.name"}},
+		}},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	v := &Validator{store: store}
+
+	configMap := func(namespace string) string {
+		return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: " + namespace + "\ndata:\n  a: b\n"
+	}
+	idx.IndexContent("/repo/base/settings.yaml", configMap("apps"))
+	idx.IndexContent("/repo/other/settings.yaml", configMap("apps"))
+	idx.IndexContent("/repo/prod/settings.yaml", configMap("prod"))
+
+	for _, path := range []string{"/repo/base/settings.yaml", "/repo/other/settings.yaml"} {
+		diags := v.Validate("file://"+path, configMap("apps"))
+		if len(diags) != 1 || diags[0].Code == nil || diags[0].Code.Value != CodeDuplicateResource {
+			t.Fatalf("%s: expected a duplicate resource diagnostic, got %v", path, diags)
+		}
+		diag := diags[0]
+		if diag.Range.Start.Line != 3 || diag.Range.Start.Character != 8 {
+			t.Errorf("%s: expected the diagnostic on metadata.name, got %+v", path, diag.Range)
+		}
+		if diag.Message != "ConfigMap settings is also defined in settings.yaml" || len(diag.RelatedInformation) != 1 || diag.RelatedInformation[0].Location.URI == "file://"+path {
+			t.Errorf("%s: expected the other file as related information, got %+v", path, diag)
+		}
+	}
+	if diags := v.Validate("file:///repo/prod/settings.yaml", configMap("prod")); len(diags) != 0 {
+		t.Errorf("Expected the same name in another namespace to be fine, got %v", diags)
+	}
+
+	// Moved to another namespace in the editor before the file is indexed
+	// again, it is no duplicate of what the file held before.
+	idx.IndexContent("/repo/moved/settings.yaml", configMap("apps"))
+	if diags := v.Validate("file:///repo/moved/settings.yaml", configMap("staging")); len(diags) != 0 {
+		t.Errorf("Expected no duplicate in staging, got %v", diags)
+	}
+}
@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeEnvFromCollision marks an envFrom source producing environment
+// variables an earlier source of the same container already sets.
+const CodeEnvFromCollision = "envfrom-collision"
+
+// checkEnvFromCollisions warns when two envFrom sources of a container
+// produce the same variable, usually overlapping keys without a prefix.
+// Kubernetes silently keeps the later value, so the later source is
+// flagged and points back at the earlier one.
+func (v *Validator) checkEnvFromCollisions(uri string, root *yaml.Node, kind, namespace string) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+	for _, sources := range indexer.ContainerEnvFrom(root, kind) {
+		producer := make(map[string]indexer.EnvFromSource)
+		type pair struct{ earlier, later indexer.EnvFromSource }
+		var pairs []pair
+		collisions := make(map[pair][]string)
+		for _, envVar := range v.store.EnvFromVars(sources, namespace) {
+			earlier, ok := producer[envVar.Name]
+			producer[envVar.Name] = envVar.Source
+			if !ok || earlier.NameNode == envVar.Source.NameNode {
+				continue
+			}
+			p := pair{earlier, envVar.Source}
+			if _, ok := collisions[p]; !ok {
+				pairs = append(pairs, p)
+			}
+			collisions[p] = append(collisions[p], envVar.Name)
+		}
+
+		for _, p := range pairs {
+			earlier, later := p.earlier.NameNode, p.later.NameNode
+			diag := newDiagnostic(later, scalarLength(later), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("%s %s overrides %s from %s %s; set a distinct prefix to keep both",
+					p.later.Kind, p.later.Name, strings.Join(collisions[p], ", "), p.earlier.Kind, p.earlier.Name))
+			diag.RelatedInformation = []protocol.DiagnosticRelatedInformation{{
+				Location: protocol.Location{
+					URI: uri,
+					Range: protocol.Range{
+						Start: protocol.Position{Line: uint32(earlier.Line - 1), Character: uint32(earlier.Column - 1)},
+						End:   protocol.Position{Line: uint32(earlier.Line - 1), Character: uint32(earlier.Column - 1 + scalarLength(earlier))},
+					},
+				},
+				Message: "also sets " + strings.Join(collisions[p], ", "),
+			}}
+			diagnostics = append(diagnostics, withCode(diag, CodeEnvFromCollision))
+		}
+	}
+	return diagnostics
+}
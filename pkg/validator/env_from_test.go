@@ -0,0 +1,81 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestEnvFromCollisions(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{{
+			Name: "k8s.resource.name",
+			Definitions: []config.SymbolDefinition{
+				{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+			},
+		}},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexContent("/tmp/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: info
+  PORT: "8080"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: db-config
+data:
+  LOG_LEVEL: warn
+  PORT: "5432"
+  HOST: db
+`)
+	v := &Validator{store: store}
+
+	diags := v.Validate("file:///tmp/deploy.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - configMapRef:
+                name: app-config
+            - configMapRef:
+                name: db-config
+        - name: sidecar
+          envFrom:
+            - configMapRef:
+                name: app-config
+            - prefix: DB_
+              configMapRef:
+                name: db-config
+`)
+	var collisions []string
+	for _, d := range diags {
+		if d.Code != nil && d.Code.Value == CodeEnvFromCollision {
+			collisions = append(collisions, d.Message)
+			if d.Range.Start.Line != 13 || d.Range.Start.Character != 22 {
+				t.Errorf("Expected the collision on db-config at 13:22, got %v", d.Range)
+			}
+			if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.Range.Start.Line != 11 {
+				t.Errorf("Expected related information at app-config, got %v", d.RelatedInformation)
+			}
+		}
+	}
+	if len(collisions) != 1 {
+		t.Fatalf("Expected one collision for the unprefixed container, got %v", collisions)
+	}
+	if !strings.Contains(collisions[0], "LOG_LEVEL, PORT") || strings.Contains(collisions[0], "HOST") {
+		t.Errorf("Expected LOG_LEVEL and PORT to collide, got %q", collisions[0])
+	}
+}
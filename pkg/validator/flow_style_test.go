@@ -0,0 +1,85 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestFlowStyleDiagnosticRanges(t *testing.T) {
+	v := &Validator{
+		store: indexer.NewStore(),
+		rules: []Rule{
+			{
+				Kind: "Service",
+				Checks: []Check{{
+					Type:       "reference",
+					Path:       "spec.selector",
+					TargetKind: "Deployment",
+					Message:    "No Deployment found matching this selector",
+				}},
+			},
+			{
+				Kind: "Pod",
+				Checks: []Check{{
+					Type:       "reference",
+					Path:       "spec.volumes.configMap.name",
+					TargetKind: "ConfigMap",
+					Message:    "ConfigMap not found",
+				}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    protocol.Range
+	}{
+		{
+			name: "flow selector",
+			content: `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector: {app: web, tier: "db"}
+`,
+			// "  selector: {app: web, tier: "db"}"
+			//  0123456789012345678901234567890123
+			want: protocol.Range{
+				Start: protocol.Position{Line: 5, Character: 13},
+				End:   protocol.Position{Line: 5, Character: 33},
+			},
+		},
+		{
+			name: "quoted name in flow sequence",
+			content: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  volumes: [{name: cfg, configMap: {name: "missing"}}]
+`,
+			// "  volumes: [{name: cfg, configMap: {name: "missing"}}]"
+			//  0123456789012345678901234567890123456789012345678901
+			want: protocol.Range{
+				Start: protocol.Position{Line: 5, Character: 42},
+				End:   protocol.Position{Line: 5, Character: 51},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := v.Validate("file:///flow.yaml", tt.content)
+			if len(diags) != 1 {
+				t.Fatalf("Expected one diagnostic, got %v", diags)
+			}
+			if diags[0].Range != tt.want {
+				t.Errorf("Expected range %v, got %v", tt.want, diags[0].Range)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestInterpolatedReferences(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{
+			Interpolation: config.Interpolation{Values: map[string]string{"APP": "web"}},
+		},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ServiceAccount"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/sa.yaml", `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web-runner
+`)
+
+	v, err := NewValidator("../../rules/validation.yaml", store, cfg)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	pod := func(name string) string {
+		return `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  serviceAccountName: ` + name + `
+`
+	}
+	if diags := v.Validate("file:///tmp/pod.yaml", pod("$(APP)-runner")); len(diags) != 0 {
+		t.Fatalf("Expected the interpolated name to resolve, got %v", diags)
+	}
+	if diags := v.Validate("file:///tmp/pod.yaml", pod("${UNKNOWN}-runner")); len(diags) != 0 {
+		t.Fatalf("Expected unresolved interpolation to be skipped, got %v", diags)
+	}
+	diags := v.Validate("file:///tmp/pod.yaml", pod("$(APP)-builder"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "web-builder") {
+		t.Fatalf("Expected a missing ServiceAccount diagnostic for web-builder, got %v", diags)
+	}
+}
@@ -0,0 +1,39 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestValidateReportsTooComplexDocument(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: Pod\nmetadata:\n  name: deep\nspec:\n")
+	for d := 1; d <= 50; d++ {
+		b.WriteString(strings.Repeat(" ", d) + "a:\n")
+	}
+
+	v := &Validator{
+		store:    indexer.NewStore(),
+		settings: config.Settings{DocumentLimits: config.DocumentLimits{MaxDepth: 20}},
+	}
+	diags := v.Validate("file:///tmp/deep.yaml", b.String())
+	if len(diags) != 1 || diags[0].Message != "document too complex for analysis" {
+		t.Fatalf("Expected a single too-complex diagnostic, got %v", diags)
+	}
+}
+
+func TestFindResourceNodeUsesConfiguredLimits(t *testing.T) {
+	v := newAutoscalingValidator(t, map[string]string{"deploy.yaml": deploymentFixture("  replicas: 3\n")})
+	target := v.store.Get("Deployment", "default", "web")
+	if target == nil || v.findResourceNode(target) == nil {
+		t.Fatalf("Expected the Deployment to be found, got %v", target)
+	}
+
+	v.settings.DocumentLimits.MaxDepth = 2
+	if root := v.findResourceNode(target); root != nil {
+		t.Errorf("Expected a document over the configured limits to be skipped, got %v", root)
+	}
+}
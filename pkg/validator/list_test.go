@@ -0,0 +1,35 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestValidateListItems(t *testing.T) {
+	v := &Validator{store: indexer.NewStore()}
+
+	diags := v.Validate("file:///tmp/list.yaml", `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: first
+    data:
+      PORT: "8080"
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: second
+    data:
+      LOG_LEVEL: info
+      LOG_LEVEL: debug
+`)
+	if len(diags) != 1 {
+		t.Fatalf("Expected one duplicate key diagnostic, got %v", diags)
+	}
+	if d := diags[0]; d.Range.Start.Line != 15 || d.Range.Start.Character != 6 {
+		t.Errorf("Expected the second LOG_LEVEL of the second item at 15:6, got %v", d.Range)
+	}
+}
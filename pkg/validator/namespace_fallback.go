@@ -0,0 +1,34 @@
+package validator
+
+import (
+	"fmt"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeNamespaceFallback marks a reference that only resolves in the
+// "default" namespace, not the namespace of the referencing resource.
+const CodeNamespaceFallback = "namespace-fallback"
+
+// namespaceFallback reports whether kind/name is missing from namespace but
+// found by the fallback to "default". Cluster-scoped kinds live there anyway.
+func (v *Validator) namespaceFallback(kind, namespace, name string) bool {
+	if !v.settings.NamespaceFallback || indexer.IsClusterScoped(kind) {
+		return false
+	}
+	res, resolved := v.store.Lookup(kind, namespace, name)
+	return res != nil && resolved != namespace
+}
+
+// namespaceFallbackDiagnostic warns about node referencing kind/name from
+// namespace when the target only exists in "default". It carries the same
+// data as a missing reference so the create quick fix still applies.
+func namespaceFallbackDiagnostic(node *yaml.Node, kind, name, namespace string) protocol.Diagnostic {
+	diag := newDiagnostic(node, scalarLength(node), protocol.DiagnosticSeverityWarning,
+		fmt.Sprintf("%s %s is not in namespace %s; it only resolves via the default namespace fallback", kind, name, namespace))
+	diag.Data = MissingReferenceData{Kind: kind, Name: name, Namespace: namespace}
+	return withCode(diag, CodeNamespaceFallback)
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestNamespaceFallbackDiagnostic(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shop-config
+  namespace: shop
+`)
+
+	v := &Validator{
+		store: store,
+		rules: []Rule{{
+			Kind: "Pod",
+			Checks: []Check{{
+				Type:       "reference",
+				Path:       "spec.volumes.configMap.name",
+				TargetKind: "ConfigMap",
+				Message:    "ConfigMap not found",
+			}},
+		}},
+	}
+	pod := func(name string) string {
+		return `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+spec:
+  volumes:
+    - name: config
+      configMap:
+        name: ` + name + `
+`
+	}
+
+	// Off by default: a fallback-only target is reported as missing.
+	diags := v.Validate("file:///tmp/pod.yaml", pod("app-config"))
+	if len(diags) != 1 || diags[0].Code != nil {
+		t.Fatalf("Expected the plain missing reference, got %v", diags)
+	}
+
+	v.settings.NamespaceFallback = true
+	diags = v.Validate("file:///tmp/pod.yaml", pod("app-config"))
+	if len(diags) != 1 || diags[0].Code == nil || diags[0].Code.Value != CodeNamespaceFallback || diags[0].Range.Start.Line != 9 {
+		t.Fatalf("Expected a namespace fallback diagnostic, got %v", diags)
+	}
+	if data, ok := diags[0].Data.(MissingReferenceData); !ok || data.Namespace != "shop" {
+		t.Errorf("Expected missing reference data for namespace shop, got %v", diags[0].Data)
+	}
+
+	// A target in the Pod's own namespace resolves without fallback.
+	if diags := v.Validate("file:///tmp/pod.yaml", pod("shop-config")); len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diags)
+	}
+	// A target missing everywhere is still a plain missing reference.
+	diags = v.Validate("file:///tmp/pod.yaml", pod("other-config"))
+	if len(diags) != 1 || diags[0].Code != nil {
+		t.Errorf("Expected the plain missing reference, got %v", diags)
+	}
+}
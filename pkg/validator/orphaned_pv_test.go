@@ -0,0 +1,75 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestOrphanedPersistentVolume(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.Settings{OrphanedPersistentVolumes: true},
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"PersistentVolume", "PersistentVolumeClaim"}, Path: "metadata.name"},
+				},
+			},
+		},
+		References: []config.Reference{
+			{
+				Name:       "pvc.volumeName.pv",
+				Symbol:     "k8s.resource.name",
+				TargetKind: "PersistentVolume",
+				Match: config.ReferenceMatch{
+					Kinds: []string{"PersistentVolumeClaim"},
+					Path:  "spec.volumeName",
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/pvc.yaml", `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+  volumeName: pv-bound
+`)
+	v := &Validator{store: store, settings: cfg.Settings}
+
+	pv := func(name, extra string) string {
+		return "apiVersion: v1\nkind: PersistentVolume\nmetadata:\n  name: " + name + "\nspec:\n  capacity:\n    storage: 1Gi\n" + extra
+	}
+
+	if diags := v.Validate("file:///tmp/bound.yaml", pv("pv-bound", "")); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics for bound PV, got %v", diags)
+	}
+
+	diags := v.Validate("file:///tmp/orphan.yaml", pv("pv-orphan", ""))
+	if len(diags) != 1 {
+		t.Fatalf("Expected one diagnostic for orphaned PV, got %v", diags)
+	}
+	if *diags[0].Severity != protocol.DiagnosticSeverityInformation || !strings.Contains(diags[0].Message, "pv-orphan") {
+		t.Errorf("Unexpected diagnostic: %+v", diags[0])
+	}
+	if diags[0].Range.Start.Line != 3 || diags[0].Range.Start.Character != 8 {
+		t.Errorf("Expected diagnostic at the PV name, got %v", diags[0].Range)
+	}
+
+	claimed := pv("pv-claimed", "  claimRef:\n    namespace: default\n    name: dynamic\n")
+	if diags := v.Validate("file:///tmp/claimed.yaml", claimed); len(diags) != 0 {
+		t.Fatalf("Expected PV with claimRef to be skipped, got %v", diags)
+	}
+
+	v.settings.OrphanedPersistentVolumes = false
+	if diags := v.Validate("file:///tmp/orphan.yaml", pv("pv-orphan", "")); len(diags) != 0 {
+		t.Fatalf("Expected check to be opt-in, got %v", diags)
+	}
+}
@@ -0,0 +1,221 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/resolver"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// MissingReferenceData is attached to the diagnostics of unresolved
+// references so code actions can fix them without parsing the message.
+type MissingReferenceData struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// defaultAPIVersions is used for new manifests of kinds not yet in the store.
+var defaultAPIVersions = map[string]string{
+	"Deployment":              "apps/v1",
+	"StatefulSet":             "apps/v1",
+	"DaemonSet":               "apps/v1",
+	"Job":                     "batch/v1",
+	"CronJob":                 "batch/v1",
+	"Ingress":                 "networking.k8s.io/v1",
+	"NetworkPolicy":           "networking.k8s.io/v1",
+	"Role":                    "rbac.authorization.k8s.io/v1",
+	"ClusterRole":             "rbac.authorization.k8s.io/v1",
+	"StorageClass":            "storage.k8s.io/v1",
+	"PodDisruptionBudget":     "policy/v1",
+	"Certificate":             "cert-manager.io/v1",
+	"HorizontalPodAutoscaler": "autoscaling/v2",
+}
+
+// QuickFixes returns code actions for the unresolved-reference diagnostics
+// in diags: creating the missing resource in a new file next to uri, and
+// changing the name to the closest existing resource of the same kind. The
+// change is checked against the text of uri that source provides, and
+// carries its version.
+func (v *Validator) QuickFixes(uri string, diags []protocol.Diagnostic, source resolver.DocumentSource) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+	for _, diag := range diags {
+		data, ok := missingReferenceData(diag)
+		if !ok {
+			continue
+		}
+		if action, ok := v.createResourceAction(uri, diag, data); ok {
+			actions = append(actions, action)
+		}
+		if action, ok := v.closestNameAction(uri, diag, data, source); ok {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// missingReferenceData decodes the data of a diagnostic sent back by the
+// client, where it arrives as generic JSON.
+func missingReferenceData(diag protocol.Diagnostic) (MissingReferenceData, bool) {
+	var data MissingReferenceData
+	if diag.Data == nil {
+		return data, false
+	}
+	raw, err := json.Marshal(diag.Data)
+	if err != nil {
+		return data, false
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, false
+	}
+	return data, data.Kind != "" && data.Name != ""
+}
+
+func (v *Validator) createResourceAction(uri string, diag protocol.Diagnostic, data MissingReferenceData) (protocol.CodeAction, bool) {
+	source, ok := filePath(uri)
+	if !ok {
+		return protocol.CodeAction{}, false
+	}
+
+	path := newManifestPath(filepath.Dir(source), data.Kind, data.Name)
+	newURI := fileURI(path)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "apiVersion: %s\n", v.apiVersionFor(data.Kind))
+	fmt.Fprintf(&b, "kind: %s\n", data.Kind)
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", data.Name)
+	if data.Namespace != "" && !indexer.IsClusterScoped(data.Kind) {
+		fmt.Fprintf(&b, "  namespace: %s\n", data.Namespace)
+	}
+
+	kind := protocol.CodeActionKindQuickFix
+	return protocol.CodeAction{
+		Title:       fmt.Sprintf("Create missing %s '%s'", data.Kind, data.Name),
+		Kind:        &kind,
+		Diagnostics: []protocol.Diagnostic{diag},
+		Edit: &protocol.WorkspaceEdit{
+			DocumentChanges: []any{
+				protocol.CreateFile{Kind: "create", URI: newURI},
+				protocol.TextDocumentEdit{
+					TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: newURI},
+					},
+					Edits: []any{protocol.TextEdit{NewText: b.String()}},
+				},
+			},
+		},
+	}, true
+}
+
+// newManifestPath picks an unused "<kind>-<name>.yaml" file name in dir.
+func newManifestPath(dir, kind, name string) string {
+	base := strings.ToLower(kind) + "-" + name
+	path := filepath.Join(dir, base+".yaml")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.yaml", base, i))
+	}
+}
+
+// apiVersionFor prefers the apiVersion of an indexed resource of the same
+// kind, so CRD kinds get their group right.
+func (v *Validator) apiVersionFor(kind string) string {
+	for _, res := range v.store.ListByKind(kind) {
+		if res.ApiVersion != "" {
+			return res.ApiVersion
+		}
+	}
+	if apiVersion, ok := defaultAPIVersions[kind]; ok {
+		return apiVersion
+	}
+	return "v1"
+}
+
+func (v *Validator) closestNameAction(uri string, diag protocol.Diagnostic, data MissingReferenceData, source resolver.DocumentSource) (protocol.CodeAction, bool) {
+	best, bestDistance := "", -1
+	for _, res := range v.store.ListByKind(data.Kind) {
+		if !indexer.IsClusterScoped(data.Kind) && v.settings.CanonicalNamespace(res.Namespace) != v.settings.CanonicalNamespace(data.Namespace) {
+			continue
+		}
+		d := levenshtein(data.Name, res.Name)
+		if bestDistance < 0 || d < bestDistance || (d == bestDistance && res.Name < best) {
+			best, bestDistance = res.Name, d
+		}
+	}
+	// Only suggest names that look like a typo of the reference.
+	if bestDistance <= 0 || bestDistance > max(2, len(data.Name)/3) {
+		return protocol.CodeAction{}, false
+	}
+
+	content, _, ok := source(uri)
+	if !ok {
+		return protocol.CodeAction{}, false
+	}
+	// The diagnostic covers quotes too; keep them.
+	oldText, newText := data.Name, best
+	if quote := quoteAt(content, diag.Range.Start); quote != 0 {
+		oldText = string(quote) + data.Name + string(quote)
+		newText = string(quote) + best + string(quote)
+	}
+	// A document edited since the diagnostic gets no edit rather than a
+	// misplaced one.
+	edit := resolver.BuildVerifiedEdit([]resolver.PendingEdit{{URI: uri, Range: diag.Range, OldText: oldText, NewText: newText}}, source)
+	if len(edit.DocumentChanges) == 0 {
+		return protocol.CodeAction{}, false
+	}
+
+	kind := protocol.CodeActionKindQuickFix
+	preferred := true
+	return protocol.CodeAction{
+		Title:       fmt.Sprintf("Change to '%s'", best),
+		Kind:        &kind,
+		Diagnostics: []protocol.Diagnostic{diag},
+		IsPreferred: &preferred,
+		Edit:        &edit.WorkspaceEdit,
+	}, true
+}
+
+// quoteAt returns the quote character at pos, or 0.
+func quoteAt(content string, pos protocol.Position) byte {
+	lines := strings.Split(content, "\n")
+	if int(pos.Line) >= len(lines) {
+		return 0
+	}
+	line := lines[pos.Line]
+	if int(pos.Character) >= len(line) {
+		return 0
+	}
+	if c := line[pos.Character]; c == '"' || c == '\'' {
+		return c
+	}
+	return 0
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,135 @@
+package validator
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestQuickFixesForMissingReference(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/cm.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: prod
+`)
+
+	v := &Validator{
+		store: store,
+		rules: []Rule{{
+			Kind: "Pod",
+			Checks: []Check{{
+				Type:       "reference",
+				Path:       "spec.volumes.configMap.name",
+				TargetKind: "ConfigMap",
+				Message:    "ConfigMap not found",
+			}},
+		}},
+	}
+
+	dir := t.TempDir()
+	uri := "file://" + filepath.Join(dir, "pod.yaml")
+	content := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: prod
+spec:
+  volumes:
+    - name: cfg
+      configMap:
+        name: "app-confg"
+`
+	diags := v.Validate(uri, content)
+	if len(diags) != 1 {
+		t.Fatalf("Expected one diagnostic, got %v", diags)
+	}
+
+	// Diagnostics come back from the client as JSON.
+	raw, err := json.Marshal(diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []protocol.Diagnostic
+	if err := json.Unmarshal(raw, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	version := protocol.Integer(3)
+	source := func(string) (string, *protocol.Integer, bool) { return content, &version, true }
+	actions := v.QuickFixes(uri, received, source)
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(actions))
+	}
+
+	create := actions[0]
+	if create.Title != "Create missing ConfigMap 'app-confg'" {
+		t.Errorf("Unexpected title %q", create.Title)
+	}
+	newURI := "file://" + filepath.Join(dir, "configmap-app-confg.yaml")
+	if cf, ok := create.Edit.DocumentChanges[0].(protocol.CreateFile); !ok || cf.URI != newURI {
+		t.Errorf("Expected create of %s, got %v", newURI, create.Edit.DocumentChanges[0])
+	}
+	textEdit := create.Edit.DocumentChanges[1].(protocol.TextDocumentEdit).Edits[0].(protocol.TextEdit)
+	if !strings.Contains(textEdit.NewText, "kind: ConfigMap\n") || !strings.Contains(textEdit.NewText, "  namespace: prod\n") {
+		t.Errorf("Unexpected skeleton:\n%s", textEdit.NewText)
+	}
+
+	change := actions[1]
+	if change.Title != "Change to 'app-config'" {
+		t.Errorf("Unexpected title %q", change.Title)
+	}
+	if len(change.Edit.DocumentChanges) != 1 {
+		t.Fatalf("Expected one document edit, got %v", change.Edit.DocumentChanges)
+	}
+	docEdit := change.Edit.DocumentChanges[0].(protocol.TextDocumentEdit)
+	if docEdit.TextDocument.URI != uri || docEdit.TextDocument.Version == nil || *docEdit.TextDocument.Version != version {
+		t.Errorf("Expected an edit of version %d of %s, got %+v", version, uri, docEdit.TextDocument)
+	}
+	edits := docEdit.Edits
+	if len(edits) != 1 || edits[0].(protocol.TextEdit).NewText != `"app-config"` || edits[0].(protocol.TextEdit).Range != diags[0].Range {
+		t.Errorf("Expected the quoted name to be replaced, got %v", edits)
+	}
+
+	// The name was changed since the diagnostic was published.
+	content = strings.Replace(content, "app-confg", "app-conf", 1)
+	for _, action := range v.QuickFixes(uri, received, source) {
+		if strings.HasPrefix(action.Title, "Change to") {
+			t.Errorf("Expected no change of an edited name, got %+v", action.Edit)
+		}
+	}
+}
+
+func TestQuickFixesSkipUnrelatedNames(t *testing.T) {
+	store := indexer.NewStore()
+	store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "database", Namespace: "default"})
+	v := &Validator{store: store}
+
+	diag := protocol.Diagnostic{
+		Message: "ConfigMap not found",
+		Data:    MissingReferenceData{Kind: "ConfigMap", Name: "frontend", Namespace: "default"},
+	}
+	source := func(string) (string, *protocol.Integer, bool) { return "", nil, true }
+	for _, action := range v.QuickFixes("file:///tmp/pod.yaml", []protocol.Diagnostic{diag}, source) {
+		if strings.HasPrefix(action.Title, "Change to") {
+			t.Errorf("Expected no rename suggestion, got %q", action.Title)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeRequiredField marks a resource missing a field a required check asks
+// for.
+const CodeRequiredField = "required-field"
+
+// checkRequired reports check.Path missing, null or empty. Through a list
+// every item must have the rest of the path, so each container lacking an
+// image gets its own diagnostic. A diagnostic goes on the key of the
+// deepest part of the path that is present, on the first key of a list
+// item lacking the rest, or on the kind when not even the first part is.
+func checkRequired(root *yaml.Node, check Check) []protocol.Diagnostic {
+	var kind *yaml.Node
+	if kinds := findNodes(root, "kind"); len(kinds) > 0 {
+		kind = kinds[0]
+	}
+	message := check.Message
+	if message == "" {
+		message = fmt.Sprintf("%s is required", check.Path)
+	}
+	var diagnostics []protocol.Diagnostic
+	for _, anchor := range missingRequired(root, strings.Split(check.Path, "."), kind) {
+		diag := newDiagnostic(anchor, scalarLength(anchor), protocol.DiagnosticSeverityWarning, message)
+		diagnostics = append(diagnostics, withCode(diag, CodeRequiredField))
+	}
+	return diagnostics
+}
+
+// missingRequired returns where parts are missing under node, reached
+// through anchor: one anchor per list item lacking them.
+func missingRequired(node *yaml.Node, parts []string, anchor *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if len(parts) == 0 {
+		if isEmptyNode(node) && anchor != nil {
+			return []*yaml.Node{anchor}
+		}
+		return nil
+	}
+	if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+		var missing []*yaml.Node
+		for _, item := range node.Content {
+			itemAnchor := anchor
+			if item.Kind == yaml.MappingNode && len(item.Content) > 0 {
+				itemAnchor = item.Content[0]
+			}
+			missing = append(missing, missingRequired(item, parts, itemAnchor)...)
+		}
+		return missing
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == parts[0] {
+				return missingRequired(node.Content[i+1], parts[1:], node.Content[i])
+			}
+		}
+	}
+	if anchor == nil {
+		return nil
+	}
+	return []*yaml.Node{anchor}
+}
+
+// isEmptyNode reports whether node holds no value: null, "", {} or [].
+func isEmptyNode(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value == "" || node.Tag == "!!null"
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.AliasNode:
+		return node.Alias == nil || isEmptyNode(node.Alias)
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestRequiredCheck(t *testing.T) {
+	v := &Validator{
+		rules: []Rule{{Kind: "Service", Checks: []Check{{Type: "required", Path: "spec.selector"}}}},
+		store: indexer.NewStore(),
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		// line and col locate the diagnostic, when there is one.
+		line, col uint32
+	}{
+		{"present", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  selector:\n    app: web\n", 0, 0},
+		{"missing field", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  ports:\n    - port: 80\n", 4, 0},
+		{"missing parent", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n", 1, 6},
+		{"empty mapping", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  selector: {}\n", 5, 2},
+		{"null", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  selector:\n", 5, 2},
+	}
+	for _, tt := range tests {
+		diags := v.Validate("file:///tmp/svc.yaml", tt.content)
+		if tt.name == "present" {
+			if len(diags) != 0 {
+				t.Errorf("%s: expected no diagnostics, got %v", tt.name, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 {
+			t.Fatalf("%s: expected one diagnostic, got %v", tt.name, diags)
+		}
+		diag := diags[0]
+		if diag.Message != "spec.selector is required" || diag.Code == nil || diag.Code.Value != CodeRequiredField {
+			t.Errorf("%s: unexpected diagnostic %+v", tt.name, diag)
+		}
+		if start := diag.Range.Start; start.Line != tt.line || start.Character != tt.col {
+			t.Errorf("%s: expected the diagnostic at %d:%d, got %+v", tt.name, tt.line, tt.col, start)
+		}
+	}
+}
+
+func TestRequiredCheckThroughLists(t *testing.T) {
+	v := &Validator{
+		rules: []Rule{{Kind: "Pod", Checks: []Check{{Type: "required", Path: "spec.containers.image", Message: "Containers need an image"}}}},
+		store: indexer.NewStore(),
+	}
+	pod := func(image string) string {
+		return "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  containers:\n    - name: app\n" + image
+	}
+	if diags := v.Validate("file:///tmp/pod.yaml", pod("      image: nginx\n")); len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diags)
+	}
+	diags := v.Validate("file:///tmp/pod.yaml", pod(""))
+	if len(diags) != 1 || diags[0].Message != "Containers need an image" || diags[0].Range.Start.Line != 6 || diags[0].Range.Start.Character != 6 {
+		t.Errorf("Expected the custom message on the container, got %v", diags)
+	}
+
+	// Every container needs one, not just the first.
+	diags = v.Validate("file:///tmp/pod.yaml", pod("      image: nginx\n    - name: sidecar\n    - name: proxy\n      image: envoy\n"))
+	if len(diags) != 1 || diags[0].Range.Start.Line != 8 {
+		t.Errorf("Expected the sidecar without an image to be reported, got %v", diags)
+	}
+
+	diags = v.Validate("file:///tmp/pod.yaml", "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  containers: []\n")
+	if len(diags) != 1 || diags[0].Range.Start.Line != 5 {
+		t.Errorf("Expected an empty list to be reported on its key, got %v", diags)
+	}
+}
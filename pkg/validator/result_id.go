@@ -0,0 +1,37 @@
+package validator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// sortDiagnostics orders diagnostics by position, then message. Checks
+// walk the Store in no particular order, so without it the same document
+// and index could validate to the same diagnostics in a different order.
+func sortDiagnostics(diagnostics []protocol.Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Character != b.Character {
+			return a.Character < b.Character
+		}
+		return diagnostics[i].Message < diagnostics[j].Message
+	})
+}
+
+// ResultID hashes diagnostics, as returned by Validate, into the resultId
+// of a pull diagnostics report: equal diagnostics give equal ids.
+func ResultID(diagnostics []protocol.Diagnostic) string {
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	data, _ := json.Marshal(diagnostics)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,32 @@
+package validator
+
+import (
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestResultIDIgnoresCheckOrder(t *testing.T) {
+	at := func(line uint32, message string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:   protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line, Character: 4}},
+			Message: message,
+		}
+	}
+	a := []protocol.Diagnostic{at(3, "b"), at(1, "a"), at(3, "a")}
+	b := []protocol.Diagnostic{at(3, "a"), at(3, "b"), at(1, "a")}
+	sortDiagnostics(a)
+	sortDiagnostics(b)
+	if ResultID(a) != ResultID(b) {
+		t.Errorf("Expected equal ids for the same diagnostics, got %s and %s", ResultID(a), ResultID(b))
+	}
+	if a[0].Range.Start.Line != 1 || a[1].Message != "a" || a[2].Message != "b" {
+		t.Errorf("Expected diagnostics ordered by position and message, got %+v", a)
+	}
+	if ResultID(a) == ResultID(a[:2]) {
+		t.Error("Expected different diagnostics to get different ids")
+	}
+	if ResultID(nil) != ResultID([]protocol.Diagnostic{}) {
+		t.Error("Expected no diagnostics to hash the same however they are passed")
+	}
+}
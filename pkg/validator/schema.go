@@ -0,0 +1,180 @@
+package validator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeSchemaType marks a custom resource value whose type its CRD's schema
+// doesn't allow.
+const CodeSchemaType = "schema-type"
+
+// checkCRDSchema checks a custom resource against the openAPIV3Schema of
+// its indexed CRD: required fields must be present, values must have the
+// declared type, and enum fields one of the listed values. apiVersion, kind
+// and metadata are left to the API server.
+func (v *Validator) checkCRDSchema(root *yaml.Node, kind string) []protocol.Diagnostic {
+	apiVersion := firstNode(root, "apiVersion")
+	if apiVersion == nil || kind == "" {
+		return nil
+	}
+	schema := v.store.Schema(apiVersion.Value, kind)
+	if schema == nil {
+		return nil
+	}
+	var diagnostics []protocol.Diagnostic
+	anchor := firstNode(root, "kind")
+	checkSchemaNode(root, schema, nil, anchor, &diagnostics)
+	return diagnostics
+}
+
+// checkSchemaNode checks node against schema. path leads to node, and
+// anchor is where a missing field of node is reported: its key, or the
+// kind for the document itself.
+func checkSchemaNode(node *yaml.Node, schema *indexer.Schema, path []string, anchor *yaml.Node, diagnostics *[]protocol.Diagnostic) {
+	if node.Kind == yaml.AliasNode {
+		return
+	}
+	if isNullNode(node) {
+		return
+	}
+	field := strings.Join(path, ".")
+	if !schemaTypeMatches(node, schema) {
+		diag := newDiagnostic(node, valueLength(node), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("%s must be %s, not %s", field, schemaTypeName(schema), nodeTypeName(node)))
+		*diagnostics = append(*diagnostics, withCode(diag, CodeSchemaType))
+		return
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, node.Value) {
+			diag := newDiagnostic(node, scalarLength(node), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("%s must be one of %s (got %q)", field, strings.Join(schema.Enum, ", "), node.Value))
+			*diagnostics = append(*diagnostics, withCode(diag, CodeEnumValue))
+		}
+	case yaml.MappingNode:
+		for _, required := range schema.Required {
+			if len(path) == 0 && isTypeMetaField(required) {
+				continue
+			}
+			if value := mappingValue(node, required); value == nil || isNullNode(value) {
+				if anchor == nil {
+					continue
+				}
+				diag := newDiagnostic(anchor, scalarLength(anchor), protocol.DiagnosticSeverityWarning,
+					fmt.Sprintf("%s is required", strings.Join(append(slices.Clone(path), required), ".")))
+				*diagnostics = append(*diagnostics, withCode(diag, CodeRequiredField))
+			}
+		}
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			key := node.Content[j]
+			if len(path) == 0 && isTypeMetaField(key.Value) {
+				continue
+			}
+			if prop := schema.Property(key.Value); prop != nil {
+				checkSchemaNode(node.Content[j+1], prop, append(slices.Clone(path), key.Value), key, diagnostics)
+			}
+		}
+	case yaml.SequenceNode:
+		if schema.Items == nil {
+			return
+		}
+		for _, item := range node.Content {
+			checkSchemaNode(item, schema.Items, path, anchor, diagnostics)
+		}
+	}
+}
+
+// isTypeMetaField reports whether a top-level field is one the schema
+// check leaves alone.
+func isTypeMetaField(name string) bool {
+	return name == "apiVersion" || name == "kind" || name == "metadata"
+}
+
+// schemaTypeMatches reports whether node has a type schema allows. An
+// untyped schema allows anything.
+func schemaTypeMatches(node *yaml.Node, schema *indexer.Schema) bool {
+	if schema.IntOrString {
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || isStringTag(node.Tag))
+	}
+	switch schema.Type {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		return node.Kind == yaml.ScalarNode && isStringTag(node.Tag)
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!int"
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float")
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	}
+	return true
+}
+
+// isStringTag reports whether a scalar with tag is a string in JSON, which
+// is what the API server sees: timestamps and binary values are sent as
+// strings.
+func isStringTag(tag string) bool {
+	return tag == "!!str" || tag == "!!timestamp" || tag == "!!binary"
+}
+
+func schemaTypeName(schema *indexer.Schema) string {
+	if schema.IntOrString {
+		return "an integer or string"
+	}
+	switch schema.Type {
+	case "object", "array", "integer":
+		return "an " + schema.Type
+	}
+	return "a " + schema.Type
+}
+
+func nodeTypeName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "an array"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "an integer"
+	case "!!float":
+		return "a number"
+	case "!!bool":
+		return "a boolean"
+	}
+	return "a string"
+}
+
+// valueLength is the width of the first line of a value, which is what a
+// diagnostic on a collection highlights.
+func valueLength(node *yaml.Node) int {
+	if node.Kind == yaml.ScalarNode {
+		return scalarLength(node)
+	}
+	return 1
+}
+
+func isNullNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		if node.Content[j].Value == key {
+			return node.Content[j+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+const databaseCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: databases.example.com
+spec:
+  group: example.com
+  names:
+    kind: Database
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          required: [spec]
+          properties:
+            spec:
+              type: object
+              required: [size]
+              properties:
+                size:
+                  type: integer
+                engine:
+                  type: string
+                  enum: [postgres, mysql]
+                port:
+                  x-kubernetes-int-or-string: true
+                restoreFrom:
+                  type: string
+                  format: date-time
+                caBundle:
+                  type: string
+                  format: byte
+`
+
+func TestCRDSchemaValidation(t *testing.T) {
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, &config.Config{}).IndexContent("/repo/crd.yaml", databaseCRD)
+	v, err := NewValidator("../../rules/validation.yaml", store, nil)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	valid := `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: orders
+spec:
+  size: 3
+  engine: postgres
+  port: http
+  restoreFrom: 2024-05-01T12:00:00Z
+  caBundle: !!binary Zm9v
+`
+	if diags := v.Validate("file:///repo/db.yaml", valid); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diags)
+	}
+	// Another version of the kind has no schema to check against.
+	if diags := v.Validate("file:///repo/db.yaml", "apiVersion: example.com/v2\nkind: Database\nmetadata:\n  name: orders\n"); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics without a schema, got %v", diags)
+	}
+
+	diags := v.Validate("file:///repo/db.yaml", `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: orders
+spec:
+  engine: oracle
+  port: [80]
+`)
+	if len(diags) != 3 {
+		t.Fatalf("Expected missing size, engine and port diagnostics, got %v", diags)
+	}
+	missing, engine, port := diags[0], diags[1], diags[2]
+	if missing.Code == nil || missing.Code.Value != CodeRequiredField || missing.Message != "spec.size is required" {
+		t.Errorf("Expected spec.size to be required, got %v", missing)
+	}
+	// A missing field is reported on its parent's key.
+	if r := missing.Range; r.Start.Line != 4 || r.Start.Character != 0 || r.End.Character != 4 {
+		t.Errorf("Expected the spec key's range, got %v", r)
+	}
+	if engine.Code == nil || engine.Code.Value != CodeEnumValue || engine.Message != `spec.engine must be one of postgres, mysql (got "oracle")` {
+		t.Errorf("Expected an enum diagnostic, got %v", engine)
+	}
+	if port.Code == nil || port.Code.Value != CodeSchemaType || port.Message != "spec.port must be an integer or string, not an array" {
+		t.Errorf("Expected a type diagnostic, got %v", port)
+	}
+
+	diags = v.Validate("file:///repo/db.yaml", "apiVersion: example.com/v1\nkind: Database\nmetadata:\n  name: orders\nspec:\n  size: three\n")
+	if len(diags) != 1 || diags[0].Code == nil || diags[0].Code.Value != CodeSchemaType || diags[0].Message != "spec.size must be an integer, not a string" {
+		t.Fatalf("Expected size to be flagged as a string, got %v", diags)
+	}
+
+	diags = v.Validate("file:///repo/db.yaml", "apiVersion: example.com/v1\nkind: Database\nmetadata:\n  name: orders\n")
+	if len(diags) != 1 || diags[0].Message != "spec is required" || diags[0].Range.Start.Line != 1 {
+		t.Fatalf("Expected a missing spec on the kind, got %v", diags)
+	}
+}
@@ -0,0 +1,129 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// CodeServiceTargetPort marks a Service port whose targetPort none of the
+// selected containers expose.
+const CodeServiceTargetPort = "service-target-port"
+
+// targetKinds returns the kinds a check looks for: TargetKinds when set,
+// otherwise TargetKind alone.
+func (c Check) targetKinds() []string {
+	if len(c.TargetKinds) > 0 {
+		return c.TargetKinds
+	}
+	return []string{c.TargetKind}
+}
+
+// checkSelector checks a label selector, such as a Service's spec.selector,
+// against the resources of the check's target kinds in namespace. With
+// CheckTargetPort set, the Service's targetPorts must also be container
+// ports of a matched workload.
+func (v *Validator) checkSelector(root, selector *yaml.Node, check Check, namespace string) []protocol.Diagnostic {
+	if len(selector.Content) == 0 {
+		return nil
+	}
+
+	kinds := check.targetKinds()
+	var matched []*indexer.K8sResource
+	for _, kind := range kinds {
+		for _, res := range v.store.ListByKind(kind) {
+			if v.settings.CanonicalNamespace(res.Namespace) != v.settings.CanonicalNamespace(namespace) {
+				continue
+			}
+			if selectorMatches(selector, res.Labels) {
+				matched = append(matched, res)
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		detail := fmt.Sprintf(" (Kind: %s)", kinds[0])
+		if len(kinds) > 1 {
+			detail = fmt.Sprintf(" (Kinds: %s)", strings.Join(kinds, ", "))
+		}
+		diag := newDiagnostic(selector.Content[0], 0, protocol.DiagnosticSeverityWarning, check.Message+detail)
+		diag.Range = selectorRange(selector)
+		return []protocol.Diagnostic{diag}
+	}
+	if !check.CheckTargetPort {
+		return nil
+	}
+	return v.checkTargetPorts(root, matched)
+}
+
+// checkTargetPorts warns on each spec.ports[] entry whose targetPort (the
+// port, when omitted) no container of the matched workloads exposes, by
+// number or, for a named targetPort, by name. Workloads whose file can't be
+// read leave the ports unchecked.
+func (v *Validator) checkTargetPorts(root *yaml.Node, matched []*indexer.K8sResource) []protocol.Diagnostic {
+	numbers := make(map[string]bool)
+	names := make(map[string]bool)
+	var workloads []string
+	var related []protocol.DiagnosticRelatedInformation
+	for _, res := range matched {
+		resRoot := v.findResourceNode(res)
+		if resRoot == nil {
+			return nil
+		}
+		specPath := "spec.template.spec"
+		if res.Kind == "Pod" {
+			specPath = "spec"
+		}
+		for _, port := range findNodes(resRoot, specPath+".containers.ports.*") {
+			if n := firstNode(port, "containerPort"); n != nil {
+				numbers[n.Value] = true
+			}
+			if n := firstNode(port, "name"); n != nil {
+				names[n.Value] = true
+			}
+		}
+		workloads = append(workloads, res.Kind+" "+res.Name)
+		related = append(related, protocol.DiagnosticRelatedInformation{
+			Location: protocol.Location{URI: fileURI(res.FilePath), Range: resourceNameRange(res)},
+			Message:  "selected by this Service",
+		})
+	}
+
+	var diagnostics []protocol.Diagnostic
+	for _, port := range findNodes(root, "spec.ports.*") {
+		node := firstNode(port, "targetPort")
+		if node == nil {
+			node = firstNode(port, "port")
+		}
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" {
+			continue
+		}
+		exposed := names[node.Value]
+		if isPortNumber(node.Value) {
+			exposed = numbers[node.Value]
+		}
+		if exposed {
+			continue
+		}
+		diag := newDiagnostic(node, scalarLength(node), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("targetPort %s is not a container port of %s", node.Value, strings.Join(workloads, ", ")))
+		diag.RelatedInformation = related
+		diagnostics = append(diagnostics, withCode(diag, CodeServiceTargetPort))
+	}
+	return diagnostics
+}
+
+// isPortNumber reports whether value is written as a number rather than a
+// port name.
+func isPortNumber(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
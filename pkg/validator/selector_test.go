@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestServiceSelectorWorkloads(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"StatefulSet", "Pod"}, Path: "metadata.name"},
+				},
+			},
+			{
+				Name: "k8s.label",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Pod"}, Path: "metadata.labels"},
+					{Kinds: []string{"StatefulSet"}, Path: "spec.template.metadata.labels"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexFile(write("db.yaml", `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    metadata:
+      labels:
+        app: db
+    spec:
+      containers:
+        - name: postgres
+          ports:
+            - containerPort: 5432
+              name: sql
+`))
+	idx.IndexFile(write("debug.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+  namespace: tools
+  labels:
+    app: debug
+`))
+
+	v, err := NewValidator("../../rules/validation.yaml", store, cfg)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	service := func(selector, ports string) string {
+		return `apiVersion: v1
+kind: Service
+metadata:
+  name: db
+spec:
+  selector: {app: ` + selector + `}
+  ports:
+` + ports
+	}
+
+	// A StatefulSet matches as well as a Deployment would, by number or name.
+	for _, ports := range []string{
+		"    - port: 5432\n",
+		"    - port: 80\n      targetPort: sql\n",
+	} {
+		if diags := v.Validate("file:///svc.yaml", service("db", ports)); len(diags) != 0 {
+			t.Errorf("Expected no diagnostics for ports %q, got %v", ports, diags)
+		}
+	}
+
+	diags := v.Validate("file:///svc.yaml", service("db", "    - port: 80\n      targetPort: 8080\n    - port: 81\n      targetPort: http\n"))
+	if len(diags) != 2 {
+		t.Fatalf("Expected both unexposed targetPorts to be reported, got %v", diags)
+	}
+	for i, want := range []string{"8080", "http"} {
+		diag := diags[i]
+		if diag.Code == nil || diag.Code.Value != CodeServiceTargetPort || !strings.Contains(diag.Message, "targetPort "+want+" is not a container port of StatefulSet db") {
+			t.Errorf("Expected a targetPort diagnostic for %s, got %v", want, diag)
+		}
+		// "      targetPort: 8080" on lines 8 and 10.
+		if start := diag.Range.Start; start.Line != uint32(8+2*i) || start.Character != 18 || diag.Range.End.Character != uint32(18+len(want)) {
+			t.Errorf("Expected the range of targetPort %s, got %v", want, diag.Range)
+		}
+		if len(diag.RelatedInformation) != 1 || !strings.HasSuffix(diag.RelatedInformation[0].Location.URI, "/db.yaml") {
+			t.Errorf("Expected the StatefulSet as related information, got %v", diag.RelatedInformation)
+		}
+	}
+
+	// A Pod in another namespace doesn't count.
+	diags = v.Validate("file:///svc.yaml", service("debug", "    - port: 80\n"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "No workload found matching this selector (Kinds: Deployment, StatefulSet, DaemonSet, Pod)") {
+		t.Fatalf("Expected a non-matching selector diagnostic, got %v", diags)
+	}
+	// "  selector: {app: debug}"
+	if r := diags[0].Range; r.Start.Line != 5 || r.Start.Character != 13 || r.End.Character != 23 {
+		t.Errorf("Expected the selector's range, got %v", r)
+	}
+}
@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic codes of the "service-structure" check.
+const (
+	CodeExternalNameSelector = "service-externalname-selector"
+	CodeExternalNameInvalid  = "service-externalname-invalid"
+	CodeHeadlessNoPods       = "service-headless-no-pods"
+	CodeEndpointsConflict    = "service-endpoints-conflict"
+)
+
+// dnsLabel is an RFC 1123 label, as required of each part of externalName.
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// podBearingKinds are the kinds whose labels a Service selector can match.
+var podBearingKinds = []string{"Pod", "Deployment", "StatefulSet", "DaemonSet", "Job", "ReplicaSet"}
+
+// checkServiceStructure checks that a Service's type, selector and
+// externalName fit together:
+//   - ExternalName Services must not have a selector
+//   - externalName must be a DNS name
+//   - a headless Service's selector should match some pods
+//   - a Service with a selector shouldn't share its name with manually
+//     defined Endpoints or EndpointSlices, which the controller would fight
+func (v *Validator) checkServiceStructure(root *yaml.Node, namespace string) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+
+	typeNode := firstNode(root, "spec.type")
+	selector := firstNode(root, "spec.selector")
+	hasSelector := selector != nil && selector.Kind == yaml.MappingNode && len(selector.Content) > 0
+
+	if typeNode != nil && typeNode.Value == "ExternalName" {
+		if hasSelector {
+			diag := newDiagnostic(selector.Content[0], 0, protocol.DiagnosticSeverityError,
+				"ExternalName Services must not have a selector")
+			diag.Range = selectorRange(selector)
+			diagnostics = append(diagnostics, withCode(diag, CodeExternalNameSelector))
+		}
+		if nameNode := firstNode(root, "spec.externalName"); nameNode == nil {
+			diagnostics = append(diagnostics, withCode(newDiagnostic(typeNode, scalarLength(typeNode), protocol.DiagnosticSeverityWarning,
+				"ExternalName Service has no spec.externalName"), CodeExternalNameInvalid))
+		} else if !isDNSName(nameNode.Value) {
+			diagnostics = append(diagnostics, withCode(newDiagnostic(nameNode, scalarLength(nameNode), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("externalName %q is not a valid DNS name", nameNode.Value)), CodeExternalNameInvalid))
+		}
+		return diagnostics
+	}
+
+	if !hasSelector {
+		return diagnostics
+	}
+
+	if clusterIP := firstNode(root, "spec.clusterIP"); clusterIP != nil && clusterIP.Value == "None" && !v.selectsAnyPods(selector, namespace) {
+		diag := newDiagnostic(selector.Content[0], 0, protocol.DiagnosticSeverityWarning,
+			"Headless Service selector matches no pods in the workspace")
+		diag.Range = selectorRange(selector)
+		diagnostics = append(diagnostics, withCode(diag, CodeHeadlessNoPods))
+	}
+
+	nameNode := firstNode(root, "metadata.name")
+	if nameNode == nil || nameNode.Value == "" {
+		return diagnostics
+	}
+	for _, kind := range []string{"Endpoints", "EndpointSlice"} {
+		for _, res := range v.store.ListByKind(kind) {
+			if v.settings.CanonicalNamespace(res.Namespace) != v.settings.CanonicalNamespace(namespace) {
+				continue
+			}
+			if res.Name != nameNode.Value && !(kind == "EndpointSlice" && v.endpointSliceService(res) == nameNode.Value) {
+				continue
+			}
+			diag := newDiagnostic(nameNode, scalarLength(nameNode), protocol.DiagnosticSeverityInformation,
+				fmt.Sprintf("Service has a selector but %s %s is defined manually", kind, res.Name))
+			diag.RelatedInformation = []protocol.DiagnosticRelatedInformation{{
+				Location: protocol.Location{URI: fileURI(res.FilePath), Range: resourceNameRange(res)},
+				Message:  fmt.Sprintf("%s %s is defined here", kind, res.Name),
+			}}
+			diagnostics = append(diagnostics, withCode(diag, CodeEndpointsConflict))
+		}
+	}
+
+	return diagnostics
+}
+
+// selectsAnyPods reports whether the selector matches the labels of any
+// pod-bearing resource in namespace.
+func (v *Validator) selectsAnyPods(selector *yaml.Node, namespace string) bool {
+	for _, kind := range podBearingKinds {
+		for _, res := range v.store.ListByKind(kind) {
+			if v.settings.CanonicalNamespace(res.Namespace) != v.settings.CanonicalNamespace(namespace) {
+				continue
+			}
+			if selectorMatches(selector, res.Labels) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endpointSliceService returns the kubernetes.io/service-name label of an
+// indexed EndpointSlice.
+func (v *Validator) endpointSliceService(res *indexer.K8sResource) string {
+	root := v.findResourceNode(res)
+	if root == nil {
+		return ""
+	}
+	// The label key contains dots, so it can't be addressed with findNodes.
+	labels := firstNode(root, "metadata.labels")
+	if labels == nil || labels.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(labels.Content); i += 2 {
+		if labels.Content[i].Value == "kubernetes.io/service-name" {
+			return labels.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// isDNSName reports whether name is an RFC 1123 subdomain, optionally fully
+// qualified with a trailing dot. IP addresses are rejected.
+func isDNSName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 || net.ParseIP(name) != nil {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !dnsLabel.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func withCode(diag protocol.Diagnostic, code string) protocol.Diagnostic {
+	diag.Code = &protocol.IntegerOrString{Value: code}
+	return diag
+}
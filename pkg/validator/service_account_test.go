@@ -0,0 +1,68 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestServiceAccountReferenceRules(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ServiceAccount"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/sa.yaml", `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: runner
+  namespace: batch
+`)
+
+	v, err := NewValidator("../../rules/validation.yaml", store, cfg)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	cronJob := func(name string) string {
+		return `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: nightly
+  namespace: batch
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          serviceAccountName: ` + name + `
+`
+	}
+	if diags := v.Validate("file:///tmp/cronjob.yaml", cronJob("runner")); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diags)
+	}
+	diags := v.Validate("file:///tmp/cronjob.yaml", cronJob("missing"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "ServiceAccount not found") || diags[0].Range.Start.Line != 10 {
+		t.Fatalf("Expected a missing ServiceAccount diagnostic, got %v", diags)
+	}
+
+	// The deprecated field is checked too, in the Pod's own namespace.
+	pod := `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  serviceAccount: runner
+`
+	if diags := v.Validate("file:///tmp/pod.yaml", pod); len(diags) != 1 {
+		t.Fatalf("Expected the default namespace to lack the ServiceAccount, got %v", diags)
+	}
+}
@@ -0,0 +1,200 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestServiceStructure(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"StatefulSet", "Endpoints", "EndpointSlice"}, Path: "metadata.name"},
+				},
+			},
+			{
+				Name: "k8s.label",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"StatefulSet"}, Path: "spec.template.metadata.labels"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	idx := indexer.NewIndexer(store, cfg)
+	idx.IndexFile(write("db.yaml", `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    metadata:
+      labels:
+        app: db
+`))
+	endpointsPath := write("endpoints.yaml", `apiVersion: v1
+kind: Endpoints
+metadata:
+  name: legacy
+---
+apiVersion: discovery.k8s.io/v1
+kind: EndpointSlice
+metadata:
+  name: cache-abc12
+  labels:
+    kubernetes.io/service-name: cache
+`)
+	idx.IndexFile(endpointsPath)
+
+	v := &Validator{
+		store: store,
+		rules: []Rule{{Kind: "Service", Checks: []Check{{Type: "service-structure"}}}},
+	}
+
+	tests := []struct {
+		name     string
+		service  string
+		code     string
+		severity protocol.DiagnosticSeverity
+		line     uint32
+	}{
+		{
+			name: "ExternalName with selector",
+			service: `apiVersion: v1
+kind: Service
+metadata:
+  name: external
+spec:
+  type: ExternalName
+  externalName: db.example.com
+  selector:
+    app: db
+`,
+			code:     CodeExternalNameSelector,
+			severity: protocol.DiagnosticSeverityError,
+			line:     8,
+		},
+		{
+			name: "ExternalName that isn't a DNS name",
+			service: `apiVersion: v1
+kind: Service
+metadata:
+  name: external
+spec:
+  type: ExternalName
+  externalName: https://db.example.com
+`,
+			code:     CodeExternalNameInvalid,
+			severity: protocol.DiagnosticSeverityWarning,
+			line:     6,
+		},
+		{
+			name: "headless Service selecting nothing",
+			service: `apiVersion: v1
+kind: Service
+metadata:
+  name: db
+spec:
+  clusterIP: None
+  selector:
+    app: missing
+`,
+			code:     CodeHeadlessNoPods,
+			severity: protocol.DiagnosticSeverityWarning,
+			line:     7,
+		},
+		{
+			name: "selector Service with manual Endpoints",
+			service: `apiVersion: v1
+kind: Service
+metadata:
+  name: legacy
+spec:
+  selector:
+    app: db
+`,
+			code:     CodeEndpointsConflict,
+			severity: protocol.DiagnosticSeverityInformation,
+			line:     3,
+		},
+		{
+			name: "selector Service with a manual EndpointSlice",
+			service: `apiVersion: v1
+kind: Service
+metadata:
+  name: cache
+spec:
+  selector:
+    app: db
+`,
+			code:     CodeEndpointsConflict,
+			severity: protocol.DiagnosticSeverityInformation,
+			line:     3,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := v.Validate("file:///tmp/svc.yaml", tc.service)
+			if len(diags) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %v", diags)
+			}
+			d := diags[0]
+			if d.Code == nil || d.Code.Value != tc.code {
+				t.Errorf("Expected code %s, got %v", tc.code, d.Code)
+			}
+			if d.Severity == nil || *d.Severity != tc.severity {
+				t.Errorf("Expected severity %v, got %v", tc.severity, d.Severity)
+			}
+			if d.Range.Start.Line != tc.line {
+				t.Errorf("Expected line %d, got %d", tc.line, d.Range.Start.Line)
+			}
+			if tc.code == CodeEndpointsConflict {
+				if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.URI != "file://"+endpointsPath {
+					t.Errorf("Expected related location in %s, got %v", endpointsPath, d.RelatedInformation)
+				}
+			}
+		})
+	}
+
+	valid := []string{
+		`apiVersion: v1
+kind: Service
+metadata:
+  name: external
+spec:
+  type: ExternalName
+  externalName: db.example.com.
+`,
+		`apiVersion: v1
+kind: Service
+metadata:
+  name: db
+spec:
+  clusterIP: None
+  selector:
+    app: db
+`,
+	}
+	for _, svc := range valid {
+		if diags := v.Validate("file:///tmp/svc.yaml", svc); len(diags) != 0 {
+			t.Errorf("Expected no diagnostics, got %v", diags)
+		}
+	}
+}
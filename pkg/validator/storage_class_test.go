@@ -0,0 +1,58 @@
+package validator
+
+import (
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestStorageClassReferences(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"StorageClass"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexContent("/tmp/sc.yaml", `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast-ssd
+provisioner: ebs.csi.aws.com
+`)
+
+	v, err := NewValidator("../../rules/validation.yaml", store, cfg)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	pvc := func(class string) string {
+		return `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: app
+spec:
+  storageClassName: ` + class + `
+`
+	}
+
+	if diags := v.Validate("file:///tmp/pvc.yaml", pvc("fast-ssd")); len(diags) != 0 {
+		t.Errorf("Expected the StorageClass to resolve from namespace app, got %v", diags)
+	}
+	if diags := v.Validate("file:///tmp/pvc.yaml", pvc(`""`)); len(diags) != 0 {
+		t.Errorf("Expected an empty storageClassName to be accepted, got %v", diags)
+	}
+	diags := v.Validate("file:///tmp/pvc.yaml", pvc("slow-hdd"))
+	if len(diags) != 1 || diags[0].Message != "StorageClass not found (Kind: StorageClass, Name: slow-hdd)" {
+		t.Fatalf("Expected a missing StorageClass diagnostic, got %v", diags)
+	}
+	if data, ok := diags[0].Data.(MissingReferenceData); !ok || data.Kind != "StorageClass" {
+		t.Errorf("Expected missing reference data for a quick fix, got %v", diags[0].Data)
+	}
+}
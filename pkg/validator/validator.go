@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
 
+	"k8s-lsp/pkg/config"
 	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/position"
+	"k8s-lsp/pkg/uri"
 
+	"github.com/rs/zerolog/log"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 	"gopkg.in/yaml.v3"
 )
@@ -18,13 +23,19 @@ type Rule struct {
 }
 
 type Check struct {
-	Type           string `yaml:"type"`           // "reference", "required", "resource-match"
-	Path           string `yaml:"path"`           // JSONPath-like string (e.g. spec.selector)
-	TargetKind     string `yaml:"targetKind"`     // For reference checks
-	TargetPath     string `yaml:"targetPath"`     // For reference checks
-	Message        string `yaml:"message"`
-	SourceProperty string `yaml:"sourceProperty"` // For resource-match
-	TargetProperty string `yaml:"targetProperty"` // For resource-match
+	Type            string   `yaml:"type"`        // "reference", "required", "enum", "regex", "resource-match", "service-ref", "service-structure"
+	Path            string   `yaml:"path"`        // JSONPath-like string (e.g. spec.selector)
+	TargetKind      string   `yaml:"targetKind"`  // For reference checks
+	TargetKinds     []string `yaml:"targetKinds"` // For selector references matching any of several kinds
+	TargetPath      string   `yaml:"targetPath"`  // For reference checks
+	Message         string   `yaml:"message"`
+	SourceProperty  string   `yaml:"sourceProperty"`  // For resource-match
+	TargetProperty  string   `yaml:"targetProperty"`  // For resource-match
+	CheckTargetPort bool     `yaml:"checkTargetPort"` // For Service selectors: targetPorts must be container ports
+	Allowed         []string `yaml:"allowed"`         // For enum checks
+	Pattern         string   `yaml:"pattern"`         // For regex checks, matched against the whole value
+
+	re *regexp.Regexp // Pattern, compiled by NewValidator
 }
 
 type Config struct {
@@ -32,11 +43,12 @@ type Config struct {
 }
 
 type Validator struct {
-	rules []Rule
-	store *indexer.Store
+	rules    []Rule
+	store    *indexer.Store
+	settings config.Settings
 }
 
-func NewValidator(rulePath string, store *indexer.Store) (*Validator, error) {
+func NewValidator(rulePath string, store *indexer.Store, serverCfg *config.Config) (*Validator, error) {
 	data, err := os.ReadFile(rulePath)
 	if err != nil {
 		return nil, err
@@ -46,27 +58,70 @@ func NewValidator(rulePath string, store *indexer.Store) (*Validator, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	for r, rule := range cfg.Rules {
+		// A check that can't work is skipped rather than costing every
+		// other rule.
+		checks := rule.Checks[:0]
+		for _, check := range rule.Checks {
+			if check.Type == "regex" {
+				re, err := compilePattern(check.Pattern)
+				if err != nil {
+					log.Error().Err(err).Str("path", rulePath).Str("kind", rule.Kind).Msg("Skipping validation check")
+					continue
+				}
+				check.re = re
+			}
+			checks = append(checks, check)
+		}
+		cfg.Rules[r].Checks = checks
+	}
 
-	return &Validator{
+	v := &Validator{
 		rules: cfg.Rules,
 		store: store,
-	}, nil
+	}
+	if serverCfg != nil {
+		v.settings = serverCfg.Settings
+	}
+	return v, nil
 }
 
 func (v *Validator) Validate(uri string, content string) []protocol.Diagnostic {
 	var diagnostics []protocol.Diagnostic
 
 	var docNode yaml.Node
-	if err := yaml.Unmarshal([]byte(content), &docNode); err != nil {
+	if err := position.Unmarshal(content, &docNode); err != nil {
 		return diagnostics
 	}
+	if err := indexer.CheckDocumentLimits(&docNode, v.settings.DocumentLimits); err != nil {
+		severity := protocol.DiagnosticSeverityWarning
+		source := "k8s-lsp"
+		return []protocol.Diagnostic{{
+			Range:    protocol.Range{},
+			Severity: &severity,
+			Source:   &source,
+			Message:  err.Error(),
+		}}
+	}
 
 	// Handle multiple documents in one file if necessary, but usually root is DocumentNode
 	// yaml.Unmarshal returns the first document if not using Decoder.
-	// But yaml.Node from Unmarshal is a DocumentNode.
+	// But yaml.Node from Unmarshal is a DocumentNode. The items of a kind:
+	// List are checked as the documents they stand for.
+	for _, doc := range indexer.ListItems(&docNode) {
+		diagnostics = append(diagnostics, v.validateDocument(uri, doc)...)
+	}
 
-	if docNode.Kind == yaml.DocumentNode && len(docNode.Content) > 0 {
-		root := docNode.Content[0]
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// validateDocument checks one document of uri.
+func (v *Validator) validateDocument(uri string, doc *yaml.Node) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root := doc.Content[0]
 		if root.Kind == yaml.MappingNode {
 			kind := ""
 			kindNodes := findNodes(root, "kind")
@@ -92,16 +147,69 @@ func (v *Validator) Validate(uri string, content string) []protocol.Diagnostic {
 							if diags := v.checkResourceMatch(uri, root, check, namespace); len(diags) > 0 {
 								diagnostics = append(diagnostics, diags...)
 							}
+						} else if check.Type == "service-ref" {
+							if diags := v.checkServiceRef(uri, root, check, namespace); len(diags) > 0 {
+								diagnostics = append(diagnostics, diags...)
+							}
+						} else if check.Type == "required" {
+							diagnostics = append(diagnostics, checkRequired(root, check)...)
+						} else if check.Type == "enum" {
+							diagnostics = append(diagnostics, checkEnum(root, check)...)
+						} else if check.Type == "regex" {
+							diagnostics = append(diagnostics, checkRegex(root, check)...)
+						} else if check.Type == "service-structure" {
+							diagnostics = append(diagnostics, v.checkServiceStructure(root, namespace)...)
 						}
 					}
 				}
 			}
+
+			if kind == "ConfigMap" || kind == "Secret" {
+				diagnostics = append(diagnostics, checkDuplicateDataKeys(uri, root)...)
+			}
+
+			diagnostics = append(diagnostics, checkDeprecatedAPIVersion(root, kind)...)
+
+			diagnostics = append(diagnostics, v.checkCRDSchema(root, kind)...)
+
+			diagnostics = append(diagnostics, v.checkDuplicateResource(uri, root, kind)...)
+
+			diagnostics = append(diagnostics, v.checkEnvFromCollisions(uri, root, kind, namespace)...)
+
+			if kind == "PersistentVolume" && v.settings.OrphanedPersistentVolumes {
+				diagnostics = append(diagnostics, v.checkOrphanedPersistentVolume(root)...)
+			}
+
+			if kind == "HorizontalPodAutoscaler" && v.settings.PolicyPackEnabled(config.PolicyPackAutoscaling) {
+				diagnostics = append(diagnostics, v.checkHorizontalPodAutoscaler(root, namespace)...)
+			}
 		}
 	}
 
 	return diagnostics
 }
 
+// checkOrphanedPersistentVolume reports a PersistentVolume that no indexed
+// PersistentVolumeClaim binds via spec.volumeName. PVs with a claimRef are
+// already bound (possibly dynamically) and are skipped.
+func (v *Validator) checkOrphanedPersistentVolume(root *yaml.Node) []protocol.Diagnostic {
+	if len(findNodes(root, "spec.claimRef")) > 0 {
+		return nil
+	}
+	nameNodes := findNodes(root, "metadata.name")
+	if len(nameNodes) == 0 || nameNodes[0].Value == "" {
+		return nil
+	}
+	nameNode := nameNodes[0]
+
+	if len(v.store.FindReferences("PersistentVolume", nameNode.Value)) > 0 {
+		return nil
+	}
+
+	return []protocol.Diagnostic{newDiagnostic(nameNode, len(nameNode.Value), protocol.DiagnosticSeverityInformation,
+		fmt.Sprintf("PersistentVolume %s is not bound by any PersistentVolumeClaim", nameNode.Value))}
+}
+
 func findNodes(root *yaml.Node, path string) []*yaml.Node {
 	currentNodes := []*yaml.Node{root}
 	parts := strings.Split(path, ".")
@@ -154,60 +262,33 @@ func (v *Validator) checkReference(uri string, root *yaml.Node, check Check, nam
 		if node.Kind == yaml.ScalarNode {
 			// Single value reference (e.g. Service Name, ConfigMap Name)
 			targetName := node.Value
-			found := v.store.Get(check.TargetKind, namespace, targetName)
-
-			if found == nil {
-				startLine := node.Line - 1
-				startChar := node.Column - 1
-				endLine := startLine
-				endChar := startChar + len(targetName)
-
-				severity := protocol.DiagnosticSeverityWarning
-				source := "k8s-lsp"
-
-				diagnostics = append(diagnostics, protocol.Diagnostic{
-					Range: protocol.Range{
-						Start: protocol.Position{Line: uint32(startLine), Character: uint32(startChar)},
-						End:   protocol.Position{Line: uint32(endLine), Character: uint32(endChar)},
-					},
-					Severity: &severity,
-					Source:   &source,
-					Message:  check.Message + fmt.Sprintf(" (Kind: %s, Name: %s)", check.TargetKind, targetName),
-				})
-			}
-		} else if node.Kind == yaml.MappingNode {
-			// For Service selector, node is a MappingNode (labels)
-			selector := make(map[string]string)
-			for i := 0; i < len(node.Content); i += 2 {
-				selector[node.Content[i].Value] = node.Content[i+1].Value
+			if indexer.HasInterpolation(targetName) {
+				vars := indexer.InterpolationVars(v.settings.Interpolation, uriPath(uri))
+				resolved, ok := indexer.Interpolate(targetName, vars)
+				if !ok {
+					// Substituted by a controller at runtime; don't guess.
+					continue
+				}
+				targetName = resolved
 			}
-
-			if len(selector) == 0 {
+			// An empty name is deliberate, e.g. storageClassName: "" opts
+			// out of dynamic provisioning.
+			if targetName == "" {
 				continue
 			}
-
-			// Check if any resource of TargetKind matches ALL labels
-			candidates := v.store.ListByKind(check.TargetKind)
-			found := false
-			for _, res := range candidates {
-				match := true
-				for k, v := range selector {
-					if res.Labels[k] != v {
-						match = false
-						break
-					}
-				}
-				if match {
-					found = true
-					break
-				}
+			lookupNamespace := namespace
+			if indexer.IsClusterScoped(check.TargetKind) {
+				lookupNamespace = ""
 			}
+			found := v.store.Get(check.TargetKind, lookupNamespace, targetName)
 
-			if !found {
+			if found == nil && v.namespaceFallback(check.TargetKind, namespace, targetName) {
+				diagnostics = append(diagnostics, namespaceFallbackDiagnostic(node, check.TargetKind, targetName, namespace))
+			} else if found == nil {
 				startLine := node.Line - 1
 				startChar := node.Column - 1
 				endLine := startLine
-				endChar := startChar + 10
+				endChar := startChar + scalarLength(node)
 
 				severity := protocol.DiagnosticSeverityWarning
 				source := "k8s-lsp"
@@ -219,9 +300,17 @@ func (v *Validator) checkReference(uri string, root *yaml.Node, check Check, nam
 					},
 					Severity: &severity,
 					Source:   &source,
-					Message:  check.Message + fmt.Sprintf(" (Kind: %s)", check.TargetKind),
+					Message:  check.Message + fmt.Sprintf(" (Kind: %s, Name: %s)", check.TargetKind, targetName),
+					Data: MissingReferenceData{
+						Kind:      check.TargetKind,
+						Name:      targetName,
+						Namespace: namespace,
+					},
 				})
 			}
+		} else if node.Kind == yaml.MappingNode {
+			// For Service selector, node is a MappingNode (labels)
+			diagnostics = append(diagnostics, v.checkSelector(root, node, check, namespace)...)
 		}
 	}
 
@@ -244,10 +333,7 @@ func (v *Validator) checkResourceMatch(uri string, root *yaml.Node, check Check,
 
 		// Find target resource
 		// Try current namespace first, then default (for cluster-scoped like PV)
-		targetRes := v.store.Get(check.TargetKind, namespace, targetName)
-		if targetRes == nil {
-			targetRes = v.store.Get(check.TargetKind, "default", targetName)
-		}
+		targetRes, _ := v.store.Lookup(check.TargetKind, namespace, targetName)
 
 		if targetRes == nil {
 			continue // Reference not found, maybe handled by reference check
@@ -270,7 +356,7 @@ func (v *Validator) checkResourceMatch(uri string, root *yaml.Node, check Check,
 			startLine := node.Line - 1
 			startChar := node.Column - 1
 			endLine := startLine
-			endChar := startChar + len(targetName)
+			endChar := startChar + scalarLength(node)
 
 			severity := protocol.DiagnosticSeverityWarning
 			source := "k8s-lsp"
@@ -289,14 +375,118 @@ func (v *Validator) checkResourceMatch(uri string, root *yaml.Node, check Check,
 	return diagnostics
 }
 
+// defaultWebhookServicePort is the port used when a webhook clientConfig.service
+// omits one.
+const defaultWebhookServicePort = "443"
+
+// checkServiceRef validates a {namespace, name, port} service reference such as
+// webhooks[].clientConfig.service: the Service must exist in the referenced
+// namespace and expose the referenced port.
+func (v *Validator) checkServiceRef(uri string, root *yaml.Node, check Check, namespace string) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+
+	for _, node := range findNodes(root, check.Path) {
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+		nameNodes := findNodes(node, "name")
+		if len(nameNodes) == 0 || nameNodes[0].Kind != yaml.ScalarNode {
+			continue
+		}
+		nameNode := nameNodes[0]
+
+		targetNamespace := namespace
+		if nsNodes := findNodes(node, "namespace"); len(nsNodes) > 0 && nsNodes[0].Value != "" {
+			targetNamespace = nsNodes[0].Value
+		}
+
+		svc := v.store.Get(check.TargetKind, targetNamespace, nameNode.Value)
+		if svc == nil {
+			diagnostics = append(diagnostics, newDiagnostic(nameNode, len(nameNode.Value), protocol.DiagnosticSeverityWarning,
+				check.Message+fmt.Sprintf(" (Kind: %s, Namespace: %s, Name: %s)", check.TargetKind, targetNamespace, nameNode.Value)))
+			continue
+		}
+
+		port := defaultWebhookServicePort
+		portNode := nameNode
+		if portNodes := findNodes(node, "port"); len(portNodes) > 0 {
+			portNode = portNodes[0]
+			port = portNode.Value
+		}
+
+		svcRoot := v.findResourceNode(svc)
+		if svcRoot == nil {
+			continue
+		}
+		exposed := false
+		for _, p := range findNodes(svcRoot, "spec.ports.port") {
+			if p.Value == port {
+				exposed = true
+				break
+			}
+		}
+		if !exposed {
+			diagnostics = append(diagnostics, newDiagnostic(portNode, len(portNode.Value), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("Service %s/%s does not expose port %s", targetNamespace, svc.Name, port)))
+		}
+	}
+	return diagnostics
+}
+
+// selectorRange spans a label selector from its first key to the end of its
+// last value. It works from the entries' own positions so flow mappings
+// ({app: web}) and block mappings get the same highlight.
+func selectorRange(node *yaml.Node) protocol.Range {
+	first := node.Content[0]
+	last := node.Content[len(node.Content)-1]
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(first.Line - 1), Character: uint32(first.Column - 1)},
+		End:   protocol.Position{Line: uint32(last.Line - 1), Character: uint32(last.Column - 1 + scalarLength(last))},
+	}
+}
+
+// scalarLength is the width of a scalar in the source, including quotes.
+func scalarLength(node *yaml.Node) int {
+	if node.Style == yaml.DoubleQuotedStyle || node.Style == yaml.SingleQuotedStyle {
+		return len(node.Value) + 2
+	}
+	return len(node.Value)
+}
+
+func newDiagnostic(node *yaml.Node, length int, severity protocol.DiagnosticSeverity, message string) protocol.Diagnostic {
+	source := "k8s-lsp"
+	return protocol.Diagnostic{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: uint32(node.Line - 1), Character: uint32(node.Column - 1)},
+			End:   protocol.Position{Line: uint32(node.Line - 1), Character: uint32(node.Column - 1 + length)},
+		},
+		Severity: &severity,
+		Source:   &source,
+		Message:  message,
+	}
+}
+
 func (v *Validator) getValueFromResource(res *indexer.K8sResource, path string) string {
-	f, err := os.Open(res.FilePath)
-	if err != nil {
+	root := v.findResourceNode(res)
+	if root == nil {
 		return ""
 	}
-	defer f.Close()
+	targetNodes := findNodes(root, path)
+	if len(targetNodes) > 0 {
+		return targetNodes[0].Value
+	}
+	return ""
+}
+
+// findResourceNode re-reads the resource's file and returns the root mapping
+// of the document that defines it.
+func (v *Validator) findResourceNode(res *indexer.K8sResource) *yaml.Node {
+	content, err := os.ReadFile(res.FilePath)
+	if err != nil {
+		return nil
+	}
 
-	decoder := yaml.NewDecoder(f)
+	decoder := position.NewDecoder(string(content))
 	for {
 		var node yaml.Node
 		if err := decoder.Decode(&node); err != nil {
@@ -305,26 +495,49 @@ func (v *Validator) getValueFromResource(res *indexer.K8sResource, path string)
 			}
 			break
 		}
-		
-		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
-			root := node.Content[0]
+		if indexer.CheckDocumentLimits(&node, v.settings.DocumentLimits) != nil {
+			continue
+		}
+
+		for _, item := range indexer.ListItems(&node) {
+			if item.Kind != yaml.DocumentNode || len(item.Content) == 0 {
+				continue
+			}
+			root := item.Content[0]
 			if root.Kind == yaml.MappingNode {
 				// Check if this is the right resource
 				kindNodes := findNodes(root, "kind")
 				nameNodes := findNodes(root, "metadata.name")
-				
+
 				if len(kindNodes) > 0 && len(nameNodes) > 0 {
 					if kindNodes[0].Value == res.Kind && nameNodes[0].Value == res.Name {
 						// Found it
-						targetNodes := findNodes(root, path)
-						if len(targetNodes) > 0 {
-							return targetNodes[0].Value
-						}
-						return ""
+						return root
 					}
 				}
 			}
 		}
 	}
-	return ""
+	return nil
+}
+
+// fileURI returns the file:// URI of an indexed resource's path.
+func fileURI(path string) string {
+	return uri.FromPath(path)
+}
+
+// filePath returns the path of a file:// URI, reporting false for other
+// schemes.
+func filePath(documentURI string) (string, bool) {
+	path, err := uri.ToPath(documentURI)
+	return path, err == nil
+}
+
+// uriPath returns the path of a file:// URI as the store keys it, or the URI
+// itself for documents that aren't files.
+func uriPath(documentURI string) string {
+	if path, ok := filePath(documentURI); ok {
+		return path
+	}
+	return documentURI
 }
@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s-lsp/pkg/indexer"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic codes of the "enum" and "regex" checks.
+const (
+	CodeEnumValue       = "enum-value"
+	CodePatternMismatch = "pattern-mismatch"
+)
+
+// compilePattern compiles a regex check's pattern, which has to match the
+// whole value.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex check pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// checkedValues returns the scalars at check.Path that enum and regex checks
+// apply to. Empty values and ones substituted later (${VAR}) are left out.
+func checkedValues(root *yaml.Node, check Check) []*yaml.Node {
+	var values []*yaml.Node
+	for _, node := range findNodes(root, check.Path) {
+		if node.Kind != yaml.ScalarNode || isEmptyNode(node) || indexer.HasInterpolation(node.Value) {
+			continue
+		}
+		values = append(values, node)
+	}
+	return values
+}
+
+// checkEnum reports values at check.Path that aren't one of check.Allowed.
+func checkEnum(root *yaml.Node, check Check) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+	for _, node := range checkedValues(root, check) {
+		if slices.Contains(check.Allowed, node.Value) {
+			continue
+		}
+		message := check.Message
+		if message == "" {
+			message = fmt.Sprintf("%s must be one of %s", check.Path, strings.Join(check.Allowed, ", "))
+		}
+		diag := newDiagnostic(node, scalarLength(node), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("%s (got %q)", message, node.Value))
+		diagnostics = append(diagnostics, withCode(diag, CodeEnumValue))
+	}
+	return diagnostics
+}
+
+// checkRegex reports values at check.Path that check.Pattern doesn't match
+// in full.
+func checkRegex(root *yaml.Node, check Check) []protocol.Diagnostic {
+	re := check.re
+	if re == nil {
+		var err error
+		if re, err = compilePattern(check.Pattern); err != nil {
+			return nil
+		}
+	}
+
+	var diagnostics []protocol.Diagnostic
+	for _, node := range checkedValues(root, check) {
+		if re.MatchString(node.Value) {
+			continue
+		}
+		message := check.Message
+		if message == "" {
+			message = fmt.Sprintf("%s must match %s", check.Path, check.Pattern)
+		}
+		diag := newDiagnostic(node, scalarLength(node), protocol.DiagnosticSeverityWarning,
+			fmt.Sprintf("%s (got %q)", message, node.Value))
+		diagnostics = append(diagnostics, withCode(diag, CodePatternMismatch))
+	}
+	return diagnostics
+}
@@ -0,0 +1,107 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/indexer"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEnumAndRegexChecks(t *testing.T) {
+	v, err := NewValidator("../../rules/validation.yaml", indexer.NewStore(), nil)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	pod := func(name, policy string) string {
+		return `apiVersion: v1
+kind: Pod
+metadata:
+  name: ` + name + `
+spec:
+  containers:
+    - name: app
+      imagePullPolicy: IfNotPresent
+    - name: sidecar
+      imagePullPolicy: ` + policy + `
+`
+	}
+
+	if diags := v.Validate("file:///pod.yaml", pod("web-0.cache", "Never")); len(diags) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", diags)
+	}
+	// Values substituted later are left alone.
+	if diags := v.Validate("file:///pod.yaml", pod("${APP}", "${PULL_POLICY}")); len(diags) != 0 {
+		t.Fatalf("Expected interpolated values to be skipped, got %v", diags)
+	}
+
+	diags := v.Validate("file:///pod.yaml", pod("Web_0", "always"))
+	if len(diags) != 2 {
+		t.Fatalf("Expected a name and a policy diagnostic, got %v", diags)
+	}
+	name, policy := diags[0], diags[1]
+	if name.Code == nil || name.Code.Value != CodePatternMismatch || name.Message != `metadata.name must be a lowercase DNS-1123 subdomain (got "Web_0")` {
+		t.Errorf("Expected a DNS-1123 diagnostic, got %v", name)
+	}
+	if r := name.Range; r.Start.Line != 3 || r.Start.Character != 8 || r.End.Character != 13 {
+		t.Errorf("Expected the name's range, got %v", r)
+	}
+	if policy.Code == nil || policy.Code.Value != CodeEnumValue || !strings.HasSuffix(policy.Message, `(got "always")`) {
+		t.Errorf("Expected an imagePullPolicy diagnostic, got %v", policy)
+	}
+	// Only the second container's value is flagged.
+	if r := policy.Range; r.Start.Line != 9 || r.Start.Character != 23 || r.End.Character != 29 {
+		t.Errorf("Expected the second imagePullPolicy's range, got %v", r)
+	}
+
+	// Without a message the allowed values are listed.
+	check := Check{Type: "enum", Path: "spec.restartPolicy", Allowed: []string{"Always", "OnFailure", "Never"}}
+	root := parseRoot(t, "spec:\n  restartPolicy: Sometimes\n")
+	if diags := checkEnum(root, check); len(diags) != 1 || diags[0].Message != `spec.restartPolicy must be one of Always, OnFailure, Never (got "Sometimes")` {
+		t.Errorf("Expected the default enum message, got %v", diags)
+	}
+	// Patterns match the whole value.
+	check = Check{Type: "regex", Path: "spec.restartPolicy", Pattern: "Some"}
+	if diags := checkRegex(root, check); len(diags) != 1 || diags[0].Message != `spec.restartPolicy must match Some (got "Sometimes")` {
+		t.Errorf("Expected a partial match to fail, got %v", diags)
+	}
+}
+
+func TestRegexCheckInvalidPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validation.yaml")
+	if err := os.WriteFile(path, []byte(`rules:
+  - kind: Pod
+    checks:
+      - type: regex
+        path: metadata.name
+        pattern: "[a-z"
+      - type: regex
+        path: spec.restartPolicy
+        pattern: "Always|Never"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The broken check is skipped; the rest still apply.
+	v, err := NewValidator(path, indexer.NewStore(), nil)
+	if err != nil {
+		t.Fatalf("Expected the invalid pattern to be skipped, got %v", err)
+	}
+	diags := v.Validate("file:///repo/pod.yaml", "apiVersion: v1\nkind: Pod\nmetadata:\n  name: Web\nspec:\n  restartPolicy: Sometimes\n")
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "spec.restartPolicy") {
+		t.Errorf("Expected only the valid check to report, got %v", diags)
+	}
+}
+
+// parseRoot returns the root mapping of a single YAML document.
+func parseRoot(t *testing.T, content string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Content[0]
+}
@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+)
+
+func TestWebhookServiceRef(t *testing.T) {
+	dir := t.TempDir()
+	svcPath := filepath.Join(dir, "svc.yaml")
+	svcYaml := `apiVersion: v1
+kind: Service
+metadata:
+  name: policy-svc
+  namespace: webhooks
+spec:
+  ports:
+    - port: 443
+      targetPort: 8443
+`
+	if err := os.WriteFile(svcPath, []byte(svcYaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"Service"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	indexer.NewIndexer(store, cfg).IndexFile(svcPath)
+
+	v := &Validator{
+		store: store,
+		rules: []Rule{{
+			Kind: "ValidatingWebhookConfiguration",
+			Checks: []Check{{
+				Type:       "service-ref",
+				Path:       "webhooks.clientConfig.service",
+				TargetKind: "Service",
+				Message:    "Webhook Service not found",
+			}},
+		}},
+	}
+
+	webhook := func(namespace, port string) string {
+		return `apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: policy
+webhooks:
+  - name: validate.example.com
+    clientConfig:
+      service:
+        namespace: ` + namespace + `
+        name: policy-svc
+` + port
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"default port exposed", webhook("webhooks", ""), ""},
+		{"explicit port exposed", webhook("webhooks", "        port: 443\n"), ""},
+		{"port not exposed", webhook("webhooks", "        port: 9443\n"), "does not expose port 9443"},
+		{"wrong namespace", webhook("other", ""), "Webhook Service not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := v.Validate("file:///webhook.yaml", tt.content)
+			if tt.want == "" {
+				if len(diags) != 0 {
+					t.Fatalf("Expected no diagnostics, got %v", diags)
+				}
+				return
+			}
+			if len(diags) != 1 || !strings.Contains(diags[0].Message, tt.want) {
+				t.Fatalf("Expected one diagnostic containing %q, got %v", tt.want, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"k8s-lsp/pkg/position"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// clientEncoding is the position encoding negotiated with the client,
+// utf-16 unless it offered another one we prefer.
+func clientEncoding() position.Encoding {
+	if state == nil || state.PositionEncoding == "" {
+		return position.UTF16
+	}
+	return state.PositionEncoding
+}
+
+// positionHandler converts the positions in every message between the
+// client's encoding and the byte columns the resolver and validator work
+// in: positions in params on the way in, and positions in results and in
+// notifications (diagnostics) on the way out. With utf-8 there is nothing
+// to convert.
+//
+// Positions belong to the document named by the closest uri around them
+// ("uri", "targetUri" or "textDocument.uri"), or to the request's document.
+// didChange is left alone; its ranges apply to the text as edited so far,
+// so applyContentChanges converts them.
+type positionHandler struct {
+	glsp.Handler
+}
+
+func (h positionHandler) Handle(context *glsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case string(protocol.MethodInitialize), string(protocol.MethodTextDocumentDidChange):
+		return h.Handler.Handle(context)
+	}
+	enc := clientEncoding()
+	if enc == position.UTF8 {
+		return h.Handler.Handle(context)
+	}
+
+	uri := requestURI(context.Params)
+	if params, ok := convertPositions(context.Params, uri, func(line string, char uint32) uint32 {
+		return uint32(position.ToByte(line, char, enc))
+	}); ok {
+		context.Params = params
+	}
+	notify := context.Notify
+	context.Notify = func(method string, params any) {
+		notify(method, convertResultPositions(params, "", enc))
+	}
+
+	result, validMethod, validParams, err := h.Handler.Handle(context)
+	return convertResultPositions(result, uri, enc), validMethod, validParams, err
+}
+
+// convertResultPositions converts the byte columns in result to enc. The
+// result is returned as is when no position needed converting.
+func convertResultPositions(result any, uri string, enc position.Encoding) any {
+	if result == nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return result
+	}
+	converted, ok := convertPositions(data, uri, func(line string, char uint32) uint32 {
+		return position.FromByte(line, int(char), enc)
+	})
+	if !ok {
+		return result
+	}
+	return converted
+}
+
+// requestURI returns the document a request is about, from its
+// textDocument, call hierarchy item or first command argument.
+func requestURI(params json.RawMessage) string {
+	var request struct {
+		TextDocument *protocol.TextDocumentIdentifier `json:"textDocument"`
+		Item         *struct {
+			URI string `json:"uri"`
+		} `json:"item"`
+		Arguments []struct {
+			TextDocument *protocol.TextDocumentIdentifier `json:"textDocument"`
+		} `json:"arguments"`
+	}
+	if json.Unmarshal(params, &request) != nil {
+		return ""
+	}
+	switch {
+	case request.TextDocument != nil:
+		return request.TextDocument.URI
+	case request.Item != nil:
+		return request.Item.URI
+	case len(request.Arguments) > 0 && request.Arguments[0].TextDocument != nil:
+		return request.Arguments[0].TextDocument.URI
+	}
+	return ""
+}
+
+// convertPositions rewrites the character of every position in data with
+// convert, given the line it is on. It reports whether any changed.
+func convertPositions(data json.RawMessage, uri string, convert func(line string, char uint32) uint32) (json.RawMessage, bool) {
+	if !bytes.Contains(data, []byte(`"character"`)) {
+		return data, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return data, false
+	}
+	c := positionConverter{convert: convert, lines: make(map[string][]string)}
+	c.walk(value, uri)
+	if !c.changed {
+		return data, false
+	}
+	converted, err := json.Marshal(value)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to convert positions")
+		return data, false
+	}
+	return converted, true
+}
+
+type positionConverter struct {
+	convert func(line string, char uint32) uint32
+	// lines caches the lines of every document positions were found in.
+	lines   map[string][]string
+	changed bool
+}
+
+func (c *positionConverter) walk(value any, uri string) {
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			c.walk(item, uri)
+		}
+	case map[string]any:
+		if c.position(v, uri) {
+			return
+		}
+		outer := uri
+		uri = objectURI(v, uri)
+		for key, child := range v {
+			switch key {
+			case "originSelectionRange":
+				// A LocationLink's origin is in the requesting document.
+				c.walk(child, outer)
+			case "fromRanges":
+				// Incoming calls are in the calling item's document.
+				if from, ok := v["from"].(map[string]any); ok {
+					c.walk(child, objectURI(from, uri))
+				} else {
+					c.walk(child, uri)
+				}
+			case "changes":
+				// WorkspaceEdit.changes is keyed by document.
+				if changes, ok := child.(map[string]any); ok {
+					for changeURI, edits := range changes {
+						c.walk(edits, changeURI)
+					}
+				}
+			default:
+				c.walk(child, uri)
+			}
+		}
+	}
+}
+
+// position converts v if it is a position, reporting whether it was.
+func (c *positionConverter) position(v map[string]any, uri string) bool {
+	if len(v) != 2 {
+		return false
+	}
+	lineNum, ok := v["line"].(json.Number)
+	if !ok {
+		return false
+	}
+	charNum, ok := v["character"].(json.Number)
+	if !ok {
+		return false
+	}
+	line, err1 := lineNum.Int64()
+	char, err2 := charNum.Int64()
+	if err1 != nil || err2 != nil || uri == "" || line < 0 || char < 0 {
+		return true
+	}
+	lines := c.documentLines(uri)
+	if int(line) >= len(lines) {
+		return true
+	}
+	if converted := c.convert(lines[line], uint32(char)); int64(converted) != char {
+		v["character"] = converted
+		c.changed = true
+	}
+	return true
+}
+
+// documentLines returns the lines of uri, open or on disk. ASCII documents
+// need no conversion and are returned as no lines.
+func (c *positionConverter) documentLines(uri string) []string {
+	if lines, ok := c.lines[uri]; ok {
+		return lines
+	}
+	var lines []string
+	if content, ok := state.Documents.GetOrLoadFromDisk(uri); ok && !position.IsASCII(content) {
+		lines = position.Lines(content)
+	}
+	c.lines[uri] = lines
+	return lines
+}
+
+// objectURI returns the document v's positions belong to: its own uri,
+// targetUri or textDocument.uri, or else uri.
+func objectURI(v map[string]any, uri string) string {
+	for _, key := range []string{"uri", "targetUri"} {
+		if u, ok := v[key].(string); ok && u != "" {
+			return u
+		}
+	}
+	if td, ok := v["textDocument"].(map[string]any); ok {
+		if u, ok := td["uri"].(string); ok && u != "" {
+			return u
+		}
+	}
+	return uri
+}
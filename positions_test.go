@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"k8s-lsp/pkg/position"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// positionsDeployment has a Korean and an astral-plane value in front of
+// the serviceAccountName reference on its last line.
+const positionsDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec: {note: "한글 😀", serviceAccountName: builder}
+  selector: {matchLabels: {app: web}}
+`
+
+func positionsClient(t *testing.T, capabilities string) (func(method, params string) any, chan json.RawMessage) {
+	t.Helper()
+	state = newServerState("rules")
+	notified := make(chan json.RawMessage, 10)
+	handler := newHandler()
+	request := func(method, params string) any {
+		t.Helper()
+		result, validMethod, validParams, err := handler.Handle(&glsp.Context{
+			Method: method,
+			Params: json.RawMessage(params),
+			Notify: func(method string, params any) {
+				if method == string(protocol.ServerTextDocumentPublishDiagnostics) {
+					data, _ := json.Marshal(params)
+					notified <- data
+				}
+			},
+			Call: func(method string, params any, result any) {},
+		})
+		if !validMethod || !validParams || err != nil {
+			t.Fatalf("Expected %s to be handled, got %v %v %v", method, validMethod, validParams, err)
+		}
+		return result
+	}
+	request("initialize", `{"capabilities":`+capabilities+`}`)
+	return request, notified
+}
+
+// decodeResult round-trips a handler result, converted or not, into v.
+func decodeResult(t *testing.T, result any, v any) {
+	t.Helper()
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPositionEncodingUTF16(t *testing.T) {
+	request, notified := positionsClient(t, `{}`)
+	if state.PositionEncoding != position.UTF16 {
+		t.Fatalf("Expected utf-16 without positionEncodings, got %q", state.PositionEncoding)
+	}
+
+	line := strings.Split(positionsDeployment, "\n")[6]
+	byteCol := strings.Index(line, "builder")
+	col := len(utf16.Encode([]rune(line[:byteCol])))
+	if col == byteCol {
+		t.Fatal("Expected the test line to need converting")
+	}
+
+	uri := "file:///ws/deploy.yaml"
+	open, _ := json.Marshal(protocol.DidOpenTextDocumentParams{TextDocument: protocol.TextDocumentItem{URI: uri, LanguageID: "yaml", Version: 1, Text: positionsDeployment}})
+	request(string(protocol.MethodTextDocumentDidOpen), string(open))
+	publishing.Wait()
+
+	var published protocol.PublishDiagnosticsParams
+	decodeResult(t, <-notified, &published)
+	if len(published.Diagnostics) != 1 {
+		t.Fatalf("Expected the missing ServiceAccount to be reported, got %+v", published.Diagnostics)
+	}
+	if rng := published.Diagnostics[0].Range; rng.Start.Character != uint32(col) || rng.End.Character != uint32(col+len("builder")) {
+		t.Errorf("Expected the diagnostic at UTF-16 columns %d-%d, got %+v", col, col+len("builder"), rng)
+	}
+
+	state.Indexer.IndexContent("/ws/sa.yaml", "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: builder\n")
+	params := `{"textDocument":{"uri":"` + uri + `"},"position":{"line":6,"character":` + strconv.Itoa(col+2) + `}}`
+	var links []protocol.LocationLink
+	decodeResult(t, request(string(protocol.MethodTextDocumentDefinition), params), &links)
+	if len(links) != 1 || links[0].TargetURI != "file:///ws/sa.yaml" {
+		t.Fatalf("Expected the reference to resolve to the ServiceAccount, got %+v", links)
+	}
+	if origin := links[0].OriginSelectionRange; origin == nil || origin.Start.Character != uint32(col) || origin.End.Character != uint32(col+len("builder")) {
+		t.Errorf("Expected the origin at UTF-16 columns %d-%d, got %+v", col, col+len("builder"), origin)
+	}
+}
+
+func TestPositionEncodingUTF8(t *testing.T) {
+	request, _ := positionsClient(t, `{"general":{"positionEncodings":["utf-16","utf-8"]}}`)
+	if state.PositionEncoding != position.UTF8 {
+		t.Fatalf("Expected utf-8 when offered, got %q", state.PositionEncoding)
+	}
+
+	uri := "file:///ws/deploy.yaml"
+	state.Documents.Set(uri, positionsDeployment, 1)
+	state.Indexer.IndexContent("/ws/sa.yaml", "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: builder\n")
+
+	line := strings.Split(positionsDeployment, "\n")[6]
+	col := strings.Index(line, "builder")
+	params := `{"textDocument":{"uri":"` + uri + `"},"position":{"line":6,"character":` + strconv.Itoa(col+2) + `}}`
+	var links []protocol.LocationLink
+	decodeResult(t, request(string(protocol.MethodTextDocumentDefinition), params), &links)
+	if len(links) != 1 || links[0].OriginSelectionRange == nil || links[0].OriginSelectionRange.Start.Character != uint32(col) {
+		t.Errorf("Expected the origin at byte column %d, got %+v", col, links)
+	}
+}
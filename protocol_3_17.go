@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"k8s-lsp/pkg/position"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Methods and capabilities from LSP 3.17, which the 3.16 protocol package
+// doesn't know. protocol317Handler routes the methods, and initialize
+// returns serverCapabilities.
+const (
+	methodTextDocumentInlayHint  = "textDocument/inlayHint"
+	methodTextDocumentDiagnostic = "textDocument/diagnostic"
+	methodDiagnosticRefresh      = "workspace/diagnostic/refresh"
+)
+
+// serverCapabilities adds the 3.17 capabilities the server implements to
+// the 3.16 ones.
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	InlayHintProvider  bool               `json:"inlayHintProvider,omitempty"`
+	DiagnosticProvider *DiagnosticOptions `json:"diagnosticProvider,omitempty"`
+	PositionEncoding   position.Encoding  `json:"positionEncoding,omitempty"`
+}
+
+// DiagnosticOptions declares textDocument/diagnostic support.
+type DiagnosticOptions struct {
+	InterFileDependencies bool `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool `json:"workspaceDiagnostics"`
+}
+
+// initializeResult is protocol.InitializeResult with serverCapabilities.
+type initializeResult struct {
+	Capabilities serverCapabilities                   `json:"capabilities"`
+	ServerInfo   *protocol.InitializeResultServerInfo `json:"serverInfo,omitempty"`
+}
+
+// clientCapabilities317 are the 3.17 client capabilities the server reads;
+// protocol.InitializeParams drops them while decoding.
+type clientCapabilities317 struct {
+	Capabilities struct {
+		General struct {
+			PositionEncodings []string `json:"positionEncodings"`
+		} `json:"general"`
+		TextDocument struct {
+			Diagnostic *json.RawMessage `json:"diagnostic"`
+		} `json:"textDocument"`
+		Workspace struct {
+			Diagnostics *struct {
+				RefreshSupport bool `json:"refreshSupport"`
+			} `json:"diagnostics"`
+		} `json:"workspace"`
+	} `json:"capabilities"`
+}
+
+// protocol317Handler answers the 3.17 requests and passes every other
+// message on to the protocol handler.
+type protocol317Handler struct {
+	*protocol.Handler
+}
+
+func (h protocol317Handler) Handle(context *glsp.Context) (any, bool, bool, error) {
+	switch context.Method {
+	case string(protocol.MethodInitialize):
+		var params clientCapabilities317
+		if err := json.Unmarshal(context.Params, &params); err == nil {
+			state.DiagnosticPull = params.Capabilities.TextDocument.Diagnostic != nil
+			state.DiagnosticRefresh = params.Capabilities.Workspace.Diagnostics != nil && params.Capabilities.Workspace.Diagnostics.RefreshSupport
+			state.PositionEncoding = position.Negotiate(params.Capabilities.General.PositionEncodings)
+		}
+		return h.Handler.Handle(context)
+	case string(protocol.MethodExit):
+		// glsp rejects everything but initialize once shutdown has
+		// uninitialized it, exit included.
+		return nil, true, true, exit(context)
+	case methodTextDocumentInlayHint:
+		if !h.IsInitialized() {
+			return nil, true, true, errors.New("server not initialized")
+		}
+		var params InlayHintParams
+		if err := json.Unmarshal(context.Params, &params); err != nil {
+			return nil, true, false, err
+		}
+		result, err := textDocumentInlayHint(context, &params)
+		return result, true, true, err
+	case methodTextDocumentDiagnostic:
+		if !h.IsInitialized() {
+			return nil, true, true, errors.New("server not initialized")
+		}
+		var params DocumentDiagnosticParams
+		if err := json.Unmarshal(context.Params, &params); err != nil {
+			return nil, true, false, err
+		}
+		result, err := textDocumentDiagnostic(context, &params)
+		return result, true, true, err
+	}
+	return h.Handler.Handle(context)
+}
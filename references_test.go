@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestReferencesPartialResults(t *testing.T) {
+	state = newServerState("rules")
+	state.Indexer.IndexContent("/ws/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared
+`)
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: shared
+`
+	state.Indexer.IndexContent("/ws/deploy.yaml", deployment)
+	state.Indexer.IndexContent("/ws/other.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: shared
+`)
+	state.Documents.Set("file:///ws/deploy.yaml", deployment, 1)
+
+	var mu sync.Mutex
+	var batches [][]protocol.Location
+	references := func(token string) []protocol.Location {
+		t.Helper()
+		params := &protocol.ReferenceParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file:///ws/deploy.yaml"},
+				Position:     protocol.Position{Line: 10, Character: 20},
+			},
+		}
+		if token != "" {
+			params.PartialResultToken = &protocol.ProgressToken{Value: token}
+		}
+		context := &glsp.Context{Notify: func(method string, params any) {
+			if method != string(protocol.MethodProgress) {
+				return
+			}
+			progress := params.(protocol.ProgressParams)
+			if progress.Token.Value != token {
+				t.Errorf("Expected progress on %q, got %v", token, progress.Token.Value)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			batches = append(batches, progress.Value.([]protocol.Location))
+		}}
+		result, err := textDocumentReferences(context, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var locs []protocol.Location
+		decodeResult(t, result, &locs)
+		return locs
+	}
+
+	all := references("")
+	if len(all) != 2 || len(batches) != 0 {
+		t.Fatalf("Expected 2 references and no partial results without a token, got %v and %v", all, batches)
+	}
+
+	final := references("refs-1")
+	if len(final) != 0 {
+		t.Errorf("Expected an empty final result once streamed, got %v", final)
+	}
+	if len(batches) != 2 || batches[0][0].URI != "file:///ws/config.yaml" {
+		t.Fatalf("Expected the definition batch before the references, got %v", batches)
+	}
+	var streamed []protocol.Location
+	for _, batch := range batches {
+		streamed = append(streamed, batch...)
+	}
+	if len(streamed) != len(all) || streamed[1].URI != "file:///ws/other.yaml" {
+		t.Errorf("Expected the streamed batches to hold the same references, got %v", streamed)
+	}
+}
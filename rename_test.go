@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestRenameReportsWithheldEdits(t *testing.T) {
+	state = newServerState("rules")
+	config := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared
+`
+	state.Indexer.IndexContent("/ws/config.yaml", config)
+	state.Indexer.IndexContent("/ws/deploy.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: shared
+`)
+	state.Documents.Set("file:///ws/config.yaml", config, 1)
+	// Edited in the client since it was indexed.
+	state.Documents.Set("file:///ws/deploy.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: other
+`, 2)
+
+	var messages []protocol.ShowMessageParams
+	context := &glsp.Context{Notify: func(method string, params any) {
+		if method == string(protocol.ServerWindowShowMessage) {
+			messages = append(messages, params.(protocol.ShowMessageParams))
+		}
+	}}
+	edit, err := textDocumentRename(context, &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///ws/config.yaml"},
+			Position:     protocol.Position{Line: 3, Character: 9},
+		},
+		NewName: "common",
+	})
+	if err != nil {
+		t.Fatalf("textDocumentRename failed: %v", err)
+	}
+	if edit == nil || len(edit.DocumentChanges) != 1 {
+		t.Fatalf("Expected only the ConfigMap to be renamed, got %+v", edit)
+	}
+	if len(messages) != 1 || messages[0].Type != protocol.MessageTypeWarning || !strings.Contains(messages[0].Message, "file:///ws/deploy.yaml") {
+		t.Errorf("Expected a warning naming the withheld edit, got %+v", messages)
+	}
+}
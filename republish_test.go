@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/resolver"
+	"k8s-lsp/pkg/validator"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestOpenDocumentsRevalidateWhenIndexChanges(t *testing.T) {
+	rulesPath := filepath.Join(t.TempDir(), "validation.yaml")
+	if err := os.WriteFile(rulesPath, []byte(`rules:
+  - kind: "Deployment"
+    checks:
+      - type: "reference"
+        path: "spec.template.spec.volumes.configMap.name"
+        targetKind: "ConfigMap"
+        message: "ConfigMap not found"
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap", "Deployment"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	val, err := validator.NewValidator(rulesPath, store, cfg)
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	state = &ServerState{
+		Store:     store,
+		Indexer:   indexer.NewIndexer(store, cfg),
+		Resolver:  resolver.NewResolver(store, cfg),
+		Validator: val,
+		Documents: NewDocumentStore(),
+	}
+
+	var mu sync.Mutex
+	published := make(map[string][]protocol.Diagnostic)
+	notified := make(chan string, 10)
+	ctx := &glsp.Context{Notify: func(method string, params any) {
+		p := params.(protocol.PublishDiagnosticsParams)
+		mu.Lock()
+		published[p.URI] = p.Diagnostics
+		mu.Unlock()
+		notified <- p.URI
+	}}
+	waitFor := func(uri string) []protocol.Diagnostic {
+		t.Helper()
+		deadline := time.After(2 * time.Second)
+		for {
+			select {
+			case got := <-notified:
+				if got == uri {
+					mu.Lock()
+					defer mu.Unlock()
+					return published[uri]
+				}
+			case <-deadline:
+				t.Fatalf("Expected diagnostics for %s", uri)
+			}
+		}
+	}
+	open := func(uri, text string) {
+		_ = textDocumentDidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: text},
+		})
+	}
+
+	const deploymentURI = "file:///tmp/deployment.yaml"
+	open(deploymentURI, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+        - name: config
+          configMap:
+            name: app-config
+`)
+	if diags := waitFor(deploymentURI); len(diags) != 1 {
+		t.Fatalf("Expected the missing ConfigMap warning, got %v", diags)
+	}
+
+	// Creating the ConfigMap in another document clears the warning in the
+	// untouched Deployment.
+	open("file:///tmp/configmap.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+`)
+	if diags := waitFor(deploymentURI); len(diags) != 0 {
+		t.Fatalf("Expected the warning to clear, got %v", diags)
+	}
+
+	// Re-opening with the same resources leaves the other document alone.
+	open("file:///tmp/configmap.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  labels:
+    app: web
+`)
+	waitFor("file:///tmp/configmap.yaml")
+	select {
+	case uri := <-notified:
+		if uri == deploymentURI {
+			t.Error("Expected no re-validation when the indexed names are unchanged")
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+	publishing.Wait()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// errRescanRunning rejects a rescan while another scan is still indexing.
+var errRescanRunning = errors.New("the workspace is already being scanned; try again once it finishes")
+
+// scanGate keeps a rescan from clearing the Store under a scan that is
+// still running: background scans hold it for reading, a rescan for
+// writing.
+var scanGate sync.RWMutex
+
+// methodRescanCompleted is the notification a rescan sends its summary
+// with once it is done.
+const methodRescanCompleted = "k8s/rescanCompleted"
+
+// RescanSummary is what a rescan reports when it completes.
+type RescanSummary struct {
+	Files      int   `json:"files"`
+	Resources  int   `json:"resources"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// handleRescanWorkspace starts rebuilding the index from scratch: the
+// Store is cleared, every workspace folder is scanned again (concurrently),
+// and library roots, CRD sources and open documents are indexed on top.
+// The reply doesn't wait for the scan, which reports progress on the
+// client's token and sends its RescanSummary in a k8s/rescanCompleted
+// notification when done.
+func handleRescanWorkspace(context *glsp.Context, token *protocol.ProgressToken) error {
+	if !scanGate.TryLock() {
+		return errRescanRunning
+	}
+	roots := append([]string(nil), state.RootPaths...)
+	scanning.Add(1)
+	go func() {
+		defer scanning.Done()
+		defer scanGate.Unlock()
+		summary := rescanWorkspace(context, token, roots)
+		context.Notify(methodRescanCompleted, summary)
+	}()
+	return nil
+}
+
+// rescanWorkspace does the work of handleRescanWorkspace; the caller holds
+// scanGate.
+func rescanWorkspace(context *glsp.Context, token *protocol.ProgressToken, roots []string) *RescanSummary {
+	start := time.Now()
+	progress := newProgressReporter(context, token)
+	progress.begin("Rescanning workspace", fmt.Sprintf("0/%d folders", len(roots)))
+	log.Info().Strs("roots", roots).Msg("Rescanning workspace")
+
+	state.Store.Clear()
+	// A rescan is asked for when the index can't be trusted, so nothing
+	// cached is reused either.
+	state.Indexer.ResetCache()
+	// CRD kinds have to be known before the custom resources are scanned.
+	downloadCRDs()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	files, done := 0, 0
+	for _, root := range roots {
+		wg.Add(1)
+		go func(root string) {
+			defer wg.Done()
+			n, err := state.Indexer.ScanWorkspaceContext(state.scanContext(), root)
+			if err != nil {
+				log.Error().Err(err).Str("root", root).Msg("Failed to scan workspace folder")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			files += n
+			done++
+			progress.report(fmt.Sprintf("%d/%d folders", done, len(roots)), done*100/len(roots))
+		}(root)
+	}
+	wg.Wait()
+
+	if err := state.Indexer.ScanLibrariesContext(state.scanContext()); err != nil {
+		log.Error().Err(err).Msg("Failed to scan library roots")
+	}
+	saveIndexCache(1)
+	// Unsaved edits win over what is on disk.
+	docs := state.Documents.All()
+	for uri, content := range docs {
+		state.Indexer.IndexContent(uriToPath(uri), content)
+	}
+
+	summary := &RescanSummary{
+		Files:      files,
+		Resources:  state.Store.Len(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	progress.end(fmt.Sprintf("Indexed %d resources from %d files", summary.Resources, summary.Files))
+	log.Info().Int("files", summary.Files).Int("resources", summary.Resources).Int64("durationMs", summary.DurationMs).Msg("Workspace rescan completed")
+
+	state.indexKeys.Store(state.Store.KeysVersion())
+	publishInBackground(context, docs)
+	refreshCodeLenses(context)
+	return summary
+}
+
+// progressReporter sends $/progress notifications for a work done token
+// the client supplied; without one it does nothing.
+type progressReporter struct {
+	context *glsp.Context
+	token   *protocol.ProgressToken
+}
+
+func newProgressReporter(context *glsp.Context, token *protocol.ProgressToken) progressReporter {
+	return progressReporter{context: context, token: token}
+}
+
+func (p progressReporter) notify(value any) {
+	if p.token == nil {
+		return
+	}
+	p.context.Notify(string(protocol.MethodProgress), protocol.ProgressParams{Token: *p.token, Value: value})
+}
+
+func (p progressReporter) begin(title, message string) {
+	percentage := protocol.UInteger(0)
+	p.notify(protocol.WorkDoneProgressBegin{Kind: "begin", Title: title, Message: &message, Percentage: &percentage})
+}
+
+func (p progressReporter) report(message string, percent int) {
+	percentage := protocol.UInteger(percent)
+	p.notify(protocol.WorkDoneProgressReport{Kind: "report", Message: &message, Percentage: &percentage})
+}
+
+func (p progressReporter) end(message string) {
+	p.notify(protocol.WorkDoneProgressEnd{Kind: "end", Message: &message})
+}
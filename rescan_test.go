@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/resolver"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestRescanWorkspace(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	state = &ServerState{
+		Store:     store,
+		Indexer:   indexer.NewIndexer(store, cfg),
+		Resolver:  resolver.NewResolver(store, cfg),
+		Documents: NewDocumentStore(),
+	}
+
+	app, infra := t.TempDir(), t.TempDir()
+	for _, dir := range []string{app, infra} {
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: `+filepath.Base(dir)+`
+`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(infra, "notes.yml"), []byte("# nothing to index\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state.RootPaths = []string{app, infra}
+
+	// A resource from a file deleted behind the server's back is dropped.
+	state.Store.Add(&indexer.K8sResource{Kind: "ConfigMap", Name: "stale", FilePath: filepath.Join(app, "gone.yaml")})
+
+	var mu sync.Mutex
+	var kinds []string
+	var summary *RescanSummary
+	// The rescan runs in the background, holding off another one until it
+	// gets past its first progress notification.
+	release := make(chan struct{})
+	ctx := &glsp.Context{Notify: func(method string, params any) {
+		if progress, ok := params.(protocol.ProgressParams); ok {
+			if _, ok := progress.Value.(protocol.WorkDoneProgressBegin); ok {
+				<-release
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if method == methodRescanCompleted {
+			summary = params.(*RescanSummary)
+			return
+		}
+		if method != string(protocol.MethodProgress) {
+			return
+		}
+		switch v := params.(protocol.ProgressParams).Value.(type) {
+		case protocol.WorkDoneProgressBegin:
+			kinds = append(kinds, v.Kind)
+		case protocol.WorkDoneProgressReport:
+			kinds = append(kinds, v.Kind)
+		case protocol.WorkDoneProgressEnd:
+			kinds = append(kinds, v.Kind)
+		}
+	}}
+	token := protocol.ProgressToken{Value: "rescan-1"}
+	if err := handleRescanWorkspace(ctx, &token); err != nil {
+		t.Fatalf("handleRescanWorkspace failed: %v", err)
+	}
+	if err := handleRescanWorkspace(ctx, nil); !errors.Is(err, errRescanRunning) {
+		t.Errorf("Expected a concurrent rescan to be rejected, got %v", err)
+	}
+	close(release)
+	scanning.Wait()
+	publishing.Wait()
+	if summary == nil {
+		t.Fatal("Expected the summary once the rescan completed")
+	}
+	if summary.Files != 3 || summary.Resources != 2 {
+		t.Errorf("Expected 3 files and 2 resources, got %+v", summary)
+	}
+	if state.Store.Get("ConfigMap", "", "stale") != nil {
+		t.Error("Expected the stale resource to be cleared")
+	}
+	if len(kinds) != 4 || kinds[0] != "begin" || kinds[3] != "end" {
+		t.Errorf("Expected begin, a report per folder and end, got %v", kinds)
+	}
+
+	// A rescan is rejected while another scan is still running.
+	scanGate.RLock()
+	err := handleRescanWorkspace(ctx, nil)
+	scanGate.RUnlock()
+	if !errors.Is(err, errRescanRunning) {
+		t.Errorf("Expected the rescan to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// parseListen splits a --listen value into the network and address to
+// listen on. "stdio" (the default) gives an empty network.
+func parseListen(value string) (network, address string, err error) {
+	if value == "" || value == "stdio" {
+		return "", "", nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid --listen address %q: %w", value, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("--listen %q has no host:port", value)
+		}
+		return "tcp", u.Host, nil
+	case "unix":
+		// unix:///run/k8s-lsp.sock is absolute, unix://k8s-lsp.sock relative.
+		path := u.Host + u.Path
+		if path == "" {
+			return "", "", fmt.Errorf("--listen %q has no socket path", value)
+		}
+		return "unix", path, nil
+	}
+	return "", "", fmt.Errorf("unsupported --listen address %q; use stdio, tcp://host:port or unix:///path", value)
+}
+
+// serveSessions serves clients of listener one at a time until ctx is
+// done, which closes the listener and the session in progress. reset runs
+// before every session but the first, which uses the state main built.
+// glsp's own RunTCP serves connections concurrently, which the global
+// state can't support, and offers no way to stop listening.
+func serveSessions(ctx gocontext.Context, listener net.Listener, handler glsp.Handler, reset func()) error {
+	stop := gocontext.AfterFunc(ctx, func() {
+		listener.Close()
+	})
+	defer stop()
+
+	for session := 1; ; session++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if session > 1 {
+			reset()
+		}
+		log.Info().Int("session", session).Str("remote", conn.RemoteAddr().String()).Msg("Client connected")
+
+		serial := newSerialHandler(jsonrpc2.HandlerWithError(rpcHandler{handler}.handle))
+		rpc := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}), serial)
+		select {
+		case <-rpc.DisconnectNotify():
+		case <-ctx.Done():
+			rpc.Close()
+		}
+		serial.wait()
+		log.Info().Int("session", session).Msg("Client disconnected")
+	}
+}
+
+// sessionReset returns the reset for serveSessions: it waits out the
+// previous session's background work, then starts from a fresh state, or
+// with keepStore from one that keeps everything indexed so far.
+func sessionReset(rulesDir string, keepStore bool) func() {
+	return func() {
+		reconfiguring.Wait()
+		scanning.Wait()
+		publishing.Wait()
+
+		serverLifecycle.reset()
+		configMu.Lock()
+		defer configMu.Unlock()
+		if keepStore {
+			state = state.nextSession()
+		} else {
+			state = newServerState(rulesDir)
+		}
+	}
+}
+
+// nextSession starts a client session from s, keeping the index, rules
+// and validator but none of the previous client's documents, folders or
+// capabilities.
+func (s *ServerState) nextSession() *ServerState {
+	background, stop := gocontext.WithCancel(gocontext.Background())
+	return &ServerState{
+		Store:      s.Store,
+		Indexer:    s.Indexer,
+		Resolver:   s.Resolver,
+		Validator:  s.Validator,
+		Documents:  NewDocumentStore(),
+		CRDs:       s.CRDs,
+		CRDSources: s.CRDSources,
+		rulesDir:   s.rulesDir,
+		options:    s.options,
+
+		background:     background,
+		stopBackground: stop,
+	}
+}
+
+// serveStdio serves handler over stdin and stdout until the client
+// disconnects.
+func serveStdio(handler glsp.Handler) {
+	serial := newSerialHandler(jsonrpc2.HandlerWithError(rpcHandler{handler}.handle))
+	rpc := jsonrpc2.NewConn(gocontext.Background(), jsonrpc2.NewBufferedStream(stdio{}, jsonrpc2.VSCodeObjectCodec{}), serial)
+	<-rpc.DisconnectNotify()
+	serial.wait()
+}
+
+// stdio is stdin and stdout as one stream.
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdio) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdio) Close() error {
+	if err := os.Stdin.Close(); err != nil {
+		return err
+	}
+	return os.Stdout.Close()
+}
+
+// serialHandler handles the messages of a connection one at a time, in the
+// order they arrive, as jsonrpc2 does by itself; but it does so on a
+// goroutine of its own, so that the connection keeps reading meanwhile.
+// $/cancelRequest is handled as soon as it is read: waiting its turn, it
+// would only reach the request it names once that had finished.
+type serialHandler struct {
+	handler jsonrpc2.Handler
+
+	mu      sync.Mutex
+	queue   []*jsonrpc2.Request
+	started bool
+	ready   chan struct{} // signalled when queue gains a message
+	done    chan struct{} // closed once the connection is gone
+}
+
+func newSerialHandler(handler jsonrpc2.Handler) *serialHandler {
+	return &serialHandler{handler: handler, ready: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+func (h *serialHandler) Handle(ctx gocontext.Context, conn *jsonrpc2.Conn, request *jsonrpc2.Request) {
+	if request.Method == string(protocol.MethodCancelRequest) {
+		h.handler.Handle(ctx, conn, request)
+		return
+	}
+	if !request.Notif {
+		inflight.expect(request.ID.String())
+	}
+	h.mu.Lock()
+	h.queue = append(h.queue, request)
+	if !h.started {
+		h.started = true
+		go h.run(ctx, conn)
+	}
+	h.mu.Unlock()
+	select {
+	case h.ready <- struct{}{}:
+	default:
+	}
+}
+
+// run handles queued messages until the connection is gone. Those still
+// queued then are dropped, as they can't be answered.
+func (h *serialHandler) run(ctx gocontext.Context, conn *jsonrpc2.Conn) {
+	defer close(h.done)
+	defer h.drop()
+	for {
+		select {
+		case <-h.ready:
+		case <-conn.DisconnectNotify():
+			return
+		}
+		for {
+			select {
+			case <-conn.DisconnectNotify():
+				return
+			default:
+			}
+			h.mu.Lock()
+			if len(h.queue) == 0 {
+				h.mu.Unlock()
+				break
+			}
+			request := h.queue[0]
+			h.queue = h.queue[1:]
+			h.mu.Unlock()
+			h.handler.Handle(ctx, conn, request)
+		}
+	}
+}
+
+func (h *serialHandler) drop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, request := range h.queue {
+		if !request.Notif {
+			inflight.forget(request.ID.String())
+		}
+	}
+	h.queue = nil
+}
+
+// wait waits, once the connection is gone, for the message it was
+// handling.
+func (h *serialHandler) wait() {
+	h.mu.Lock()
+	started := h.started
+	h.mu.Unlock()
+	if started {
+		<-h.done
+	}
+}
+
+// rpcHandler serves a glsp.Handler over a jsonrpc2 connection the way the
+// glsp server does, also recording the ID of each request so that
+// $/cancelRequest can find its work.
+type rpcHandler struct {
+	handler glsp.Handler
+}
+
+func (h rpcHandler) handle(ctx gocontext.Context, conn *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+	context := &glsp.Context{
+		Method: request.Method,
+		Notify: func(method string, params any) {
+			if err := conn.Notify(ctx, method, params); err != nil {
+				log.Error().Err(err).Str("method", method).Msg("Failed to send notification")
+			}
+		},
+		Call: func(method string, params any, result any) {
+			if err := conn.Call(ctx, method, params, result); err != nil {
+				log.Error().Err(err).Str("method", method).Msg("Failed to call client")
+			}
+		},
+	}
+	if request.Params != nil {
+		context.Params = *request.Params
+	}
+
+	if !request.Notif {
+		inflight.begin(context, request.ID.String())
+		defer inflight.end(context)
+	}
+
+	if request.Method == "exit" {
+		h.handler.Handle(context)
+		return nil, conn.Close()
+	}
+	result, validMethod, validParams, err := h.handler.Handle(context)
+	switch {
+	case !validMethod:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", request.Method)}
+	case !validParams:
+		rpcErr := &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+		if err != nil {
+			rpcErr.Message = err.Error()
+		}
+		return nil, rpcErr
+	case err != nil:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: err.Error()}
+	}
+	return result, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestParseListen(t *testing.T) {
+	tests := []struct {
+		value, network, address string
+		wantErr                 bool
+	}{
+		{"stdio", "", "", false},
+		{"", "", "", false},
+		{"tcp://127.0.0.1:7432", "tcp", "127.0.0.1:7432", false},
+		{"unix:///run/k8s-lsp.sock", "unix", "/run/k8s-lsp.sock", false},
+		{"tcp://", "", "", true},
+		{"http://127.0.0.1:7432", "", "", true},
+	}
+	for _, tt := range tests {
+		network, address, err := parseListen(tt.value)
+		if (err != nil) != tt.wantErr || network != tt.network || address != tt.address {
+			t.Errorf("parseListen(%q) = %q, %q, %v", tt.value, network, address, err)
+		}
+	}
+}
+
+func TestTCPSessions(t *testing.T) {
+	dir := t.TempDir()
+	configMapPath := filepath.Join(dir, "config.yaml")
+	configMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+`
+	deploymentPath := filepath.Join(dir, "deploy.yaml")
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - configMapRef:
+                name: app-config
+`
+	for path, content := range map[string]string{configMapPath: configMap, deploymentPath: deployment} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state = newServerState("rules")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serveSessions(ctx, listener, newHandler(), sessionReset("rules", false))
+	}()
+
+	connect := func() *jsonrpc2.Conn {
+		t.Helper()
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The client ignores notifications and accepts every server request.
+		client := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}),
+			jsonrpc2.HandlerWithError(func(gocontext.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+				return nil, nil
+			}))
+		var result json.RawMessage
+		if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &result); err != nil {
+			t.Fatalf("initialize failed: %v", err)
+		}
+		if err := client.Notify(ctx, "initialized", protocol.InitializedParams{}); err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+	definition := func(client *jsonrpc2.Conn) []protocol.LocationLink {
+		t.Helper()
+		var links []protocol.LocationLink
+		if err := client.Call(ctx, "textDocument/definition", protocol.DefinitionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file://" + deploymentPath},
+				Position:     protocol.Position{Line: 11, Character: 24},
+			},
+		}, &links); err != nil {
+			t.Fatalf("definition failed: %v", err)
+		}
+		return links
+	}
+	disconnect := func(client *jsonrpc2.Conn) {
+		t.Helper()
+		if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+			t.Fatalf("shutdown failed: %v", err)
+		}
+		client.Notify(ctx, "exit", nil)
+		<-client.DisconnectNotify()
+	}
+
+	client := connect()
+	for path, content := range map[string]string{configMapPath: configMap, deploymentPath: deployment} {
+		if err := client.Notify(ctx, "textDocument/didOpen", protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: "file://" + path, LanguageID: "yaml", Version: 1, Text: content},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if links := definition(client); len(links) != 1 || links[0].TargetURI != "file://"+configMapPath {
+		t.Errorf("Expected the ConfigMap over TCP, got %v", links)
+	}
+	disconnect(client)
+
+	// The next client starts from an empty index.
+	client = connect()
+	if links := definition(client); len(links) != 0 {
+		t.Errorf("Expected a fresh session not to know the ConfigMap, got %v", links)
+	}
+	if len(state.Documents.All()) != 0 {
+		t.Error("Expected a fresh session without open documents")
+	}
+
+	cancel()
+	<-client.DisconnectNotify()
+	if err := <-served; err != nil {
+		t.Errorf("Expected the server to stop cleanly, got %v", err)
+	}
+	scanning.Wait()
+	publishing.Wait()
+}
+
+func TestNextSessionKeepsStore(t *testing.T) {
+	state = newServerState("rules")
+	state.Documents.Set("file:///ws/a.yaml", "kind: ConfigMap\n", 1)
+	state.RootPaths = []string{"/ws"}
+	next := state.nextSession()
+	if next.Store != state.Store || next.Resolver != state.Resolver {
+		t.Error("Expected the index to carry over")
+	}
+	if len(next.Documents.All()) != 0 || len(next.RootPaths) != 0 {
+		t.Error("Expected documents and folders to start empty")
+	}
+}
+
+// slowDefinitions stands in for a definition lookup that runs until its
+// request is cancelled.
+type slowDefinitions struct {
+	glsp.Handler
+}
+
+func (h slowDefinitions) Handle(context *glsp.Context) (any, bool, bool, error) {
+	if context.Method != string(protocol.MethodTextDocumentDefinition) {
+		return h.Handler.Handle(context)
+	}
+	links, _ := withinBudgetContext(context, "definition", time.Minute, func(ctx gocontext.Context) ([]protocol.LocationLink, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	return links, true, true, nil
+}
+
+func TestCancelRequestOverConnection(t *testing.T) {
+	state = newServerState("rules")
+	serverSide, clientSide := net.Pipe()
+	ctx := gocontext.Background()
+	serial := newSerialHandler(jsonrpc2.HandlerWithError(rpcHandler{slowDefinitions{newHandler()}}.handle))
+	server := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(serverSide, jsonrpc2.VSCodeObjectCodec{}), serial)
+	defer server.Close()
+	client := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(clientSide, jsonrpc2.VSCodeObjectCodec{}),
+		jsonrpc2.HandlerWithError(func(gocontext.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+			return nil, nil
+		}))
+	defer client.Close()
+	var result json.RawMessage
+	if err := client.Call(ctx, "initialize", protocol.InitializeParams{}, &result); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	call, err := client.DispatchCall(ctx, "textDocument/definition", protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///ws/deploy.yaml"},
+		},
+	}, jsonrpc2.PickID(jsonrpc2.ID{Num: 7}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// net.Pipe is unbuffered: the cancellation is only sent once the
+	// server reads it.
+	go client.Notify(ctx, "$/cancelRequest", map[string]any{"id": 7})
+
+	waitCtx, cancel := gocontext.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var links []protocol.LocationLink
+	if err := call.Wait(waitCtx, &links); err != nil {
+		t.Fatalf("Expected the cancelled request to be answered promptly, got %v", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("Expected no results from the cancelled request, got %v", links)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// WatchedChange is the last event seen for a path within a window.
+type WatchedChange struct {
+	Path string
+	Type protocol.UInteger // protocol.FileChangeType*
+}
+
+// WatchCoalescer batches watched-file events: the first event of a burst
+// starts a window, and when it closes flush receives one change per path in
+// first-seen order, carrying that path's latest event type. A bulk git
+// checkout thus re-indexes each file once.
+type WatchCoalescer struct {
+	window time.Duration
+	flush  func([]WatchedChange)
+
+	mu      sync.Mutex
+	pending map[string]protocol.UInteger
+	order   []string
+	timer   *time.Timer
+}
+
+func NewWatchCoalescer(window time.Duration, flush func([]WatchedChange)) *WatchCoalescer {
+	return &WatchCoalescer{
+		window:  window,
+		flush:   flush,
+		pending: make(map[string]protocol.UInteger),
+	}
+}
+
+// Add records an event for path, starting the window if none is open.
+func (c *WatchCoalescer) Add(path string, changeType protocol.UInteger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.pending[path]; !ok {
+		c.order = append(c.order, path)
+	}
+	c.pending[path] = changeType
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.window, c.Flush)
+	}
+}
+
+// Flush hands the pending changes to the flush function now. It does
+// nothing when no events are pending.
+func (c *WatchCoalescer) Flush() {
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.order) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	changes := make([]WatchedChange, 0, len(c.order))
+	for _, path := range c.order {
+		changes = append(changes, WatchedChange{Path: path, Type: c.pending[path]})
+	}
+	c.pending = make(map[string]protocol.UInteger)
+	c.order = nil
+	c.mu.Unlock()
+
+	c.flush(changes)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestWatchCoalescer(t *testing.T) {
+	var mu sync.Mutex
+	var flushes [][]WatchedChange
+	flushed := make(chan struct{}, 10)
+	c := NewWatchCoalescer(20*time.Millisecond, func(changes []WatchedChange) {
+		mu.Lock()
+		flushes = append(flushes, changes)
+		mu.Unlock()
+		flushed <- struct{}{}
+	})
+
+	// A checkout touching 100 files, each reported several times.
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 100; i++ {
+			c.Add(fmt.Sprintf("/ws/file-%03d.yaml", i), protocol.FileChangeTypeChanged)
+		}
+	}
+	c.Add("/ws/gone.yaml", protocol.FileChangeTypeCreated)
+	c.Add("/ws/gone.yaml", protocol.FileChangeTypeDeleted)
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the window to flush")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(flushes) != 1 {
+		t.Fatalf("Expected a single flush, got %d", len(flushes))
+	}
+	changes := flushes[0]
+	if len(changes) != 101 {
+		t.Fatalf("Expected one change per file, got %d", len(changes))
+	}
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		if seen[change.Path] {
+			t.Fatalf("File %s re-indexed more than once", change.Path)
+		}
+		seen[change.Path] = true
+	}
+	if changes[0].Path != "/ws/file-000.yaml" {
+		t.Errorf("Expected first-seen order, got %s first", changes[0].Path)
+	}
+	if last := changes[100]; last.Path != "/ws/gone.yaml" || last.Type != protocol.FileChangeTypeDeleted {
+		t.Errorf("Expected the latest event to win, got %+v", last)
+	}
+}
+
+func TestWatchCoalescerFlushWithoutEvents(t *testing.T) {
+	calls := 0
+	c := NewWatchCoalescer(time.Hour, func([]WatchedChange) { calls++ })
+	c.Flush()
+	if calls != 0 {
+		t.Fatal("Expected no flush without pending events")
+	}
+
+	c.Add("/ws/a.yaml", protocol.FileChangeTypeChanged)
+	c.Flush()
+	c.Flush()
+	if calls != 1 {
+		t.Fatalf("Expected an explicit flush to drain the window once, got %d", calls)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"slices"
+	"sync"
+
+	"k8s-lsp/pkg/uri"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// workspaceDiagnostics remembers the files that aren't open which
+// diagnostics were published for, so they are cleared once the file is
+// deleted, fixed or the option is turned off.
+type workspaceDiagnostics struct {
+	mu        sync.Mutex
+	published map[string]bool
+}
+
+// any reports whether diagnostics are published for any file.
+func (w *workspaceDiagnostics) any() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.published) > 0
+}
+
+// scheduleWorkspaceValidation validates the files that aren't open once
+// the index has been quiet for the change debounce. Nothing is scheduled
+// while the option is off and there is nothing left to clear.
+func scheduleWorkspaceValidation() {
+	if state.WorkspaceValidation == nil {
+		return
+	}
+	if state.options.workspaceDiagnostics() == workspaceDiagnosticsOff && !state.workspaceDiagnostics.any() {
+		return
+	}
+	state.WorkspaceValidation.Schedule("")
+}
+
+// validateWorkspace publishes diagnostics for the indexed files that aren't
+// open, as selected by the workspaceDiagnostics option, reading each from
+// disk. Open documents publish their own; library roots are read-only and
+// are never reported. Files that had diagnostics and no longer do, or are
+// no longer selected, get theirs cleared.
+func validateWorkspace(context *glsp.Context) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	w := &state.workspaceDiagnostics
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var paths []string
+	if mode := state.options.workspaceDiagnostics(); mode != workspaceDiagnosticsOff && state.Validator != nil && !state.DiagnosticPull {
+		paths = workspaceFiles(mode == workspaceDiagnosticsReferences)
+	}
+
+	visited := make(map[string]bool, len(paths))
+	published := make(map[string]bool)
+	for _, path := range paths {
+		fileURI := uri.FromPath(path)
+		if _, open := state.Documents.Get(fileURI); open {
+			continue
+		}
+		content, ok := loadFromDisk(fileURI)
+		if !ok {
+			continue
+		}
+		visited[fileURI] = true
+		diagnostics := documentDiagnostics(fileURI, content)
+		if len(diagnostics) == 0 && !w.published[fileURI] {
+			continue
+		}
+		context.Notify(string(protocol.ServerTextDocumentPublishDiagnostics), protocol.PublishDiagnosticsParams{
+			URI:         fileURI,
+			Diagnostics: diagnostics,
+		})
+		if len(diagnostics) > 0 {
+			published[fileURI] = true
+		}
+	}
+
+	for fileURI := range w.published {
+		if visited[fileURI] {
+			continue
+		}
+		// An open document publishes over what was reported for the file.
+		if _, open := state.Documents.Get(fileURI); open {
+			continue
+		}
+		context.Notify(string(protocol.ServerTextDocumentPublishDiagnostics), protocol.PublishDiagnosticsParams{
+			URI:         fileURI,
+			Diagnostics: []protocol.Diagnostic{},
+		})
+	}
+	if len(paths) > 0 || len(w.published) > 0 {
+		log.Debug().Int("files", len(visited)).Int("withDiagnostics", len(published)).Msg("Validated workspace files")
+	}
+	w.published = published
+}
+
+// workspaceFiles returns the files resources were indexed from, outside
+// library roots, in order. With referencing set, only files with a
+// resource making a reference are returned.
+func workspaceFiles(referencing bool) []string {
+	seen := make(map[string]bool)
+	for _, res := range state.Store.Snapshot() {
+		if res.Library || res.FilePath == "" || (referencing && len(res.References) == 0) {
+			continue
+		}
+		seen[res.FilePath] = true
+	}
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-lsp/pkg/uri"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestValidateWorkspace(t *testing.T) {
+	state = newServerState("rules")
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		state.Indexer.IndexFile(path)
+		return path
+	}
+	deployPath := write("deploy.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector: {matchLabels: {app: web}}
+  template:
+    spec:
+      serviceAccountName: builder
+`)
+	write("config.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n")
+	deployURI := uri.FromPath(deployPath)
+
+	var published map[string][]protocol.Diagnostic
+	ctx := &glsp.Context{Notify: func(method string, params any) {
+		if p, ok := params.(protocol.PublishDiagnosticsParams); ok {
+			published[p.URI] = p.Diagnostics
+		}
+	}}
+	validate := func() {
+		published = make(map[string][]protocol.Diagnostic)
+		validateWorkspace(ctx)
+	}
+
+	validate()
+	if len(published) != 0 {
+		t.Fatalf("Expected nothing while the option is off, got %v", published)
+	}
+
+	state.options.WorkspaceDiagnostics = workspaceDiagnosticsReferences
+	validate()
+	if diags, ok := published[deployURI]; !ok || len(diags) != 1 || diags[0].Message != "ServiceAccount not found (Kind: ServiceAccount, Name: builder)" {
+		t.Fatalf("Expected the unopened Deployment's missing ServiceAccount, got %v", published)
+	}
+	if len(published) != 1 {
+		t.Errorf("Expected only the referencing file to be validated, got %v", published)
+	}
+
+	// Open documents publish their own diagnostics.
+	state.Documents.Set(deployURI, "kind: Deployment\n", 1)
+	validate()
+	if _, ok := published[deployURI]; ok {
+		t.Errorf("Expected an open document to be left alone, got %v", published[deployURI])
+	}
+	state.Documents.Delete(deployURI)
+
+	validate()
+	if len(published[deployURI]) != 1 {
+		t.Fatalf("Expected the closed document to be reported again, got %v", published)
+	}
+
+	// Deleting the file clears what was published for it.
+	if err := os.Remove(deployPath); err != nil {
+		t.Fatal(err)
+	}
+	state.Indexer.RemoveFile(deployPath)
+	validate()
+	if diags, ok := published[deployURI]; !ok || len(diags) != 0 {
+		t.Errorf("Expected the deleted file's diagnostics to be cleared, got %v", published)
+	}
+	validate()
+	if len(published) != 0 {
+		t.Errorf("Expected a cleared file to stay quiet, got %v", published)
+	}
+}
+
+func TestValidateWorkspaceTurnedOff(t *testing.T) {
+	state = newServerState("rules")
+	path := filepath.Join(t.TempDir(), "pod.yaml")
+	if err := os.WriteFile(path, []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  serviceAccountName: builder\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state.Indexer.IndexFile(path)
+
+	var cleared []string
+	count := 0
+	ctx := &glsp.Context{Notify: func(method string, params any) {
+		p := params.(protocol.PublishDiagnosticsParams)
+		count++
+		if len(p.Diagnostics) == 0 {
+			cleared = append(cleared, p.URI)
+		}
+	}}
+	state.options.WorkspaceDiagnostics = workspaceDiagnosticsAll
+	validateWorkspace(ctx)
+	if count != 1 || len(cleared) != 0 {
+		t.Fatalf("Expected the Pod to be reported, got %d publishes", count)
+	}
+
+	state.options.WorkspaceDiagnostics = workspaceDiagnosticsOff
+	validateWorkspace(ctx)
+	if len(cleared) != 1 || cleared[0] != uri.FromPath(path) {
+		t.Errorf("Expected turning the option off to clear the Pod, got %v", cleared)
+	}
+}
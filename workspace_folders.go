@@ -0,0 +1,208 @@
+package main
+
+import (
+	gocontext "context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s-lsp/pkg/uri"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const watchersRegistrationID = "k8s-lsp-yaml-watchers"
+
+// watchRegistration orders the unregister/register round trips of
+// successive registerWatchers calls.
+var watchRegistration sync.Mutex
+
+// scanning tracks folders added mid-session being indexed in the
+// background, so tests can wait for them.
+var scanning sync.WaitGroup
+
+// workspaceRoots returns the local folders the client opened: every
+// workspace folder of a multi-root workspace, otherwise the single rootUri
+// (or the deprecated rootPath).
+func workspaceRoots(params *protocol.InitializeParams) []string {
+	var roots []string
+	for _, folder := range params.WorkspaceFolders {
+		if root, ok := fileURIPath(folder.URI); ok {
+			roots = append(roots, root)
+		}
+	}
+	if len(roots) > 0 {
+		return roots
+	}
+	if params.RootURI != nil {
+		if root, ok := fileURIPath(*params.RootURI); ok {
+			return []string{root}
+		}
+	} else if params.RootPath != nil && *params.RootPath != "" {
+		return []string{*params.RootPath}
+	}
+	return nil
+}
+
+func fileURIPath(folderURI string) (string, bool) {
+	path, err := uri.ToPath(folderURI)
+	return path, err == nil
+}
+
+// primaryRoot is the first workspace folder, against which relative
+// settings such as libraryRoots are resolved.
+func primaryRoot() string {
+	if len(state.RootPaths) == 0 {
+		return ""
+	}
+	return state.RootPaths[0]
+}
+
+// scanRoots indexes every YAML file under roots.
+func scanRoots(roots []string) {
+	scanGate.RLock()
+	defer scanGate.RUnlock()
+	for _, root := range roots {
+		if _, err := state.Indexer.ScanWorkspaceContext(state.scanContext(), root); errors.Is(err, gocontext.Canceled) {
+			return
+		} else if err != nil {
+			log.Error().Err(err).Str("root", root).Msg("Failed to scan workspace folder")
+		}
+	}
+}
+
+func workspaceDidChangeWorkspaceFolders(context *glsp.Context, params *protocol.DidChangeWorkspaceFoldersParams) error {
+	removed := false
+	var rescan []string
+	for _, folder := range params.Event.Removed {
+		root, ok := fileURIPath(folder.URI)
+		if !ok {
+			continue
+		}
+		state.RootPaths = removeRoot(state.RootPaths, root)
+		// A folder nested in one that stays open is still part of the
+		// workspace.
+		if containingRoot(state.RootPaths, root) != "" {
+			continue
+		}
+		files := state.Indexer.RemovePathPrefix(root)
+		log.Info().Str("root", root).Int("files", files).Msg("Workspace folder removed")
+		removed = true
+		// Folders still open inside the removed one lost their resources too.
+		for _, r := range state.RootPaths {
+			if isWithin(r, root) && !containsPath(rescan, r) {
+				rescan = append(rescan, r)
+			}
+		}
+	}
+
+	added := rescan
+	for _, folder := range params.Event.Added {
+		root, ok := fileURIPath(folder.URI)
+		if !ok || containsPath(state.RootPaths, root) {
+			continue
+		}
+		state.RootPaths = append(state.RootPaths, root)
+		log.Info().Str("root", root).Msg("Workspace folder added")
+		if containingRoot(state.RootPaths, root) == root && !containsPath(added, root) {
+			added = append(added, root)
+		}
+	}
+
+	registerWatchers(context)
+	if len(added) == 0 && !removed {
+		return nil
+	}
+	scanning.Add(1)
+	go func() {
+		defer scanning.Done()
+		scanRoots(added)
+		republishOnIndexChange(context, "")
+		refreshCodeLenses(context)
+	}()
+	return nil
+}
+
+// containingRoot returns the outermost root that contains path, or "".
+func containingRoot(roots []string, path string) string {
+	found := ""
+	for _, root := range roots {
+		if isWithin(path, root) && (found == "" || len(root) < len(found)) {
+			found = root
+		}
+	}
+	return found
+}
+
+func isWithin(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func containsPath(roots []string, root string) bool {
+	for _, r := range roots {
+		if filepath.Clean(r) == filepath.Clean(root) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeRoot(roots []string, root string) []string {
+	out := roots[:0]
+	for _, r := range roots {
+		if filepath.Clean(r) != filepath.Clean(root) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// watchedFileGlobs are registered under each workspace folder. Brace
+// expansion isn't supported by every client's glob matcher, so each
+// extension gets its own pattern; kustomization.yaml is covered by the first.
+var watchedFileGlobs = []string{"**/*.yaml", "**/*.yml", "**/*.json"}
+
+// registerWatchers asks the client to report manifest changes under every
+// workspace folder, replacing any earlier registration. Clients that can't
+// register watchers dynamically keep their own (e.g. the VS Code extension's
+// fileEvents glob). The client answers with an empty result; failures are
+// logged by the connection and otherwise ignored.
+func registerWatchers(context *glsp.Context) {
+	if !state.WatcherRegistration {
+		return
+	}
+	var watchers []protocol.FileSystemWatcher
+	for _, root := range state.RootPaths {
+		for _, glob := range watchedFileGlobs {
+			watchers = append(watchers, protocol.FileSystemWatcher{
+				GlobPattern: strings.TrimSuffix(filepath.ToSlash(root), "/") + "/" + glob,
+			})
+		}
+	}
+	registered := state.watchersRegistered
+	state.watchersRegistered = len(watchers) > 0
+
+	go func() {
+		watchRegistration.Lock()
+		defer watchRegistration.Unlock()
+		method := string(protocol.MethodWorkspaceDidChangeWatchedFiles)
+		if registered {
+			context.Call(string(protocol.ServerClientUnregisterCapability), protocol.UnregistrationParams{
+				Unregisterations: []protocol.Unregistration{{ID: watchersRegistrationID, Method: method}},
+			}, nil)
+		}
+		if len(watchers) > 0 {
+			context.Call(string(protocol.ServerClientRegisterCapability), protocol.RegistrationParams{
+				Registrations: []protocol.Registration{{
+					ID:              watchersRegistrationID,
+					Method:          method,
+					RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+				}},
+			}, nil)
+		}
+	}()
+}
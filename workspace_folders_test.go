@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s-lsp/pkg/config"
+	"k8s-lsp/pkg/indexer"
+	"k8s-lsp/pkg/resolver"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestWorkspaceRoots(t *testing.T) {
+	rootURI := "file:///ws/app"
+	params := &protocol.InitializeParams{
+		RootURI: &rootURI,
+		WorkspaceFolders: []protocol.WorkspaceFolder{
+			{URI: "file:///ws/app", Name: "app"},
+			{URI: "file:///ws/infra", Name: "infra"},
+		},
+	}
+	if got := workspaceRoots(params); !reflect.DeepEqual(got, []string{"/ws/app", "/ws/infra"}) {
+		t.Errorf("Expected both workspace folders, got %v", got)
+	}
+
+	params.WorkspaceFolders = nil
+	if got := workspaceRoots(params); !reflect.DeepEqual(got, []string{"/ws/app"}) {
+		t.Errorf("Expected rootUri without workspace folders, got %v", got)
+	}
+}
+
+func TestDidChangeWorkspaceFolders(t *testing.T) {
+	cfg := &config.Config{
+		Symbols: []config.Symbol{
+			{
+				Name: "k8s.resource.name",
+				Definitions: []config.SymbolDefinition{
+					{Kinds: []string{"ConfigMap"}, Path: "metadata.name"},
+				},
+			},
+		},
+	}
+	store := indexer.NewStore()
+	state = &ServerState{
+		Store:     store,
+		Indexer:   indexer.NewIndexer(store, cfg),
+		Resolver:  resolver.NewResolver(store, cfg),
+		Documents: NewDocumentStore(),
+	}
+	ctx := &glsp.Context{Notify: func(string, any) {}}
+
+	writeConfigMap := func(dir, name string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: `+name+`
+`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	app := writeConfigMap(t.TempDir(), "app")
+	infra := writeConfigMap(t.TempDir(), "infra")
+	state.RootPaths = []string{app}
+	scanRoots(state.RootPaths)
+
+	wait := func() {
+		scanning.Wait()
+		publishing.Wait()
+	}
+
+	workspaceDidChangeWorkspaceFolders(ctx, &protocol.DidChangeWorkspaceFoldersParams{
+		Event: protocol.WorkspaceFoldersChangeEvent{
+			Added: []protocol.WorkspaceFolder{{URI: "file://" + infra, Name: "infra"}},
+		},
+	})
+	wait()
+	if store.Get("ConfigMap", "", "infra") == nil {
+		t.Error("Expected the added folder to be indexed")
+	}
+	if !reflect.DeepEqual(state.RootPaths, []string{app, infra}) {
+		t.Errorf("Expected both roots, got %v", state.RootPaths)
+	}
+
+	workspaceDidChangeWorkspaceFolders(ctx, &protocol.DidChangeWorkspaceFoldersParams{
+		Event: protocol.WorkspaceFoldersChangeEvent{
+			Removed: []protocol.WorkspaceFolder{{URI: "file://" + app, Name: "app"}},
+		},
+	})
+	wait()
+	if store.Get("ConfigMap", "", "app") != nil {
+		t.Error("Expected the removed folder's resources to be dropped")
+	}
+	if store.Get("ConfigMap", "", "infra") == nil {
+		t.Error("Expected the remaining folder to stay indexed")
+	}
+	if !reflect.DeepEqual(state.RootPaths, []string{infra}) {
+		t.Errorf("Expected only the infra root, got %v", state.RootPaths)
+	}
+}
+
+func TestRegisterWatchersCoversEveryRoot(t *testing.T) {
+	type call struct {
+		method string
+		params any
+	}
+	calls := make(chan call, 4)
+	ctx := &glsp.Context{Call: func(method string, params any, result any) {
+		calls <- call{method, params}
+	}}
+	next := func() call {
+		t.Helper()
+		select {
+		case c := <-calls:
+			return c
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected a request to the client")
+		}
+		return call{}
+	}
+
+	state = &ServerState{RootPaths: []string{"/ws/app", "/ws/infra"}}
+	registerWatchers(ctx)
+	select {
+	case c := <-calls:
+		t.Fatalf("Expected no registration without client support, got %v", c)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	state.WatcherRegistration = true
+	registerWatchers(ctx)
+	c := next()
+	if c.method != string(protocol.ServerClientRegisterCapability) {
+		t.Fatalf("Expected client/registerCapability, got %s", c.method)
+	}
+	reg := c.params.(protocol.RegistrationParams).Registrations[0]
+	if reg.Method != string(protocol.MethodWorkspaceDidChangeWatchedFiles) {
+		t.Errorf("Expected a didChangeWatchedFiles registration, got %s", reg.Method)
+	}
+	var globs []string
+	for _, w := range reg.RegisterOptions.(protocol.DidChangeWatchedFilesRegistrationOptions).Watchers {
+		globs = append(globs, w.GlobPattern)
+	}
+	want := []string{"/ws/app/**/*.yaml", "/ws/app/**/*.yml", "/ws/app/**/*.json", "/ws/infra/**/*.yaml", "/ws/infra/**/*.yml", "/ws/infra/**/*.json"}
+	if !reflect.DeepEqual(globs, want) {
+		t.Errorf("Expected %v, got %v", want, globs)
+	}
+
+	// A folder change replaces the earlier registration.
+	state.RootPaths = []string{"/ws/infra"}
+	registerWatchers(ctx)
+	if c := next(); c.method != string(protocol.ServerClientUnregisterCapability) {
+		t.Errorf("Expected client/unregisterCapability first, got %s", c.method)
+	}
+	c = next()
+	watchers := c.params.(protocol.RegistrationParams).Registrations[0].RegisterOptions.(protocol.DidChangeWatchedFilesRegistrationOptions).Watchers
+	if c.method != string(protocol.ServerClientRegisterCapability) || len(watchers) != len(watchedFileGlobs) {
+		t.Errorf("Expected watchers for the remaining root, got %s %v", c.method, watchers)
+	}
+}
+
+func TestLibraryRootsScannedWithoutWorkspace(t *testing.T) {
+	lib := t.TempDir()
+	if err := os.WriteFile(filepath.Join(lib, "config.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: shared\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	state = newServerState("rules")
+	defer state.stopBackground()
+	state.Indexer.Config.Settings.LibraryRoots = []string{lib}
+	ctx := &glsp.Context{Notify: func(string, any) {}, Call: func(string, any, any) {}}
+	if err := initialized(ctx, &protocol.InitializedParams{}); err != nil {
+		t.Fatal(err)
+	}
+	scanning.Wait()
+
+	if res := state.Store.Get("ConfigMap", "default", "shared"); res == nil || !res.Library {
+		t.Fatalf("Expected the library root to be indexed without a workspace root, got %+v", res)
+	}
+}